@@ -5,8 +5,11 @@ import { api, APIError } from "@/lib/api";
 
 export type AuthedUser = {
   id: string;
+  role: UserRole;
 };
 
+export type UserRole = "admin" | "operator" | "read_only";
+
 export function useAuthedUser() {
   const queryFn = useCallback(async () => {
     try {
@@ -0,0 +1,87 @@
+import { useMutation, useQuery } from "@tanstack/react-query";
+
+import { api } from "@/lib/api";
+
+export type ChillstreamsDevice = {
+  first_seen_at: string;
+  id: string;
+  is_revoked: boolean;
+  kind: "fingerprint" | "token";
+  label: string;
+  last_ip: string;
+  last_seen_at: string;
+  stream_count: number;
+  user_agent: string;
+};
+
+export type UpdateChillstreamsDeviceParams = {
+  id: string;
+  label: string;
+  userId: string;
+};
+
+export function useChillstreamsDeviceMutation() {
+  const update = useMutation({
+    mutationFn: updateChillstreamsDevice,
+    onSuccess: async (data, { userId }, __, ctx) => {
+      const queryKey = ["/chillstreams/users/{userId}/devices", userId];
+      const list = ctx.client.getQueryData<ChillstreamsDevice[]>(queryKey);
+      if (list) {
+        ctx.client.setQueryData(
+          queryKey,
+          list.map((item) => (item.id === data.id ? data : item)),
+        );
+      }
+    },
+  });
+
+  const revoke = useMutation({
+    mutationFn: revokeChillstreamsDevice,
+    onSuccess: async (_, { userId }, __, ctx) => {
+      await ctx.client.invalidateQueries({
+        queryKey: ["/chillstreams/users/{userId}/devices", userId],
+      });
+    },
+  });
+
+  return { revoke, update };
+}
+
+export function useChillstreamsDevices(userId: string) {
+  return useQuery({
+    enabled: Boolean(userId),
+    queryFn: () => getChillstreamsDevices(userId),
+    queryKey: ["/chillstreams/users/{userId}/devices", userId],
+  });
+}
+
+async function getChillstreamsDevices(userId: string) {
+  const { data } = await api<ChillstreamsDevice[]>(
+    `/chillstreams/users/${userId}/devices`,
+  );
+  return data;
+}
+
+async function revokeChillstreamsDevice({
+  id,
+  userId,
+}: {
+  id: string;
+  userId: string;
+}) {
+  await api(`DELETE /chillstreams/users/${userId}/devices/${id}`);
+}
+
+async function updateChillstreamsDevice({
+  id,
+  label,
+  userId,
+}: UpdateChillstreamsDeviceParams) {
+  const { data } = await api<ChillstreamsDevice>(
+    `PATCH /chillstreams/users/${userId}/devices/${id}`,
+    {
+      body: { label },
+    },
+  );
+  return data;
+}
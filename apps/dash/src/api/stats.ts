@@ -19,6 +19,7 @@ type ServerStats = {
     vault: boolean;
   };
   integration: {
+    chillstreams: boolean;
     trakt: boolean;
   };
   started_at: string;
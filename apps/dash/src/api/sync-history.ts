@@ -0,0 +1,78 @@
+import { useMutation, useQuery } from "@tanstack/react-query";
+
+import { api } from "@/lib/api";
+
+export type SyncChange = {
+  action: SyncChangeAction;
+  after?: unknown;
+  before?: unknown;
+  item_id: string;
+  target: "stremio" | "trakt";
+  target_account_id: string;
+};
+
+export type SyncChangeAction =
+  | "stremio.library.update"
+  | "trakt.history.add"
+  | "trakt.playback.pause"
+  | "trakt.playback.remove"
+  | "trakt.watchlist.add"
+  | "trakt.watchlist.remove";
+
+export type SyncDryRunResult = {
+  changes: SyncChange[];
+  error?: string;
+};
+
+export type SyncHistoryEntry = SyncChange & {
+  created_at: string;
+  id: number;
+  job_id: string;
+  reverted_at?: string;
+};
+
+export type SyncRevertResult = {
+  conflicts: (SyncHistoryEntry & { reason: string })[];
+  reverted: SyncHistoryEntry[];
+};
+
+// linkPath is the path of the link, e.g. `/sync/stremio-trakt/links/{id}`
+export function useSyncHistory(linkPath: string, enabled = true) {
+  return useQuery({
+    enabled,
+    queryFn: () => getSyncHistory(linkPath),
+    queryKey: ["/sync/{link}/history", linkPath],
+  });
+}
+
+export function useSyncHistoryMutation(linkPath: string) {
+  const dryRun = useMutation({
+    mutationFn: () => dryRunSync(linkPath),
+  });
+
+  const revert = useMutation({
+    mutationFn: () => revertLastSync(linkPath),
+    onSuccess: async (_, __, ___, ctx) => {
+      await ctx.client.invalidateQueries({
+        queryKey: ["/sync/{link}/history", linkPath],
+      });
+    },
+  });
+
+  return { dryRun, revert };
+}
+
+async function dryRunSync(linkPath: string) {
+  const { data } = await api<SyncDryRunResult>(`POST ${linkPath}/dry-run`);
+  return data;
+}
+
+async function getSyncHistory(linkPath: string) {
+  const { data } = await api<SyncHistoryEntry[]>(`${linkPath}/history`);
+  return data;
+}
+
+async function revertLastSync(linkPath: string) {
+  const { data } = await api<SyncRevertResult>(`POST ${linkPath}/revert`);
+  return data;
+}
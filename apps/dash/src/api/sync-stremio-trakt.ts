@@ -18,27 +18,63 @@ export type StremioTraktLink = {
 };
 
 export type SyncConfig = {
+  progress: SyncConfigProgress;
+  ratings: SyncConfigRatings;
   watched: SyncConfigWatched;
+  watchlist: SyncConfigWatchlist;
+};
+
+export type SyncConfigProgress = {
+  dir: SyncDirection;
+  on_remove: SyncRemovalPolicy;
+};
+
+export type SyncConfigRatings = {
+  dir: Extract<SyncDirection, "none" | "trakt_to_stremio">;
 };
 
 export type SyncConfigWatched = {
   dir: SyncDirection;
 };
 
+export type SyncConfigWatchlist = {
+  dir: SyncDirection;
+  on_remove: SyncRemovalPolicy;
+};
+
 export type SyncDirection =
   | "both"
   | "none"
   | "stremio_to_trakt"
   | "trakt_to_stremio";
 
+export type SyncRemovalPolicy = "keep" | "propagate";
+
 export type SyncState = {
+  progress: SyncStateProgress;
+  ratings: SyncStateRatings;
   watched: SyncStateWatched;
+  watchlist: SyncStateWatchlist;
+};
+
+export type SyncStateProgress = {
+  ids?: string[];
+  last_synced_at?: string;
+};
+
+export type SyncStateRatings = {
+  last_synced_at?: string;
 };
 
 export type SyncStateWatched = {
   last_synced_at?: string;
 };
 
+export type SyncStateWatchlist = {
+  ids?: string[];
+  last_synced_at?: string;
+};
+
 export type UpdateStremioTraktLinkParams = {
   sync_config: SyncConfig;
 };
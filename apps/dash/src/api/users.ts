@@ -0,0 +1,126 @@
+import { useMutation, useQuery } from "@tanstack/react-query";
+
+import { api } from "@/lib/api";
+
+import { UserRole } from "./auth";
+
+export type CreateUserParams = {
+  id: string;
+  password: string;
+  role: UserRole;
+};
+
+export type DashSession = {
+  created_at: string;
+  expires_at: string;
+  ip: string;
+  is_current: boolean;
+  key: string;
+  role: UserRole;
+  user: string;
+  user_agent: string;
+};
+
+export type DashUser = {
+  created_at?: string;
+  id: string;
+  is_builtin: boolean;
+  role: UserRole;
+  updated_at?: string;
+};
+
+export type UpdateUserParams = {
+  password?: string;
+  role?: UserRole;
+};
+
+export function useDashSessionMutation() {
+  const revoke = useMutation({
+    mutationFn: revokeDashSession,
+    onSuccess: async (_, __, ___, ctx) => {
+      await ctx.client.invalidateQueries({
+        queryKey: ["/auth/sessions"],
+      });
+    },
+  });
+
+  return { revoke };
+}
+
+export function useDashSessions() {
+  return useQuery({
+    queryFn: getDashSessions,
+    queryKey: ["/auth/sessions"],
+  });
+}
+
+export function useDashUserMutation() {
+  const create = useMutation({
+    mutationFn: createDashUser,
+    onSuccess: async (_, __, ___, ctx) => {
+      await ctx.client.invalidateQueries({
+        queryKey: ["/users"],
+      });
+    },
+  });
+
+  const update = useMutation({
+    mutationFn: ({ id, ...params }: UpdateUserParams & { id: string }) =>
+      updateDashUser(id, params),
+    onSuccess: async (_, __, ___, ctx) => {
+      await Promise.all([
+        ctx.client.invalidateQueries({ queryKey: ["/users"] }),
+        ctx.client.invalidateQueries({ queryKey: ["/auth/sessions"] }),
+      ]);
+    },
+  });
+
+  const remove = useMutation({
+    mutationFn: deleteDashUser,
+    onSuccess: async (_, __, ___, ctx) => {
+      await Promise.all([
+        ctx.client.invalidateQueries({ queryKey: ["/users"] }),
+        ctx.client.invalidateQueries({ queryKey: ["/auth/sessions"] }),
+      ]);
+    },
+  });
+
+  return { create, remove, update };
+}
+
+export function useDashUsers() {
+  return useQuery({
+    queryFn: getDashUsers,
+    queryKey: ["/users"],
+  });
+}
+
+async function createDashUser(params: CreateUserParams) {
+  const { data } = await api<DashUser>("POST /users", { body: params });
+  return data;
+}
+
+async function deleteDashUser(id: string) {
+  await api(`DELETE /users/${id}`);
+}
+
+async function getDashSessions() {
+  const { data } = await api<DashSession[]>("/auth/sessions");
+  return data;
+}
+
+async function getDashUsers() {
+  const { data } = await api<DashUser[]>("/users");
+  return data;
+}
+
+async function revokeDashSession(key: string) {
+  await api(`DELETE /auth/sessions/${key}`);
+}
+
+async function updateDashUser(id: string, params: UpdateUserParams) {
+  const { data } = await api<DashUser>(`PATCH /users/${id}`, {
+    body: params,
+  });
+  return data;
+}
@@ -1,4 +1,4 @@
-import { useMutation, useQuery } from "@tanstack/react-query";
+import { QueryClient, useMutation, useQuery } from "@tanstack/react-query";
 
 import { api } from "@/lib/api";
 
@@ -8,20 +8,41 @@ export type WorkerDetails = Record<
     has_failed_job: boolean;
     id: string;
     interval: number;
+    is_enabled: boolean;
+    is_paused: boolean;
+    is_running: boolean;
     title: string;
   }
 >;
 
 export type WorkerJobLog = {
   created_at: string;
-  data?: unknown;
+  data?: null | WorkerJobProgress;
   error?: string;
   id: string;
   name: string;
-  status: "done" | "failed" | "started";
+  status: "cancelled" | "done" | "failed" | "started";
   updated_at: string;
 };
 
+export type WorkerJobProgress = {
+  errors: number;
+  last_error?: string;
+  processed: number;
+};
+
+export type WorkerState = {
+  interval: number;
+  is_enabled: boolean;
+  is_paused: boolean;
+  is_running: boolean;
+  job?: WorkerJobProgress & {
+    id: string;
+    is_cancelled: boolean;
+    started_at: string;
+  };
+};
+
 export type WorkerTemporaryFile = {
   modified_at: string;
   path: string;
@@ -79,7 +100,62 @@ export function useWorkerMutation(workerId: string) {
     },
   });
 
-  return { deleteJobLog, purgeJobLogs, purgeTemporaryFiles };
+  const onStateChange = async (
+    state: WorkerState,
+    ctx: { client: QueryClient },
+  ) => {
+    ctx.client.setQueryData(["/workers/{id}/state", workerId], state);
+    await Promise.all([
+      ctx.client.invalidateQueries({ queryKey: ["/workers/details"] }),
+      ctx.client.invalidateQueries({
+        queryKey: ["/workers/{id}/job-logs", workerId],
+      }),
+    ]);
+  };
+
+  const control = useMutation({
+    mutationFn: async (action: "cancel" | "pause" | "resume" | "trigger") => {
+      const { data } = await api<WorkerState>(
+        `POST /workers/${workerId}/${action}`,
+      );
+      return data;
+    },
+    onSuccess: async (state, _, __, ctx) => {
+      await onStateChange(state, ctx);
+    },
+  });
+
+  const updateInterval = useMutation({
+    mutationFn: async (interval: string) => {
+      const { data } = await api<WorkerState>(`PATCH /workers/${workerId}`, {
+        body: { interval },
+      });
+      return data;
+    },
+    onSuccess: async (state, _, __, ctx) => {
+      await onStateChange(state, ctx);
+    },
+  });
+
+  return {
+    control,
+    deleteJobLog,
+    purgeJobLogs,
+    purgeTemporaryFiles,
+    updateInterval,
+  };
+}
+
+export function useWorkerState(workerId: string) {
+  return useQuery({
+    enabled: Boolean(workerId),
+    queryFn: async () => {
+      const { data } = await api<WorkerState>(`/workers/${workerId}/state`);
+      return data;
+    },
+    queryKey: ["/workers/{id}/state", workerId],
+    refetchInterval: (query) => (query.state.data?.is_running ? 2000 : 15000),
+  });
 }
 
 export function useWorkerTemporaryFiles(workerId: string) {
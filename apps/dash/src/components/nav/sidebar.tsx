@@ -9,6 +9,7 @@ import {
   Lock,
   LogOut,
   MagnetIcon,
+  MonitorSmartphone,
   Moon,
   Sparkles,
   Sun,
@@ -230,23 +231,32 @@ function NavUser() {
 
 function useNavItems(): NavItem[] {
   const { data: server } = useServerStats();
+  const user = useCurrentUser();
   return useMemo(() => {
+    const dashboard: NavItem = {
+      icon: LayoutDashboard,
+      items: [
+        {
+          path: "/dash",
+          title: "Stats",
+        },
+        {
+          path: "/dash/workers",
+          title: "Workers",
+        },
+      ],
+      path: "/dash",
+      title: "Dashboard",
+    };
+    if (user.role === "admin") {
+      dashboard.items!.push({
+        path: "/dash/users",
+        title: "Users",
+      });
+    }
+
     const items: NavItem[] = [
-      {
-        icon: LayoutDashboard,
-        items: [
-          {
-            path: "/dash",
-            title: "Stats",
-          },
-          {
-            path: "/dash/workers",
-            title: "Workers",
-          },
-        ],
-        path: "/dash",
-        title: "Dashboard",
-      },
+      dashboard,
       {
         icon: LayoutList,
         items: [
@@ -271,6 +281,20 @@ function useNavItems(): NavItem[] {
       },
     ];
 
+    if (server?.integration.chillstreams) {
+      items.push({
+        icon: MonitorSmartphone,
+        items: [
+          {
+            path: "/dash/devices",
+            title: "Devices",
+          },
+        ],
+        path: "/dash/devices",
+        title: "Chillstreams",
+      });
+    }
+
     if (server?.feature.vault) {
       const vault: NavItem = {
         icon: Lock,
@@ -320,5 +344,10 @@ function useNavItems(): NavItem[] {
     }
 
     return items;
-  }, [server?.feature.vault, server?.integration.trakt]);
+  }, [
+    server?.feature.vault,
+    server?.integration.chillstreams,
+    server?.integration.trakt,
+    user.role,
+  ]);
 }
@@ -0,0 +1,225 @@
+import { Eye, History, Undo2 } from "lucide-react";
+import { DateTime } from "luxon";
+import { useState } from "react";
+import { toast } from "sonner";
+
+import {
+  SyncChange,
+  SyncChangeAction,
+  SyncDryRunResult,
+  useSyncHistory,
+  useSyncHistoryMutation,
+} from "@/api/sync-history";
+import { APIError } from "@/lib/api";
+
+import {
+  AlertDialog,
+  AlertDialogAction,
+  AlertDialogCancel,
+  AlertDialogContent,
+  AlertDialogDescription,
+  AlertDialogFooter,
+  AlertDialogHeader,
+  AlertDialogTitle,
+  AlertDialogTrigger,
+} from "./ui/alert-dialog";
+import { Button } from "./ui/button";
+import {
+  Dialog,
+  DialogContent,
+  DialogDescription,
+  DialogHeader,
+  DialogTitle,
+} from "./ui/dialog";
+import {
+  Item,
+  ItemContent,
+  ItemDescription,
+  ItemGroup,
+  ItemTitle,
+} from "./ui/item";
+import { ScrollArea, ScrollBar } from "./ui/scroll-area";
+
+const actionLabel: Record<SyncChangeAction, string> = {
+  "stremio.library.update": "Update Stremio Library",
+  "trakt.history.add": "Add to Trakt History",
+  "trakt.playback.pause": "Save Trakt Playback Progress",
+  "trakt.playback.remove": "Remove Trakt Playback Progress",
+  "trakt.watchlist.add": "Add to Trakt Watchlist",
+  "trakt.watchlist.remove": "Remove from Trakt Watchlist",
+};
+
+export function SyncHistoryActions({
+  disabled,
+  linkPath,
+}: {
+  disabled?: boolean;
+  linkPath: string;
+}) {
+  const { dryRun, revert } = useSyncHistoryMutation(linkPath);
+
+  const [preview, setPreview] = useState<null | SyncDryRunResult>(null);
+  const [isHistoryOpen, setIsHistoryOpen] = useState(false);
+
+  const history = useSyncHistory(linkPath, isHistoryOpen);
+
+  const handlePreview = () => {
+    toast.promise(dryRun.mutateAsync(), {
+      error(err: APIError) {
+        console.error(err);
+        return {
+          closeButton: true,
+          message: err.message,
+        };
+      },
+      loading: "Computing changes...",
+      success: (result) => {
+        setPreview(result);
+        return {
+          closeButton: true,
+          message: `${result.changes.length} change${result.changes.length !== 1 ? "s" : ""} pending`,
+        };
+      },
+    });
+  };
+
+  const handleRevert = () => {
+    toast.promise(revert.mutateAsync(), {
+      error(err: APIError) {
+        console.error(err);
+        return {
+          closeButton: true,
+          message: err.message,
+        };
+      },
+      loading: "Reverting last sync...",
+      success: ({ conflicts, reverted }) => ({
+        closeButton: true,
+        description: conflicts.length
+          ? `Skipped ${conflicts.length} change${conflicts.length !== 1 ? "s" : ""} made after the sync: ${conflicts.map((c) => `${c.item_id} (${c.reason})`).join(", ")}`
+          : undefined,
+        message: `Reverted ${reverted.length} change${reverted.length !== 1 ? "s" : ""}!`,
+      }),
+    });
+  };
+
+  return (
+    <div className="flex gap-2">
+      <Button
+        className="flex-1"
+        disabled={disabled || dryRun.isPending}
+        onClick={handlePreview}
+        size="sm"
+        variant="ghost"
+      >
+        <Eye className="mr-2 size-4" />
+        Preview
+      </Button>
+      <Button
+        className="flex-1"
+        onClick={() => setIsHistoryOpen(true)}
+        size="sm"
+        variant="ghost"
+      >
+        <History className="mr-2 size-4" />
+        History
+      </Button>
+      <AlertDialog>
+        <AlertDialogTrigger asChild>
+          <Button
+            className="flex-1"
+            disabled={revert.isPending}
+            size="sm"
+            variant="ghost"
+          >
+            <Undo2 className="mr-2 size-4" />
+            Revert
+          </Button>
+        </AlertDialogTrigger>
+        <AlertDialogContent>
+          <AlertDialogHeader>
+            <AlertDialogTitle>Revert Last Sync?</AlertDialogTitle>
+            <AlertDialogDescription>
+              This will undo the changes applied by the last sync run. Items
+              changed since then will be overwritten.
+            </AlertDialogDescription>
+          </AlertDialogHeader>
+          <AlertDialogFooter>
+            <AlertDialogCancel>Cancel</AlertDialogCancel>
+            <AlertDialogAction asChild>
+              <Button disabled={revert.isPending} onClick={handleRevert}>
+                Revert
+              </Button>
+            </AlertDialogAction>
+          </AlertDialogFooter>
+        </AlertDialogContent>
+      </AlertDialog>
+
+      <Dialog
+        onOpenChange={(open) => !open && setPreview(null)}
+        open={preview !== null}
+      >
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>Sync Preview</DialogTitle>
+            <DialogDescription>
+              {preview?.error ||
+                "These changes will be applied on the next sync."}
+            </DialogDescription>
+          </DialogHeader>
+          <SyncChangeList changes={preview?.changes ?? []} />
+        </DialogContent>
+      </Dialog>
+
+      <Dialog onOpenChange={setIsHistoryOpen} open={isHistoryOpen}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>Sync History</DialogTitle>
+            <DialogDescription>
+              Changes applied by the recent sync runs.
+            </DialogDescription>
+          </DialogHeader>
+          <SyncChangeList changes={history.data ?? []} />
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
+
+function SyncChangeList({
+  changes,
+}: {
+  changes: Array<
+    SyncChange & { created_at?: string; job_id?: string; reverted_at?: string }
+  >;
+}) {
+  if (changes.length === 0) {
+    return <div className="text-muted-foreground text-sm">No changes.</div>;
+  }
+
+  return (
+    <ScrollArea className="max-h-96">
+      <ItemGroup className="gap-2">
+        {changes.map((change, idx) => (
+          <Item key={idx} size="sm" variant="muted">
+            <ItemContent>
+              <ItemTitle>
+                <strong>{actionLabel[change.action] ?? change.action}</strong>
+                <code>{change.item_id}</code>
+              </ItemTitle>
+              <ItemDescription>
+                {change.target_account_id}
+                {change.created_at &&
+                  ` · ${DateTime.fromISO(change.created_at).toLocaleString(
+                    DateTime.DATETIME_MED,
+                  )}`}
+                {change.reverted_at && " · Reverted"}
+              </ItemDescription>
+            </ItemContent>
+          </Item>
+        ))}
+      </ItemGroup>
+      <ScrollBar orientation="vertical" />
+    </ScrollArea>
+  );
+}
@@ -13,10 +13,12 @@ import { Route as DashRouteImport } from './routes/dash'
 import { Route as DashIndexRouteImport } from './routes/dash/index'
 import { Route as DashWorkersRouteImport } from './routes/dash/workers'
 import { Route as DashVaultRouteImport } from './routes/dash/vault'
+import { Route as DashUsersRouteImport } from './routes/dash/users'
 import { Route as DashTorrentsRouteImport } from './routes/dash/torrents'
 import { Route as DashSyncRouteImport } from './routes/dash/sync'
 import { Route as DashLoginRouteImport } from './routes/dash/login'
 import { Route as DashListsRouteImport } from './routes/dash/lists'
+import { Route as DashDevicesRouteImport } from './routes/dash/devices'
 import { Route as DashVaultIndexRouteImport } from './routes/dash/vault/index'
 import { Route as DashTorrentsIndexRouteImport } from './routes/dash/torrents/index'
 import { Route as DashSyncIndexRouteImport } from './routes/dash/sync/index'
@@ -46,6 +48,11 @@ const DashVaultRoute = DashVaultRouteImport.update({
   path: '/vault',
   getParentRoute: () => DashRoute,
 } as any)
+const DashUsersRoute = DashUsersRouteImport.update({
+  id: '/users',
+  path: '/users',
+  getParentRoute: () => DashRoute,
+} as any)
 const DashTorrentsRoute = DashTorrentsRouteImport.update({
   id: '/torrents',
   path: '/torrents',
@@ -66,6 +73,11 @@ const DashListsRoute = DashListsRouteImport.update({
   path: '/lists',
   getParentRoute: () => DashRoute,
 } as any)
+const DashDevicesRoute = DashDevicesRouteImport.update({
+  id: '/devices',
+  path: '/devices',
+  getParentRoute: () => DashRoute,
+} as any)
 const DashVaultIndexRoute = DashVaultIndexRouteImport.update({
   id: '/',
   path: '/',
@@ -110,10 +122,12 @@ const DashSyncStremioStremioRoute = DashSyncStremioStremioRouteImport.update({
 
 export interface FileRoutesByFullPath {
   '/dash': typeof DashRouteWithChildren
+  '/dash/devices': typeof DashDevicesRoute
   '/dash/lists': typeof DashListsRouteWithChildren
   '/dash/login': typeof DashLoginRoute
   '/dash/sync': typeof DashSyncRouteWithChildren
   '/dash/torrents': typeof DashTorrentsRouteWithChildren
+  '/dash/users': typeof DashUsersRoute
   '/dash/vault': typeof DashVaultRouteWithChildren
   '/dash/workers': typeof DashWorkersRoute
   '/dash/': typeof DashIndexRoute
@@ -127,7 +141,9 @@ export interface FileRoutesByFullPath {
   '/dash/vault/': typeof DashVaultIndexRoute
 }
 export interface FileRoutesByTo {
+  '/dash/devices': typeof DashDevicesRoute
   '/dash/login': typeof DashLoginRoute
+  '/dash/users': typeof DashUsersRoute
   '/dash/workers': typeof DashWorkersRoute
   '/dash': typeof DashIndexRoute
   '/dash/sync/stremio-stremio': typeof DashSyncStremioStremioRoute
@@ -142,10 +158,12 @@ export interface FileRoutesByTo {
 export interface FileRoutesById {
   __root__: typeof rootRouteImport
   '/dash': typeof DashRouteWithChildren
+  '/dash/devices': typeof DashDevicesRoute
   '/dash/lists': typeof DashListsRouteWithChildren
   '/dash/login': typeof DashLoginRoute
   '/dash/sync': typeof DashSyncRouteWithChildren
   '/dash/torrents': typeof DashTorrentsRouteWithChildren
+  '/dash/users': typeof DashUsersRoute
   '/dash/vault': typeof DashVaultRouteWithChildren
   '/dash/workers': typeof DashWorkersRoute
   '/dash/': typeof DashIndexRoute
@@ -162,10 +180,12 @@ export interface FileRouteTypes {
   fileRoutesByFullPath: FileRoutesByFullPath
   fullPaths:
     | '/dash'
+    | '/dash/devices'
     | '/dash/lists'
     | '/dash/login'
     | '/dash/sync'
     | '/dash/torrents'
+    | '/dash/users'
     | '/dash/vault'
     | '/dash/workers'
     | '/dash/'
@@ -179,7 +199,9 @@ export interface FileRouteTypes {
     | '/dash/vault/'
   fileRoutesByTo: FileRoutesByTo
   to:
+    | '/dash/devices'
     | '/dash/login'
+    | '/dash/users'
     | '/dash/workers'
     | '/dash'
     | '/dash/sync/stremio-stremio'
@@ -193,10 +215,12 @@ export interface FileRouteTypes {
   id:
     | '__root__'
     | '/dash'
+    | '/dash/devices'
     | '/dash/lists'
     | '/dash/login'
     | '/dash/sync'
     | '/dash/torrents'
+    | '/dash/users'
     | '/dash/vault'
     | '/dash/workers'
     | '/dash/'
@@ -244,6 +268,13 @@ declare module '@tanstack/react-router' {
       preLoaderRoute: typeof DashVaultRouteImport
       parentRoute: typeof DashRoute
     }
+    '/dash/users': {
+      id: '/dash/users'
+      path: '/users'
+      fullPath: '/dash/users'
+      preLoaderRoute: typeof DashUsersRouteImport
+      parentRoute: typeof DashRoute
+    }
     '/dash/torrents': {
       id: '/dash/torrents'
       path: '/torrents'
@@ -272,6 +303,13 @@ declare module '@tanstack/react-router' {
       preLoaderRoute: typeof DashListsRouteImport
       parentRoute: typeof DashRoute
     }
+    '/dash/devices': {
+      id: '/dash/devices'
+      path: '/devices'
+      fullPath: '/dash/devices'
+      preLoaderRoute: typeof DashDevicesRouteImport
+      parentRoute: typeof DashRoute
+    }
     '/dash/vault/': {
       id: '/dash/vault/'
       path: '/'
@@ -388,20 +426,24 @@ const DashVaultRouteWithChildren = DashVaultRoute._addFileChildren(
 )
 
 interface DashRouteChildren {
+  DashDevicesRoute: typeof DashDevicesRoute
   DashListsRoute: typeof DashListsRouteWithChildren
   DashLoginRoute: typeof DashLoginRoute
   DashSyncRoute: typeof DashSyncRouteWithChildren
   DashTorrentsRoute: typeof DashTorrentsRouteWithChildren
+  DashUsersRoute: typeof DashUsersRoute
   DashVaultRoute: typeof DashVaultRouteWithChildren
   DashWorkersRoute: typeof DashWorkersRoute
   DashIndexRoute: typeof DashIndexRoute
 }
 
 const DashRouteChildren: DashRouteChildren = {
+  DashDevicesRoute: DashDevicesRoute,
   DashListsRoute: DashListsRouteWithChildren,
   DashLoginRoute: DashLoginRoute,
   DashSyncRoute: DashSyncRouteWithChildren,
   DashTorrentsRoute: DashTorrentsRouteWithChildren,
+  DashUsersRoute: DashUsersRoute,
   DashVaultRoute: DashVaultRouteWithChildren,
   DashWorkersRoute: DashWorkersRoute,
   DashIndexRoute: DashIndexRoute,
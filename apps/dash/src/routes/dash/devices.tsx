@@ -0,0 +1,321 @@
+import { createFileRoute, Navigate } from "@tanstack/react-router";
+import { ColumnDef, createColumnHelper } from "@tanstack/react-table";
+import { Ban, Pencil } from "lucide-react";
+import { DateTime } from "luxon";
+import { useState } from "react";
+import { toast } from "sonner";
+
+import {
+  ChillstreamsDevice,
+  useChillstreamsDeviceMutation,
+  useChillstreamsDevices,
+} from "@/api/chillstreams-devices";
+import { useServerStats } from "@/api/stats";
+import { DataTable } from "@/components/data-table";
+import { useDataTable } from "@/components/data-table/use-data-table";
+import { Form } from "@/components/form";
+import { useAppForm } from "@/components/form/hook";
+import {
+  AlertDialog,
+  AlertDialogAction,
+  AlertDialogCancel,
+  AlertDialogContent,
+  AlertDialogDescription,
+  AlertDialogFooter,
+  AlertDialogHeader,
+  AlertDialogTitle,
+  AlertDialogTrigger,
+} from "@/components/ui/alert-dialog";
+import { Button } from "@/components/ui/button";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+} from "@/components/ui/dialog";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import { useDebouncedValue } from "@/hooks/use-debounced-value";
+import { APIError } from "@/lib/api";
+
+declare module "@/components/data-table" {
+  export interface DataTableMetaCtx {
+    ChillstreamsDevice: {
+      revokeDevice: ReturnType<typeof useChillstreamsDeviceMutation>["revoke"];
+      setEditItem: (item: ChillstreamsDevice) => void;
+      userId: string;
+    };
+  }
+
+  export interface DataTableMetaCtxKey {
+    ChillstreamsDevice: ChillstreamsDevice;
+  }
+}
+
+const UUID_REGEX =
+  /^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/i;
+
+const col = createColumnHelper<ChillstreamsDevice>();
+
+const columns: ColumnDef<ChillstreamsDevice>[] = [
+  col.accessor("label", {
+    cell: ({ getValue, row }) => {
+      const label = getValue();
+      return (
+        <div className="flex flex-col">
+          <span className={label ? "" : "text-muted-foreground"}>
+            {label || "Unnamed"}
+          </span>
+          <span className="text-muted-foreground font-mono text-xs">
+            {row.original.id.slice(0, 12)}
+          </span>
+        </div>
+      );
+    },
+    header: "Device",
+  }),
+  col.accessor("kind", {
+    cell: ({ getValue }) =>
+      getValue() === "token" ? "Registered" : "IP + User-Agent",
+    header: "Identified By",
+  }),
+  col.accessor("last_ip", {
+    header: "Last IP",
+  }),
+  col.accessor("user_agent", {
+    cell: ({ getValue }) => (
+      <span className="block max-w-64 truncate" title={getValue()}>
+        {getValue()}
+      </span>
+    ),
+    header: "User Agent",
+  }),
+  col.accessor("stream_count", {
+    header: "Streams",
+  }),
+  col.accessor("first_seen_at", {
+    cell: ({ getValue }) => {
+      const date = DateTime.fromISO(getValue());
+      return date.toLocaleString(DateTime.DATETIME_MED);
+    },
+    header: "First Seen",
+  }),
+  col.accessor("last_seen_at", {
+    cell: ({ getValue }) => {
+      const date = DateTime.fromISO(getValue());
+      return date.toRelative() ?? date.toLocaleString(DateTime.DATETIME_MED);
+    },
+    header: "Last Seen",
+  }),
+  col.accessor("is_revoked", {
+    cell: ({ getValue }) =>
+      getValue() ? (
+        <span className="text-red-500">Revoked</span>
+      ) : (
+        <span className="text-green-500">Active</span>
+      ),
+    header: "Status",
+  }),
+  col.display({
+    cell: (c) => {
+      const { revokeDevice, setEditItem, userId } =
+        c.table.options.meta!.ctx;
+      const item = c.row.original;
+      return (
+        <div className="flex gap-1">
+          <Button
+            onClick={() => setEditItem(item)}
+            size="icon-sm"
+            variant="ghost"
+          >
+            <Pencil />
+          </Button>
+          <AlertDialog>
+            <AlertDialogTrigger asChild>
+              <Button disabled={item.is_revoked} size="icon-sm" variant="ghost">
+                <Ban className="text-destructive" />
+              </Button>
+            </AlertDialogTrigger>
+            <AlertDialogContent>
+              <AlertDialogHeader>
+                <AlertDialogTitle>Revoke Device?</AlertDialogTitle>
+                <AlertDialogDescription>
+                  This will reject every further request from the device{" "}
+                  <strong>{item.label || item.id.slice(0, 12)}</strong> and
+                  free its slot in the device limit. This action cannot be
+                  undone.
+                </AlertDialogDescription>
+              </AlertDialogHeader>
+              <AlertDialogFooter>
+                <AlertDialogCancel>Cancel</AlertDialogCancel>
+                <AlertDialogAction asChild>
+                  <Button
+                    disabled={revokeDevice.isPending}
+                    onClick={() => {
+                      toast.promise(
+                        revokeDevice.mutateAsync({ id: item.id, userId }),
+                        {
+                          error(err: APIError) {
+                            console.error(err);
+                            return {
+                              closeButton: true,
+                              message: err.message,
+                            };
+                          },
+                          loading: "Revoking...",
+                          success: {
+                            closeButton: true,
+                            message: "Revoked successfully!",
+                          },
+                        },
+                      );
+                    }}
+                    variant="destructive"
+                  >
+                    Revoke
+                  </Button>
+                </AlertDialogAction>
+              </AlertDialogFooter>
+            </AlertDialogContent>
+          </AlertDialog>
+        </div>
+      );
+    },
+    header: "",
+    id: "actions",
+  }),
+];
+
+export const Route = createFileRoute("/dash/devices")({
+  component: RouteComponent,
+  staticData: {
+    crumb: "Devices",
+  },
+});
+
+function RenameDeviceForm({
+  item,
+  onClose,
+  userId,
+}: {
+  item: ChillstreamsDevice;
+  onClose: () => void;
+  userId: string;
+}) {
+  const { update } = useChillstreamsDeviceMutation();
+
+  const form = useAppForm({
+    defaultValues: {
+      label: item.label,
+    },
+    onSubmit: async ({ value }) => {
+      await update.mutateAsync({ id: item.id, label: value.label, userId });
+      toast.success("Device renamed successfully!");
+      onClose();
+    },
+  });
+
+  return (
+    <Form className="flex flex-col gap-4" form={form}>
+      <form.AppField name="label">
+        {(field) => <field.Input label="Label" placeholder="Living Room TV" />}
+      </form.AppField>
+
+      <form.AppForm>
+        <form.SubmitButton className="w-full">Save</form.SubmitButton>
+      </form.AppForm>
+    </Form>
+  );
+}
+
+function RouteComponent() {
+  const { data: server } = useServerStats();
+
+  const [_userId, setUserId] = useState("");
+  const userId = useDebouncedValue(_userId.trim(), 300);
+  const isValidUserId = UUID_REGEX.test(userId);
+
+  const devices = useChillstreamsDevices(isValidUserId ? userId : "");
+  const { revoke: revokeDevice } = useChillstreamsDeviceMutation();
+
+  const [editItem, setEditItem] = useState<ChillstreamsDevice | null>(null);
+
+  const table = useDataTable({
+    columns,
+    data: devices.data ?? [],
+    initialState: {
+      columnPinning: { right: ["actions"] },
+    },
+    meta: {
+      ctx: {
+        revokeDevice,
+        setEditItem,
+        userId,
+      },
+    },
+  });
+
+  if (server && !server.integration.chillstreams) {
+    return <Navigate to="/dash" />;
+  }
+
+  const activeCount =
+    devices.data?.filter((item) => !item.is_revoked).length ?? 0;
+
+  return (
+    <div className="flex flex-col gap-6">
+      <div className="flex items-center justify-between">
+        <h2 className="text-lg font-semibold">Devices</h2>
+        {devices.data ? (
+          <span className="text-muted-foreground text-sm">
+            {activeCount} active
+          </span>
+        ) : null}
+      </div>
+
+      <div className="flex max-w-md flex-col gap-2">
+        <Label htmlFor="chillstreams-user-id">Chillstreams User ID</Label>
+        <Input
+          id="chillstreams-user-id"
+          onChange={(e) => setUserId(e.target.value)}
+          placeholder="00000000-0000-0000-0000-000000000000"
+          value={_userId}
+        />
+      </div>
+
+      {!isValidUserId ? (
+        <div className="text-muted-foreground text-sm">
+          Enter a user id to see their devices.
+        </div>
+      ) : devices.isLoading ? (
+        <div className="text-muted-foreground text-sm">Loading...</div>
+      ) : devices.isError ? (
+        <div className="text-sm text-red-600">Error loading devices</div>
+      ) : (
+        <DataTable table={table} />
+      )}
+
+      <Dialog
+        onOpenChange={(open) => {
+          if (!open) {
+            setEditItem(null);
+          }
+        }}
+        open={Boolean(editItem)}
+      >
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>Rename Device</DialogTitle>
+          </DialogHeader>
+          {editItem ? (
+            <RenameDeviceForm
+              item={editItem}
+              onClose={() => setEditItem(null)}
+              userId={userId}
+            />
+          ) : null}
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
@@ -30,6 +30,7 @@ import {
 import { Form } from "@/components/form/Form";
 import { useAppForm } from "@/components/form/hook";
 import { IMDBSearch } from "@/components/imdb-search";
+import { SyncHistoryActions } from "@/components/sync-history";
 import {
   AlertDialog,
   AlertDialogAction,
@@ -602,6 +603,14 @@ function LinkCard({
             </div>
           </div>
         )}
+
+        <SyncHistoryActions
+          disabled={
+            link.sync_config.watched.dir === "none" ||
+            link.sync_config.watched.ids.length === 0
+          }
+          linkPath={`/sync/stremio-stremio/links/${link.account_a_id}:${link.account_b_id}`}
+        />
       </CardContent>
       <CardFooter className="mt-auto gap-4">
         <Button
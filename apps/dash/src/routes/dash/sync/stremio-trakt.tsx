@@ -15,7 +15,9 @@ import { toast } from "sonner";
 
 import {
   StremioTraktLink,
+  SyncConfig,
   SyncDirection,
+  SyncRemovalPolicy,
   useStremioTraktLinkMutation,
   useStremioTraktLinks,
 } from "@/api/sync-stremio-trakt";
@@ -26,6 +28,7 @@ import {
 import { TraktAccount, useTraktAccounts } from "@/api/vault-trakt-account";
 import { Form } from "@/components/form/Form";
 import { useAppForm } from "@/components/form/hook";
+import { SyncHistoryActions } from "@/components/sync-history";
 import {
   AlertDialog,
   AlertDialogAction,
@@ -97,6 +100,124 @@ const syncDirectionOptions: Array<{
   },
 ];
 
+const syncRemovalPolicyOptions: Array<{
+  label: string;
+  value: SyncRemovalPolicy;
+}> = [
+  {
+    label: "Keep on the other side",
+    value: "keep",
+  },
+  {
+    label: "Remove from the other side",
+    value: "propagate",
+  },
+];
+
+const defaultSyncConfig: SyncConfig = {
+  progress: { dir: "none", on_remove: "keep" },
+  ratings: { dir: "none" },
+  watched: { dir: "none" },
+  watchlist: { dir: "none", on_remove: "keep" },
+};
+
+function getLastSyncedAt(link: StremioTraktLink) {
+  const timestamps = [
+    link.sync_state.watched?.last_synced_at,
+    link.sync_state.watchlist?.last_synced_at,
+    link.sync_state.progress?.last_synced_at,
+    link.sync_state.ratings?.last_synced_at,
+  ].filter((ts): ts is string => Boolean(ts));
+  return timestamps.sort().at(-1);
+}
+
+function isSyncDisabled(config: SyncConfig) {
+  return (
+    config.watched.dir === "none" &&
+    config.watchlist.dir === "none" &&
+    config.progress.dir === "none" &&
+    config.ratings.dir === "none"
+  );
+}
+
+function SyncDirectionSelect({
+  label,
+  onValueChange,
+  options = syncDirectionOptions,
+  value,
+}: {
+  label: string;
+  onValueChange: (value: SyncDirection) => void;
+  options?: typeof syncDirectionOptions;
+  value: SyncDirection;
+}) {
+  const selected = options.find((opt) => opt.value === value);
+  const SelectedIcon = selected?.icon || XCircle;
+
+  return (
+    <div className="flex flex-col gap-2">
+      <label className="text-sm font-medium">{label}</label>
+      <Select
+        onValueChange={(value) => onValueChange(value as SyncDirection)}
+        value={value}
+      >
+        <SelectTrigger className="w-full">
+          <SelectValue>
+            <div className="flex items-center gap-2">
+              <SelectedIcon className="size-4" />
+              {selected?.label}
+            </div>
+          </SelectValue>
+        </SelectTrigger>
+        <SelectContent>
+          {options.map((option) => {
+            const OptionIcon = option.icon;
+            return (
+              <SelectItem key={option.value} value={option.value}>
+                <div className="flex items-center gap-2">
+                  <OptionIcon className="size-4" />
+                  {option.label}
+                </div>
+              </SelectItem>
+            );
+          })}
+        </SelectContent>
+      </Select>
+    </div>
+  );
+}
+
+function SyncRemovalPolicySelect({
+  label,
+  onValueChange,
+  value,
+}: {
+  label: string;
+  onValueChange: (value: SyncRemovalPolicy) => void;
+  value: SyncRemovalPolicy;
+}) {
+  return (
+    <div className="flex flex-col gap-2">
+      <label className="text-sm font-medium">{label}</label>
+      <Select
+        onValueChange={(value) => onValueChange(value as SyncRemovalPolicy)}
+        value={value}
+      >
+        <SelectTrigger className="w-full">
+          <SelectValue />
+        </SelectTrigger>
+        <SelectContent>
+          {syncRemovalPolicyOptions.map((option) => (
+            <SelectItem key={option.value} value={option.value}>
+              {option.label}
+            </SelectItem>
+          ))}
+        </SelectContent>
+      </Select>
+    </div>
+  );
+}
+
 function LinkAccountSheet({
   onClose,
   stremioAccounts,
@@ -119,7 +240,7 @@ function LinkAccountSheet({
     onSubmit: async ({ value }) => {
       await create.mutateAsync({
         stremio_account_id: value.stremio_account_id,
-        sync_config: { watched: { dir: "none" } },
+        sync_config: defaultSyncConfig,
         trakt_account_id: value.trakt_account_id,
       });
       toast.success("Accounts linked successfully!");
@@ -232,16 +353,28 @@ function LinkCard({
   const { remove, resetSyncState, sync, update } =
     useStremioTraktLinkMutation();
 
-  const selectedWatchedSyncDirection = syncDirectionOptions.find(
-    (opt) => opt.value === link.sync_config.watched.dir,
-  );
-  const SyncDirectionIcon = selectedWatchedSyncDirection?.icon || XCircle;
+  const syncConfig: SyncConfig = {
+    progress: { ...defaultSyncConfig.progress, ...link.sync_config.progress },
+    ratings: { ...defaultSyncConfig.ratings, ...link.sync_config.ratings },
+    watched: { ...defaultSyncConfig.watched, ...link.sync_config.watched },
+    watchlist: {
+      ...defaultSyncConfig.watchlist,
+      ...link.sync_config.watchlist,
+    },
+  };
+  const lastSyncedAt = getLastSyncedAt(link);
 
-  const handleWatchedSyncDirectionChange = (value: string) => {
+  const handleSyncConfigChange = <K extends keyof SyncConfig>(
+    channel: K,
+    value: Partial<SyncConfig[K]>,
+  ) => {
     toast.promise(
       update.mutateAsync({
         stremio_account_id: link.stremio_account_id,
-        sync_config: { watched: { dir: value as SyncDirection } },
+        sync_config: {
+          ...syncConfig,
+          [channel]: { ...syncConfig[channel], ...value },
+        },
         trakt_account_id: link.trakt_account_id,
       }),
       {
@@ -252,10 +385,10 @@ function LinkCard({
             message: err.message,
           };
         },
-        loading: "Updating sync direction...",
+        loading: "Updating sync config...",
         success: {
           closeButton: true,
-          message: "Sync direction updated!",
+          message: "Sync config updated!",
         },
       },
     );
@@ -351,46 +484,65 @@ function LinkCard({
         </CardDescription>
       </CardHeader>
       <CardContent className="flex flex-col gap-4">
-        <div className="flex flex-col gap-2">
-          <label className="text-sm font-medium">Watched Sync Direction</label>
-          <Select
-            onValueChange={handleWatchedSyncDirectionChange}
-            value={link.sync_config.watched.dir}
-          >
-            <SelectTrigger className="w-full">
-              <SelectValue>
-                <div className="flex items-center gap-2">
-                  <SyncDirectionIcon className="size-4" />
-                  {selectedWatchedSyncDirection?.label}
-                </div>
-              </SelectValue>
-            </SelectTrigger>
-            <SelectContent>
-              {syncDirectionOptions.map((option) => {
-                const OptionIcon = option.icon;
-                return (
-                  <SelectItem key={option.value} value={option.value}>
-                    <div className="flex items-center gap-2">
-                      <OptionIcon className="size-4" />
-                      {option.label}
-                    </div>
-                  </SelectItem>
-                );
-              })}
-            </SelectContent>
-          </Select>
-        </div>
+        <SyncDirectionSelect
+          label="Watched Sync Direction"
+          onValueChange={(dir) => handleSyncConfigChange("watched", { dir })}
+          value={syncConfig.watched.dir}
+        />
+
+        <SyncDirectionSelect
+          label="Watchlist Sync Direction"
+          onValueChange={(dir) => handleSyncConfigChange("watchlist", { dir })}
+          value={syncConfig.watchlist.dir}
+        />
+        {syncConfig.watchlist.dir !== "none" && (
+          <SyncRemovalPolicySelect
+            label="When Removed from Watchlist/Library"
+            onValueChange={(on_remove) =>
+              handleSyncConfigChange("watchlist", { on_remove })
+            }
+            value={syncConfig.watchlist.on_remove}
+          />
+        )}
+
+        <SyncDirectionSelect
+          label="Progress Sync Direction"
+          onValueChange={(dir) => handleSyncConfigChange("progress", { dir })}
+          value={syncConfig.progress.dir}
+        />
+        {syncConfig.progress.dir !== "none" && (
+          <SyncRemovalPolicySelect
+            label="When Progress is Cleared"
+            onValueChange={(on_remove) =>
+              handleSyncConfigChange("progress", { on_remove })
+            }
+            value={syncConfig.progress.on_remove}
+          />
+        )}
 
-        {link.sync_state.watched.last_synced_at && (
+        <SyncDirectionSelect
+          label="Ratings Sync Direction"
+          onValueChange={(dir) =>
+            handleSyncConfigChange("ratings", {
+              dir: dir as SyncConfig["ratings"]["dir"],
+            })
+          }
+          options={syncDirectionOptions.filter(
+            (opt) => opt.value === "none" || opt.value === "trakt_to_stremio",
+          )}
+          value={syncConfig.ratings.dir}
+        />
+
+        {lastSyncedAt && (
           <div className="text-muted-foreground flex flex-col gap-1 text-sm">
             <div className="flex items-center justify-between gap-2">
               <div className="flex items-center gap-1">
                 <CheckCircle className="size-3.5 text-green-500" />
                 <span>
                   Last synced:{" "}
-                  {DateTime.fromISO(
-                    link.sync_state.watched.last_synced_at,
-                  ).toLocaleString(DateTime.DATETIME_MED)}
+                  {DateTime.fromISO(lastSyncedAt).toLocaleString(
+                    DateTime.DATETIME_MED,
+                  )}
                 </span>
               </div>
               <AlertDialog>
@@ -424,11 +576,16 @@ function LinkCard({
             </div>
           </div>
         )}
+
+        <SyncHistoryActions
+          disabled={isSyncDisabled(syncConfig)}
+          linkPath={`/sync/stremio-trakt/links/${link.stremio_account_id}:${link.trakt_account_id}`}
+        />
       </CardContent>
       <CardFooter className="mt-auto gap-4">
         <Button
           className="flex-1"
-          disabled={link.sync_config.watched.dir === "none" || sync.isPending}
+          disabled={isSyncDisabled(syncConfig) || sync.isPending}
           onClick={handleSync}
           size="sm"
           variant="outline"
@@ -0,0 +1,415 @@
+import { createFileRoute, Navigate } from "@tanstack/react-router";
+import { ColumnDef, createColumnHelper } from "@tanstack/react-table";
+import { Ban, Plus, Trash2 } from "lucide-react";
+import { DateTime } from "luxon";
+import { useState } from "react";
+import { toast } from "sonner";
+
+import { UserRole } from "@/api/auth";
+import {
+  DashSession,
+  DashUser,
+  useDashSessionMutation,
+  useDashSessions,
+  useDashUserMutation,
+  useDashUsers,
+} from "@/api/users";
+import { DataTable } from "@/components/data-table";
+import { useDataTable } from "@/components/data-table/use-data-table";
+import { Form } from "@/components/form";
+import { useAppForm } from "@/components/form/hook";
+import {
+  AlertDialog,
+  AlertDialogAction,
+  AlertDialogCancel,
+  AlertDialogContent,
+  AlertDialogDescription,
+  AlertDialogFooter,
+  AlertDialogHeader,
+  AlertDialogTitle,
+  AlertDialogTrigger,
+} from "@/components/ui/alert-dialog";
+import { Button } from "@/components/ui/button";
+import {
+  Dialog,
+  DialogContent,
+  DialogHeader,
+  DialogTitle,
+} from "@/components/ui/dialog";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
+import { useCurrentUser } from "@/hooks/auth";
+import { APIError } from "@/lib/api";
+
+declare module "@/components/data-table" {
+  export interface DataTableMetaCtx {
+    DashSession: {
+      revokeSession: ReturnType<typeof useDashSessionMutation>["revoke"];
+    };
+    DashUser: {
+      currentUserId: string;
+      removeUser: ReturnType<typeof useDashUserMutation>["remove"];
+      updateUser: ReturnType<typeof useDashUserMutation>["update"];
+    };
+  }
+
+  export interface DataTableMetaCtxKey {
+    DashSession: DashSession;
+    DashUser: DashUser;
+  }
+}
+
+const roleOptions: Array<{ label: string; value: UserRole }> = [
+  { label: "Admin", value: "admin" },
+  { label: "Operator", value: "operator" },
+  { label: "Read-Only", value: "read_only" },
+];
+
+const toastError = (err: APIError) => {
+  console.error(err);
+  return {
+    closeButton: true,
+    message: err.message,
+  };
+};
+
+const userCol = createColumnHelper<DashUser>();
+
+const userColumns: ColumnDef<DashUser>[] = [
+  userCol.accessor("id", {
+    cell: ({ getValue, row }) => (
+      <div className="flex flex-col">
+        <span>{getValue()}</span>
+        {row.original.is_builtin && (
+          <span className="text-muted-foreground text-xs">
+            Configured in environment
+          </span>
+        )}
+      </div>
+    ),
+    header: "User",
+  }),
+  userCol.accessor("role", {
+    cell: (c) => {
+      const { updateUser } = c.table.options.meta!.ctx;
+      const item = c.row.original;
+      if (item.is_builtin) {
+        return roleOptions.find((opt) => opt.value === item.role)?.label;
+      }
+      return (
+        <Select
+          disabled={updateUser.isPending}
+          onValueChange={(value) => {
+            toast.promise(
+              updateUser.mutateAsync({ id: item.id, role: value as UserRole }),
+              {
+                error: toastError,
+                loading: "Updating role...",
+                success: {
+                  closeButton: true,
+                  message: "Role updated!",
+                },
+              },
+            );
+          }}
+          value={item.role}
+        >
+          <SelectTrigger className="w-36" size="sm">
+            <SelectValue />
+          </SelectTrigger>
+          <SelectContent>
+            {roleOptions.map((option) => (
+              <SelectItem key={option.value} value={option.value}>
+                {option.label}
+              </SelectItem>
+            ))}
+          </SelectContent>
+        </Select>
+      );
+    },
+    header: "Role",
+  }),
+  userCol.accessor("created_at", {
+    cell: ({ getValue }) => {
+      const value = getValue();
+      if (!value) {
+        return null;
+      }
+      return DateTime.fromISO(value).toLocaleString(DateTime.DATETIME_MED);
+    },
+    header: "Created At",
+  }),
+  userCol.display({
+    cell: (c) => {
+      const { currentUserId, removeUser } = c.table.options.meta!.ctx;
+      const item = c.row.original;
+      return (
+        <AlertDialog>
+          <AlertDialogTrigger asChild>
+            <Button
+              disabled={item.is_builtin || item.id === currentUserId}
+              size="icon-sm"
+              variant="ghost"
+            >
+              <Trash2 className="text-destructive" />
+            </Button>
+          </AlertDialogTrigger>
+          <AlertDialogContent>
+            <AlertDialogHeader>
+              <AlertDialogTitle>Delete User?</AlertDialogTitle>
+              <AlertDialogDescription>
+                This will delete the user <strong>{item.id}</strong> and sign
+                out all of their sessions.
+              </AlertDialogDescription>
+            </AlertDialogHeader>
+            <AlertDialogFooter>
+              <AlertDialogCancel>Cancel</AlertDialogCancel>
+              <AlertDialogAction asChild>
+                <Button
+                  disabled={removeUser.isPending}
+                  onClick={() => {
+                    toast.promise(removeUser.mutateAsync(item.id), {
+                      error: toastError,
+                      loading: "Deleting...",
+                      success: {
+                        closeButton: true,
+                        message: "Deleted successfully!",
+                      },
+                    });
+                  }}
+                  variant="destructive"
+                >
+                  Delete
+                </Button>
+              </AlertDialogAction>
+            </AlertDialogFooter>
+          </AlertDialogContent>
+        </AlertDialog>
+      );
+    },
+    header: "",
+    id: "actions",
+  }),
+];
+
+const sessionCol = createColumnHelper<DashSession>();
+
+const sessionColumns: ColumnDef<DashSession>[] = [
+  sessionCol.accessor("user", {
+    cell: ({ getValue, row }) => (
+      <div className="flex flex-col">
+        <span>{getValue()}</span>
+        {row.original.is_current && (
+          <span className="text-muted-foreground text-xs">This session</span>
+        )}
+      </div>
+    ),
+    header: "User",
+  }),
+  sessionCol.accessor("ip", {
+    header: "IP",
+  }),
+  sessionCol.accessor("user_agent", {
+    cell: ({ getValue }) => (
+      <span className="block max-w-64 truncate" title={getValue()}>
+        {getValue()}
+      </span>
+    ),
+    header: "User Agent",
+  }),
+  sessionCol.accessor("created_at", {
+    cell: ({ getValue }) => {
+      const date = DateTime.fromISO(getValue());
+      return date.toLocaleString(DateTime.DATETIME_MED);
+    },
+    header: "Signed In",
+  }),
+  sessionCol.accessor("expires_at", {
+    cell: ({ getValue }) => {
+      const date = DateTime.fromISO(getValue());
+      return date.toRelative() ?? date.toLocaleString(DateTime.DATETIME_MED);
+    },
+    header: "Expires",
+  }),
+  sessionCol.display({
+    cell: (c) => {
+      const { revokeSession } = c.table.options.meta!.ctx;
+      const item = c.row.original;
+      return (
+        <Button
+          disabled={item.is_current || revokeSession.isPending}
+          onClick={() => {
+            toast.promise(revokeSession.mutateAsync(item.key), {
+              error: toastError,
+              loading: "Revoking...",
+              success: {
+                closeButton: true,
+                message: "Revoked successfully!",
+              },
+            });
+          }}
+          size="icon-sm"
+          variant="ghost"
+        >
+          <Ban className="text-destructive" />
+        </Button>
+      );
+    },
+    header: "",
+    id: "actions",
+  }),
+];
+
+export const Route = createFileRoute("/dash/users")({
+  component: RouteComponent,
+  staticData: {
+    crumb: "Users",
+  },
+});
+
+function CreateUserForm({ onClose }: { onClose: () => void }) {
+  const { create } = useDashUserMutation();
+
+  const form = useAppForm({
+    defaultValues: {
+      id: "",
+      password: "",
+      role: "read_only" as UserRole,
+    },
+    onSubmit: async ({ value }) => {
+      await create.mutateAsync(value);
+      toast.success("User created successfully!");
+      onClose();
+    },
+  });
+
+  return (
+    <Form className="flex flex-col gap-4" form={form}>
+      <form.AppField name="id">
+        {(field) => <field.Input label="User" />}
+      </form.AppField>
+
+      <form.AppField name="password">
+        {(field) => <field.Input label="Password" type="password" />}
+      </form.AppField>
+
+      <form.AppField name="role">
+        {(field) => (
+          <div className="flex flex-col gap-2">
+            <label className="text-sm font-medium" htmlFor={field.name}>
+              Role
+            </label>
+            <Select
+              onValueChange={(value) => field.handleChange(value as UserRole)}
+              value={field.state.value}
+            >
+              <SelectTrigger className="w-full">
+                <SelectValue />
+              </SelectTrigger>
+              <SelectContent>
+                {roleOptions.map((option) => (
+                  <SelectItem key={option.value} value={option.value}>
+                    {option.label}
+                  </SelectItem>
+                ))}
+              </SelectContent>
+            </Select>
+          </div>
+        )}
+      </form.AppField>
+
+      <form.AppForm>
+        <form.SubmitButton className="w-full">Create</form.SubmitButton>
+      </form.AppForm>
+    </Form>
+  );
+}
+
+function RouteComponent() {
+  const user = useCurrentUser();
+
+  const users = useDashUsers();
+  const sessions = useDashSessions();
+  const { remove: removeUser, update: updateUser } = useDashUserMutation();
+  const { revoke: revokeSession } = useDashSessionMutation();
+
+  const [isCreateOpen, setIsCreateOpen] = useState(false);
+
+  const userTable = useDataTable({
+    columns: userColumns,
+    data: users.data ?? [],
+    initialState: {
+      columnPinning: { right: ["actions"] },
+    },
+    meta: {
+      ctx: {
+        currentUserId: user.id,
+        removeUser,
+        updateUser,
+      },
+    },
+  });
+
+  const sessionTable = useDataTable({
+    columns: sessionColumns,
+    data: sessions.data ?? [],
+    initialState: {
+      columnPinning: { right: ["actions"] },
+    },
+    meta: {
+      ctx: {
+        revokeSession,
+      },
+    },
+  });
+
+  if (user.role !== "admin") {
+    return <Navigate to="/dash" />;
+  }
+
+  return (
+    <div className="flex flex-col gap-6">
+      <div className="flex items-center justify-between">
+        <h2 className="text-lg font-semibold">Users</h2>
+        <Button onClick={() => setIsCreateOpen(true)} size="sm">
+          <Plus className="mr-2 size-4" />
+          Add User
+        </Button>
+      </div>
+
+      {users.isLoading ? (
+        <div className="text-muted-foreground text-sm">Loading...</div>
+      ) : users.isError ? (
+        <div className="text-sm text-red-600">Error loading users</div>
+      ) : (
+        <DataTable table={userTable} />
+      )}
+
+      <h2 className="text-lg font-semibold">Sessions</h2>
+
+      {sessions.isLoading ? (
+        <div className="text-muted-foreground text-sm">Loading...</div>
+      ) : sessions.isError ? (
+        <div className="text-sm text-red-600">Error loading sessions</div>
+      ) : (
+        <DataTable table={sessionTable} />
+      )}
+
+      <Dialog onOpenChange={setIsCreateOpen} open={isCreateOpen}>
+        <DialogContent>
+          <DialogHeader>
+            <DialogTitle>Add User</DialogTitle>
+          </DialogHeader>
+          {isCreateOpen ? (
+            <CreateUserForm onClose={() => setIsCreateOpen(false)} />
+          ) : null}
+        </DialogContent>
+      </Dialog>
+    </div>
+  );
+}
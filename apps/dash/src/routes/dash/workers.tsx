@@ -1,8 +1,8 @@
 import { createFileRoute } from "@tanstack/react-router";
 import { ColumnDef } from "@tanstack/react-table";
-import { Trash2 } from "lucide-react";
+import { Pause, Play, Square, Trash2, Zap } from "lucide-react";
 import { DateTime, Duration } from "luxon";
-import { useEffect, useMemo } from "react";
+import { useEffect, useMemo, useState } from "react";
 import { useLocalStorage } from "react-use";
 import { toast } from "sonner";
 
@@ -10,8 +10,10 @@ import {
   useWorkerDetails,
   useWorkerJobLogs,
   useWorkerMutation,
+  useWorkerState,
   useWorkerTemporaryFiles,
   WorkerJobLog,
+  WorkerJobProgress,
 } from "@/api/workers";
 import { DataTable } from "@/components/data-table";
 import { useDataTable } from "@/components/data-table/use-data-table";
@@ -27,6 +29,7 @@ import {
   AlertDialogTrigger,
 } from "@/components/ui/alert-dialog";
 import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
 import {
   Item,
   ItemContent,
@@ -49,6 +52,7 @@ import {
   TooltipContent,
   TooltipTrigger,
 } from "@/components/ui/tooltip";
+import { useCurrentUser } from "@/hooks/auth";
 import { APIError } from "@/lib/api";
 
 declare module "@/components/data-table" {
@@ -81,6 +85,7 @@ const jobLogsColumns: ColumnDef<WorkerJobLog>[] = [
     cell: ({ getValue }) => {
       const status = getValue<string>();
       const colors = {
+        cancelled: "text-amber-500",
         done: "text-green-500",
         failed: "text-red-500",
         started: "text-cyan-500",
@@ -101,6 +106,17 @@ const jobLogsColumns: ColumnDef<WorkerJobLog>[] = [
     },
     header: "Last Heartbeat At",
   },
+  {
+    accessorKey: "data",
+    cell: ({ getValue }) => {
+      const progress = getValue<null | undefined | WorkerJobProgress>();
+      if (!progress) {
+        return "-";
+      }
+      return formatProgress(progress);
+    },
+    header: "Progress",
+  },
   {
     accessorKey: "error",
     cell: ({ getValue }) => {
@@ -154,6 +170,182 @@ const jobLogsColumns: ColumnDef<WorkerJobLog>[] = [
   },
 ];
 
+function formatProgress(progress: WorkerJobProgress) {
+  return `${progress.processed} processed, ${progress.errors} errors`;
+}
+
+function formatInterval(interval: number) {
+  return Duration.fromMillis(interval / 1000 / 1000)
+    .shiftTo("months", "days", "hours", "minutes", "seconds")
+    .removeZeros()
+    .toHuman({ maximumFractionDigits: 0 });
+}
+
+function toastError(err: APIError) {
+  console.error(err);
+  return {
+    closeButton: true,
+    message: err.message,
+  };
+}
+
+function WorkerControls({ workerId }: { workerId: string }) {
+  const user = useCurrentUser();
+  const state = useWorkerState(workerId);
+  const { control, updateInterval } = useWorkerMutation(workerId);
+
+  const [intervalInput, setIntervalInput] = useState("");
+
+  if (!state.data) {
+    return null;
+  }
+
+  const { is_enabled, is_paused, is_running, job } = state.data;
+
+  if (!is_enabled) {
+    return (
+      <div className="text-muted-foreground text-sm">
+        Worker is not running on this instance.
+      </div>
+    );
+  }
+
+  const canControl = user.role !== "read_only";
+  const isPending = control.isPending || updateInterval.isPending;
+
+  return (
+    <div className="flex flex-col gap-4">
+      <div className="flex flex-row flex-wrap items-center gap-2">
+        <span className="text-sm">
+          Status:{" "}
+          {is_running ? (
+            <span className="text-cyan-500">
+              {job?.is_cancelled ? "cancelling" : "running"}
+            </span>
+          ) : is_paused ? (
+            <span className="text-amber-500">paused</span>
+          ) : (
+            <span className="text-green-500">idle</span>
+          )}
+        </span>
+        {canControl && (
+          <>
+            <Button
+              disabled={isPending || is_running}
+              onClick={() => {
+                toast.promise(control.mutateAsync("trigger"), {
+                  error: toastError,
+                  loading: "Triggering...",
+                  success: {
+                    closeButton: true,
+                    message: "Triggered!",
+                  },
+                });
+              }}
+              size="sm"
+              variant="outline"
+            >
+              <Zap /> Run Now
+            </Button>
+            <Button
+              disabled={isPending}
+              onClick={() => {
+                const action = is_paused ? "resume" : "pause";
+                toast.promise(control.mutateAsync(action), {
+                  error: toastError,
+                  loading: is_paused ? "Resuming..." : "Pausing...",
+                  success: {
+                    closeButton: true,
+                    message: is_paused ? "Resumed!" : "Paused!",
+                  },
+                });
+              }}
+              size="sm"
+              variant="outline"
+            >
+              {is_paused ? (
+                <>
+                  <Play /> Resume
+                </>
+              ) : (
+                <>
+                  <Pause /> Pause
+                </>
+              )}
+            </Button>
+            <Button
+              disabled={isPending || !is_running || job?.is_cancelled}
+              onClick={() => {
+                toast.promise(control.mutateAsync("cancel"), {
+                  error: toastError,
+                  loading: "Cancelling...",
+                  success: {
+                    closeButton: true,
+                    message: "Cancellation requested!",
+                  },
+                });
+              }}
+              size="sm"
+              variant="destructive"
+            >
+              <Square /> Cancel Job
+            </Button>
+            <form
+              className="flex flex-row items-center gap-2"
+              onSubmit={(e) => {
+                e.preventDefault();
+                toast.promise(updateInterval.mutateAsync(intervalInput), {
+                  error: toastError,
+                  loading: "Updating interval...",
+                  success: () => {
+                    setIntervalInput("");
+                    return {
+                      closeButton: true,
+                      message: "Interval updated!",
+                    };
+                  },
+                });
+              }}
+            >
+              <Input
+                className="h-8 w-28"
+                onChange={(e) => setIntervalInput(e.target.value)}
+                placeholder="e.g. 30m, 6h"
+                value={intervalInput}
+              />
+              <Button
+                disabled={isPending || !intervalInput}
+                size="sm"
+                type="submit"
+                variant="outline"
+              >
+                Set Interval
+              </Button>
+            </form>
+          </>
+        )}
+      </div>
+      {canControl && (
+        <div className="text-muted-foreground text-xs">
+          Pause and interval only apply to this instance, and are reset on
+          restart.
+        </div>
+      )}
+      {job && (
+        <div className="text-muted-foreground text-sm">
+          {is_running ? "Current" : "Last"} job <strong>{job.id}</strong>:{" "}
+          {formatProgress(job)}
+          {job.last_error && (
+            <div className="font-mono text-xs text-red-600">
+              {job.last_error}
+            </div>
+          )}
+        </div>
+      )}
+    </div>
+  );
+}
+
 const canPurgeTemporaryDataByWorkerId: Record<string, boolean> = {
   "sync-imdb": true,
 };
@@ -289,10 +481,7 @@ function RouteComponent() {
     if (!worker) {
       return "";
     }
-    return Duration.fromMillis(worker.interval / 1000 / 1000)
-      .shiftTo("months", "days", "hours", "minutes", "seconds")
-      .removeZeros()
-      .toHuman({ maximumFractionDigits: 0 });
+    return formatInterval(worker.interval);
   }, [selectedWorkerId, workerDetails.data]);
 
   const table = useDataTable({
@@ -337,6 +526,8 @@ function RouteComponent() {
         </div>
       </div>
 
+      {selectedWorkerId && <WorkerControls workerId={selectedWorkerId} />}
+
       <div>
         <div className="mb-4 flex flex-row flex-wrap items-center justify-between">
           <h3 className="font-semibold">Job Logs</h3>
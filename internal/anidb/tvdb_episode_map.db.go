@@ -311,6 +311,49 @@ func (ms AniDBTVDBEpisodeMaps) GetByAnidbEpisode(ep int) *AniDBTVDBEpisodeMap {
 	return nil
 }
 
+// GetAniDBIds returns the anidb ids mapped to the tvdb season, all of them if
+// tvSeason is -1.
+func (ms AniDBTVDBEpisodeMaps) GetAniDBIds(tvSeason int) []string {
+	anidbIds := []string{}
+	for _, m := range ms {
+		if tvSeason != -1 && m.TVDBSeason != tvSeason {
+			continue
+		}
+		if !slices.Contains(anidbIds, m.AniDBId) {
+			anidbIds = append(anidbIds, m.AniDBId)
+		}
+	}
+	return anidbIds
+}
+
+// GetAniDBEpisode returns the anidb id and episode for the tvdb episode. For
+// the seasons split across multiple anidb ids, the one with the lowest
+// episode number wins.
+func (ms AniDBTVDBEpisodeMaps) GetAniDBEpisode(tvSeason int, tvEpisode int) (anidbId string, anidbEpisode int) {
+	anidbEpisode = -1
+	for _, m := range ms {
+		if m.TVDBSeason != tvSeason {
+			continue
+		}
+		for ep, tvdbEpisodes := range m.Map {
+			if slices.Contains(tvdbEpisodes, tvEpisode) {
+				return m.AniDBId, ep
+			}
+		}
+		ep := tvEpisode - m.Offset
+		if ep < 1 || (m.Start != 0 && ep < m.Start) || (m.End != 0 && ep > m.End) {
+			continue
+		}
+		if _, ok := m.Map[ep]; ok {
+			continue
+		}
+		if anidbEpisode == -1 || ep < anidbEpisode {
+			anidbId, anidbEpisode = m.AniDBId, ep
+		}
+	}
+	return anidbId, anidbEpisode
+}
+
 func (ms AniDBTVDBEpisodeMaps) GetTVDBId() string {
 	return ms[0].TVDBId
 }
@@ -513,12 +556,8 @@ func (r *AniDBTVDBEpisodeMapsResult) HasJoinedTVSeasons() bool {
 	return false
 }
 
-func GetTVDBEpisodeMaps(anidbId string, includeRelated bool) (*AniDBTVDBEpisodeMapsResult, error) {
-	query := query_get_tvdb_episode_maps_by_anidbid
-	if includeRelated {
-		query = query_get_tvdb_episode_maps_by_anidbid_with_related
-	}
-	rows, err := db.Query(query, anidbId)
+func queryTVDBEpisodeMaps(query string, args ...any) (AniDBTVDBEpisodeMaps, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -545,6 +584,35 @@ func GetTVDBEpisodeMaps(anidbId string, includeRelated bool) (*AniDBTVDBEpisodeM
 		}
 		maps = append(maps, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	maps.Sort()
+	return maps, nil
+}
+
+func GetTVDBEpisodeMaps(anidbId string, includeRelated bool) (*AniDBTVDBEpisodeMapsResult, error) {
+	query := query_get_tvdb_episode_maps_by_anidbid
+	if includeRelated {
+		query = query_get_tvdb_episode_maps_by_anidbid_with_related
+	}
+	maps, err := queryTVDBEpisodeMaps(query, anidbId)
+	if err != nil {
+		return nil, err
+	}
 	return &AniDBTVDBEpisodeMapsResult{AniDBTVDBEpisodeMaps: maps}, nil
 }
+
+var query_get_tvdb_episode_maps_by_tvdbid = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s = 1`,
+	db.JoinColumnNames(TVDBEpisodeMapColumns...),
+	TVDBEpisodeMapTableName,
+	TVDBEpisodeMapColumn.TVDBId,
+	TVDBEpisodeMapColumn.AniDBSeason,
+)
+
+// GetTVDBEpisodeMapsByTVDBId returns the maps of the regular anidb seasons
+// for the tvdb show.
+func GetTVDBEpisodeMapsByTVDBId(tvdbId string) (AniDBTVDBEpisodeMaps, error) {
+	return queryTVDBEpisodeMaps(query_get_tvdb_episode_maps_by_tvdbid, tvdbId)
+}
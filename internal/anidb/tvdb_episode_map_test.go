@@ -0,0 +1,38 @@
+package anidb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAniDBTVDBEpisodeMapsGetAniDBEpisode(t *testing.T) {
+	maps := AniDBTVDBEpisodeMaps{
+		// season 1 split across two anidb ids
+		{AniDBId: "100", TVDBId: "1", AniDBSeason: 1, TVDBSeason: 1, Start: 1, End: 12},
+		{AniDBId: "101", TVDBId: "1", AniDBSeason: 1, TVDBSeason: 1, Start: 1, End: 12, Offset: 12},
+		// season 2 with an explicitly mapped episode
+		{AniDBId: "200", TVDBId: "1", AniDBSeason: 1, TVDBSeason: 2, Map: AniDBTVDBEpisodeMapMap{1: {1, 2}}, Offset: 1},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		season  int
+		episode int
+		anidbId string
+		anidbEp int
+	}{
+		{"first part", 1, 3, "100", 3},
+		{"second part", 1, 15, "101", 3},
+		{"out of range", 1, 30, "", -1},
+		{"mapped", 2, 2, "200", 1},
+		{"offset", 2, 5, "200", 4},
+		{"unknown season", 3, 1, "", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			anidbId, anidbEp := maps.GetAniDBEpisode(tc.season, tc.episode)
+			assert.Equal(t, tc.anidbId, anidbId)
+			assert.Equal(t, tc.anidbEp, anidbEp)
+		})
+	}
+}
@@ -436,6 +436,50 @@ func GetAniDBIdByMALId(malId string) (anidbId, season string, err error) {
 	return anidbId, season, nil
 }
 
+var query_get_anidb_ids_by_tvdb_id = fmt.Sprintf(
+	`SELECT DISTINCT %s FROM %s WHERE %s = ? AND %s IS NOT NULL`,
+	IdMapColumn.AniDB,
+	IdMapTableName,
+	IdMapColumn.TVDB,
+	IdMapColumn.AniDB,
+)
+
+var query_get_anidb_ids_by_tmdb_id = fmt.Sprintf(
+	`SELECT DISTINCT %s FROM %s WHERE %s = ? AND %s IS NOT NULL`,
+	IdMapColumn.AniDB,
+	IdMapTableName,
+	IdMapColumn.TMDB,
+	IdMapColumn.AniDB,
+)
+
+func getAniDBIds(query string, id string) ([]string, error) {
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anidbIds := []string{}
+	for rows.Next() {
+		var anidbId string
+		if err := rows.Scan(&anidbId); err != nil {
+			return nil, err
+		}
+		if anidbId = normalizeOptionalId(anidbId); anidbId != "" {
+			anidbIds = append(anidbIds, anidbId)
+		}
+	}
+	return anidbIds, rows.Err()
+}
+
+func GetAniDBIdsByTVDBId(tvdbId string) ([]string, error) {
+	return getAniDBIds(query_get_anidb_ids_by_tvdb_id, tvdbId)
+}
+
+func GetAniDBIdsByTMDBId(tmdbId string) ([]string, error) {
+	return getAniDBIds(query_get_anidb_ids_by_tmdb_id, tmdbId)
+}
+
 var query_bulk_record_id_maps_before_values = fmt.Sprintf(
 	`INSERT INTO %s AS aim (%s) VALUES `,
 	IdMapTableName,
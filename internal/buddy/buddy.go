@@ -14,7 +14,7 @@ var DefaultHTTPClient = func() *http.Client {
 	transport := config.DefaultHTTPTransport.Clone()
 	transport.Proxy = config.Tunnel.GetProxy(config.TUNNEL_TYPE_NONE)
 	return &http.Client{
-		Transport: transport,
+		Transport: config.TracePropagatingHTTPTransport(transport),
 		Timeout:   60 * time.Second,
 	}
 }()
@@ -1,7 +1,7 @@
 package buddy
 
 import (
-	"regexp"
+	"errors"
 	"slices"
 	"sync"
 	"time"
@@ -153,15 +153,24 @@ func BulkTrackMagnet(s store.Store, tInfos []TorrentInfoInput, cached map[string
 }
 
 func CheckMagnet(s store.Store, hashes []string, storeToken string, clientIp string, sid string) (*store.CheckMagnetData, error) {
-	if matched, err := regexp.MatchString("^tt[0-9]+(:[0-9]{1,2}:[0-9]{1,3})?$", sid); err != nil || !matched {
-		sid = ""
+	var nsid *torrent_stream.NormalizedStremId
+	if sid != "" {
+		if n, err := torrent_stream.NormalizeStreamId(sid); err != nil {
+			if !errors.Is(err, torrent_stream.ErrUnsupportedStremId) {
+				buddyLog.Warn("failed to normalize strem id", "error", err, "sid", sid)
+			}
+			sid = ""
+		} else {
+			nsid = n
+			sid = nsid.ToSId()
+		}
 	}
 
 	data := &store.CheckMagnetData{
 		Items: []store.CheckMagnetDataItem{},
 	}
 
-	mcs, err := magnet_cache.GetByHashes(s.GetName().Code(), hashes, sid)
+	mcs, err := magnet_cache.GetByHashes(s.GetName().Code(), hashes, nsid)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +260,7 @@ func CheckMagnet(s store.Store, hashes []string, storeToken string, clientIp str
 							Name:   f.Name,
 							Size:   f.Size,
 							SId:    f.SId,
+							ASId:   f.ASId,
 							Source: f.Source,
 						})
 					}
@@ -267,15 +277,17 @@ func CheckMagnet(s store.Store, hashes []string, storeToken string, clientIp str
 	if config.HasPeer {
 		if config.PeerFlag.Lazy {
 			storeCode := string(s.GetName().Code())
-			for _, hash := range staleOrMissingHashes {
-				worker_queue.MagnetCachePullerQueue.Queue(worker_queue.MagnetCachePullerQueueItem{
+			items := make([]worker_queue.MagnetCachePullerQueueItem, len(staleOrMissingHashes))
+			for i, hash := range staleOrMissingHashes {
+				items[i] = worker_queue.MagnetCachePullerQueueItem{
 					ClientIP:   clientIp,
 					Hash:       hash,
 					SId:        sid,
 					StoreCode:  storeCode,
-					StoreToken: storeToken,
-				})
+					StoreToken: worker_queue.SecretToken(storeToken),
+				}
 			}
+			worker_queue.MagnetCachePullerQueue.QueueMany(items)
 			return data, nil
 		}
 
@@ -330,6 +342,8 @@ func CheckMagnet(s store.Store, hashes []string, storeToken string, clientIp str
 									Size:      f.Size,
 									Source:    f.Source,
 									VideoHash: f.VideoHash,
+									SId:       f.SId,
+									ASId:      f.ASId,
 								})
 							}
 						}
@@ -22,6 +22,7 @@ type CheckMagnetCacheDataItemFile struct {
 	Name   string `json:"n"`
 	Size   int64  `json:"s"`
 	SId    string `json:"sid"`
+	ASId   string `json:"asid,omitempty"`
 	Source string `json:"src"`
 }
 
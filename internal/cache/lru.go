@@ -4,14 +4,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/MunifTanjim/stremthru/internal/metrics"
+
 	"github.com/elastic/go-freelru"
 	"github.com/zeebo/xxh3"
 )
 
 type LRUCache[V any] struct {
-	c    *freelru.LRU[string, V]
-	name string
-	m    sync.Mutex
+	c       *freelru.LRU[string, V]
+	name    string
+	m       sync.Mutex
+	lookups *metrics.CacheLookupCounter
 }
 
 func (cache *LRUCache[V]) GetName() string {
@@ -40,6 +43,7 @@ func (cache *LRUCache[V]) Get(key string, value *V) bool {
 
 	val, ok := cache.c.Get(key)
 	*value = val
+	cache.lookups.Observe(ok)
 	return ok
 }
 
@@ -70,6 +74,6 @@ func NewLRUCache[V any](config *CacheConfig) *LRUCache[V] {
 	if config.Lifetime != 0 {
 		lru.SetLifetime(config.Lifetime)
 	}
-	cache := &LRUCache[V]{c: lru, name: config.Name}
+	cache := &LRUCache[V]{c: lru, name: config.Name, lookups: metrics.NewCacheLookupCounter(config.Name)}
 	return cache
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
 	"github.com/elastic/go-freelru"
 	rc "github.com/go-redis/cache/v9"
 	r "github.com/redis/go-redis/v9"
@@ -88,6 +89,7 @@ type RedisCache[V any] struct {
 	c        *rc.Cache
 	name     string
 	lifetime time.Duration
+	lookups  *metrics.CacheLookupCounter
 }
 
 func (cache *RedisCache[V]) GetName() string {
@@ -114,6 +116,7 @@ func (cache *RedisCache[V]) AddWithLifetime(key string, value V, lifetime time.D
 
 func (cache *RedisCache[V]) Get(key string, value *V) bool {
 	err := cache.c.Get(context.Background(), cache.name+":"+key, value)
+	cache.lookups.Observe(err == nil)
 	if err != nil {
 		return false
 	}
@@ -144,6 +147,7 @@ func newRedisCache[V any](conf *CacheConfig) *RedisCache[V] {
 		}),
 		name:     conf.Name,
 		lifetime: conf.Lifetime,
+		lookups:  metrics.NewCacheLookupCounter(conf.Name),
 	}
 
 	return cache
@@ -4,11 +4,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
 )
 
+// ResponseError is returned when Chillstreams responds with a non-OK status.
+type ResponseError struct {
+	StatusCode int
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("chillstreams returned %d", e.StatusCode)
+}
+
+// IsUnavailable reports whether err means Chillstreams could not serve the
+// request (network failure, timeout, rate limit or server error), as opposed
+// to an explicit rejection.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rerr *ResponseError
+	if errors.As(err, &rerr) {
+		return rerr.StatusCode >= http.StatusInternalServerError || rerr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
 type Client struct {
 	baseURL string
 	apiKey  string
@@ -19,10 +48,34 @@ func NewClient(baseURL, apiKey string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		client:  &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: observedTransport{base: config.TracePropagatingHTTPTransport(http.DefaultTransport)},
+		},
 	}
 }
 
+// observedTransport records the outcome of the Chillstreams API calls, the
+// operation is the path after `/internal/`, e.g. `pool/get-key`.
+type observedTransport struct {
+	base http.RoundTripper
+}
+
+func (t observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	operation := req.URL.Path
+	if _, op, ok := strings.Cut(operation, "/internal/"); ok {
+		operation = op
+	}
+	outcome := "error"
+	if err == nil {
+		outcome = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.ObserveChillstreamsRequest(operation, outcome, time.Since(start))
+	return resp, err
+}
+
 // GetPoolKey fetches assigned pool key for user
 func (c *Client) GetPoolKey(ctx context.Context, req GetPoolKeyRequest) (*GetPoolKeyResponse, error) {
 	body, _ := json.Marshal(req)
@@ -37,7 +90,7 @@ func (c *Client) GetPoolKey(ctx context.Context, req GetPoolKeyRequest) (*GetPoo
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("chillstreams returned %d", resp.StatusCode)
+		return nil, &ResponseError{StatusCode: resp.StatusCode}
 	}
 
 	var result GetPoolKeyResponse
@@ -68,11 +121,97 @@ func (c *Client) LogUsage(ctx context.Context, req LogUsageRequest) error {
 	return nil
 }
 
+type ReportKeyHealthRequest struct {
+	UserID    string          `json:"userId"`
+	PoolKeyID string          `json:"poolKeyId"`
+	Store     string          `json:"store"`
+	Status    KeyHealthStatus `json:"status"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// ReportKeyHealth reports a pool key that failed at the store, so that
+// Chillstreams can stop assigning it.
+func (c *Client) ReportKeyHealth(ctx context.Context, req ReportKeyHealthRequest) error {
+	body, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/internal/pool/report-health", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call chillstreams: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ResponseError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+type ReleaseDeviceRequest struct {
+	UserID   string `json:"userId"`
+	DeviceID string `json:"deviceId"`
+}
+
+// ReleaseDevice asks Chillstreams to stop counting the device towards the
+// user's device limit.
+func (c *Client) ReleaseDevice(ctx context.Context, req ReleaseDeviceRequest) error {
+	body, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/internal/pool/release-device", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call chillstreams: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ResponseError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+type LogUsageBatchRequest struct {
+	Events []LogUsageRequest `json:"events"`
+}
+
+// LogUsageBatch logs multiple pool key usage events to Chillstreams in a
+// single request.
+func (c *Client) LogUsageBatch(ctx context.Context, events []LogUsageRequest) error {
+	body, err := json.Marshal(LogUsageBatchRequest{Events: events})
+	if err != nil {
+		return err
+	}
+	httpReq, _ := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v1/internal/pool/log-usage/batch", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call chillstreams: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ResponseError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
 type GetPoolKeyRequest struct {
 	UserID   string `json:"userId"`
 	DeviceID string `json:"deviceId"`
 	Action   string `json:"action"`
 	Hash     string `json:"hash"`
+	Store    string `json:"store,omitempty"`
+
+	ExcludePoolKeyIDs []string `json:"excludePoolKeyIds,omitempty"` // pool keys that must not be assigned
 }
 
 type GetPoolKeyResponse struct {
@@ -81,6 +220,7 @@ type GetPoolKeyResponse struct {
 	Allowed     bool   `json:"allowed"`
 	DeviceCount int    `json:"deviceCount"`
 	Message     string `json:"message,omitempty"`
+	TTL         int    `json:"ttl,omitempty"` // seconds the pool key assignment stays valid
 }
 
 type LogUsageRequest struct {
@@ -90,5 +230,7 @@ type LogUsageRequest struct {
 	Hash      string `json:"hash"`
 	Cached    bool   `json:"cached"`
 	Bytes     int64  `json:"bytes"`
+	// identifies the event, so that Chillstreams can drop the ones that are
+	// delivered more than once.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
-
@@ -256,6 +256,54 @@ func TestLogUsage_Error(t *testing.T) {
 	}
 }
 
+func TestLogUsageBatch_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/internal/pool/log-usage/batch" {
+			t.Errorf("Expected /api/v1/internal/pool/log-usage/batch, got %s", r.URL.Path)
+		}
+
+		var req LogUsageBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		if len(req.Events) != 2 {
+			t.Errorf("Expected 2 events, got %d", len(req.Events))
+		} else if req.Events[1].Bytes != 2048 {
+			t.Errorf("Expected Bytes 2048, got %d", req.Events[1].Bytes)
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+
+	err := client.LogUsageBatch(context.Background(), []LogUsageRequest{
+		{UserID: "test-user", PoolKeyID: "key-123", Action: "stream-served", Bytes: 1024},
+		{UserID: "test-user", PoolKeyID: "key-123", Action: "stream-served", Bytes: 2048},
+	})
+
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestLogUsageBatch_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+
+	err := client.LogUsageBatch(context.Background(), []LogUsageRequest{{UserID: "test-user"}})
+
+	if !IsUnavailable(err) {
+		t.Errorf("Expected unavailable error, got %v", err)
+	}
+}
+
 func TestGetPoolKey_Timeout(t *testing.T) {
 	// Mock server with delay
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -278,4 +326,3 @@ func TestGetPoolKey_Timeout(t *testing.T) {
 		t.Errorf("Expected timeout error, got nil")
 	}
 }
-
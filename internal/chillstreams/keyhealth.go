@@ -0,0 +1,123 @@
+package chillstreams
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+)
+
+type KeyHealthStatus string
+
+const (
+	KeyHealthStatusRateLimited     KeyHealthStatus = "rate_limited"
+	KeyHealthStatusUnauthorized    KeyHealthStatus = "unauthorized"
+	KeyHealthStatusPaymentRequired KeyHealthStatus = "payment_required"
+)
+
+// only the auth and account level errors, the others (e.g. forbidden or limit
+// exceeded) can be about a single magnet and say nothing about the key.
+var keyHealthStatusByErrorCode = map[core.ErrorCode]KeyHealthStatus{
+	core.ErrorCodeTooManyRequests: KeyHealthStatusRateLimited,
+	core.ErrorCodeUnauthorized:    KeyHealthStatusUnauthorized,
+	core.ErrorCodePaymentRequired: KeyHealthStatusPaymentRequired,
+}
+
+// how long a pool key is kept out of rotation after a failure
+var cooldownByKeyHealthStatus = map[KeyHealthStatus]time.Duration{
+	KeyHealthStatusRateLimited:     2 * time.Minute,
+	KeyHealthStatusUnauthorized:    1 * time.Hour,
+	KeyHealthStatusPaymentRequired: 1 * time.Hour,
+}
+
+// GetKeyHealthStatus reports whether err returned by a store means the pool
+// key itself is unusable, and if so, in what way.
+func GetKeyHealthStatus(err error) (KeyHealthStatus, bool) {
+	if err == nil {
+		return "", false
+	}
+	var serr core.StremThruError
+	if !errors.As(err, &serr) {
+		return "", false
+	}
+	status, ok := keyHealthStatusByErrorCode[serr.GetError().Code]
+	return status, ok
+}
+
+type KeyCircuitBreakerStats struct {
+	Open    int   `json:"open"`
+	Tripped int64 `json:"tripped"`
+}
+
+// KeyCircuitBreaker keeps track of pool keys that recently failed, so that
+// they are not reused within this process until their cooldown is over.
+type KeyCircuitBreaker struct {
+	m         sync.Mutex
+	openUntil map[string]time.Time
+	tripped   atomic.Int64
+}
+
+func NewKeyCircuitBreaker() *KeyCircuitBreaker {
+	return &KeyCircuitBreaker{
+		openUntil: map[string]time.Time{},
+	}
+}
+
+// Trip takes the pool key out of rotation for the cooldown of status.
+func (b *KeyCircuitBreaker) Trip(poolKeyId string, status KeyHealthStatus) time.Time {
+	cooldown, ok := cooldownByKeyHealthStatus[status]
+	if !ok {
+		cooldown = 5 * time.Minute
+	}
+	until := time.Now().Add(cooldown)
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if current, ok := b.openUntil[poolKeyId]; !ok || current.Before(until) {
+		b.openUntil[poolKeyId] = until
+	}
+	b.tripped.Add(1)
+	return b.openUntil[poolKeyId]
+}
+
+func (b *KeyCircuitBreaker) IsOpen(poolKeyId string) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	until, ok := b.openUntil[poolKeyId]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(b.openUntil, poolKeyId)
+		return false
+	}
+	return true
+}
+
+// GetOpenKeyIds returns the ids of every pool key currently out of rotation.
+func (b *KeyCircuitBreaker) GetOpenKeyIds() []string {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	now := time.Now()
+	ids := make([]string, 0, len(b.openUntil))
+	for id, until := range b.openUntil {
+		if now.After(until) {
+			delete(b.openUntil, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (b *KeyCircuitBreaker) Stats() KeyCircuitBreakerStats {
+	return KeyCircuitBreakerStats{
+		Open:    len(b.GetOpenKeyIds()),
+		Tripped: b.tripped.Load(),
+	}
+}
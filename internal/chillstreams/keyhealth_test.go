@@ -0,0 +1,78 @@
+package chillstreams
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/core"
+)
+
+func TestGetKeyHealthStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status KeyHealthStatus
+		ok     bool
+	}{
+		{"nil", nil, "", false},
+		{"plain error", errors.New("boom"), "", false},
+		{"rate limited", func() error {
+			err := core.NewUpstreamError("cooldown")
+			err.Code = core.ErrorCodeTooManyRequests
+			return err
+		}(), KeyHealthStatusRateLimited, true},
+		{"unauthorized", func() error {
+			err := core.NewUpstreamError("bad token")
+			err.Code = core.ErrorCodeUnauthorized
+			return err
+		}(), KeyHealthStatusUnauthorized, true},
+		{"forbidden", func() error {
+			err := core.NewUpstreamError("infringing file")
+			err.Code = core.ErrorCodeForbidden
+			return err
+		}(), "", false},
+		{"store limit exceeded", func() error {
+			err := core.NewUpstreamError("torrent too big")
+			err.Code = core.ErrorCodeStoreLimitExceeded
+			return err
+		}(), "", false},
+		{"not found", func() error {
+			err := core.NewUpstreamError("not found")
+			err.Code = core.ErrorCodeNotFound
+			return err
+		}(), "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, ok := GetKeyHealthStatus(tc.err)
+			if status != tc.status || ok != tc.ok {
+				t.Errorf("Expected (%s, %v), got (%s, %v)", tc.status, tc.ok, status, ok)
+			}
+		})
+	}
+}
+
+func TestKeyCircuitBreaker(t *testing.T) {
+	breaker := NewKeyCircuitBreaker()
+
+	if breaker.IsOpen("key-1") {
+		t.Errorf("Expected key-1 to be closed")
+	}
+
+	breaker.Trip("key-1", KeyHealthStatusRateLimited)
+
+	if !breaker.IsOpen("key-1") {
+		t.Errorf("Expected key-1 to be open")
+	}
+	if breaker.IsOpen("key-2") {
+		t.Errorf("Expected key-2 to be closed")
+	}
+
+	ids := breaker.GetOpenKeyIds()
+	if len(ids) != 1 || ids[0] != "key-1" {
+		t.Errorf("Expected [key-1], got %v", ids)
+	}
+
+	if stats := breaker.Stats(); stats.Open != 1 || stats.Tripped != 1 {
+		t.Errorf("Expected 1 open and 1 tripped, got %+v", stats)
+	}
+}
@@ -0,0 +1,210 @@
+package chillstreams
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
+	"golang.org/x/sync/singleflight"
+)
+
+const leaseFetchTimeout = 10 * time.Second
+
+type PoolKeyLease struct {
+	PoolKey     string    `json:"k"`
+	PoolKeyID   string    `json:"kid"`
+	DeviceCount int       `json:"dc"`
+	RefreshAt   time.Time `json:"rat"`
+	ExpiresAt   time.Time `json:"eat"`
+	FailedAt    time.Time `json:"fat"`
+}
+
+func (l *PoolKeyLease) toResponse() *GetPoolKeyResponse {
+	return &GetPoolKeyResponse{
+		PoolKey:     l.PoolKey,
+		PoolKeyID:   l.PoolKeyID,
+		Allowed:     true,
+		DeviceCount: l.DeviceCount,
+	}
+}
+
+type PoolKeyLeaseStats struct {
+	Hit        int64 `json:"hit"`
+	Miss       int64 `json:"miss"`
+	Stale      int64 `json:"stale"`
+	FetchError int64 `json:"fetch_error"`
+}
+
+type PoolKeyLeaseCacheConfig struct {
+	Client      *Client
+	DefaultTTL  time.Duration // used when chillstreams does not send a ttl
+	GracePeriod time.Duration // how long an expired lease is served while chillstreams is unreachable
+	RetryAfter  time.Duration // how long to wait before retrying chillstreams after a failure
+}
+
+// PoolKeyLeaseCache keeps pool key assignments per (user, device, store),
+// refreshing them in the background before they expire and falling back to
+// the last known good key while Chillstreams is unreachable.
+type PoolKeyLeaseCache struct {
+	client      *Client
+	cache       cache.Cache[PoolKeyLease]
+	defaultTTL  time.Duration
+	gracePeriod time.Duration
+	retryAfter  time.Duration
+
+	g          singleflight.Group
+	refreshing sync.Map
+
+	hit        atomic.Int64
+	miss       atomic.Int64
+	stale      atomic.Int64
+	fetchError atomic.Int64
+}
+
+func NewPoolKeyLeaseCache(conf *PoolKeyLeaseCacheConfig) *PoolKeyLeaseCache {
+	if conf.DefaultTTL == 0 {
+		conf.DefaultTTL = 10 * time.Minute
+	}
+	if conf.RetryAfter == 0 {
+		conf.RetryAfter = 30 * time.Second
+	}
+
+	return &PoolKeyLeaseCache{
+		client: conf.Client,
+		cache: cache.NewCache[PoolKeyLease](&cache.CacheConfig{
+			Name:          "chillstreams:pool-key-lease",
+			Lifetime:      conf.DefaultTTL + conf.GracePeriod,
+			LocalCapacity: 4096,
+		}),
+		defaultTTL:  conf.DefaultTTL,
+		gracePeriod: conf.GracePeriod,
+		retryAfter:  conf.RetryAfter,
+	}
+}
+
+func getLeaseKey(req *GetPoolKeyRequest) string {
+	return req.UserID + ":" + req.DeviceID + ":" + req.Store
+}
+
+// Get returns the pool key assigned for req. Responses with Allowed=false are
+// returned as-is and never cached.
+func (c *PoolKeyLeaseCache) Get(ctx context.Context, req GetPoolKeyRequest) (*GetPoolKeyResponse, error) {
+	key := getLeaseKey(&req)
+
+	lease := PoolKeyLease{}
+	if !c.cache.Get(key, &lease) {
+		c.miss.Add(1)
+		res, _, err := c.fetch(ctx, key, req, nil)
+		return res, err
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(lease.ExpiresAt):
+		c.hit.Add(1)
+		if now.After(lease.RefreshAt) && now.After(lease.FailedAt.Add(c.retryAfter)) {
+			c.refreshInBackground(key, req, lease)
+		}
+		return lease.toResponse(), nil
+	case now.Before(lease.ExpiresAt.Add(c.gracePeriod)):
+		if now.Before(lease.FailedAt.Add(c.retryAfter)) {
+			c.stale.Add(1)
+			return lease.toResponse(), nil
+		}
+		res, isStale, err := c.fetch(ctx, key, req, &lease)
+		if isStale {
+			c.stale.Add(1)
+		}
+		return res, err
+	default:
+		c.miss.Add(1)
+		res, _, err := c.fetch(ctx, key, req, nil)
+		return res, err
+	}
+}
+
+// Invalidate drops the cached lease for req, forcing the next Get to ask
+// Chillstreams again.
+func (c *PoolKeyLeaseCache) Invalidate(req GetPoolKeyRequest) {
+	c.cache.Remove(getLeaseKey(&req))
+}
+
+func (c *PoolKeyLeaseCache) Stats() PoolKeyLeaseStats {
+	return PoolKeyLeaseStats{
+		Hit:        c.hit.Load(),
+		Miss:       c.miss.Load(),
+		Stale:      c.stale.Load(),
+		FetchError: c.fetchError.Load(),
+	}
+}
+
+// fetch asks Chillstreams for a fresh lease. If it is unreachable and
+// lastKnown is still within the grace period, lastKnown is returned instead.
+func (c *PoolKeyLeaseCache) fetch(ctx context.Context, key string, req GetPoolKeyRequest, lastKnown *PoolKeyLease) (res *GetPoolKeyResponse, isStale bool, err error) {
+	// the fetch is shared by the concurrent callers for the same lease, it
+	// runs detached from the caller that started it so that the others do
+	// not fail if that one is cancelled.
+	ch := c.g.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(tracing.Detach(ctx), leaseFetchTimeout)
+		defer cancel()
+		return c.client.GetPoolKey(fetchCtx, req)
+	})
+
+	var v any
+	select {
+	case <-ctx.Done():
+		return nil, false, context.Cause(ctx)
+	case r := <-ch:
+		v, err = r.Val, r.Err
+	}
+	if err != nil {
+		c.fetchError.Add(1)
+		if lastKnown != nil && IsUnavailable(err) && time.Now().Before(lastKnown.ExpiresAt.Add(c.gracePeriod)) {
+			lastKnown.FailedAt = time.Now()
+			c.set(key, lastKnown)
+			return lastKnown.toResponse(), true, nil
+		}
+		return nil, false, err
+	}
+
+	res = v.(*GetPoolKeyResponse)
+	if !res.Allowed || res.PoolKey == "" {
+		c.cache.Remove(key)
+		return res, false, nil
+	}
+
+	ttl := c.defaultTTL
+	if res.TTL > 0 {
+		ttl = time.Duration(res.TTL) * time.Second
+	}
+	now := time.Now()
+	c.set(key, &PoolKeyLease{
+		PoolKey:     res.PoolKey,
+		PoolKeyID:   res.PoolKeyID,
+		DeviceCount: res.DeviceCount,
+		RefreshAt:   now.Add(ttl * 4 / 5),
+		ExpiresAt:   now.Add(ttl),
+	})
+	return res, false, nil
+}
+
+func (c *PoolKeyLeaseCache) refreshInBackground(key string, req GetPoolKeyRequest, lease PoolKeyLease) {
+	if _, loaded := c.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer c.refreshing.Delete(key)
+
+		ctx, cancel := context.WithTimeout(context.Background(), leaseFetchTimeout)
+		defer cancel()
+
+		c.fetch(ctx, key, req, &lease)
+	}()
+}
+
+func (c *PoolKeyLeaseCache) set(key string, lease *PoolKeyLease) {
+	c.cache.AddWithLifetime(key, *lease, time.Until(lease.ExpiresAt)+c.gracePeriod)
+}
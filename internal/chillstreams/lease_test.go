@@ -0,0 +1,214 @@
+package chillstreams
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newLeaseTestServer(t *testing.T, status *atomic.Int32, calls *atomic.Int32, response GetPoolKeyResponse) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestPoolKeyLeaseCache_Hit(t *testing.T) {
+	status, calls := atomic.Int32{}, atomic.Int32{}
+	status.Store(http.StatusOK)
+	server := newLeaseTestServer(t, &status, &calls, GetPoolKeyResponse{
+		PoolKey:   "pool-key-123",
+		PoolKeyID: "key-id-456",
+		Allowed:   true,
+		TTL:       60,
+	})
+	defer server.Close()
+
+	leases := NewPoolKeyLeaseCache(&PoolKeyLeaseCacheConfig{
+		Client:      NewClient(server.URL, "test-key"),
+		GracePeriod: time.Minute,
+	})
+
+	req := GetPoolKeyRequest{UserID: "user-hit", DeviceID: "device-1", Store: "torbox"}
+	for range 3 {
+		resp, err := leases.Get(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.PoolKey != "pool-key-123" {
+			t.Errorf("Expected PoolKey pool-key-123, got %s", resp.PoolKey)
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 call to chillstreams, got %d", calls.Load())
+	}
+
+	stats := leases.Stats()
+	if stats.Miss != 1 || stats.Hit != 2 {
+		t.Errorf("Expected 1 miss and 2 hits, got %+v", stats)
+	}
+}
+
+func TestPoolKeyLeaseCache_ServesStaleWhenUnavailable(t *testing.T) {
+	status, calls := atomic.Int32{}, atomic.Int32{}
+	status.Store(http.StatusOK)
+	server := newLeaseTestServer(t, &status, &calls, GetPoolKeyResponse{
+		PoolKey:   "pool-key-123",
+		PoolKeyID: "key-id-456",
+		Allowed:   true,
+		TTL:       1,
+	})
+	defer server.Close()
+
+	leases := NewPoolKeyLeaseCache(&PoolKeyLeaseCacheConfig{
+		Client:      NewClient(server.URL, "test-key"),
+		GracePeriod: time.Minute,
+	})
+
+	req := GetPoolKeyRequest{UserID: "user-stale", DeviceID: "device-1", Store: "torbox"}
+	if _, err := leases.Get(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	status.Store(http.StatusServiceUnavailable)
+	time.Sleep(1100 * time.Millisecond)
+
+	resp, err := leases.Get(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected stale lease, got error %v", err)
+	}
+	if resp.PoolKey != "pool-key-123" {
+		t.Errorf("Expected stale PoolKey pool-key-123, got %s", resp.PoolKey)
+	}
+
+	// retry is backed off, so chillstreams should not be called again
+	if _, err := leases.Get(context.Background(), req); err != nil {
+		t.Fatalf("Expected stale lease, got error %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 calls to chillstreams, got %d", calls.Load())
+	}
+
+	if stats := leases.Stats(); stats.Stale != 2 {
+		t.Errorf("Expected 2 stale, got %+v", stats)
+	}
+}
+
+func TestPoolKeyLeaseCache_NotAllowedIsNotCached(t *testing.T) {
+	status, calls := atomic.Int32{}, atomic.Int32{}
+	status.Store(http.StatusOK)
+	server := newLeaseTestServer(t, &status, &calls, GetPoolKeyResponse{
+		Allowed: false,
+		Message: "Maximum device limit reached",
+	})
+	defer server.Close()
+
+	leases := NewPoolKeyLeaseCache(&PoolKeyLeaseCacheConfig{
+		Client:      NewClient(server.URL, "test-key"),
+		GracePeriod: time.Minute,
+	})
+
+	req := GetPoolKeyRequest{UserID: "user-denied", DeviceID: "device-1", Store: "torbox"}
+	for range 2 {
+		resp, err := leases.Get(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.Allowed {
+			t.Errorf("Expected Allowed false, got true")
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected 2 calls to chillstreams, got %d", calls.Load())
+	}
+}
+
+func TestPoolKeyLeaseCache_ErrorWithoutLastKnown(t *testing.T) {
+	status, calls := atomic.Int32{}, atomic.Int32{}
+	status.Store(http.StatusInternalServerError)
+	server := newLeaseTestServer(t, &status, &calls, GetPoolKeyResponse{})
+	defer server.Close()
+
+	leases := NewPoolKeyLeaseCache(&PoolKeyLeaseCacheConfig{
+		Client:      NewClient(server.URL, "test-key"),
+		GracePeriod: time.Minute,
+	})
+
+	_, err := leases.Get(context.Background(), GetPoolKeyRequest{UserID: "user-error", DeviceID: "device-1", Store: "torbox"})
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	if !IsUnavailable(err) {
+		t.Errorf("Expected unavailable error, got %v", err)
+	}
+}
+
+func TestPoolKeyLeaseCache_SharedFetchSurvivesCancelledCaller(t *testing.T) {
+	calls := atomic.Int32{}
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		received <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetPoolKeyResponse{PoolKey: "pool-key-123", PoolKeyID: "key-id-456", Allowed: true, TTL: 60})
+	}))
+	defer server.Close()
+
+	leases := NewPoolKeyLeaseCache(&PoolKeyLeaseCacheConfig{
+		Client:      NewClient(server.URL, "test-key"),
+		GracePeriod: time.Minute,
+	})
+
+	req := GetPoolKeyRequest{UserID: "user-shared", DeviceID: "device-1", Store: "torbox"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := leases.Get(ctx, req)
+		firstErr <- err
+	}()
+	<-received
+
+	type result struct {
+		resp *GetPoolKeyResponse
+		err  error
+	}
+	second := make(chan result, 1)
+	go func() {
+		resp, err := leases.Get(context.Background(), req)
+		second <- result{resp, err}
+	}()
+	// let the second caller join the shared fetch
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; err == nil {
+		t.Errorf("Expected error for the cancelled caller, got nil")
+	}
+
+	close(release)
+	res := <-second
+	if res.err != nil {
+		t.Fatalf("Expected no error for the waiting caller, got %v", res.err)
+	}
+	if res.resp.PoolKey != "pool-key-123" {
+		t.Errorf("Expected PoolKey pool-key-123, got %s", res.resp.PoolKey)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 call to chillstreams, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,409 @@
+package chillstreams_usage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/util"
+	"github.com/google/uuid"
+)
+
+const TableName = "chillstreams_usage_outbox"
+
+var log = logger.Scoped(TableName)
+
+const (
+	StatusPending = "pending"
+	StatusDead    = "dead"
+)
+
+const (
+	MaxAttempts    = 10
+	BackoffBase    = 30 * time.Second
+	BackoffMax     = 1 * time.Hour
+	DeadRetainedBy = 7 * 24 * time.Hour
+)
+
+// claimed events not delivered or failed within the lease are picked up
+// again, e.g. after the claiming instance crashed. An event delivered after
+// its lease ran out can be sent twice, the idempotency key lets Chillstreams
+// drop the duplicate.
+const claimLeaseDuration = 5 * time.Minute
+
+type UsageEvent struct {
+	Id             int64
+	UserId         string
+	PoolKeyId      string
+	Action         string
+	Hash           string
+	Cached         bool
+	Bytes          int64
+	IdempotencyKey string
+	Status         string
+	Attempts       int
+	NextAttemptAt  db.Timestamp
+	LastError      string
+	CAt            db.Timestamp
+	UAt            db.Timestamp
+}
+
+func (e *UsageEvent) ToRequest() chillstreams.LogUsageRequest {
+	return chillstreams.LogUsageRequest{
+		UserID:    e.UserId,
+		PoolKeyID: e.PoolKeyId,
+		Action:    e.Action,
+		Hash:      e.Hash,
+		Cached:    e.Cached,
+		Bytes:     e.Bytes,
+
+		IdempotencyKey: e.IdempotencyKey,
+	}
+}
+
+var Column = struct {
+	Id             string
+	UserId         string
+	PoolKeyId      string
+	Action         string
+	Hash           string
+	Cached         string
+	Bytes          string
+	IdempotencyKey string
+	Status         string
+	Attempts       string
+	NextAttemptAt  string
+	LastError      string
+	ClaimedBy      string
+	ClaimedUntil   string
+	CAt            string
+	UAt            string
+}{
+	Id:             "id",
+	UserId:         "user_id",
+	PoolKeyId:      "pool_key_id",
+	Action:         "action",
+	Hash:           "hash",
+	Cached:         "cached",
+	Bytes:          "bytes",
+	IdempotencyKey: "idempotency_key",
+	Status:         "status",
+	Attempts:       "attempts",
+	NextAttemptAt:  "next_attempt_at",
+	LastError:      "last_error",
+	ClaimedBy:      "claimed_by",
+	ClaimedUntil:   "claimed_until",
+	CAt:            "cat",
+	UAt:            "uat",
+}
+
+var columns = []string{
+	Column.Id,
+	Column.UserId,
+	Column.PoolKeyId,
+	Column.Action,
+	Column.Hash,
+	Column.Cached,
+	Column.Bytes,
+	Column.IdempotencyKey,
+	Column.Status,
+	Column.Attempts,
+	Column.NextAttemptAt,
+	Column.LastError,
+	Column.CAt,
+	Column.UAt,
+}
+
+var query_enqueue = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?,?,?,?,?,?,?)`,
+	TableName,
+	db.JoinColumnNames(
+		Column.UserId,
+		Column.PoolKeyId,
+		Column.Action,
+		Column.Hash,
+		Column.Cached,
+		Column.Bytes,
+		Column.IdempotencyKey,
+		Column.Status,
+		Column.NextAttemptAt,
+	),
+)
+
+// Enqueue stores the usage event in the outbox, to be delivered to
+// Chillstreams by the report-chillstreams-usage worker.
+func Enqueue(req *chillstreams.LogUsageRequest) error {
+	idempotencyKey := req.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	_, err := db.Exec(query_enqueue,
+		req.UserID,
+		req.PoolKeyID,
+		req.Action,
+		req.Hash,
+		req.Cached,
+		req.Bytes,
+		idempotencyKey,
+		StatusPending,
+		db.Timestamp{Time: time.Now()},
+	)
+	return err
+}
+
+// Record enqueues the usage event, logging instead of failing on error.
+func Record(req *chillstreams.LogUsageRequest) {
+	if err := Enqueue(req); err != nil {
+		log.Error("failed to enqueue usage event", "error", err, "user_id", req.UserID, "pool_key_id", req.PoolKeyID, "action", req.Action)
+	}
+}
+
+var query_has_due = fmt.Sprintf(
+	`SELECT 1 FROM %s WHERE %s = ? AND %s <= ? AND (%s IS NULL OR %s <= ?) LIMIT 1`,
+	TableName,
+	Column.Status,
+	Column.NextAttemptAt,
+	Column.ClaimedUntil,
+	Column.ClaimedUntil,
+)
+
+// HasDue checks if there is any unclaimed pending event whose next attempt
+// is due.
+func HasDue() (bool, error) {
+	now := db.Timestamp{Time: time.Now()}
+	one := 0
+	err := db.QueryRow(query_has_due, StatusPending, now, now).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var query_get_claimable = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s <= ? AND (%s IS NULL OR %s <= ?) ORDER BY %s ASC LIMIT ?`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.Status,
+	Column.NextAttemptAt,
+	Column.ClaimedUntil,
+	Column.ClaimedUntil,
+	Column.Id,
+)
+
+var query_claim_before_values = fmt.Sprintf(
+	`UPDATE %s SET %s = ?, %s = ? WHERE %s IN `,
+	TableName,
+	Column.ClaimedBy,
+	Column.ClaimedUntil,
+	Column.Id,
+)
+
+// Claim leases up to limit pending events whose next attempt is due to this
+// instance, oldest first. The advisory lock makes sure concurrent instances
+// never claim the same events.
+func Claim(limit int) ([]UsageEvent, error) {
+	lock := db.NewAdvisoryLock(TableName, "claim")
+	if lock == nil {
+		return nil, errors.New("failed to create advisory lock")
+	}
+	if !lock.TryAcquire() {
+		log.Debug("skipping claim, another instance is claiming")
+		return nil, lock.Err()
+	}
+	defer lock.Release()
+
+	now := time.Now()
+	rows, err := lock.Query(query_get_claimable, StatusPending, db.Timestamp{Time: now}, db.Timestamp{Time: now}, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []UsageEvent{}
+	for rows.Next() {
+		item := UsageEvent{}
+		if err := rows.Scan(
+			&item.Id,
+			&item.UserId,
+			&item.PoolKeyId,
+			&item.Action,
+			&item.Hash,
+			&item.Cached,
+			&item.Bytes,
+			&item.IdempotencyKey,
+			&item.Status,
+			&item.Attempts,
+			&item.NextAttemptAt,
+			&item.LastError,
+			&item.CAt,
+			&item.UAt,
+		); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	args := make([]any, 0, 2+len(items))
+	args = append(args, config.InstanceId, db.Timestamp{Time: now.Add(claimLeaseDuration)})
+	args = append(args, getIds(items)...)
+	query := query_claim_before_values + "(" + util.RepeatJoin("?", len(items), ",") + ")"
+	if _, err := lock.Exec(query, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func getIds(items []UsageEvent) []any {
+	ids := make([]any, len(items))
+	for i := range items {
+		ids[i] = items[i].Id
+	}
+	return ids
+}
+
+var query_delete_before_values = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s IN `,
+	TableName,
+	Column.Id,
+)
+
+// Delete removes delivered events from the outbox.
+func Delete(items []UsageEvent) error {
+	if len(items) == 0 {
+		return nil
+	}
+	query := query_delete_before_values + "(" + util.RepeatJoin("?", len(items), ",") + ")"
+	_, err := db.Exec(query, getIds(items)...)
+	return err
+}
+
+// GetBackoff returns the delay before the next delivery attempt, after the
+// given number of failed attempts.
+func GetBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := float64(BackoffBase) * math.Pow(2, float64(attempts-1))
+	if backoff > float64(BackoffMax) {
+		return BackoffMax
+	}
+	return time.Duration(backoff)
+}
+
+var query_mark_failed = fmt.Sprintf(
+	`UPDATE %s SET %s = ?, %s = ?, %s = ?, %s = ?, %s = '', %s = NULL, %s = %s WHERE %s = ?`,
+	TableName,
+	Column.Status,
+	Column.Attempts,
+	Column.NextAttemptAt,
+	Column.LastError,
+	Column.ClaimedBy,
+	Column.ClaimedUntil,
+	Column.UAt,
+	db.CurrentTimestamp,
+	Column.Id,
+)
+
+// MarkFailed schedules the events for another attempt with exponential
+// backoff, or moves them to the dead letter state once MaxAttempts is reached,
+// releasing the claim.
+// It returns the number of events that were dead-lettered.
+func MarkFailed(items []UsageEvent, cause error) (deadCount int, err error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	lastError := cause.Error()
+	now := time.Now()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	for i := range items {
+		item := &items[i]
+		item.Attempts++
+		item.LastError = lastError
+		if item.Attempts >= MaxAttempts {
+			item.Status = StatusDead
+			deadCount++
+		} else {
+			item.NextAttemptAt = db.Timestamp{Time: now.Add(GetBackoff(item.Attempts))}
+		}
+		if _, err := tx.Exec(query_mark_failed, item.Status, item.Attempts, item.NextAttemptAt, item.LastError, item.Id); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return deadCount, tx.Commit()
+}
+
+var query_purge_dead = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ? AND %s < ?`,
+	TableName,
+	Column.Status,
+	Column.UAt,
+)
+
+// PurgeDead removes dead-lettered events that have not been touched for
+// DeadRetainedBy.
+func PurgeDead() (int64, error) {
+	result, err := db.Exec(query_purge_dead, StatusDead, db.Timestamp{Time: time.Now().Add(-DeadRetainedBy)})
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type Stats struct {
+	Pending int64 `json:"pending"`
+	Dead    int64 `json:"dead"`
+}
+
+var query_get_stats = fmt.Sprintf(
+	`SELECT %s, COUNT(*) FROM %s GROUP BY %s`,
+	Column.Status,
+	TableName,
+	Column.Status,
+)
+
+func GetStats() (*Stats, error) {
+	rows, err := db.Query(query_get_stats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &Stats{}
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		switch status {
+		case StatusPending:
+			stats.Pending = count
+		case StatusDead:
+			stats.Dead = count
+		}
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,109 @@
+package chillstreams_usage
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+)
+
+// the bytes served for the running streams are enqueued this often, so that
+// at most this much usage is lost if the instance crashes.
+const streamFlushInterval = 30 * time.Second
+
+type stream struct {
+	event  chillstreams.LogUsageRequest
+	active int
+	bytes  int64 // not enqueued yet
+}
+
+var streamsMutex sync.Mutex
+var streamByKey = map[string]*stream{}
+
+var startStreamFlusher = sync.OnceFunc(func() {
+	go func() {
+		ticker := time.NewTicker(streamFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			FlushStreams()
+		}
+	}()
+})
+
+// StreamWriter counts the bytes written to the client for the stream.
+type StreamWriter struct {
+	http.ResponseWriter
+
+	stream *stream
+}
+
+// NewStreamWriter wraps w to count the bytes served for the stream
+// identified by key (e.g. the proxy link token) across all of its requests,
+// Close must be called once the request ends. The bytes are enqueued as
+// usage events every streamFlushInterval while the stream runs, and by
+// FlushStreams on shutdown.
+func NewStreamWriter(w http.ResponseWriter, key string, event *chillstreams.LogUsageRequest) *StreamWriter {
+	startStreamFlusher()
+
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+	s, ok := streamByKey[key]
+	if !ok {
+		s = &stream{event: *event}
+		streamByKey[key] = s
+	}
+	s.active++
+
+	return &StreamWriter{ResponseWriter: w, stream: s}
+}
+
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if n > 0 {
+		streamsMutex.Lock()
+		w.stream.bytes += int64(n)
+		streamsMutex.Unlock()
+	}
+	return n, err
+}
+
+func (w *StreamWriter) Close() {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+	w.stream.active--
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *StreamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// takeStreamUsages returns the usage events for the bytes served since the
+// last call, and removes the streams without any running request.
+func takeStreamUsages() []chillstreams.LogUsageRequest {
+	streamsMutex.Lock()
+	defer streamsMutex.Unlock()
+
+	events := []chillstreams.LogUsageRequest{}
+	for key, s := range streamByKey {
+		if s.bytes > 0 {
+			event := s.event
+			event.Bytes = s.bytes
+			events = append(events, event)
+			s.bytes = 0
+		}
+		if s.active == 0 {
+			delete(streamByKey, key)
+		}
+	}
+	return events
+}
+
+// FlushStreams enqueues the bytes served for the streams since the last
+// flush. It must be called on shutdown, after the server has stopped.
+func FlushStreams() {
+	for _, event := range takeStreamUsages() {
+		Record(&event)
+	}
+}
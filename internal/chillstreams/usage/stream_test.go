@@ -0,0 +1,38 @@
+package chillstreams_usage
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWriter(t *testing.T) {
+	event := &chillstreams.LogUsageRequest{UserID: "user", PoolKeyID: "key", Action: "stream-served", Hash: "hash"}
+
+	w1 := NewStreamWriter(httptest.NewRecorder(), "token-a", event)
+	w2 := NewStreamWriter(httptest.NewRecorder(), "token-a", event)
+	w3 := NewStreamWriter(httptest.NewRecorder(), "token-b", event)
+	w1.Write(make([]byte, 100))
+	w2.Write(make([]byte, 50))
+	w1.Close()
+	w3.Close()
+
+	events := takeStreamUsages()
+	if assert.Len(t, events, 1, "stream without bytes is dropped") {
+		assert.Equal(t, "user", events[0].UserID)
+		assert.Equal(t, int64(150), events[0].Bytes, "bytes of all the requests of the stream are reported together")
+	}
+	assert.Len(t, streamByKey, 1, "stream with a running request is kept")
+
+	assert.Empty(t, takeStreamUsages(), "nothing is reported without new bytes")
+
+	w2.Write(make([]byte, 25))
+	w2.Close()
+	events = takeStreamUsages()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, int64(25), events[0].Bytes, "only the bytes since the last flush are reported")
+	}
+	assert.Empty(t, streamByKey)
+}
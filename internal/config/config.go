@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -56,6 +57,7 @@ var defaultValueByEnv = map[string]map[string]string{
 	},
 	"": {
 		"STREMTHRU_BASE_URL":                               "http://localhost:8080",
+		"STREMTHRU_CONTENT_PROXY_CACHE_CHUNK_SIZE":         "4MB",
 		"STREMTHRU_CONTENT_PROXY_CONNECTION_LIMIT":         "*:0",
 		"STREMTHRU_DATABASE_URI":                           "sqlite://./data/stremthru.db",
 		"STREMTHRU_DATA_DIR":                               "./data",
@@ -77,11 +79,18 @@ var defaultValueByEnv = map[string]map[string]string{
 		"STREMTHRU_STREMIO_STORE_CATALOG_ITEM_LIMIT":       "2000",
 		"STREMTHRU_STREMIO_STORE_CATALOG_CACHE_TIME":       "10m",
 		"STREMTHRU_STREMIO_TORZ_INDEXER_MAX_TIMEOUT":       "10s",
+		"STREMTHRU_STREMIO_TORZ_INDEXER_RESULT_QUOTA":      "0",
 		"STREMTHRU_STREMIO_TORZ_PUBLIC_MAX_INDEXER_COUNT":  "2",
 		"STREMTHRU_STREMIO_TORZ_PUBLIC_MAX_STORE_COUNT":    "3",
 		"STREMTHRU_STREMIO_WRAP_PUBLIC_MAX_UPSTREAM_COUNT": "5",
 		"STREMTHRU_STREMIO_WRAP_PUBLIC_MAX_STORE_COUNT":    "3",
 		"STREMTHRU_IP_CHECKER":                             "aws",
+		"STREMTHRU_MAGNET_CACHE_WARMER_STORE_BUDGET":       "*:500",
+		"STREMTHRU_MAGNET_CACHE_WARMER_TITLE_LIMIT":        "100",
+		"STREMTHRU_MAGNET_CACHE_WARMER_TORRENT_LIMIT":      "10",
+		"CHILLSTREAMS_POOL_KEY_LEASE_TTL":                  "10m",
+		"CHILLSTREAMS_POOL_KEY_GRACE_PERIOD":               "15m",
+		"CHILLSTREAMS_TRUSTED_PROXY_CIDRS":                 "127.0.0.0/8,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,::1/128,fc00::/7",
 	},
 }
 
@@ -639,6 +648,22 @@ func init() {
 	}
 	ChillstreamsAPIKey = getEnv("CHILLSTREAMS_API_KEY")
 	EnableChillstreamsAuth = getEnv("ENABLE_CHILLSTREAMS_AUTH") == "true"
+	ChillstreamsPoolKeyLeaseTTL = mustParseDuration("chillstreams pool key lease ttl", getEnv("CHILLSTREAMS_POOL_KEY_LEASE_TTL"), 30*time.Second)
+	ChillstreamsPoolKeyGracePeriod = mustParseDuration("chillstreams pool key grace period", getEnv("CHILLSTREAMS_POOL_KEY_GRACE_PERIOD"), 0, 24*time.Hour)
+	ChillstreamsDeviceSecret = getEnv("CHILLSTREAMS_DEVICE_SECRET")
+	ChillstreamsRequireDeviceToken = getEnv("CHILLSTREAMS_REQUIRE_DEVICE_TOKEN") == "true"
+	if ChillstreamsRequireDeviceToken && ChillstreamsDeviceSecret == "" {
+		log.Fatal("CHILLSTREAMS_REQUIRE_DEVICE_TOKEN needs CHILLSTREAMS_DEVICE_SECRET")
+	}
+	for _, cidr := range strings.FieldsFunc(getEnv("CHILLSTREAMS_TRUSTED_PROXY_CIDRS"), func(c rune) bool {
+		return c == ','
+	}) {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			log.Fatalf("invalid chillstreams trusted proxy cidr (%s): %v", cidr, err)
+		}
+		ChillstreamsTrustedProxyCIDRs = append(ChillstreamsTrustedProxyCIDRs, prefix)
+	}
 }
 
 var LogLevel = config.LogLevel
@@ -802,6 +827,21 @@ func PrintConfig(state *AppState) {
 			if cpcl := ContentProxyConnectionLimit.Get(user); cpcl > 0 {
 				l.Println("       content_proxy_connection_limit: " + strconv.FormatUint(uint64(cpcl), 10))
 			}
+			if cprl := ContentProxy.RateLimit.Get(user); cprl > 0 {
+				l.Println("       content_proxy_rate_limit: " + util.ToSize(cprl) + "/s")
+			}
+			if cpdq := ContentProxy.DailyQuota.Get(user); cpdq > 0 {
+				l.Println("       content_proxy_daily_quota: " + util.ToSize(cpdq))
+			}
+			if cpmq := ContentProxy.MonthlyQuota.Get(user); cpmq > 0 {
+				l.Println("       content_proxy_monthly_quota: " + util.ToSize(cpmq))
+			}
+		}
+		if ContentProxy.GlobalRateLimit > 0 {
+			l.Println("   content_proxy_global_rate_limit: " + util.ToSize(ContentProxy.GlobalRateLimit) + "/s")
+		}
+		if ContentProxy.IsCacheEnabled() {
+			l.Println("   content_proxy_cache: " + util.ToSize(ContentProxy.CacheSize) + " (chunk: " + util.ToSize(ContentProxy.CacheChunkSize) + ")")
 		}
 		l.Println()
 	}
@@ -879,6 +919,17 @@ func PrintConfig(state *AppState) {
 		l.Println()
 	}
 
+	if MagnetCacheWarmer.IsEnabled() {
+		l.Println(" Magnet Cache Warmer:")
+		l.Println("   title limit: " + strconv.Itoa(MagnetCacheWarmer.TitleLimit))
+		l.Println("   torrent limit: " + strconv.Itoa(MagnetCacheWarmer.TorrentLimit))
+		l.Println("   chillstreams user: " + MagnetCacheWarmer.ChillstreamsUserId)
+		for _, storeName := range MagnetCacheWarmer.Stores {
+			l.Println("   - " + storeName + " (budget: " + strconv.Itoa(MagnetCacheWarmer.StoreBudget.Get(storeName)) + "/h)")
+		}
+		l.Println()
+	}
+
 	if RedisURI != "" {
 		uri, err := getRedactedURI(RedisURI)
 		if err != nil {
@@ -933,6 +984,9 @@ func PrintConfig(state *AppState) {
 				break
 			}
 			l.Println("            indexer max timeout: " + Stremio.Torz.IndexerMaxTimeout.String())
+			if Stremio.Torz.IndexerResultQuota > 0 {
+				l.Println("           indexer result quota: " + strconv.Itoa(Stremio.Torz.IndexerResultQuota))
+			}
 			l.Println("       public max indexer count: " + strconv.Itoa(Stremio.Torz.PublicMaxIndexerCount))
 			l.Println("         public max store count: " + strconv.Itoa(Stremio.Torz.PublicMaxStoreCount))
 			if Stremio.Torz.LazyPull {
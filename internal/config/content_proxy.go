@@ -0,0 +1,92 @@
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+// ContentProxyByteLimitMap has the limit in bytes by user, `*` is the default
+// for the users without one.
+type ContentProxyByteLimitMap map[string]int64
+
+func (m ContentProxyByteLimitMap) Get(user string) int64 {
+	if limit, ok := m[user]; ok {
+		return limit
+	}
+	return m["*"]
+}
+
+func parseContentProxyByteLimitMap(key string) ContentProxyByteLimitMap {
+	m := ContentProxyByteLimitMap{}
+	for _, item := range strings.FieldsFunc(getEnv(key), func(c rune) bool {
+		return c == ','
+	}) {
+		if user, size, ok := strings.Cut(item, ":"); ok {
+			bytes := util.ToBytes(size)
+			if bytes < 0 {
+				log.Fatalf("Invalid %s: %s", key, item)
+			}
+			m[user] = bytes
+		}
+	}
+	return m
+}
+
+type contentProxyConfig struct {
+	// bytes per second, by user
+	RateLimit ContentProxyByteLimitMap
+	// bytes per second, across all users
+	GlobalRateLimit int64
+	DailyQuota      ContentProxyByteLimitMap
+	MonthlyQuota    ContentProxyByteLimitMap
+
+	// disk budget for the chunk cache, 0 if disabled
+	CacheSize      int64
+	CacheChunkSize int64
+	CacheDir       string
+}
+
+func (conf contentProxyConfig) IsCacheEnabled() bool {
+	return conf.CacheSize > 0
+}
+
+func (conf contentProxyConfig) HasQuota(user string) bool {
+	return conf.DailyQuota.Get(user) > 0 || conf.MonthlyQuota.Get(user) > 0
+}
+
+var ContentProxy = func() contentProxyConfig {
+	conf := contentProxyConfig{
+		RateLimit:    parseContentProxyByteLimitMap("STREMTHRU_CONTENT_PROXY_RATE_LIMIT"),
+		DailyQuota:   parseContentProxyByteLimitMap("STREMTHRU_CONTENT_PROXY_DAILY_QUOTA"),
+		MonthlyQuota: parseContentProxyByteLimitMap("STREMTHRU_CONTENT_PROXY_MONTHLY_QUOTA"),
+	}
+
+	if value := getEnv("STREMTHRU_CONTENT_PROXY_GLOBAL_RATE_LIMIT"); value != "" {
+		conf.GlobalRateLimit = util.ToBytes(value)
+		if conf.GlobalRateLimit < 0 {
+			log.Fatalf("Invalid content proxy global rate limit: %s", value)
+		}
+	}
+
+	if value := getEnv("STREMTHRU_CONTENT_PROXY_CACHE_SIZE"); value != "" {
+		conf.CacheSize = util.ToBytes(value)
+		if conf.CacheSize < 0 {
+			log.Fatalf("Invalid content proxy cache size: %s", value)
+		}
+	}
+
+	conf.CacheChunkSize = util.ToBytes(getEnv("STREMTHRU_CONTENT_PROXY_CACHE_CHUNK_SIZE"))
+	if conf.CacheChunkSize <= 0 {
+		log.Fatalf("Invalid content proxy cache chunk size: %s", getEnv("STREMTHRU_CONTENT_PROXY_CACHE_CHUNK_SIZE"))
+	}
+	if conf.CacheSize > 0 && conf.CacheSize < conf.CacheChunkSize {
+		log.Fatalf("Content proxy cache size must be at least the chunk size")
+	}
+
+	conf.CacheDir = filepath.Join(DataDir, "content_proxy_cache")
+
+	return conf
+}()
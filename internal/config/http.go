@@ -275,7 +275,7 @@ var DefaultHTTPTransport = func() *http.Transport {
 var DefaultHTTPClient = func() *http.Client {
 	transport := DefaultHTTPTransport.Clone()
 	return &http.Client{
-		Transport: transport,
+		Transport: TraceHTTPTransport(transport),
 		Timeout:   90 * time.Second,
 	}
 }()
@@ -284,7 +284,7 @@ func GetHTTPClient(tunnelType TunnelType) *http.Client {
 	transport := DefaultHTTPTransport.Clone()
 	transport.Proxy = Tunnel.GetProxy(tunnelType)
 	return &http.Client{
-		Transport: transport,
+		Transport: TraceHTTPTransport(transport),
 		Timeout:   90 * time.Second,
 	}
 }
@@ -295,7 +295,7 @@ func getHTTPClientWithProxy(proxyUrl *url.URL) *http.Client {
 		return proxyUrl, nil
 	}
 	return &http.Client{
-		Transport: transport,
+		Transport: TraceHTTPTransport(transport),
 		Timeout:   90 * time.Second,
 	}
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/netip"
 	"net/url"
 	"time"
 )
@@ -78,9 +79,9 @@ func (c *IntegrationTraktConfig) IsEnabled() bool {
 }
 
 type IntegrationTVDBConfig struct {
-	APIKey               string
-	SystemOAuthTokenId   string
-	ListStaleTime        time.Duration
+	APIKey             string
+	SystemOAuthTokenId string
+	ListStaleTime      time.Duration
 }
 
 func (c *IntegrationTVDBConfig) IsEnabled() bool {
@@ -105,8 +106,12 @@ var Integration *IntegrationConfig
 
 // Chillstreams integration config variables
 var (
-	ChillstreamsAPIURL       string
-	ChillstreamsAPIKey       string
-	EnableChillstreamsAuth   bool
+	ChillstreamsAPIURL             string
+	ChillstreamsAPIKey             string
+	EnableChillstreamsAuth         bool
+	ChillstreamsPoolKeyLeaseTTL    time.Duration
+	ChillstreamsPoolKeyGracePeriod time.Duration
+	ChillstreamsDeviceSecret       string         // signs device tokens, device tokens are disabled if empty
+	ChillstreamsRequireDeviceToken bool           // reject requests without a device token
+	ChillstreamsTrustedProxyCIDRs  []netip.Prefix // proxies allowed to set X-Forwarded-For / X-Real-IP
 )
-
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/util"
+	"github.com/MunifTanjim/stremthru/store"
+)
+
+type magnetCacheWarmerStoreBudgetMap map[string]int
+
+func (m magnetCacheWarmerStoreBudgetMap) Get(storeName string) int {
+	if budget, ok := m[storeName]; ok {
+		return budget
+	}
+	if storeName != "*" {
+		return m.Get("*")
+	}
+	return 0
+}
+
+type MagnetCacheWarmerConfig struct {
+	// chillstreams user the pool keys used for the checks are leased for
+	ChillstreamsUserId string
+	Stores             []string
+	// store name -> max hashes checked per hour
+	StoreBudget  magnetCacheWarmerStoreBudgetMap
+	TitleLimit   int
+	TorrentLimit int
+}
+
+func (c MagnetCacheWarmerConfig) IsEnabled() bool {
+	return len(c.Stores) > 0 && c.ChillstreamsUserId != "" && ChillstreamsAPIKey != ""
+}
+
+func parseMagnetCacheWarmer() MagnetCacheWarmerConfig {
+	warmer := MagnetCacheWarmerConfig{
+		ChillstreamsUserId: getEnv("STREMTHRU_MAGNET_CACHE_WARMER_CHILLSTREAMS_USER_ID"),
+		Stores:             []string{},
+		StoreBudget:        magnetCacheWarmerStoreBudgetMap{},
+		TitleLimit:         util.MustParseInt(getEnv("STREMTHRU_MAGNET_CACHE_WARMER_TITLE_LIMIT")),
+		TorrentLimit:       util.MustParseInt(getEnv("STREMTHRU_MAGNET_CACHE_WARMER_TORRENT_LIMIT")),
+	}
+
+	for _, storeName := range strings.FieldsFunc(getEnv("STREMTHRU_MAGNET_CACHE_WARMER_STORES"), func(c rune) bool {
+		return c == ','
+	}) {
+		storeName = strings.TrimSpace(storeName)
+		if !store.StoreName(storeName).IsValid() {
+			log.Fatalf("invalid magnet cache warmer store name: %s", storeName)
+		}
+		warmer.Stores = append(warmer.Stores, storeName)
+	}
+
+	for _, storeBudget := range strings.FieldsFunc(getEnv("STREMTHRU_MAGNET_CACHE_WARMER_STORE_BUDGET"), func(c rune) bool {
+		return c == ','
+	}) {
+		if storeName, budgetStr, ok := strings.Cut(storeBudget, ":"); ok {
+			budget, err := strconv.Atoi(budgetStr)
+			if err != nil {
+				log.Fatalf("invalid magnet cache warmer store budget (%s): %v", storeBudget, err)
+			}
+			warmer.StoreBudget[storeName] = max(0, budget)
+		}
+	}
+
+	return warmer
+}
+
+var MagnetCacheWarmer = parseMagnetCacheWarmer()
@@ -0,0 +1,20 @@
+package config
+
+type metricsConfig struct {
+	Enabled bool
+	// bearer token required to scrape the metrics, if set
+	Token string
+}
+
+func (conf metricsConfig) IsEnabled() bool {
+	return conf.Enabled
+}
+
+var Metrics = func() metricsConfig {
+	conf := metricsConfig{
+		Enabled: getEnv("STREMTHRU_METRICS_ENABLED") == "true",
+		Token:   getEnv("STREMTHRU_METRICS_TOKEN"),
+	}
+
+	return conf
+}()
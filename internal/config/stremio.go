@@ -18,6 +18,7 @@ type stremioConfigStore struct {
 
 type stremioConfigTorz struct {
 	IndexerMaxTimeout     time.Duration
+	IndexerResultQuota    int
 	LazyPull              bool
 	PublicMaxIndexerCount int
 	PublicMaxStoreCount   int
@@ -46,6 +47,7 @@ func parseStremio() StremioConfig {
 		},
 		Torz: stremioConfigTorz{
 			IndexerMaxTimeout:     mustParseDuration("stremio torz indexer max timeout", getEnv("STREMTHRU_STREMIO_TORZ_INDEXER_MAX_TIMEOUT"), 2*time.Second, 60*time.Second),
+			IndexerResultQuota:    util.MustParseInt(getEnv("STREMTHRU_STREMIO_TORZ_INDEXER_RESULT_QUOTA")),
 			LazyPull:              strings.ToLower(getEnv("STREMTHRU_STREMIO_TORZ_LAZY_PULL")) == "true",
 			PublicMaxIndexerCount: util.MustParseInt(getEnv("STREMTHRU_STREMIO_TORZ_PUBLIC_MAX_INDEXER_COUNT")),
 			PublicMaxStoreCount:   util.MustParseInt(getEnv("STREMTHRU_STREMIO_TORZ_PUBLIC_MAX_STORE_COUNT")),
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracingConfig struct {
+	// OTLP/HTTP traces endpoint of the collector, e.g. `http://localhost:4318/v1/traces`
+	Endpoint string
+	Headers  map[string]string
+	// fraction of the traces to sample, between 0 and 1
+	SampleRatio float64
+	// continue the trace of the `traceparent` header of the incoming
+	// requests, only safe behind a trusted proxy.
+	TrustTraceParent bool
+}
+
+func (conf tracingConfig) IsEnabled() bool {
+	return conf.Endpoint != ""
+}
+
+var Tracing = func() tracingConfig {
+	conf := tracingConfig{
+		Headers:     map[string]string{},
+		SampleRatio: 1,
+	}
+
+	if endpoint := getEnv("STREMTHRU_TRACING_OTLP_ENDPOINT"); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			log.Fatalf("invalid tracing otlp endpoint: %s\n", endpoint)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		conf.Endpoint = u.String()
+	}
+
+	for _, header := range strings.FieldsFunc(getEnv("STREMTHRU_TRACING_OTLP_HEADERS"), func(c rune) bool {
+		return c == ','
+	}) {
+		if key, value, ok := strings.Cut(header, "="); ok {
+			conf.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	if value := getEnv("STREMTHRU_TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			log.Fatalf("invalid tracing sample ratio: %s\n", value)
+		}
+		conf.SampleRatio = ratio
+	}
+
+	conf.TrustTraceParent = getEnv("STREMTHRU_TRACING_TRUST_TRACEPARENT") == "true"
+
+	return conf
+}()
+
+type tracedTransport struct {
+	base      http.RoundTripper
+	propagate bool
+}
+
+func (t tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer("github.com/MunifTanjim/stremthru").Start(
+		req.Context(),
+		req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		// path and query are left out, they can have api keys
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	if t.propagate && span.SpanContext().IsValid() {
+		req = req.Clone(ctx)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return res, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+	if res.StatusCode >= 500 {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	return res, nil
+}
+
+// TraceHTTPTransport creates a span for each outgoing request, as child of
+// the span in the request context. The trace is not propagated, the
+// upstream is a third party.
+func TraceHTTPTransport(transport http.RoundTripper) http.RoundTripper {
+	return tracedTransport{base: transport}
+}
+
+// TracePropagatingHTTPTransport is like TraceHTTPTransport, but also
+// propagates the trace to the upstream. Only for first-party services, e.g.
+// Chillstreams, buddy and peer.
+func TracePropagatingHTTPTransport(transport http.RoundTripper) http.RoundTripper {
+	return tracedTransport{base: transport, propagate: true}
+}
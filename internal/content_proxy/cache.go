@@ -0,0 +1,367 @@
+package content_proxy
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
+	"golang.org/x/sync/singleflight"
+)
+
+const cacheMetaFilename = "meta.json"
+
+// CacheMeta describes the cached content.
+type CacheMeta struct {
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// shared chunk fetches are not tied to the request that started those, so
+// that the other waiting requests are not affected once it is gone.
+const chunkFetchTimeout = 2 * time.Minute
+
+type cachedChunk struct {
+	path string
+	size int64
+}
+
+// cachedContent is the content dir, removed with its meta once its last
+// chunk is evicted.
+type cachedContent struct {
+	chunks   int
+	metaSize int64
+}
+
+// ChunkCache is a disk backed read-through cache for the proxied content. The
+// content is stored in fixed-size chunks, the least recently used chunks are
+// evicted once the cache (including the meta of the content) grows over the
+// budget.
+type ChunkCache struct {
+	dir       string
+	chunkSize int64
+	maxSize   int64
+
+	mu       sync.Mutex
+	size     int64
+	lru      *list.List                // *cachedChunk, most recently used at front
+	items    map[string]*list.Element  // by path
+	contents map[string]*cachedContent // by dir
+
+	fetchGroup singleflight.Group
+}
+
+// CacheKey identifies the content, it is the same for everyone streaming the
+// file from the store.
+func CacheKey(storeCode string, hash string, fileIdx int) string {
+	return storeCode + ":" + strings.ToLower(hash) + ":" + strconv.Itoa(fileIdx)
+}
+
+var GetCache = sync.OnceValue(func() *ChunkCache {
+	if !config.ContentProxy.IsCacheEnabled() {
+		return nil
+	}
+	c := &ChunkCache{
+		dir:       config.ContentProxy.CacheDir,
+		chunkSize: config.ContentProxy.CacheChunkSize,
+		maxSize:   config.ContentProxy.CacheSize,
+		lru:       list.New(),
+		items:     map[string]*list.Element{},
+		contents:  map[string]*cachedContent{},
+	}
+	if err := c.load(); err != nil {
+		log.Error("failed to load cache, disabling it", "error", err, "dir", c.dir)
+		return nil
+	}
+	log.Info("loaded cache", "chunks", c.lru.Len(), "size", c.size)
+	return c
+})
+
+func (c *ChunkCache) ChunkSize() int64 {
+	return c.chunkSize
+}
+
+// load picks up the chunks left on disk from the previous run, the least
+// recently modified ones are evicted first.
+func (c *ChunkCache) load() error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	type diskChunk struct {
+		cachedChunk
+		modTime time.Time
+	}
+	chunks := []diskChunk{}
+	metaSizeByDir := map[string]int64{}
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(path, ".tmp") {
+			return os.Remove(path)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.Name() == cacheMetaFilename {
+			metaSizeByDir[filepath.Dir(path)] = info.Size()
+			return nil
+		}
+		chunks = append(chunks, diskChunk{
+			cachedChunk: cachedChunk{path: path, size: info.Size()},
+			modTime:     info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(chunks, func(a, b diskChunk) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	c.mu.Lock()
+	for i := range chunks {
+		c.add(&chunks[i].cachedChunk)
+	}
+	emptyDirs := []string{}
+	for dir, size := range metaSizeByDir {
+		if _, ok := c.contents[dir]; ok {
+			c.setMetaSize(dir, size)
+		} else {
+			emptyDirs = append(emptyDirs, dir)
+		}
+	}
+	chunkPaths, evictedDirs := c.evict()
+	c.mu.Unlock()
+
+	removeEvicted(chunkPaths, append(emptyDirs, evictedDirs...))
+	return nil
+}
+
+func (c *ChunkCache) getContentDir(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+}
+
+func (c *ChunkCache) getChunkPath(key string, idx int64) string {
+	return filepath.Join(c.getContentDir(key), strconv.FormatInt(idx, 10))
+}
+
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (c *ChunkCache) GetMeta(key string) (*CacheMeta, bool) {
+	blob, err := os.ReadFile(filepath.Join(c.getContentDir(key), cacheMetaFilename))
+	if err != nil {
+		return nil, false
+	}
+	meta := &CacheMeta{}
+	if err := json.Unmarshal(blob, meta); err != nil {
+		return nil, false
+	}
+	return meta, true
+}
+
+func (c *ChunkCache) SetMeta(key string, meta *CacheMeta) error {
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	dir := c.getContentDir(key)
+	if err := writeFile(filepath.Join(dir, cacheMetaFilename), blob); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.setMetaSize(dir, int64(len(blob)))
+	chunkPaths, dirs := c.evict()
+	c.mu.Unlock()
+	removeEvicted(chunkPaths, dirs)
+	return nil
+}
+
+// getContent needs the lock to be held.
+func (c *ChunkCache) getContent(dir string) *cachedContent {
+	content, ok := c.contents[dir]
+	if !ok {
+		content = &cachedContent{}
+		c.contents[dir] = content
+	}
+	return content
+}
+
+// setMetaSize needs the lock to be held.
+func (c *ChunkCache) setMetaSize(dir string, size int64) {
+	content := c.getContent(dir)
+	c.size += size - content.metaSize
+	content.metaSize = size
+}
+
+// add needs the lock to be held.
+func (c *ChunkCache) add(chunk *cachedChunk) {
+	if elem, ok := c.items[chunk.path]; ok {
+		c.size -= elem.Value.(*cachedChunk).size
+		c.lru.Remove(elem)
+	} else {
+		c.getContent(filepath.Dir(chunk.path)).chunks++
+	}
+	c.items[chunk.path] = c.lru.PushFront(chunk)
+	c.size += chunk.size
+}
+
+// remove needs the lock to be held. It returns the content dir if it has no
+// chunk left, its meta is dropped with it.
+func (c *ChunkCache) remove(elem *list.Element) (emptyDir string) {
+	chunk := c.lru.Remove(elem).(*cachedChunk)
+	delete(c.items, chunk.path)
+	c.size -= chunk.size
+
+	dir := filepath.Dir(chunk.path)
+	content := c.contents[dir]
+	content.chunks--
+	if content.chunks > 0 {
+		return ""
+	}
+	c.size -= content.metaSize
+	delete(c.contents, dir)
+	return dir
+}
+
+// evict needs the lock to be held. The returned chunks and content dirs are
+// removed from the disk with removeEvicted, after releasing the lock.
+func (c *ChunkCache) evict() (chunkPaths []string, dirs []string) {
+	for c.size > c.maxSize {
+		elem := c.lru.Back()
+		if elem == nil {
+			break
+		}
+		chunkPaths = append(chunkPaths, elem.Value.(*cachedChunk).path)
+		if dir := c.remove(elem); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return chunkPaths, dirs
+}
+
+func removeEvicted(chunkPaths []string, dirs []string) {
+	for _, path := range chunkPaths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error("failed to evict chunk", "error", err, "path", path)
+		}
+	}
+	for _, dir := range dirs {
+		if err := os.Remove(filepath.Join(dir, cacheMetaFilename)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Error("failed to remove content meta", "error", err, "dir", dir)
+		}
+		// fails if a chunk was written to it in the meantime, it is kept then
+		os.Remove(dir)
+	}
+}
+
+func (c *ChunkCache) get(path string) ([]byte, bool) {
+	c.mu.Lock()
+	elem, ok := c.items[path]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.mu.Lock()
+		if elem, ok := c.items[path]; ok {
+			c.remove(elem)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	// keeps the order of use across restarts
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+func (c *ChunkCache) put(path string, data []byte) error {
+	if err := writeFile(path, data); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.add(&cachedChunk{path: path, size: int64(len(data))})
+	chunkPaths, dirs := c.evict()
+	c.mu.Unlock()
+	removeEvicted(chunkPaths, dirs)
+	return nil
+}
+
+// GetChunk returns the chunk at `idx` of the content, calling `fetch` to get
+// it on cache miss. Concurrent misses for the same chunk share the fetch,
+// which runs on its own context, so that it is not cut short once the request
+// that started it is gone. If the shared fetch fails, the waiting requests
+// fetch the chunk on their own.
+func (c *ChunkCache) GetChunk(ctx context.Context, key string, idx int64, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	path := c.getChunkPath(key, idx)
+	if data, ok := c.get(path); ok {
+		return data, nil
+	}
+
+	fetchAndPut := func(ctx context.Context) ([]byte, error) {
+		data, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.put(path, data); err != nil {
+			log.Error("failed to cache chunk", "error", err, "path", path)
+		}
+		return data, nil
+	}
+
+	// the fetch is run by the request that started it, the waiting
+	// requests fetch again on their own if it fails.
+	isOwner := false
+	ch := c.fetchGroup.DoChan(path, func() (any, error) {
+		isOwner = true
+		fetchCtx, cancel := context.WithTimeout(tracing.Detach(ctx), chunkFetchTimeout)
+		defer cancel()
+		return fetchAndPut(fetchCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	case res := <-ch:
+		if res.Err == nil {
+			return res.Val.([]byte), nil
+		}
+		if isOwner {
+			return nil, res.Err
+		}
+		log.Warn("shared chunk fetch failed, fetching again", "error", res.Err, "path", path)
+		return fetchAndPut(ctx)
+	}
+}
@@ -0,0 +1,199 @@
+package content_proxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, maxSize int64) *ChunkCache {
+	c := &ChunkCache{
+		dir:       t.TempDir(),
+		chunkSize: 10,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		items:     map[string]*list.Element{},
+		contents:  map[string]*cachedContent{},
+	}
+	require.NoError(t, c.load())
+	return c
+}
+
+func fetchData(data string) func(ctx context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		return []byte(data), nil
+	}
+}
+
+func isCached(c *ChunkCache, key string, idx int64) bool {
+	_, err := os.Stat(c.getChunkPath(key, idx))
+	return err == nil
+}
+
+func TestChunkCacheEviction(t *testing.T) {
+	c := newTestCache(t, 30)
+	ctx := context.Background()
+
+	for idx := range int64(3) {
+		_, err := c.GetChunk(ctx, "a", idx, fetchData("0123456789"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(30), c.size)
+
+	// used recently, so not evicted
+	data, err := c.GetChunk(ctx, "a", 0, func(ctx context.Context) ([]byte, error) {
+		return nil, errors.New("not cached")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	_, err = c.GetChunk(ctx, "b", 0, fetchData("0123456789"))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(30), c.size)
+	assert.True(t, isCached(c, "a", 0))
+	assert.False(t, isCached(c, "a", 1), "least recently used chunk is evicted")
+	assert.True(t, isCached(c, "a", 2))
+	assert.True(t, isCached(c, "b", 0))
+}
+
+func TestChunkCacheMeta(t *testing.T) {
+	c := newTestCache(t, 100)
+	ctx := context.Background()
+
+	require.NoError(t, c.SetMeta("a", &CacheMeta{Size: 20, ContentType: "video/mp4"}))
+	metaSize := c.size
+	assert.Greater(t, metaSize, int64(0), "meta is counted in the size")
+
+	_, err := c.GetChunk(ctx, "a", 0, fetchData("0123456789"))
+	require.NoError(t, err)
+
+	meta, ok := c.GetMeta("a")
+	require.True(t, ok)
+	assert.Equal(t, int64(20), meta.Size)
+
+	c.mu.Lock()
+	c.maxSize = metaSize
+	chunkPaths, dirs := c.evict()
+	c.mu.Unlock()
+	removeEvicted(chunkPaths, dirs)
+
+	assert.Equal(t, int64(0), c.size, "meta is dropped with the last chunk")
+	_, ok = c.GetMeta("a")
+	assert.False(t, ok)
+	_, err = os.Stat(c.getContentDir("a"))
+	assert.True(t, os.IsNotExist(err), "content dir is removed")
+}
+
+func TestChunkCacheLoad(t *testing.T) {
+	c := newTestCache(t, 100)
+	ctx := context.Background()
+
+	require.NoError(t, c.SetMeta("a", &CacheMeta{Size: 20}))
+	_, err := c.GetChunk(ctx, "a", 0, fetchData("0123456789"))
+	require.NoError(t, err)
+	require.NoError(t, c.SetMeta("b", &CacheMeta{Size: 20}))
+	require.NoError(t, os.WriteFile(filepath.Join(c.getContentDir("a"), "1.tmp"), []byte("x"), 0644))
+
+	loaded := &ChunkCache{
+		dir:       c.dir,
+		chunkSize: c.chunkSize,
+		maxSize:   c.maxSize,
+		lru:       list.New(),
+		items:     map[string]*list.Element{},
+		contents:  map[string]*cachedContent{},
+	}
+	require.NoError(t, loaded.load())
+
+	assert.Equal(t, 1, loaded.lru.Len())
+	_, ok := loaded.GetMeta("b")
+	assert.False(t, ok, "meta without chunks is removed")
+	size := int64(0)
+	for dir, content := range loaded.contents {
+		size += content.metaSize
+		_, err := os.Stat(filepath.Join(dir, "1.tmp"))
+		assert.True(t, os.IsNotExist(err), "partial chunks are removed")
+	}
+	assert.Equal(t, size+10, loaded.size)
+}
+
+func TestChunkCacheSharedFetch(t *testing.T) {
+	c := newTestCache(t, 100)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+
+	var firstErr error
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		_, firstErr = c.GetChunk(firstCtx, "a", 0, func(ctx context.Context) ([]byte, error) {
+			close(started)
+			select {
+			case <-release:
+				return []byte("0123456789"), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+	})
+	<-started
+
+	var secondData []byte
+	var secondErr error
+	wg.Go(func() {
+		secondData, secondErr = c.GetChunk(context.Background(), "a", 0, func(ctx context.Context) ([]byte, error) {
+			return nil, errors.New("shared fetch is used")
+		})
+	})
+
+	cancelFirst()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.ErrorIs(t, firstErr, context.Canceled)
+	require.NoError(t, secondErr, "shared fetch is not cancelled with the request that started it")
+	assert.Equal(t, "0123456789", string(secondData))
+	assert.True(t, isCached(c, "a", 0))
+}
+
+func TestChunkCacheSharedFetchFailure(t *testing.T) {
+	c := newTestCache(t, 100)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var firstErr error
+	wg := sync.WaitGroup{}
+	wg.Go(func() {
+		_, firstErr = c.GetChunk(context.Background(), "a", 0, func(ctx context.Context) ([]byte, error) {
+			close(started)
+			<-release
+			return nil, errors.New("link expired")
+		})
+	})
+	<-started
+
+	var secondData []byte
+	var secondErr error
+	wg.Go(func() {
+		secondData, secondErr = c.GetChunk(context.Background(), "a", 0, fetchData("0123456789"))
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Error(t, firstErr)
+	require.NoError(t, secondErr, "waiting request fetches on its own once the shared fetch fails")
+	assert.Equal(t, "0123456789", string(secondData))
+}
@@ -0,0 +1,124 @@
+package content_proxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const connectionHeartbeatInterval = 30 * time.Second
+
+const usagePurgeInterval = 24 * time.Hour
+
+// connections without a heartbeat for this long are considered dead, e.g.
+// the instance serving them was killed.
+const connectionStaleAfter = 3 * connectionHeartbeatInterval
+
+var ErrConnectionKilled = errors.New("content proxy connection killed")
+
+type localConnection struct {
+	cancel context.CancelCauseFunc
+	bytes  *atomic.Int64
+}
+
+// connections served by this instance
+var localConnections sync.Map // map[string]*localConnection
+
+var startHeartbeat = sync.OnceFunc(func() {
+	go func() {
+		ticker := time.NewTicker(connectionHeartbeatInterval)
+		defer ticker.Stop()
+		lastUsagePurgeAt := time.Time{}
+		for range ticker.C {
+			heartbeat()
+			if time.Since(lastUsagePurgeAt) > usagePurgeInterval {
+				lastUsagePurgeAt = time.Now()
+				if count, err := PurgeUsage(); err != nil {
+					log.Error("failed to purge usage", "error", err)
+				} else if count > 0 {
+					log.Info("purged usage", "count", count)
+				}
+			}
+		}
+	}()
+})
+
+func heartbeat() {
+	bytesById := map[string]int64{}
+	localConnections.Range(func(key, value any) bool {
+		bytesById[key.(string)] = value.(*localConnection).bytes.Load()
+		return true
+	})
+	if err := heartbeatConnections(bytesById); err != nil {
+		log.Error("failed to heartbeat connections", "error", err, "count", len(bytesById))
+	}
+
+	killedIds, err := getKilledConnectionIds()
+	if err != nil {
+		log.Error("failed to get killed connections", "error", err)
+	}
+	for _, id := range killedIds {
+		killLocalConnection(id)
+	}
+
+	if count, err := deleteStaleConnections(); err != nil {
+		log.Error("failed to delete stale connections", "error", err)
+	} else if count > 0 {
+		log.Info("deleted stale connections", "count", count)
+	}
+}
+
+func killLocalConnection(id string) bool {
+	value, ok := localConnections.Load(id)
+	if !ok {
+		return false
+	}
+	value.(*localConnection).cancel(ErrConnectionKilled)
+	return true
+}
+
+// TrackConnection records the connection until `untrack` is called. The
+// returned context is cancelled once the connection is killed.
+func TrackConnection(ctx context.Context, user, ip, link string) (connCtx context.Context, untrack func(), err error) {
+	startHeartbeat()
+
+	id := xid.New().String()
+
+	if err := insertConnection(id, user, ip, link); err != nil {
+		return ctx, nil, err
+	}
+
+	connCtx, cancel := context.WithCancelCause(ctx)
+	conn := &localConnection{cancel: cancel, bytes: &atomic.Int64{}}
+	localConnections.Store(id, conn)
+
+	return context.WithValue(connCtx, localConnectionKey{}, conn), func() {
+		localConnections.Delete(id)
+		cancel(nil)
+		if err := deleteConnection(id); err != nil {
+			log.Error("failed to delete connection", "error", err, "id", id)
+		}
+	}, nil
+}
+
+type localConnectionKey struct{}
+
+// addConnectionBytes counts the bytes served on the connection, reported
+// with the heartbeat.
+func addConnectionBytes(ctx context.Context, bytes int64) {
+	if conn, ok := ctx.Value(localConnectionKey{}).(*localConnection); ok {
+		conn.bytes.Add(bytes)
+	}
+}
+
+// KillConnection stops the connection, on whichever instance it is served.
+func KillConnection(id string) (bool, error) {
+	if killLocalConnection(id) {
+		return true, nil
+	}
+	return markConnectionKilled(id)
+}
@@ -0,0 +1,93 @@
+package content_proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupConnections(t *testing.T) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, ConnectionTableName)
+}
+
+func TestTrackConnection(t *testing.T) {
+	setupConnections(t)
+
+	ctx1, untrack1, err := TrackConnection(context.Background(), "alice", "127.0.0.1", "https://example.com/a")
+	require.NoError(t, err)
+	_, untrack2, err := TrackConnection(context.Background(), "alice", "127.0.0.1", "https://example.com/a")
+	require.NoError(t, err, "connection ids are generated by the server")
+
+	count, err := CountActiveConnections("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	addConnectionBytes(ctx1, 42)
+	heartbeat()
+
+	items, err := ListActiveConnections()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	totalBytes := int64(0)
+	for _, item := range items {
+		totalBytes += item.Bytes
+	}
+	assert.Equal(t, int64(42), totalBytes, "heartbeat records the bytes of the connections")
+
+	killed, err := KillConnection(items[0].Id)
+	require.NoError(t, err)
+	assert.True(t, killed)
+
+	untrack1()
+	untrack2()
+
+	count, err = CountActiveConnections("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestKillConnection(t *testing.T) {
+	setupConnections(t)
+
+	ctx, untrack, err := TrackConnection(context.Background(), "bob", "127.0.0.1", "https://example.com/b")
+	require.NoError(t, err)
+	defer untrack()
+
+	items, err := ListActiveConnections()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	killed, err := KillConnection(items[0].Id)
+	require.NoError(t, err)
+	assert.True(t, killed)
+	assert.ErrorIs(t, context.Cause(ctx), ErrConnectionKilled)
+}
+
+func TestKilledConnectionOfOtherInstance(t *testing.T) {
+	setupConnections(t)
+
+	_, err := db.Exec(query_insert_connection, "remote", "carol", config.InstanceId+"-other", "127.0.0.1", "https://example.com/c")
+	require.NoError(t, err)
+
+	count, err := CountActiveConnections("carol")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	killed, err := KillConnection("remote")
+	require.NoError(t, err)
+	assert.True(t, killed, "connection of other instance is marked killed")
+
+	count, err = CountActiveConnections("carol")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "killed connections are not counted")
+
+	items, err := ListActiveConnections()
+	require.NoError(t, err)
+	assert.Empty(t, items, "killed connections are not listed")
+}
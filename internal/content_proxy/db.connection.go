@@ -0,0 +1,248 @@
+package content_proxy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+const ConnectionTableName = "content_proxy_connection"
+
+type ConnectionColumnStruct struct {
+	Id         string
+	Username   string
+	InstanceId string
+	IP         string
+	Link       string
+	Bytes      string
+	Killed     string
+	HAt        string
+	CAt        string
+}
+
+var ConnectionColumn = ConnectionColumnStruct{
+	Id:         "id",
+	Username:   "username",
+	InstanceId: "instance_id",
+	IP:         "ip",
+	Link:       "link",
+	Bytes:      "bytes",
+	Killed:     "killed",
+	HAt:        "hat",
+	CAt:        "cat",
+}
+
+// Connection is an active content proxy connection, on any instance.
+type Connection struct {
+	Id          string       `json:"id"`
+	User        string       `json:"user"`
+	InstanceId  string       `json:"instance_id"`
+	IP          string       `json:"ip"`
+	Link        string       `json:"link"`
+	Bytes       int64        `json:"bytes"`
+	HeartbeatAt db.Timestamp `json:"heartbeat_at"`
+	CreatedAt   db.Timestamp `json:"created_at"`
+}
+
+func getStaleBefore() db.Timestamp {
+	return db.Timestamp{Time: time.Now().Add(-connectionStaleAfter)}
+}
+
+var query_insert_connection = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?,?,?)`,
+	ConnectionTableName,
+	db.JoinColumnNames(
+		ConnectionColumn.Id,
+		ConnectionColumn.Username,
+		ConnectionColumn.InstanceId,
+		ConnectionColumn.IP,
+		ConnectionColumn.Link,
+	),
+)
+
+func insertConnection(id, user, ip, link string) error {
+	_, err := db.Exec(query_insert_connection, id, user, config.InstanceId, ip, link)
+	return err
+}
+
+var query_delete_connection = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ?`,
+	ConnectionTableName,
+	ConnectionColumn.Id,
+)
+
+func deleteConnection(id string) error {
+	_, err := db.Exec(query_delete_connection, id)
+	return err
+}
+
+var query_heartbeat_connections_before_cases = fmt.Sprintf(
+	`UPDATE %s SET %s = %s, %s = CASE %s`,
+	ConnectionTableName,
+	ConnectionColumn.HAt,
+	db.CurrentTimestamp,
+	ConnectionColumn.Bytes,
+	ConnectionColumn.Id,
+)
+var query_heartbeat_connections_case = ` WHEN ? THEN ?`
+var query_heartbeat_connections_after_cases = fmt.Sprintf(
+	` ELSE %s END WHERE %s = ? AND %s IN `,
+	ConnectionColumn.Bytes,
+	ConnectionColumn.InstanceId,
+	ConnectionColumn.Id,
+)
+
+const heartbeatBatchSize = 200
+
+// heartbeatConnections updates the bytes of the connections of this instance
+// and marks those as alive, with one query per batch.
+func heartbeatConnections(bytesById map[string]int64) error {
+	ids := make([]string, 0, len(bytesById))
+	for id := range bytesById {
+		ids = append(ids, id)
+	}
+	for cIds := range slices.Chunk(ids, heartbeatBatchSize) {
+		var query strings.Builder
+		query.WriteString(query_heartbeat_connections_before_cases)
+		args := make([]any, 0, 3*len(cIds)+1)
+		for _, id := range cIds {
+			query.WriteString(query_heartbeat_connections_case)
+			args = append(args, id, bytesById[id])
+		}
+		query.WriteString(query_heartbeat_connections_after_cases)
+		query.WriteString("(" + util.RepeatJoin("?", len(cIds), ",") + ")")
+		args = append(args, config.InstanceId)
+		for _, id := range cIds {
+			args = append(args, id)
+		}
+		if _, err := db.Exec(query.String(), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var query_get_killed_connection_ids = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s = %s`,
+	ConnectionColumn.Id,
+	ConnectionTableName,
+	ConnectionColumn.InstanceId,
+	ConnectionColumn.Killed,
+	db.BooleanTrue,
+)
+
+// getKilledConnectionIds returns the connections of this instance killed
+// from other instances.
+func getKilledConnectionIds() ([]string, error) {
+	rows, err := db.Query(query_get_killed_connection_ids, config.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+var query_delete_stale_connections = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s < ?`,
+	ConnectionTableName,
+	ConnectionColumn.HAt,
+)
+
+// deleteStaleConnections removes the connections of the instances that
+// stopped without cleaning up.
+func deleteStaleConnections() (int64, error) {
+	result, err := db.Exec(query_delete_stale_connections, getStaleBefore())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+var query_count_active_connections = fmt.Sprintf(
+	`SELECT COUNT(*) FROM %s WHERE %s = ? AND %s >= ? AND %s = %s`,
+	ConnectionTableName,
+	ConnectionColumn.Username,
+	ConnectionColumn.HAt,
+	ConnectionColumn.Killed,
+	db.BooleanFalse,
+)
+
+// CountActiveConnections returns the number of live connections of the user,
+// across all instances.
+func CountActiveConnections(user string) (int, error) {
+	count := 0
+	row := db.QueryRow(query_count_active_connections, user, getStaleBefore())
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+var query_list_active_connections = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s >= ? AND %s = %s ORDER BY %s DESC`,
+	db.JoinColumnNames(
+		ConnectionColumn.Id,
+		ConnectionColumn.Username,
+		ConnectionColumn.InstanceId,
+		ConnectionColumn.IP,
+		ConnectionColumn.Link,
+		ConnectionColumn.Bytes,
+		ConnectionColumn.HAt,
+		ConnectionColumn.CAt,
+	),
+	ConnectionTableName,
+	ConnectionColumn.HAt,
+	ConnectionColumn.Killed,
+	db.BooleanFalse,
+	ConnectionColumn.CAt,
+)
+
+// ListActiveConnections returns the live connections, across all instances.
+func ListActiveConnections() ([]Connection, error) {
+	rows, err := db.Query(query_list_active_connections, getStaleBefore())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Connection{}
+	for rows.Next() {
+		item := Connection{}
+		if err := rows.Scan(&item.Id, &item.User, &item.InstanceId, &item.IP, &item.Link, &item.Bytes, &item.HeartbeatAt, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+var query_kill_connection = fmt.Sprintf(
+	`UPDATE %s SET %s = %s WHERE %s = ?`,
+	ConnectionTableName,
+	ConnectionColumn.Killed,
+	db.BooleanTrue,
+	ConnectionColumn.Id,
+)
+
+func markConnectionKilled(id string) (bool, error) {
+	result, err := db.Exec(query_kill_connection, id)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
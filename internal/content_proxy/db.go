@@ -0,0 +1,136 @@
+package content_proxy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+)
+
+const TableName = "content_proxy_usage"
+
+var Column = struct {
+	Username string
+	Day      string
+	Bytes    string
+	CAt      string
+	UAt      string
+}{
+	Username: "username",
+	Day:      "day",
+	Bytes:    "bytes",
+	CAt:      "cat",
+	UAt:      "uat",
+}
+
+const dayLayout = time.DateOnly
+
+func getDay(t time.Time) string {
+	return t.UTC().Format(dayLayout)
+}
+
+func getMonthStartDay(t time.Time) string {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Format(dayLayout)
+}
+
+var query_record_usage = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?) ON CONFLICT (%s) DO UPDATE SET %s`,
+	TableName,
+	db.JoinColumnNames(Column.Username, Column.Day, Column.Bytes),
+	db.JoinColumnNames(Column.Username, Column.Day),
+	strings.Join([]string{
+		fmt.Sprintf(`%s = %s.%s + EXCLUDED.%s`, Column.Bytes, TableName, Column.Bytes, Column.Bytes),
+		fmt.Sprintf(`%s = %s`, Column.UAt, db.CurrentTimestamp),
+	}, ", "),
+)
+
+// RecordUsage adds the proxied bytes to the usage of the user for today.
+func RecordUsage(user string, bytes int64) error {
+	if bytes <= 0 {
+		return nil
+	}
+	_, err := db.Exec(query_record_usage, user, getDay(time.Now()), bytes)
+	return err
+}
+
+type Usage struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+var query_get_usage = fmt.Sprintf(
+	`SELECT COALESCE(SUM(CASE WHEN %s = ? THEN %s ELSE 0 END), 0), COALESCE(SUM(%s), 0) FROM %s WHERE %s = ? AND %s >= ?`,
+	Column.Day,
+	Column.Bytes,
+	Column.Bytes,
+	TableName,
+	Column.Username,
+	Column.Day,
+)
+
+func GetUsage(user string) (*Usage, error) {
+	now := time.Now()
+	usage := Usage{}
+	row := db.QueryRow(query_get_usage, getDay(now), user, getMonthStartDay(now))
+	if err := row.Scan(&usage.Daily, &usage.Monthly); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+type UserUsage struct {
+	User string `json:"user"`
+	Usage
+}
+
+var query_list_usage = fmt.Sprintf(
+	`SELECT %s, SUM(CASE WHEN %s = ? THEN %s ELSE 0 END), SUM(%s) FROM %s WHERE %s >= ? GROUP BY %s ORDER BY %s`,
+	Column.Username,
+	Column.Day,
+	Column.Bytes,
+	Column.Bytes,
+	TableName,
+	Column.Day,
+	Column.Username,
+	Column.Username,
+)
+
+// ListUsage returns the usage of the users with traffic in the current month.
+func ListUsage() ([]UserUsage, error) {
+	now := time.Now()
+	rows, err := db.Query(query_list_usage, getDay(now), getMonthStartDay(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []UserUsage{}
+	for rows.Next() {
+		item := UserUsage{}
+		if err := rows.Scan(&item.User, &item.Daily, &item.Monthly); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+var query_purge_usage = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s < ?`,
+	TableName,
+	Column.Day,
+)
+
+// PurgeUsage deletes the usage before the previous month, only the usage of
+// the current month is used for the quota.
+func PurgeUsage() (int64, error) {
+	now := time.Now().UTC()
+	lastMonth := now.AddDate(0, 0, -now.Day())
+	result, err := db.Exec(query_purge_usage, getMonthStartDay(lastMonth))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,318 @@
+package content_proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"golang.org/x/time/rate"
+)
+
+var log = logger.Scoped("content_proxy")
+
+var ErrQuotaExceeded = errors.New("content proxy quota exceeded")
+
+// io.Copy writes in chunks of 32KB, the burst needs to fit at least one.
+const minBurst = 64 * 1024
+
+// usage is flushed to the database periodically for long running streams,
+// so that the other connections of the user see it.
+const usageFlushInterval = 1 * time.Minute
+
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(max(bytesPerSecond, minBurst)))
+}
+
+var globalLimiter = func() *rate.Limiter {
+	if config.ContentProxy.GlobalRateLimit <= 0 {
+		return nil
+	}
+	return newLimiter(config.ContentProxy.GlobalRateLimit)
+}()
+
+var limiterByUser sync.Map // map[string]*rate.Limiter
+
+func getUserLimiter(user string) *rate.Limiter {
+	bytesPerSecond := config.ContentProxy.RateLimit.Get(user)
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	if limiter, ok := limiterByUser.Load(user); ok {
+		return limiter.(*rate.Limiter)
+	}
+	limiter, _ := limiterByUser.LoadOrStore(user, newLimiter(bytesPerSecond))
+	return limiter.(*rate.Limiter)
+}
+
+type Quota struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+func GetQuota(user string) Quota {
+	return Quota{
+		Daily:   config.ContentProxy.DailyQuota.Get(user),
+		Monthly: config.ContentProxy.MonthlyQuota.Get(user),
+	}
+}
+
+// GetRemainingQuota returns the bytes the user can still proxy, -1 if there
+// is no quota for the user.
+func GetRemainingQuota(user string) (int64, error) {
+	quota := GetQuota(user)
+	if quota.Daily <= 0 && quota.Monthly <= 0 {
+		return -1, nil
+	}
+
+	usage, err := GetUsage(user)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := int64(-1)
+	if quota.Daily > 0 {
+		remaining = max(0, quota.Daily-usage.Daily)
+	}
+	if quota.Monthly > 0 {
+		if monthly := max(0, quota.Monthly-usage.Monthly); remaining == -1 || monthly < remaining {
+			remaining = monthly
+		}
+	}
+	return remaining, nil
+}
+
+// userQuota is the quota left for the user, shared by all the connections of
+// the user on this instance. It is loaded from the recorded usage once the
+// first connection opens, loaded again once the (UTC) day it was loaded for
+// has passed, and dropped once the last connection closes, after the usage of
+// the connections is recorded.
+type userQuota struct {
+	mu          sync.Mutex
+	remaining   int64 // -1 for unlimited
+	day         string
+	connections int
+}
+
+var quotaByUserMutex sync.Mutex
+var quotaByUser = map[string]*userQuota{}
+
+// acquireQuota returns the shared quota of the user, releaseQuota must be
+// called once the connection is closed.
+func acquireQuota(user string) (*userQuota, error) {
+	quotaByUserMutex.Lock()
+	if q, ok := quotaByUser[user]; ok {
+		q.connections++
+		quotaByUserMutex.Unlock()
+		if err := q.reload(user); err != nil {
+			releaseQuota(user, q)
+			return nil, err
+		}
+		return q, nil
+	}
+	quotaByUserMutex.Unlock()
+
+	day := getDay(time.Now())
+	remaining, err := GetRemainingQuota(user)
+	if err != nil {
+		return nil, err
+	}
+
+	quotaByUserMutex.Lock()
+	defer quotaByUserMutex.Unlock()
+	q, ok := quotaByUser[user]
+	if !ok {
+		q = &userQuota{remaining: remaining, day: day}
+		quotaByUser[user] = q
+	}
+	q.connections++
+	return q, nil
+}
+
+func releaseQuota(user string, q *userQuota) {
+	quotaByUserMutex.Lock()
+	defer quotaByUserMutex.Unlock()
+	q.connections--
+	if q.connections == 0 && quotaByUser[user] == q {
+		delete(quotaByUser, user)
+	}
+}
+
+// reload loads the remaining quota again if the day it was loaded for has
+// passed, the daily and the monthly quota both reset on a new day.
+func (q *userQuota) reload(user string) error {
+	day := getDay(time.Now())
+	q.mu.Lock()
+	isStale := q.day != day
+	q.mu.Unlock()
+	if !isStale {
+		return nil
+	}
+
+	remaining, err := GetRemainingQuota(user)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.day != day {
+		q.remaining = remaining
+		q.day = day
+	}
+	return nil
+}
+
+// reserve takes up to n bytes from the quota, returns the bytes granted.
+func (q *userQuota) reserve(n int64) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remaining == -1 {
+		return n
+	}
+	n = min(n, q.remaining)
+	q.remaining -= n
+	return n
+}
+
+// refund gives back the reserved bytes that were not written.
+func (q *userQuota) refund(n int64) {
+	if n <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remaining != -1 {
+		q.remaining += n
+	}
+}
+
+func (q *userQuota) isExceeded() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.remaining == 0
+}
+
+// ResponseWriter limits the rate of the bytes written to the client. For the
+// user, it also stops once the quota of the user runs out, and records the
+// usage of the user.
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	ctx      context.Context
+	user     string
+	limiters []*rate.Limiter
+	quota    *userQuota
+
+	unrecorded   int64
+	lastRecordAt time.Time
+}
+
+// NewResponseWriter wraps w, `user` is empty for the connections without a
+// user, those are only limited by the global rate limit. Close must be called
+// once done. It fails with ErrQuotaExceeded if the quota of the user has
+// already run out.
+func NewResponseWriter(w http.ResponseWriter, r *http.Request, user string) (*ResponseWriter, error) {
+	limiters := []*rate.Limiter{}
+	var quota *userQuota
+	if user != "" {
+		if limiter := getUserLimiter(user); limiter != nil {
+			limiters = append(limiters, limiter)
+		}
+		q, err := acquireQuota(user)
+		if err != nil {
+			return nil, err
+		}
+		if q.isExceeded() {
+			releaseQuota(user, q)
+			return nil, ErrQuotaExceeded
+		}
+		quota = q
+	}
+	if globalLimiter != nil {
+		limiters = append(limiters, globalLimiter)
+	}
+	return &ResponseWriter{
+		ResponseWriter: w,
+		ctx:            r.Context(),
+		user:           user,
+		limiters:       limiters,
+		quota:          quota,
+		lastRecordAt:   time.Now(),
+	}, nil
+}
+
+func (w *ResponseWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if err := context.Cause(w.ctx); err != nil {
+			return n, err
+		}
+
+		chunk := p[:min(len(p), minBurst)]
+		if w.quota != nil {
+			granted := w.quota.reserve(int64(len(chunk)))
+			if granted == 0 {
+				if err := w.quota.reload(w.user); err != nil {
+					log.Error("failed to reload quota", "error", err, "user", w.user)
+				} else {
+					granted = w.quota.reserve(int64(len(chunk)))
+				}
+			}
+			if granted == 0 {
+				return n, ErrQuotaExceeded
+			}
+			chunk = chunk[:granted]
+		}
+
+		for _, limiter := range w.limiters {
+			if err := limiter.WaitN(w.ctx, len(chunk)); err != nil {
+				if w.quota != nil {
+					w.quota.refund(int64(len(chunk)))
+				}
+				return n, err
+			}
+		}
+
+		written, err := w.ResponseWriter.Write(chunk)
+		n += written
+		p = p[written:]
+		w.unrecorded += int64(written)
+		addConnectionBytes(w.ctx, int64(written))
+		if w.quota != nil {
+			w.quota.refund(int64(len(chunk) - written))
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if w.user != "" && time.Since(w.lastRecordAt) > usageFlushInterval {
+		w.recordUsage()
+	}
+	return n, nil
+}
+
+func (w *ResponseWriter) recordUsage() {
+	if err := RecordUsage(w.user, w.unrecorded); err != nil {
+		log.Error("failed to record usage", "error", err, "user", w.user)
+		return
+	}
+	w.unrecorded = 0
+	w.lastRecordAt = time.Now()
+}
+
+func (w *ResponseWriter) Close() {
+	if w.user == "" {
+		return
+	}
+	w.recordUsage()
+	releaseQuota(w.user, w.quota)
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
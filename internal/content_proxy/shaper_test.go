@@ -0,0 +1,146 @@
+package content_proxy
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m,
+		"20250101000000_init",
+		"20251228120000_create_table_content_proxy_usage",
+		"20251229120000_create_table_content_proxy_connection",
+	))
+}
+
+func setupQuota(t *testing.T, user string, daily int64) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, TableName)
+	config.ContentProxy.DailyQuota[user] = daily
+	t.Cleanup(func() {
+		delete(config.ContentProxy.DailyQuota, user)
+	})
+}
+
+func newTestResponseWriter(t *testing.T, user string) (*ResponseWriter, *httptest.ResponseRecorder, error) {
+	rec := httptest.NewRecorder()
+	w, err := NewResponseWriter(rec, httptest.NewRequest("GET", "/", nil), user)
+	return w, rec, err
+}
+
+func TestResponseWriterSharedQuota(t *testing.T) {
+	setupQuota(t, "alice", 100)
+	require.NoError(t, RecordUsage("alice", 40))
+
+	w1, rec1, err := newTestResponseWriter(t, "alice")
+	require.NoError(t, err)
+	w2, rec2, err := newTestResponseWriter(t, "alice")
+	require.NoError(t, err)
+
+	n, err := w1.Write(make([]byte, 50))
+	assert.NoError(t, err)
+	assert.Equal(t, 50, n)
+
+	n, err = w2.Write(make([]byte, 50))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, 10, n, "quota is shared by the connections of the user")
+
+	w1.Close()
+	w2.Close()
+	assert.Equal(t, 50, rec1.Body.Len())
+	assert.Equal(t, 10, rec2.Body.Len())
+
+	usage, err := GetUsage("alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), usage.Daily)
+
+	_, _, err = newTestResponseWriter(t, "alice")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Empty(t, quotaByUser, "quota is dropped once the connections are closed")
+}
+
+func TestResponseWriterWithoutQuota(t *testing.T) {
+	setupQuota(t, "bob", 0)
+
+	w, rec, err := newTestResponseWriter(t, "bob")
+	require.NoError(t, err)
+	n, err := w.Write(make([]byte, 3*minBurst))
+	assert.NoError(t, err)
+	assert.Equal(t, 3*minBurst, n)
+	w.Close()
+	assert.Equal(t, 3*minBurst, rec.Body.Len())
+
+	usage, err := GetUsage("bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*minBurst), usage.Daily)
+}
+
+func TestResponseWriterWithoutUser(t *testing.T) {
+	setupQuota(t, "*", 10)
+
+	w, rec, err := newTestResponseWriter(t, "")
+	require.NoError(t, err)
+	n, err := w.Write(make([]byte, 100))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	w.Close()
+	assert.Equal(t, 100, rec.Body.Len())
+
+	items, err := ListUsage()
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestPurgeUsage(t *testing.T) {
+	setupQuota(t, "carol", 0)
+
+	now := time.Now().UTC()
+	for _, day := range []time.Time{now, now.AddDate(0, -1, -now.Day()+1), now.AddDate(0, -2, 0)} {
+		_, err := db.Exec(query_record_usage, "carol", getDay(day), 10)
+		require.NoError(t, err)
+	}
+
+	count, err := PurgeUsage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "usage before the previous month is purged")
+}
+
+func TestResponseWriterQuotaReset(t *testing.T) {
+	setupQuota(t, "dave", 100)
+	require.NoError(t, RecordUsage("dave", 40))
+
+	w1, _, err := newTestResponseWriter(t, "dave")
+	require.NoError(t, err)
+	n, err := w1.Write(make([]byte, 100))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, 60, n)
+
+	_, _, err = newTestResponseWriter(t, "dave")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// a new day, without any usage yet
+	dbtest.Truncate(t, TableName)
+	quotaByUser["dave"].day = getDay(time.Now().AddDate(0, 0, -1))
+
+	w2, _, err := newTestResponseWriter(t, "dave")
+	require.NoError(t, err, "quota is reloaded on a new day while a connection is still open")
+	n, err = w2.Write(make([]byte, 30))
+	assert.NoError(t, err)
+	assert.Equal(t, 30, n)
+
+	n, err = w1.Write(make([]byte, 30))
+	assert.NoError(t, err)
+	assert.Equal(t, 30, n, "open connection gets the reloaded quota")
+
+	w1.Close()
+	w2.Close()
+	assert.Empty(t, quotaByUser)
+}
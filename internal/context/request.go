@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
 	"github.com/MunifTanjim/stremthru/internal/logger"
 	"github.com/MunifTanjim/stremthru/store"
 )
@@ -19,11 +20,25 @@ type StoreContext struct {
 	ProxyAuthPassword string
 	ClientIP          string // optional
 
+	// optional, set when StoreAuthToken is a Chillstreams pool key; the event
+	// is reported once the stream link is served
+	ChillstreamsUsage *chillstreams.LogUsageRequest
+	// optional, the device the pool key is leased for
+	ChillstreamsDeviceId string
+
+	// optional, identifies the file behind the link for the content proxy
+	// cache; FileIdx is only valid if FileHash is set
+	FileHash string
+	FileIdx  int
+
+	// context of the incoming request, carries the trace to the store calls
+	Context context.Context
+
 	Log *logger.Logger
 }
 
 func SetStoreContext(r *http.Request) *http.Request {
-	ctx := context.WithValue(r.Context(), storeContextKey{}, &StoreContext{})
+	ctx := context.WithValue(r.Context(), storeContextKey{}, &StoreContext{Context: r.Context()})
 	return r.WithContext(ctx)
 }
 
@@ -1,80 +1,125 @@
 package dash_api
 
 import (
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/cache"
 	"github.com/MunifTanjim/stremthru/internal/config"
+	dash_session "github.com/MunifTanjim/stremthru/internal/dash/session"
+	dash_user "github.com/MunifTanjim/stremthru/internal/dash/user"
+	"github.com/MunifTanjim/stremthru/internal/db"
 	"github.com/MunifTanjim/stremthru/internal/shared"
 	stremio_shared "github.com/MunifTanjim/stremthru/internal/stremio/shared"
-	"github.com/MunifTanjim/stremthru/internal/util"
 	"github.com/google/uuid"
 )
 
 type Session struct {
-	Id   string
-	User string
+	Id        string
+	User      string
+	Role      dash_user.Role
+	IP        string
+	UserAgent string
 }
 
 type SessionStorage interface {
 	Add(id string, session Session) error
 	Remove(id string)
 	Get(id string, session *Session) bool
+	// RemoveByKey revokes the session by its key, the value exposed for
+	// listing sessions instead of the session id.
+	RemoveByKey(key string) error
+	RemoveByUser(user string) error
 }
 
-type TemporaryFileSessionStorage struct {
-	directory string
+const sessionLifetime = 7 * 24 * time.Hour
+
+// the session id is the secret in the cookie, so only its hash is stored
+func getSessionKey(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(hash[:])
 }
 
-func (t TemporaryFileSessionStorage) Add(id string, session Session) error {
-	data, err := json.Marshal(session)
-	if err != nil {
+type DatabaseSessionStorage struct {
+	cache cache.Cache[Session]
+}
+
+func (t DatabaseSessionStorage) Add(id string, session Session) error {
+	key := getSessionKey(id)
+	s := &dash_session.DashSession{
+		Id:        key,
+		UserId:    session.User,
+		Role:      session.Role,
+		IP:        session.IP,
+		UserAgent: session.UserAgent,
+		EAt:       db.Timestamp{Time: time.Now().Add(sessionLifetime)},
+	}
+	if err := s.Upsert(); err != nil {
 		return err
 	}
-	filePath := filepath.Join(t.directory, id+".json")
-	return os.WriteFile(filePath, data, 0600)
+	if err := dash_session.DeleteExpired(); err != nil {
+		log.Error("failed to delete expired sessions", "error", err)
+	}
+	return t.cache.Add(key, session)
 }
 
-func (t TemporaryFileSessionStorage) Get(id string, session *Session) bool {
-	filePath := filepath.Join(t.directory, id+".json")
-	data, err := os.ReadFile(filePath)
+func (t DatabaseSessionStorage) Get(id string, session *Session) bool {
+	key := getSessionKey(id)
+	if t.cache.Get(key, session) {
+		return true
+	}
+	s, err := dash_session.GetById(key)
 	if err != nil {
+		log.Error("failed to get session", "error", err)
 		return false
 	}
-	if err := json.Unmarshal(data, session); err != nil {
+	if s == nil {
 		return false
 	}
+	*session = Session{
+		Id:        id,
+		User:      s.UserId,
+		Role:      s.Role,
+		IP:        s.IP,
+		UserAgent: s.UserAgent,
+	}
+	if err := t.cache.Add(key, *session); err != nil {
+		log.Error("failed to cache session", "error", err)
+	}
 	return true
 }
 
-func (t TemporaryFileSessionStorage) Remove(id string) {
-	filePath := filepath.Join(t.directory, id+".json")
-	os.Remove(filePath)
+func (t DatabaseSessionStorage) Remove(id string) {
+	if err := t.RemoveByKey(getSessionKey(id)); err != nil {
+		log.Error("failed to remove session", "error", err)
+	}
 }
 
-var sessionStorage = func() SessionStorage {
-	if config.Environment == config.EnvDev {
-		directory := filepath.Join(config.DataDir, "dash-session")
-		err := util.EnsureDir(directory)
-		if err != nil {
-			panic(err)
-		}
-		return TemporaryFileSessionStorage{
-			directory: directory,
-		}
+func (t DatabaseSessionStorage) RemoveByKey(key string) error {
+	t.cache.Remove(key)
+	return dash_session.Delete(key)
+}
+
+func (t DatabaseSessionStorage) RemoveByUser(user string) error {
+	keys, err := dash_session.DeleteByUserId(user)
+	for _, key := range keys {
+		t.cache.Remove(key)
 	}
+	return err
+}
 
-	return cache.NewCache[Session](&cache.CacheConfig{
-		Lifetime:      7 * 24 * time.Hour,
-		Name:          "dash:session",
-		LocalCapacity: 8,
-	})
-}()
+var sessionStorage SessionStorage = DatabaseSessionStorage{
+	// short lifetime, so that revoked sessions are not served by the cache
+	// of other instances for long
+	cache: cache.NewCache[Session](&cache.CacheConfig{
+		Lifetime:      1 * time.Minute,
+		Name:          "dash:session:db",
+		LocalCapacity: 64,
+	}),
+}
 
 const SESSION_COOKIE_NAME = "stremthru.dash.session"
 const SESSION_COOKIE_PATH = "/dash/"
@@ -83,6 +128,8 @@ func (s Session) Save(w http.ResponseWriter, r *http.Request) error {
 	if s.Id == "" {
 		s.Id = strings.ReplaceAll(uuid.NewString(), "-", "")
 	}
+	s.IP = GetReqCtx(r).ClientIP
+	s.UserAgent = r.UserAgent()
 	if err := sessionStorage.Add(s.Id, s); err != nil {
 		return err
 	}
@@ -126,13 +173,15 @@ func getSession(w http.ResponseWriter, r *http.Request) (*Session, error) {
 }
 
 type GetUserResponse struct {
-	Id string `json:"id"`
+	Id   string         `json:"id"`
+	Role dash_user.Role `json:"role"`
 }
 
 func HandleGetUser(w http.ResponseWriter, r *http.Request) {
 	ctx := GetReqCtx(r)
 	SendData(w, r, 200, GetUserResponse{
-		Id: ctx.Session.User,
+		Id:   ctx.Session.User,
+		Role: ctx.Session.Role,
 	})
 }
 
@@ -153,7 +202,24 @@ func HandleSignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if password := config.AdminPassword.GetPassword(request.User); password == "" || password != request.Password {
+	isBuiltinAdmin := false
+	role := dash_user.Role("")
+	if password := config.AdminPassword.GetPassword(request.User); password != "" {
+		if password == request.Password {
+			isBuiltinAdmin = true
+			role = dash_user.RoleAdmin
+		}
+	} else {
+		user, err := dash_user.GetById(request.User)
+		if err != nil {
+			SendError(w, r, err)
+			return
+		}
+		if user != nil && user.VerifyPassword(request.Password) {
+			role = user.Role
+		}
+	}
+	if role == "" {
 		ErrorUnauthorized(r, "Invalid Credentials").Send(w, r)
 		return
 	}
@@ -164,15 +230,19 @@ func HandleSignIn(w http.ResponseWriter, r *http.Request) {
 		ctx.Session = &Session{}
 	}
 	ctx.Session.User = request.User
+	ctx.Session.Role = role
 	if err := ctx.Session.Save(w, r); err != nil {
 		SendError(w, r, err)
 		return
 	}
 
-	stremio_shared.SetAdminCookie(w, request.User, request.Password)
+	if isBuiltinAdmin {
+		stremio_shared.SetAdminCookie(w, request.User, request.Password)
+	}
 
 	SendData(w, r, 200, GetUserResponse{
-		Id: ctx.Session.User,
+		Id:   ctx.Session.User,
+		Role: ctx.Session.Role,
 	})
 }
 
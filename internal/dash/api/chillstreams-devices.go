@@ -0,0 +1,128 @@
+package dash_api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/device"
+	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
+)
+
+// getChillstreamsUserId returns the user id of the request path, it sends
+// the error response if the id is invalid.
+func getChillstreamsUserId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId := r.PathValue("userId")
+	if !core.IsValidUUID(userId) {
+		ErrorBadRequest(r, "").Append(Error{
+			Location: "userId",
+			Message:  "invalid user id",
+		}).Send(w, r)
+		return "", false
+	}
+	return userId, true
+}
+
+func getChillstreamsDevice(w http.ResponseWriter, r *http.Request) *device.Device {
+	userId, ok := getChillstreamsUserId(w, r)
+	if !ok {
+		return nil
+	}
+	item, err := device.GetById(userId, r.PathValue("id"))
+	if err != nil {
+		SendError(w, r, err)
+		return nil
+	}
+	if item == nil {
+		ErrorNotFound(r, "device not found").Send(w, r)
+		return nil
+	}
+	return item
+}
+
+func handleGetChillstreamsDevices(w http.ResponseWriter, r *http.Request) {
+	userId, ok := getChillstreamsUserId(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := device.GetByUserId(userId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	data := make([]device.Info, len(items))
+	for i := range items {
+		data[i] = items[i].ToInfo()
+	}
+
+	SendData(w, r, 200, data)
+}
+
+type UpdateChillstreamsDeviceRequest struct {
+	Label string `json:"label"`
+}
+
+func handleUpdateChillstreamsDevice(w http.ResponseWriter, r *http.Request) {
+	request := &UpdateChillstreamsDeviceRequest{}
+	if err := ReadRequestBodyJSON(r, request); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	item := getChillstreamsDevice(w, r)
+	if item == nil {
+		return
+	}
+
+	item.Label = strings.TrimSpace(request.Label)
+	if err := device.SetLabel(item.UserId, item.Id, item.Label); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, item.ToInfo())
+}
+
+func handleRevokeChillstreamsDevice(w http.ResponseWriter, r *http.Request) {
+	item := getChillstreamsDevice(w, r)
+	if item == nil {
+		return
+	}
+
+	if err := stremio_userdata.RevokeChillstreamsDevice(r.Context(), item.UserId, item.Id); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 204, nil)
+}
+
+func AddChillstreamsDeviceEndpoints(router *http.ServeMux) {
+	if !config.EnableChillstreamsAuth {
+		return
+	}
+
+	authed := EnsureAuthed
+
+	router.HandleFunc("/chillstreams/users/{userId}/devices", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetChillstreamsDevices(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/chillstreams/users/{userId}/devices/{id}", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			handleUpdateChillstreamsDevice(w, r)
+		case http.MethodDelete:
+			handleRevokeChillstreamsDevice(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+}
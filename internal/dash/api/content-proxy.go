@@ -0,0 +1,114 @@
+package dash_api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/content_proxy"
+)
+
+type ContentProxyUserUsage struct {
+	User      string              `json:"user"`
+	Usage     content_proxy.Usage `json:"usage"`
+	Quota     content_proxy.Quota `json:"quota"`
+	RateLimit int64               `json:"rate_limit"`
+}
+
+type ContentProxyUsage struct {
+	GlobalRateLimit int64                   `json:"global_rate_limit"`
+	Users           []ContentProxyUserUsage `json:"users"`
+}
+
+func handleGetContentProxyUsage(w http.ResponseWriter, r *http.Request) {
+	items, err := content_proxy.ListUsage()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	usageByUser := make(map[string]content_proxy.Usage, len(items))
+	for _, item := range items {
+		usageByUser[item.User] = item.Usage
+	}
+
+	users := []string{}
+	for user := range config.ProxyAuthPassword {
+		users = append(users, user)
+	}
+	for user := range usageByUser {
+		if !slices.Contains(users, user) {
+			users = append(users, user)
+		}
+	}
+	slices.Sort(users)
+
+	data := ContentProxyUsage{
+		GlobalRateLimit: config.ContentProxy.GlobalRateLimit,
+		Users:           make([]ContentProxyUserUsage, 0, len(users)),
+	}
+	for _, user := range users {
+		data.Users = append(data.Users, ContentProxyUserUsage{
+			User:      user,
+			Usage:     usageByUser[user],
+			Quota:     content_proxy.GetQuota(user),
+			RateLimit: config.ContentProxy.RateLimit.Get(user),
+		})
+	}
+	SendData(w, r, 200, data)
+}
+
+func handleGetContentProxyConnections(w http.ResponseWriter, r *http.Request) {
+	items, err := content_proxy.ListActiveConnections()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	SendData(w, r, 200, items)
+}
+
+func handleKillContentProxyConnection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	found, err := content_proxy.KillConnection(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if !found {
+		ErrorNotFound(r, "connection not found").Send(w, r)
+		return
+	}
+
+	SendData(w, r, 204, nil)
+}
+
+func AddContentProxyEndpoints(router *http.ServeMux) {
+	authed := EnsureAuthed
+	admin := EnsureAdmin
+
+	router.HandleFunc("/content-proxy/usage", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetContentProxyUsage(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/content-proxy/connections", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetContentProxyConnections(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/content-proxy/connections/{id}", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			handleKillContentProxyConnection(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+}
@@ -0,0 +1,7 @@
+package dash_api
+
+import (
+	"github.com/MunifTanjim/stremthru/internal/logger"
+)
+
+var log = logger.Scoped("dash/api")
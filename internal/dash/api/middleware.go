@@ -18,7 +18,7 @@ type ReqCtx struct {
 }
 
 func (c *ReqCtx) IsAuthed() bool {
-	return c.Session != nil && c.Session.User != ""
+	return c.Session != nil && c.Session.User != "" && c.Session.Role.IsValid()
 }
 
 func GetReqCtx(r *http.Request) *ReqCtx {
@@ -52,6 +52,25 @@ func EnsureAuthed(next http.HandlerFunc) http.HandlerFunc {
 			ErrorUnauthorized(r, "").Send(w, r)
 			return
 		}
+		if !ctx.Session.Role.CanAccess(r.Method) {
+			ErrorForbidden(r, "").Send(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func EnsureAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := GetReqCtx(r)
+		if !ctx.IsAuthed() {
+			ErrorUnauthorized(r, "").Send(w, r)
+			return
+		}
+		if !ctx.Session.Role.IsAdmin() {
+			ErrorForbidden(r, "").Send(w, r)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -7,12 +7,15 @@ import (
 
 	"github.com/MunifTanjim/stremthru/internal/anilist"
 	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/db"
 	"github.com/MunifTanjim/stremthru/internal/imdb_title"
 	"github.com/MunifTanjim/stremthru/internal/letterboxd"
 	"github.com/MunifTanjim/stremthru/internal/mdblist"
 	"github.com/MunifTanjim/stremthru/internal/shared"
+	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
 	"github.com/MunifTanjim/stremthru/internal/tmdb"
 	"github.com/MunifTanjim/stremthru/internal/torrent_info"
 	"github.com/MunifTanjim/stremthru/internal/trakt"
@@ -87,7 +90,8 @@ type ServerStatsFeature struct {
 }
 
 type ServerStatsIntegration struct {
-	Trakt bool `json:"trakt"`
+	Trakt        bool `json:"trakt"`
+	Chillstreams bool `json:"chillstreams"`
 }
 
 type ServerStats struct {
@@ -110,7 +114,8 @@ func HandleGetServerStats(w http.ResponseWriter, r *http.Request) {
 			Vault: config.Feature.HasVault(),
 		},
 		Integration: ServerStatsIntegration{
-			Trakt: config.Integration.Trakt.IsEnabled(),
+			Trakt:        config.Integration.Trakt.IsEnabled(),
+			Chillstreams: config.EnableChillstreamsAuth,
 		},
 	}
 	SendData(w, r, 200, data)
@@ -214,3 +219,29 @@ func HandleGetListsStats(w http.ResponseWriter, r *http.Request) {
 
 	SendData(w, r, 200, stats)
 }
+
+type ChillstreamsStats struct {
+	PoolKeyLease *chillstreams.PoolKeyLeaseStats     `json:"pool_key_lease"`
+	UsageOutbox  *chillstreams_usage.Stats           `json:"usage_outbox"`
+	KeyHealth    chillstreams.KeyCircuitBreakerStats `json:"key_health"`
+}
+
+func HandleGetChillstreamsStats(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodGet) {
+		ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	usageOutbox, err := chillstreams_usage.GetStats()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	data := ChillstreamsStats{
+		PoolKeyLease: stremio_userdata.GetChillstreamsPoolKeyLeaseStats(),
+		UsageOutbox:  usageOutbox,
+		KeyHealth:    stremio_userdata.GetChillstreamsKeyHealthStats(),
+	}
+	SendData(w, r, 200, data)
+}
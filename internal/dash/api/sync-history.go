@@ -0,0 +1,103 @@
+package dash_api
+
+import (
+	"net/http"
+	"time"
+
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
+	"github.com/MunifTanjim/stremthru/internal/worker"
+)
+
+const syncHistoryLimit = 500
+
+type SyncHistoryEntryResponse struct {
+	Id    int64  `json:"id"`
+	JobId string `json:"job_id"`
+	sync_history.Change
+	RevertedAt string `json:"reverted_at,omitempty"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func toSyncHistoryEntryResponse(entry *sync_history.Entry) SyncHistoryEntryResponse {
+	resp := SyncHistoryEntryResponse{
+		Id:        entry.Id,
+		JobId:     entry.JobId,
+		Change:    entry.Change,
+		CreatedAt: entry.CAt.Format(time.RFC3339),
+	}
+	if !entry.RevertedAt.IsZero() {
+		resp.RevertedAt = entry.RevertedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toSyncHistoryEntriesResponse(entries []sync_history.Entry) []SyncHistoryEntryResponse {
+	data := make([]SyncHistoryEntryResponse, len(entries))
+	for i := range entries {
+		data[i] = toSyncHistoryEntryResponse(&entries[i])
+	}
+	return data
+}
+
+type SyncDryRunResponse struct {
+	Changes []sync_history.Change `json:"changes"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// the changes computed before a failure are still returned, along with the
+// error.
+func sendSyncDryRun(w http.ResponseWriter, r *http.Request, changes []sync_history.Change, err error) {
+	data := SyncDryRunResponse{Changes: changes}
+	if data.Changes == nil {
+		data.Changes = []sync_history.Change{}
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	SendData(w, r, 200, data)
+}
+
+func sendSyncHistory(w http.ResponseWriter, r *http.Request, linkType sync_history.LinkType, linkId string) {
+	entries, err := sync_history.GetByLink(linkType, linkId, syncHistoryLimit)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, toSyncHistoryEntriesResponse(entries))
+}
+
+type SyncRevertConflictResponse struct {
+	SyncHistoryEntryResponse
+	Reason string `json:"reason"`
+}
+
+type SyncRevertResponse struct {
+	Reverted  []SyncHistoryEntryResponse   `json:"reverted"`
+	Conflicts []SyncRevertConflictResponse `json:"conflicts"`
+}
+
+func sendSyncRevertLastRun(w http.ResponseWriter, r *http.Request, linkType sync_history.LinkType, linkId string) {
+	result, err := worker.RevertLastSyncRun(linkType, linkId)
+	if err != nil {
+		if worker.IsSyncHistoryNothingToRevert(err) {
+			ErrorBadRequest(r, err.Error()).Send(w, r)
+			return
+		}
+		SendError(w, r, err)
+		return
+	}
+
+	data := SyncRevertResponse{
+		Reverted:  toSyncHistoryEntriesResponse(result.Reverted),
+		Conflicts: make([]SyncRevertConflictResponse, len(result.Conflicts)),
+	}
+	for i := range result.Conflicts {
+		conflict := &result.Conflicts[i]
+		data.Conflicts[i] = SyncRevertConflictResponse{
+			SyncHistoryEntryResponse: toSyncHistoryEntryResponse(&conflict.Entry),
+			Reason:                   conflict.Reason,
+		}
+	}
+	SendData(w, r, 200, data)
+}
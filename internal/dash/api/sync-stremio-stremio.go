@@ -5,7 +5,9 @@ import (
 	"strings"
 	"time"
 
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
 	sync_stremio_stremio "github.com/MunifTanjim/stremthru/internal/sync/stremio_stremio"
+	"github.com/MunifTanjim/stremthru/internal/worker"
 )
 
 type StremioStremioLinkResponse struct {
@@ -181,6 +183,11 @@ func handleDeleteStremioStremioLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := sync_history.DeleteByLink(sync_history.LinkTypeStremioStremio, accountAId+":"+accountBId); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
 	SendData(w, r, 204, nil)
 }
 
@@ -228,6 +235,45 @@ func handleResetStremioStremioLinkSyncState(w http.ResponseWriter, r *http.Reque
 	SendData(w, r, 200, toStremioStremioLinkResponse(link))
 }
 
+func handleDryRunStremioStremioLink(w http.ResponseWriter, r *http.Request) {
+	accountAId, accountBId := parseStremioAccountIdPair(r.PathValue("account_id_pair"))
+
+	link, err := sync_stremio_stremio.GetById(accountAId, accountBId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if link == nil {
+		ErrorNotFound(r, "").Send(w, r)
+		return
+	}
+
+	changes, err := worker.DryRunSyncStremioStremioLink(link)
+	sendSyncDryRun(w, r, changes, err)
+}
+
+func handleGetStremioStremioLinkHistory(w http.ResponseWriter, r *http.Request) {
+	accountAId, accountBId := parseStremioAccountIdPair(r.PathValue("account_id_pair"))
+
+	sendSyncHistory(w, r, sync_history.LinkTypeStremioStremio, accountAId+":"+accountBId)
+}
+
+func handleRevertStremioStremioLinkLastSync(w http.ResponseWriter, r *http.Request) {
+	accountAId, accountBId := parseStremioAccountIdPair(r.PathValue("account_id_pair"))
+
+	link, err := sync_stremio_stremio.GetById(accountAId, accountBId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if link == nil {
+		ErrorNotFound(r, "").Send(w, r)
+		return
+	}
+
+	sendSyncRevertLastRun(w, r, sync_history.LinkTypeStremioStremio, accountAId+":"+accountBId)
+}
+
 func AddSyncStremioStremioEndpoints(router *http.ServeMux) {
 	authed := EnsureAuthed
 
@@ -269,4 +315,28 @@ func AddSyncStremioStremioEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/sync/stremio-stremio/links/{account_id_pair}/dry-run", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleDryRunStremioStremioLink(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/sync/stremio-stremio/links/{account_id_pair}/history", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetStremioStremioLinkHistory(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/sync/stremio-stremio/links/{account_id_pair}/revert", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleRevertStremioStremioLinkLastSync(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 }
@@ -5,7 +5,9 @@ import (
 	"strings"
 	"time"
 
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
 	sync_stremio_trakt "github.com/MunifTanjim/stremthru/internal/sync/stremio_trakt"
+	"github.com/MunifTanjim/stremthru/internal/worker"
 )
 
 type StremioTraktLinkResponse struct {
@@ -85,8 +87,9 @@ func handleCreateStremioTraktLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !request.SyncConfig.Watched.Direction.IsValid() {
-		ErrorBadRequest(r, "invalid sync direction").Send(w, r)
+	request.SyncConfig.Normalize()
+	if err := request.SyncConfig.Validate(); err != nil {
+		ErrorBadRequest(r, err.Error()).Send(w, r)
 		return
 	}
 
@@ -143,8 +146,9 @@ func handleUpdateStremioTraktLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !request.SyncConfig.Watched.Direction.IsValid() {
-		ErrorBadRequest(r, "invalid sync direction").Send(w, r)
+	request.SyncConfig.Normalize()
+	if err := request.SyncConfig.Validate(); err != nil {
+		ErrorBadRequest(r, err.Error()).Send(w, r)
 		return
 	}
 
@@ -175,6 +179,11 @@ func handleDeleteStremioTraktLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := sync_history.DeleteByLink(sync_history.LinkTypeStremioTrakt, stremioAccountId+":"+traktAccountId); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
 	SendData(w, r, 204, nil)
 }
 
@@ -208,7 +217,7 @@ func handleResetStremioTraktLinkSyncState(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	link.SyncState.Watched.LastSyncedAt = nil
+	link.SyncState = sync_stremio_trakt.SyncState{}
 
 	if err := sync_stremio_trakt.SetSyncState(
 		link.StremioAccountId,
@@ -222,6 +231,45 @@ func handleResetStremioTraktLinkSyncState(w http.ResponseWriter, r *http.Request
 	SendData(w, r, 200, toStremioTraktLinkResponse(link))
 }
 
+func handleDryRunStremioTraktLink(w http.ResponseWriter, r *http.Request) {
+	stremioAccountId, traktAccountId := parseAccountIdPair(r.PathValue("account_id_pair"))
+
+	link, err := sync_stremio_trakt.GetById(stremioAccountId, traktAccountId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if link == nil {
+		ErrorNotFound(r, "").Send(w, r)
+		return
+	}
+
+	changes, err := worker.DryRunSyncStremioTraktLink(link)
+	sendSyncDryRun(w, r, changes, err)
+}
+
+func handleGetStremioTraktLinkHistory(w http.ResponseWriter, r *http.Request) {
+	stremioAccountId, traktAccountId := parseAccountIdPair(r.PathValue("account_id_pair"))
+
+	sendSyncHistory(w, r, sync_history.LinkTypeStremioTrakt, stremioAccountId+":"+traktAccountId)
+}
+
+func handleRevertStremioTraktLinkLastSync(w http.ResponseWriter, r *http.Request) {
+	stremioAccountId, traktAccountId := parseAccountIdPair(r.PathValue("account_id_pair"))
+
+	link, err := sync_stremio_trakt.GetById(stremioAccountId, traktAccountId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if link == nil {
+		ErrorNotFound(r, "").Send(w, r)
+		return
+	}
+
+	sendSyncRevertLastRun(w, r, sync_history.LinkTypeStremioTrakt, stremioAccountId+":"+traktAccountId)
+}
+
 func AddSyncStremioTraktEndpoints(router *http.ServeMux) {
 	authed := EnsureAuthed
 
@@ -263,4 +311,28 @@ func AddSyncStremioTraktEndpoints(router *http.ServeMux) {
 			ErrorMethodNotAllowed(r).Send(w, r)
 		}
 	}))
+	router.HandleFunc("/sync/stremio-trakt/links/{account_id_pair}/dry-run", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleDryRunStremioTraktLink(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/sync/stremio-trakt/links/{account_id_pair}/history", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetStremioTraktLinkHistory(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/sync/stremio-trakt/links/{account_id_pair}/revert", authed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handleRevertStremioTraktLinkLastSync(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
 }
@@ -0,0 +1,310 @@
+package dash_api
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	dash_session "github.com/MunifTanjim/stremthru/internal/dash/session"
+	dash_user "github.com/MunifTanjim/stremthru/internal/dash/user"
+)
+
+type UserResponse struct {
+	Id        string         `json:"id"`
+	Role      dash_user.Role `json:"role"`
+	IsBuiltin bool           `json:"is_builtin"`
+	CreatedAt string         `json:"created_at,omitempty"`
+	UpdatedAt string         `json:"updated_at,omitempty"`
+}
+
+func toUserResponse(item *dash_user.DashUser) UserResponse {
+	return UserResponse{
+		Id:        item.Id,
+		Role:      item.Role,
+		CreatedAt: item.CAt.Format(time.RFC3339),
+		UpdatedAt: item.UAt.Format(time.RFC3339),
+	}
+}
+
+func isBuiltinUser(id string) bool {
+	return config.AdminPassword.GetPassword(id) != ""
+}
+
+func handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	items, err := dash_user.GetAll()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	builtinIds := make([]string, 0, len(config.AdminPassword))
+	for id := range config.AdminPassword {
+		builtinIds = append(builtinIds, id)
+	}
+	slices.Sort(builtinIds)
+
+	data := make([]UserResponse, 0, len(builtinIds)+len(items))
+	for _, id := range builtinIds {
+		data = append(data, UserResponse{
+			Id:        id,
+			Role:      dash_user.RoleAdmin,
+			IsBuiltin: true,
+		})
+	}
+	for i := range items {
+		data = append(data, toUserResponse(&items[i]))
+	}
+
+	SendData(w, r, 200, data)
+}
+
+type CreateUserRequest struct {
+	Id       string         `json:"id"`
+	Password string         `json:"password"`
+	Role     dash_user.Role `json:"role"`
+}
+
+func handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	request := &CreateUserRequest{}
+	if err := ReadRequestBodyJSON(r, request); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	errs := []Error{}
+	if request.Id == "" {
+		errs = append(errs, Error{
+			Location: "id",
+			Message:  "missing id",
+		})
+	}
+	if request.Password == "" {
+		errs = append(errs, Error{
+			Location: "password",
+			Message:  "missing password",
+		})
+	}
+	if !request.Role.IsValid() {
+		errs = append(errs, Error{
+			Location: "role",
+			Message:  "invalid role",
+		})
+	}
+	if len(errs) > 0 {
+		ErrorBadRequest(r, "").Append(errs...).Send(w, r)
+		return
+	}
+
+	if isBuiltinUser(request.Id) {
+		ErrorBadRequest(r, "user already exists").Send(w, r)
+		return
+	}
+	existing, err := dash_user.GetById(request.Id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if existing != nil {
+		ErrorBadRequest(r, "user already exists").Send(w, r)
+		return
+	}
+
+	user, err := dash_user.NewDashUser(request.Id, request.Password, request.Role)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if err := user.Upsert(); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 201, toUserResponse(user))
+}
+
+type UpdateUserRequest struct {
+	Password string         `json:"password"`
+	Role     dash_user.Role `json:"role"`
+}
+
+func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if isBuiltinUser(id) {
+		ErrorBadRequest(r, "builtin user can not be modified").Send(w, r)
+		return
+	}
+
+	request := &UpdateUserRequest{}
+	if err := ReadRequestBodyJSON(r, request); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	if request.Role != "" && !request.Role.IsValid() {
+		ErrorBadRequest(r, "").Append(Error{
+			Location: "role",
+			Message:  "invalid role",
+		}).Send(w, r)
+		return
+	}
+
+	user, err := dash_user.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if user == nil {
+		ErrorNotFound(r, "user not found").Send(w, r)
+		return
+	}
+
+	if request.Role != "" {
+		user.Role = request.Role
+	}
+	if request.Password != "" {
+		if err := user.SetPassword(request.Password); err != nil {
+			SendError(w, r, err)
+			return
+		}
+	}
+	if err := user.Upsert(); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	// existing sessions carry the old role and credentials
+	if err := sessionStorage.RemoveByUser(user.Id); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, toUserResponse(user))
+}
+
+func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if isBuiltinUser(id) {
+		ErrorBadRequest(r, "builtin user can not be deleted").Send(w, r)
+		return
+	}
+
+	ctx := GetReqCtx(r)
+	if ctx.Session.User == id {
+		ErrorBadRequest(r, "can not delete the current user").Send(w, r)
+		return
+	}
+
+	user, err := dash_user.GetById(id)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if user == nil {
+		ErrorNotFound(r, "user not found").Send(w, r)
+		return
+	}
+
+	if err := dash_user.Delete(id); err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if err := sessionStorage.RemoveByUser(id); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 204, nil)
+}
+
+type SessionResponse struct {
+	Key       string         `json:"key"`
+	User      string         `json:"user"`
+	Role      dash_user.Role `json:"role"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	IsCurrent bool           `json:"is_current"`
+	ExpiresAt string         `json:"expires_at"`
+	CreatedAt string         `json:"created_at"`
+}
+
+func handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	items, err := dash_session.GetAllActive()
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	currentKey := getSessionKey(GetReqCtx(r).Session.Id)
+
+	data := make([]SessionResponse, len(items))
+	for i := range items {
+		item := &items[i]
+		data[i] = SessionResponse{
+			Key:       item.Id,
+			User:      item.UserId,
+			Role:      item.Role,
+			IP:        item.IP,
+			UserAgent: item.UserAgent,
+			IsCurrent: item.Id == currentKey,
+			ExpiresAt: item.EAt.Format(time.RFC3339),
+			CreatedAt: item.CAt.Format(time.RFC3339),
+		}
+	}
+
+	SendData(w, r, 200, data)
+}
+
+func handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	if err := sessionStorage.RemoveByKey(key); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 204, nil)
+}
+
+func AddUserEndpoints(router *http.ServeMux) {
+	admin := EnsureAdmin
+
+	router.HandleFunc("/users", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetUsers(w, r)
+		case http.MethodPost:
+			handleCreateUser(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/users/{id}", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			handleUpdateUser(w, r)
+		case http.MethodDelete:
+			handleDeleteUser(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/auth/sessions", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetSessions(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	router.HandleFunc("/auth/sessions/{key}", admin(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			handleRevokeSession(w, r)
+		default:
+			ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+}
@@ -21,6 +21,9 @@ type WorkerDetails struct {
 	Title        string        `json:"title"`
 	Interval     time.Duration `json:"interval"`
 	HasFailedJob bool          `json:"has_failed_job"`
+	IsEnabled    bool          `json:"is_enabled"`
+	IsPaused     bool          `json:"is_paused"`
+	IsRunning    bool          `json:"is_running"`
 }
 
 func handleGetWorkersDetails(w http.ResponseWriter, r *http.Request) {
@@ -32,10 +35,14 @@ func handleGetWorkersDetails(w http.ResponseWriter, r *http.Request) {
 	data := make(map[string]*WorkerDetails, len(worker.WorkerDetailsById))
 
 	for name, details := range worker.WorkerDetailsById {
+		state := worker.GetWorkerState(name)
 		data[name] = &WorkerDetails{
-			Id:       details.Id,
-			Title:    details.Title,
-			Interval: details.Interval,
+			Id:        details.Id,
+			Title:     details.Title,
+			Interval:  state.Interval,
+			IsEnabled: state.IsEnabled,
+			IsPaused:  state.IsPaused,
+			IsRunning: state.IsRunning,
 		}
 	}
 
@@ -206,6 +213,102 @@ func handleWorkerTemporaryFiles(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func sendWorkerControlError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, worker.ErrWorkerJobRunning):
+		ErrorLocked(r, err.Error()).WithCause(err).Send(w, r)
+	case errors.Is(err, worker.ErrWorkerNotFound), errors.Is(err, worker.ErrWorkerJobIdle):
+		ErrorBadRequest(r, err.Error()).WithCause(err).Send(w, r)
+	case errors.Is(err, worker.ErrInvalidInterval):
+		ErrorBadRequest(r, "").Append(Error{
+			Location: "interval",
+			Message:  err.Error(),
+		}).Send(w, r)
+	default:
+		SendError(w, r, err)
+	}
+}
+
+func handleWorkerControl(action func(id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !shared.IsMethod(r, http.MethodPost) {
+			ErrorMethodNotAllowed(r).Send(w, r)
+			return
+		}
+
+		name := r.PathValue("id")
+		if _, ok := worker.WorkerDetailsById[name]; !ok {
+			ErrorBadRequest(r, "invalid worker id").Send(w, r)
+			return
+		}
+
+		if err := action(name); err != nil {
+			sendWorkerControlError(w, r, err)
+			return
+		}
+
+		SendData(w, r, 200, worker.GetWorkerState(name))
+	}
+}
+
+type UpdateWorkerRequest struct {
+	Interval string `json:"interval"`
+}
+
+func handleUpdateWorker(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("id")
+	if _, ok := worker.WorkerDetailsById[name]; !ok {
+		ErrorBadRequest(r, "invalid worker id").Send(w, r)
+		return
+	}
+
+	request := &UpdateWorkerRequest{}
+	if err := ReadRequestBodyJSON(r, request); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	interval, err := time.ParseDuration(request.Interval)
+	if err != nil {
+		ErrorBadRequest(r, "").Append(Error{
+			Location: "interval",
+			Message:  "invalid interval",
+		}).Send(w, r)
+		return
+	}
+
+	if err := worker.SetWorkerInterval(name, interval); err != nil {
+		sendWorkerControlError(w, r, err)
+		return
+	}
+
+	SendData(w, r, 200, worker.GetWorkerState(name))
+}
+
+func handleWorker(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPatch:
+		handleUpdateWorker(w, r)
+	default:
+		ErrorMethodNotAllowed(r).Send(w, r)
+	}
+}
+
+func handleGetWorkerState(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodGet) {
+		ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	name := r.PathValue("id")
+	if _, ok := worker.WorkerDetailsById[name]; !ok {
+		ErrorBadRequest(r, "invalid worker id").Send(w, r)
+		return
+	}
+
+	SendData(w, r, 200, worker.GetWorkerState(name))
+}
+
 func AddWorkerEndpoints(router *http.ServeMux) {
 	authed := EnsureAuthed
 
@@ -213,4 +316,10 @@ func AddWorkerEndpoints(router *http.ServeMux) {
 	router.HandleFunc("/workers/{id}/job-logs", authed(handleWorkerJobLogs))
 	router.HandleFunc("/workers/{id}/job-logs/{jobId}", authed(handleWorkerJobLog))
 	router.HandleFunc("/workers/{id}/temporary-files", authed(handleWorkerTemporaryFiles))
+	router.HandleFunc("/workers/{id}", authed(handleWorker))
+	router.HandleFunc("/workers/{id}/state", authed(handleGetWorkerState))
+	router.HandleFunc("/workers/{id}/trigger", authed(handleWorkerControl(worker.TriggerWorker)))
+	router.HandleFunc("/workers/{id}/pause", authed(handleWorkerControl(worker.PauseWorker)))
+	router.HandleFunc("/workers/{id}/resume", authed(handleWorkerControl(worker.ResumeWorker)))
+	router.HandleFunc("/workers/{id}/cancel", authed(handleWorkerControl(worker.CancelWorkerJob)))
 }
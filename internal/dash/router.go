@@ -24,16 +24,20 @@ func AddEndpoints(mux *http.ServeMux) {
 	authed := dash_api.EnsureAuthed
 
 	router.HandleFunc("/auth/signin", dash_api.HandleSignIn)
-	router.HandleFunc("/auth/signout", authed(dash_api.HandleSignOut))
+	router.HandleFunc("/auth/signout", dash_api.HandleSignOut)
 	router.HandleFunc("/auth/user", authed(dash_api.HandleGetUser))
 
 	router.HandleFunc("/stats/lists", authed(dash_api.HandleGetListsStats))
 	router.HandleFunc("/stats/imdb-titles", authed(dash_api.HandleGetIMDBTitleStats))
 	router.HandleFunc("/stats/torrents", authed(dash_api.HandleGetTorrentsStats))
 	router.HandleFunc("/stats/server", authed(dash_api.HandleGetServerStats))
+	router.HandleFunc("/stats/chillstreams", authed(dash_api.HandleGetChillstreamsStats))
 
+	dash_api.AddUserEndpoints(router)
 	dash_api.AddIMDBEndpoints(router)
 	dash_api.AddWorkerEndpoints(router)
+	dash_api.AddChillstreamsDeviceEndpoints(router)
+	dash_api.AddContentProxyEndpoints(router)
 
 	if config.Feature.HasVault() {
 		dash_api.AddVaultStremioEndpoints(router)
@@ -0,0 +1,210 @@
+package dash_session
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	dash_user "github.com/MunifTanjim/stremthru/internal/dash/user"
+	"github.com/MunifTanjim/stremthru/internal/db"
+)
+
+const TableName = "dash_session"
+
+type DashSession struct {
+	Id        string
+	UserId    string
+	Role      dash_user.Role
+	IP        string
+	UserAgent string
+	EAt       db.Timestamp
+	CAt       db.Timestamp
+	UAt       db.Timestamp
+}
+
+func (s *DashSession) IsExpired() bool {
+	return !s.EAt.After(time.Now())
+}
+
+var Column = struct {
+	Id        string
+	UserId    string
+	Role      string
+	IP        string
+	UserAgent string
+	EAt       string
+	CAt       string
+	UAt       string
+}{
+	Id:        "id",
+	UserId:    "user_id",
+	Role:      "role",
+	IP:        "ip",
+	UserAgent: "user_agent",
+	EAt:       "eat",
+	CAt:       "cat",
+	UAt:       "uat",
+}
+
+var columns = []string{
+	Column.Id,
+	Column.UserId,
+	Column.Role,
+	Column.IP,
+	Column.UserAgent,
+	Column.EAt,
+	Column.CAt,
+	Column.UAt,
+}
+
+var query_upsert = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?,?,?,?) ON CONFLICT (%s) DO UPDATE SET %s`,
+	TableName,
+	db.JoinColumnNames(
+		Column.Id,
+		Column.UserId,
+		Column.Role,
+		Column.IP,
+		Column.UserAgent,
+		Column.EAt,
+	),
+	Column.Id,
+	strings.Join([]string{
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.UserId, Column.UserId),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Role, Column.Role),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.IP, Column.IP),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.UserAgent, Column.UserAgent),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.EAt, Column.EAt),
+		fmt.Sprintf(`%s = %s`, Column.UAt, db.CurrentTimestamp),
+	}, ", "),
+)
+
+func (s *DashSession) Upsert() error {
+	_, err := db.Exec(query_upsert, s.Id, s.UserId, s.Role, s.IP, s.UserAgent, s.EAt)
+	if s.CAt.IsZero() {
+		s.CAt = db.Timestamp{Time: time.Now()}
+		s.UAt = db.Timestamp{Time: s.CAt.Time}
+	} else {
+		s.UAt = db.Timestamp{Time: time.Now()}
+	}
+	return err
+}
+
+func scanSession(row interface{ Scan(dest ...any) error }, item *DashSession) error {
+	return row.Scan(&item.Id, &item.UserId, &item.Role, &item.IP, &item.UserAgent, &item.EAt, &item.CAt, &item.UAt)
+}
+
+var query_get_all_active = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s > ? ORDER BY %s DESC`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.EAt,
+	Column.CAt,
+)
+
+// GetAllActive returns the sessions that are not expired, newest first.
+func GetAllActive() ([]DashSession, error) {
+	rows, err := db.Query(query_get_all_active, db.Timestamp{Time: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []DashSession{}
+	for rows.Next() {
+		item := DashSession{}
+		if err := scanSession(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+var query_get_by_id = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ?`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.Id,
+)
+
+// GetById returns the session, if it exists and is not expired.
+func GetById(id string) (*DashSession, error) {
+	row := db.QueryRow(query_get_by_id, id)
+
+	item := DashSession{}
+	if err := scanSession(row, &item); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if item.IsExpired() {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+var query_delete = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ?`,
+	TableName,
+	Column.Id,
+)
+
+func Delete(id string) error {
+	_, err := db.Exec(query_delete, id)
+	return err
+}
+
+var query_get_ids_by_user_id = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ?`,
+	Column.Id,
+	TableName,
+	Column.UserId,
+)
+
+var query_delete_by_user_id = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ?`,
+	TableName,
+	Column.UserId,
+)
+
+// DeleteByUserId deletes all the sessions of the user, and returns the
+// deleted ids.
+func DeleteByUserId(userId string) ([]string, error) {
+	rows, err := db.Query(query_get_ids_by_user_id, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if _, err := db.Exec(query_delete_by_user_id, userId); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+var query_delete_expired = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s <= ?`,
+	TableName,
+	Column.EAt,
+)
+
+func DeleteExpired() error {
+	_, err := db.Exec(query_delete_expired, db.Timestamp{Time: time.Now()})
+	return err
+}
@@ -0,0 +1,183 @@
+package dash_user
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const TableName = "dash_user"
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleOperator Role = "operator"
+	RoleReadOnly Role = "read_only"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleOperator, RoleReadOnly:
+		return true
+	}
+	return false
+}
+
+func (r Role) IsAdmin() bool {
+	return r == RoleAdmin
+}
+
+// CanAccess reports if the role is allowed to make a request with the
+// method. Read-only users can not make changes.
+func (r Role) CanAccess(method string) bool {
+	switch r {
+	case RoleAdmin, RoleOperator:
+		return true
+	case RoleReadOnly:
+		return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	}
+	return false
+}
+
+type DashUser struct {
+	Id       string
+	Password string
+	Role     Role
+	CAt      db.Timestamp
+	UAt      db.Timestamp
+}
+
+func NewDashUser(id, password string, role Role) (*DashUser, error) {
+	user := &DashUser{
+		Id:   id,
+		Role: role,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *DashUser) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hash)
+	return nil
+}
+
+func (u *DashUser) VerifyPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
+
+var Column = struct {
+	Id       string
+	Password string
+	Role     string
+	CAt      string
+	UAt      string
+}{
+	Id:       "id",
+	Password: "password",
+	Role:     "role",
+	CAt:      "cat",
+	UAt:      "uat",
+}
+
+var columns = []string{
+	Column.Id,
+	Column.Password,
+	Column.Role,
+	Column.CAt,
+	Column.UAt,
+}
+
+var query_upsert = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?) ON CONFLICT (%s) DO UPDATE SET %s`,
+	TableName,
+	db.JoinColumnNames(
+		Column.Id,
+		Column.Password,
+		Column.Role,
+	),
+	Column.Id,
+	strings.Join([]string{
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Password, Column.Password),
+		fmt.Sprintf(`%s = EXCLUDED.%s`, Column.Role, Column.Role),
+		fmt.Sprintf(`%s = %s`, Column.UAt, db.CurrentTimestamp),
+	}, ", "),
+)
+
+func (u *DashUser) Upsert() error {
+	_, err := db.Exec(query_upsert, u.Id, u.Password, u.Role)
+	if u.CAt.IsZero() {
+		u.CAt = db.Timestamp{Time: time.Now()}
+		u.UAt = db.Timestamp{Time: u.CAt.Time}
+	} else {
+		u.UAt = db.Timestamp{Time: time.Now()}
+	}
+	return err
+}
+
+var query_get_all = fmt.Sprintf(
+	`SELECT %s FROM %s ORDER BY %s`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.Id,
+)
+
+func GetAll() ([]DashUser, error) {
+	rows, err := db.Query(query_get_all)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []DashUser{}
+	for rows.Next() {
+		item := DashUser{}
+		if err := rows.Scan(&item.Id, &item.Password, &item.Role, &item.CAt, &item.UAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+var query_get_by_id = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ?`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.Id,
+)
+
+func GetById(id string) (*DashUser, error) {
+	row := db.QueryRow(query_get_by_id, id)
+
+	item := DashUser{}
+	if err := row.Scan(&item.Id, &item.Password, &item.Role, &item.CAt, &item.UAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+var query_delete = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ?`,
+	TableName,
+	Column.Id,
+)
+
+func Delete(id string) error {
+	_, err := db.Exec(query_delete, id)
+	return err
+}
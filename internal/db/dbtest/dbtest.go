@@ -0,0 +1,110 @@
+// Package dbtest runs the tests of the packages working with the database
+// against a throwaway sqlite database.
+package dbtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+)
+
+// the database is only set up with this uri, so that the tests never touch
+// a real database
+const defaultURI = "sqlite://./data/stremthru.db"
+
+var ready = false
+
+func getMigrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations", "sqlite")
+}
+
+// readUpMigration returns the statements of the `Up` section of the sqlite
+// migration.
+func readUpMigration(dir, name string) (string, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, name+".sql"))
+	if err != nil {
+		return "", err
+	}
+	up, _, _ := strings.Cut(string(blob), "-- +goose Down")
+	lines := []string{}
+	for line := range strings.SplitSeq(up, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "-- +goose") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Main sets up the database with the given migrations (file names without
+// extension) and runs the tests. The database lives in a temporary working
+// directory, using the default sqlite uri. If other database is configured,
+// the tests using Require are skipped.
+func Main(m *testing.M, migrations ...string) int {
+	if config.DatabaseURI != defaultURI {
+		return m.Run()
+	}
+
+	migrationsDir := getMigrationsDir()
+	statements := make([]string, len(migrations))
+	for i, name := range migrations {
+		statement, err := readUpMigration(migrationsDir, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dbtest: failed to read migration: %v\n", err)
+			return 1
+		}
+		statements[i] = statement
+	}
+
+	dir, err := os.MkdirTemp("", "stremthru-dbtest-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dbtest: failed to create dir: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "data"), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "dbtest: failed to create dir: %v\n", err)
+		return 1
+	}
+	// the default uri is relative to the working directory
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "dbtest: failed to change dir: %v\n", err)
+		return 1
+	}
+
+	database := db.Open()
+	defer database.Close()
+	for i, statement := range statements {
+		if _, err := database.Exec(statement); err != nil {
+			fmt.Fprintf(os.Stderr, "dbtest: failed to run migration %s: %v\n", migrations[i], err)
+			return 1
+		}
+	}
+
+	ready = true
+	return m.Run()
+}
+
+// Require skips the test if the database is not set up by Main.
+func Require(t testing.TB) {
+	t.Helper()
+	if !ready {
+		t.Skip("dbtest: sqlite database not available")
+	}
+}
+
+// Truncate removes all the rows of the tables.
+func Truncate(t testing.TB, tableNames ...string) {
+	t.Helper()
+	for _, tableName := range tableNames {
+		if _, err := db.Exec("DELETE FROM " + tableName); err != nil {
+			t.Fatalf("dbtest: failed to truncate %s: %v", tableName, err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package device
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/google/uuid"
+)
+
+const TableName = "chillstreams_device"
+
+var log = logger.Scoped(TableName)
+
+type Kind string
+
+const (
+	KindToken       Kind = "token"       // registered, identified by a signed token
+	KindFingerprint Kind = "fingerprint" // identified by IP + User-Agent
+)
+
+type Device struct {
+	Id          string
+	UserId      string
+	Kind        Kind
+	Label       string
+	LastIP      string
+	UserAgent   string
+	StreamCount int64
+	LastSeenAt  db.Timestamp
+	RevokedAt   db.Timestamp
+	CAt         db.Timestamp
+	UAt         db.Timestamp
+}
+
+func (d *Device) IsRevoked() bool {
+	return !d.RevokedAt.IsZero()
+}
+
+// Info is the device as exposed by the apis.
+type Info struct {
+	Id          string `json:"id"`
+	Kind        string `json:"kind"`
+	Label       string `json:"label"`
+	LastIP      string `json:"last_ip"`
+	UserAgent   string `json:"user_agent"`
+	StreamCount int64  `json:"stream_count"`
+	IsRevoked   bool   `json:"is_revoked"`
+	FirstSeenAt string `json:"first_seen_at"`
+	LastSeenAt  string `json:"last_seen_at"`
+}
+
+func (d *Device) ToInfo() Info {
+	return Info{
+		Id:          d.Id,
+		Kind:        string(d.Kind),
+		Label:       d.Label,
+		LastIP:      d.LastIP,
+		UserAgent:   d.UserAgent,
+		StreamCount: d.StreamCount,
+		IsRevoked:   d.IsRevoked(),
+		FirstSeenAt: d.CAt.Format(time.RFC3339),
+		LastSeenAt:  d.LastSeenAt.Format(time.RFC3339),
+	}
+}
+
+var Column = struct {
+	Id          string
+	UserId      string
+	Kind        string
+	Label       string
+	LastIP      string
+	UserAgent   string
+	StreamCount string
+	LastSeenAt  string
+	RevokedAt   string
+	CAt         string
+	UAt         string
+}{
+	Id:          "id",
+	UserId:      "user_id",
+	Kind:        "kind",
+	Label:       "label",
+	LastIP:      "last_ip",
+	UserAgent:   "user_agent",
+	StreamCount: "stream_count",
+	LastSeenAt:  "last_seen_at",
+	RevokedAt:   "revoked_at",
+	CAt:         "cat",
+	UAt:         "uat",
+}
+
+var columns = []string{
+	Column.Id,
+	Column.UserId,
+	Column.Kind,
+	Column.Label,
+	Column.LastIP,
+	Column.UserAgent,
+	Column.StreamCount,
+	Column.LastSeenAt,
+	Column.RevokedAt,
+	Column.CAt,
+	Column.UAt,
+}
+
+type scannable interface {
+	Scan(dest ...any) error
+}
+
+func scanDevice(row scannable, d *Device) error {
+	return row.Scan(&d.Id, &d.UserId, &d.Kind, &d.Label, &d.LastIP, &d.UserAgent, &d.StreamCount, &d.LastSeenAt, &d.RevokedAt, &d.CAt, &d.UAt)
+}
+
+var deviceCache = cache.NewCache[Device](&cache.CacheConfig{
+	Name:          "chillstreams:device",
+	Lifetime:      1 * time.Minute,
+	LocalCapacity: 4096,
+})
+
+var lastSeenCache = cache.NewCache[string](&cache.CacheConfig{
+	Name:          "chillstreams:device:last-seen",
+	Lifetime:      5 * time.Minute,
+	LocalCapacity: 4096,
+})
+
+var query_insert = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?,?)`,
+	TableName,
+	db.JoinColumnNames(
+		Column.Id,
+		Column.UserId,
+		Column.Kind,
+		Column.Label,
+	),
+)
+
+// Register adds a new device for the Chillstreams user.
+func Register(userId, label string) (*Device, error) {
+	now := time.Now()
+	d := &Device{
+		Id:         strings.ReplaceAll(uuid.NewString(), "-", ""),
+		UserId:     userId,
+		Kind:       KindToken,
+		Label:      label,
+		LastSeenAt: db.Timestamp{Time: now},
+		CAt:        db.Timestamp{Time: now},
+		UAt:        db.Timestamp{Time: now},
+	}
+	if _, err := db.Exec(query_insert, d.Id, d.UserId, d.Kind, d.Label); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var query_get_by_id = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s = ?`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.UserId,
+	Column.Id,
+)
+
+func getCacheKey(userId, id string) string {
+	return userId + ":" + id
+}
+
+func GetById(userId, id string) (*Device, error) {
+	cacheKey := getCacheKey(userId, id)
+	d := Device{}
+	if deviceCache.Get(cacheKey, &d) {
+		return &d, nil
+	}
+
+	row := db.QueryRow(query_get_by_id, userId, id)
+	if err := scanDevice(row, &d); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	deviceCache.Add(cacheKey, d)
+	return &d, nil
+}
+
+var query_get_by_user_id = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? ORDER BY %s DESC`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.UserId,
+	Column.LastSeenAt,
+)
+
+func GetByUserId(userId string) ([]Device, error) {
+	rows, err := db.Query(query_get_by_user_id, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Device{}
+	for rows.Next() {
+		item := Device{}
+		if err := scanDevice(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+var query_seen = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES (?,?,?,?,?,%s) ON CONFLICT (%s, %s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s`,
+	TableName,
+	db.JoinColumnNames(
+		Column.Id,
+		Column.UserId,
+		Column.Kind,
+		Column.LastIP,
+		Column.UserAgent,
+		Column.LastSeenAt,
+	),
+	db.CurrentTimestamp,
+	Column.UserId,
+	Column.Id,
+	Column.LastIP,
+	Column.LastIP,
+	Column.UserAgent,
+	Column.UserAgent,
+	Column.LastSeenAt,
+	Column.LastSeenAt,
+)
+
+// Seen records that the device made a request from ip with userAgent. Devices
+// identified by fingerprint are added on first sight. Writes are throttled,
+// unless the ip or user agent changed.
+func Seen(userId, id string, kind Kind, ip, userAgent string) error {
+	cacheKey := getCacheKey(userId, id)
+	seenAs := ip + "|" + userAgent
+	lastSeenAs := ""
+	if lastSeenCache.Get(cacheKey, &lastSeenAs) && lastSeenAs == seenAs {
+		return nil
+	}
+	if _, err := db.Exec(query_seen, id, userId, kind, ip, userAgent); err != nil {
+		return err
+	}
+	lastSeenCache.Add(cacheKey, seenAs)
+	return nil
+}
+
+var query_increment_stream_count = fmt.Sprintf(
+	`UPDATE %s SET %s = %s + 1 WHERE %s = ? AND %s = ?`,
+	TableName,
+	Column.StreamCount,
+	Column.StreamCount,
+	Column.UserId,
+	Column.Id,
+)
+
+// RecordStream counts a stream served to the device.
+func RecordStream(userId, id string) error {
+	_, err := db.Exec(query_increment_stream_count, userId, id)
+	return err
+}
+
+var query_set_label = fmt.Sprintf(
+	`UPDATE %s SET %s = ?, %s = %s WHERE %s = ? AND %s = ?`,
+	TableName,
+	Column.Label,
+	Column.UAt,
+	db.CurrentTimestamp,
+	Column.UserId,
+	Column.Id,
+)
+
+func SetLabel(userId, id, label string) error {
+	_, err := db.Exec(query_set_label, label, userId, id)
+	deviceCache.Remove(getCacheKey(userId, id))
+	return err
+}
+
+var query_revoke = fmt.Sprintf(
+	`UPDATE %s SET %s = %s, %s = %s WHERE %s = ? AND %s = ? AND %s IS NULL`,
+	TableName,
+	Column.RevokedAt,
+	db.CurrentTimestamp,
+	Column.UAt,
+	db.CurrentTimestamp,
+	Column.UserId,
+	Column.Id,
+	Column.RevokedAt,
+)
+
+// Revoke rejects every further request from the device.
+func Revoke(userId, id string) error {
+	_, err := db.Exec(query_revoke, userId, id)
+	deviceCache.Remove(getCacheKey(userId, id))
+	return err
+}
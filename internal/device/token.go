@@ -0,0 +1,100 @@
+package device
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const tokenAudience = "chillstreams-device"
+
+var (
+	ErrTokenDisabled = errors.New("device token is not configured")
+	ErrTokenRequired = errors.New("device token required")
+	ErrTokenInvalid  = errors.New("invalid device token")
+	ErrRevoked       = errors.New("device revoked")
+)
+
+type tokenData struct {
+	UserId string `json:"uid"`
+}
+
+// IssueToken signs a token identifying the device, to be embedded in the
+// addon userdata.
+func IssueToken(d *Device) (string, error) {
+	if config.ChillstreamsDeviceSecret == "" {
+		return "", ErrTokenDisabled
+	}
+	return core.CreateJWT(config.ChillstreamsDeviceSecret, core.JWTClaims[tokenData]{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   "stremthru",
+			Subject:  d.Id,
+			Audience: jwt.ClaimStrings{tokenAudience},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+		Data: &tokenData{
+			UserId: d.UserId,
+		},
+	})
+}
+
+// ParseToken verifies the token's signature and returns the device and user
+// it was issued for.
+func ParseToken(token string) (deviceId string, userId string, err error) {
+	if config.ChillstreamsDeviceSecret == "" {
+		return "", "", ErrTokenDisabled
+	}
+	claims := &core.JWTClaims[tokenData]{}
+	_, err = core.ParseJWT(func(t *jwt.Token) (any, error) {
+		return []byte(config.ChillstreamsDeviceSecret), nil
+	}, token, claims, jwt.WithAudience(tokenAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.Data == nil || claims.Subject == "" {
+		return "", "", ErrTokenInvalid
+	}
+	return claims.Subject, claims.Data.UserId, nil
+}
+
+// ResolveDeviceID returns the id of the device making the request on behalf
+// of the Chillstreams user. Registered devices are identified by their signed
+// token. Without a token, the IP + User-Agent fingerprint is used, unless
+// device tokens are required.
+func ResolveDeviceID(r *http.Request, userId, token string) (string, error) {
+	kind := KindFingerprint
+	deviceId := ""
+	if token == "" {
+		if config.ChillstreamsRequireDeviceToken {
+			return "", ErrTokenRequired
+		}
+		deviceId = GenerateDeviceID(r)
+	} else {
+		id, tokenUserId, err := ParseToken(token)
+		if err != nil {
+			return "", err
+		}
+		if tokenUserId != userId {
+			return "", ErrTokenInvalid
+		}
+		kind, deviceId = KindToken, id
+	}
+
+	d, err := GetById(userId, deviceId)
+	if err != nil {
+		return "", err
+	}
+	if d == nil && kind == KindToken {
+		return "", ErrTokenInvalid
+	}
+	if d != nil && d.IsRevoked() {
+		return "", ErrRevoked
+	}
+
+	if err := Seen(userId, deviceId, kind, GetClientIP(r), r.Header.Get("User-Agent")); err != nil {
+		log.Warn("failed to record device activity", "error", err, "device_id", deviceId)
+	}
+
+	return deviceId, nil
+}
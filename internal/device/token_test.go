@@ -0,0 +1,35 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+)
+
+func TestToken(t *testing.T) {
+	secret := config.ChillstreamsDeviceSecret
+	defer func() { config.ChillstreamsDeviceSecret = secret }()
+
+	config.ChillstreamsDeviceSecret = "secret"
+	token, err := IssueToken(&Device{Id: "device-id", UserId: "user-id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deviceId, userId, err := ParseToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deviceId != "device-id" || userId != "user-id" {
+		t.Errorf("unexpected claims: %s, %s", deviceId, userId)
+	}
+
+	if _, _, err := ParseToken(token + "x"); err != ErrTokenInvalid {
+		t.Errorf("expected tampered token to be rejected, got %v", err)
+	}
+
+	config.ChillstreamsDeviceSecret = "other-secret"
+	if _, _, err := ParseToken(token); err != ErrTokenInvalid {
+		t.Errorf("expected token signed with other secret to be rejected, got %v", err)
+	}
+}
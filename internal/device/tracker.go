@@ -3,32 +3,77 @@ package device
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
 )
 
-// GenerateDeviceID creates consistent device ID from IP + User-Agent
+// GenerateDeviceID creates consistent device ID from IP + User-Agent. It is
+// only a fallback for requests without a device token, as it changes with the
+// client's IP and can be spoofed.
 func GenerateDeviceID(r *http.Request) string {
-	ip := getClientIP(r)
+	ip := GetClientIP(r)
 	ua := r.Header.Get("User-Agent")
 
 	hash := sha256.Sum256([]byte(ip + "|" + ua))
 	return hex.EncodeToString(hash[:])
 }
 
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For, X-Real-IP headers
+func parseIP(value string) (netip.Addr, bool) {
+	ip, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return ip, false
+	}
+	return ip.Unmap(), true
+}
+
+func isTrustedProxy(ip netip.Addr) bool {
+	for _, prefix := range config.ChillstreamsTrustedProxyCIDRs {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientIP returns the IP of the client making the request. Forwarding
+// headers are only honored when the request comes from a trusted proxy, and
+// X-Forwarded-For is read right to left, skipping trusted proxies.
+func GetClientIP(r *http.Request) string {
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+
+	remoteIP, ok := parseIP(remoteAddr)
+	if !ok {
+		return remoteAddr
+	}
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP.String()
+	}
+
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return strings.Split(xff, ",")[0]
+		ips := strings.Split(xff, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip, ok := parseIP(ips[i])
+			if !ok {
+				break
+			}
+			if i == 0 || !isTrustedProxy(ip) {
+				return ip.String()
+			}
+		}
 	}
+
 	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-	// Extract IP from RemoteAddr (remove port)
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
+		if ip, ok := parseIP(xri); ok {
+			return ip.String()
+		}
 	}
-	return ip
-}
 
+	return remoteIP.String()
+}
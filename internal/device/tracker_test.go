@@ -233,3 +233,28 @@ func TestGetClientIP_IPv6WithBrackets(t *testing.T) {
 	}
 }
 
+
+func TestGetClientIP_UntrustedProxy(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.RemoteAddr = "198.51.100.7:12345"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1")
+	req.Header.Set("X-Real-IP", "203.0.113.2")
+
+	ip := GetClientIP(req)
+
+	if ip != "198.51.100.7" {
+		t.Errorf("Expected forwarding headers to be ignored for untrusted remote, got %s", ip)
+	}
+}
+
+func TestGetClientIP_XForwardedForSpoofed(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.1")
+
+	ip := GetClientIP(req)
+
+	if ip != "203.0.113.1" {
+		t.Errorf("Expected rightmost untrusted IP from X-Forwarded-For, got %s", ip)
+	}
+}
@@ -0,0 +1,243 @@
+package endpoint
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/device"
+	"github.com/MunifTanjim/stremthru/internal/prowlarr"
+	"github.com/MunifTanjim/stremthru/internal/shared"
+	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
+)
+
+// ChillstreamsAuthed only lets through requests made by the Chillstreams
+// backend, authenticated with the shared API key.
+func ChillstreamsAuthed(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.ChillstreamsAPIKey)) != 1 {
+			shared.ErrorUnauthorized(r).Send(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type RegisterChillstreamsDevicePayload struct {
+	UserId string `json:"user_id"`
+	Label  string `json:"label"`
+}
+
+type RegisterChillstreamsDeviceData struct {
+	Id    string `json:"id"`
+	Token string `json:"token"`
+}
+
+func handleRegisterChillstreamsDevice(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodPost) {
+		shared.ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	payload := &RegisterChillstreamsDevicePayload{}
+	if err := shared.ReadRequestBodyJSON(r, payload); err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if !core.IsValidUUID(payload.UserId) {
+		shared.ErrorBadRequest(r, "invalid user_id").Send(w, r)
+		return
+	}
+
+	d, err := device.Register(payload.UserId, strings.TrimSpace(payload.Label))
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	token, err := device.IssueToken(d)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendResponse(w, r, 201, &RegisterChillstreamsDeviceData{
+		Id:    d.Id,
+		Token: token,
+	}, nil)
+}
+
+// getChillstreamsUserId returns the user id of the request path, it sends
+// the error response if the id is invalid.
+func getChillstreamsUserId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId := r.PathValue("userId")
+	if !core.IsValidUUID(userId) {
+		shared.ErrorBadRequest(r, "invalid user_id").Send(w, r)
+		return "", false
+	}
+	return userId, true
+}
+
+func handleListChillstreamsDevices(w http.ResponseWriter, r *http.Request) {
+	userId, ok := getChillstreamsUserId(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := device.GetByUserId(userId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	data := make([]device.Info, len(items))
+	for i := range items {
+		data[i] = items[i].ToInfo()
+	}
+	SendResponse(w, r, 200, data, nil)
+}
+
+type UpdateChillstreamsDevicePayload struct {
+	Label string `json:"label"`
+}
+
+func handleUpdateChillstreamsDevice(w http.ResponseWriter, r *http.Request) {
+	userId, ok := getChillstreamsUserId(w, r)
+	if !ok {
+		return
+	}
+	deviceId := r.PathValue("deviceId")
+
+	payload := &UpdateChillstreamsDevicePayload{}
+	if err := shared.ReadRequestBodyJSON(r, payload); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	d, err := device.GetById(userId, deviceId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if d == nil {
+		shared.ErrorNotFound(r).Send(w, r)
+		return
+	}
+
+	d.Label = strings.TrimSpace(payload.Label)
+	if err := device.SetLabel(userId, deviceId, d.Label); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendResponse(w, r, 200, d.ToInfo(), nil)
+}
+
+func handleRevokeChillstreamsDevice(w http.ResponseWriter, r *http.Request) {
+	userId, ok := getChillstreamsUserId(w, r)
+	if !ok {
+		return
+	}
+	deviceId := r.PathValue("deviceId")
+
+	d, err := device.GetById(userId, deviceId)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+	if d == nil {
+		shared.ErrorNotFound(r).Send(w, r)
+		return
+	}
+
+	if err := stremio_userdata.RevokeChillstreamsDevice(r.Context(), userId, deviceId); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+type ChillstreamsIndexerStats struct {
+	IndexerId   string  `json:"indexer_id"`
+	IndexerName string  `json:"indexer_name"`
+	Total       int64   `json:"total"`
+	SuccessRate float64 `json:"success_rate"`
+	TimeoutRate float64 `json:"timeout_rate"`
+	P50Ms       int64   `json:"p50_ms"`
+	P95Ms       int64   `json:"p95_ms"`
+}
+
+func handleGetChillstreamsIndexerStats(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodGet) {
+		shared.ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	window := prowlarr.StatsWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > prowlarr.SearchLogRetention {
+			shared.ErrorBadRequest(r, "invalid window").Send(w, r)
+			return
+		}
+		window = d
+	}
+
+	items, err := prowlarr.GetIndexerStats(r.Context(), window)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	data := make([]ChillstreamsIndexerStats, len(items))
+	for i := range items {
+		item := &items[i]
+		data[i] = ChillstreamsIndexerStats{
+			IndexerId:   item.IndexerId,
+			IndexerName: item.IndexerName,
+			Total:       item.Total,
+			SuccessRate: item.SuccessRate,
+			TimeoutRate: item.TimeoutRate,
+			P50Ms:       item.P50.Milliseconds(),
+			P95Ms:       item.P95.Milliseconds(),
+		}
+	}
+	SendResponse(w, r, 200, data, nil)
+}
+
+func AddChillstreamsEndpoints(mux *http.ServeMux) {
+	if !config.EnableChillstreamsAuth || config.ChillstreamsAPIKey == "" {
+		return
+	}
+
+	if config.ChillstreamsDeviceSecret != "" {
+		mux.HandleFunc("/v0/chillstreams/devices", ChillstreamsAuthed(handleRegisterChillstreamsDevice))
+	}
+
+	if prowlarr.IsSearchLogEnabled() {
+		mux.HandleFunc("/v0/chillstreams/indexers/stats", ChillstreamsAuthed(handleGetChillstreamsIndexerStats))
+	}
+
+	mux.HandleFunc("/v0/chillstreams/users/{userId}/devices", ChillstreamsAuthed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleListChillstreamsDevices(w, r)
+		default:
+			shared.ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+	mux.HandleFunc("/v0/chillstreams/users/{userId}/devices/{deviceId}", ChillstreamsAuthed(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			handleUpdateChillstreamsDevice(w, r)
+		case http.MethodDelete:
+			handleRevokeChillstreamsDevice(w, r)
+		default:
+			shared.ErrorMethodNotAllowed(r).Send(w, r)
+		}
+	}))
+}
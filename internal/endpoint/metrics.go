@@ -0,0 +1,16 @@
+package endpoint
+
+import (
+	"net/http"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
+)
+
+func AddMetricsEndpoints(mux *http.ServeMux) {
+	if !config.Metrics.IsEnabled() {
+		return
+	}
+
+	mux.Handle("GET /metrics", metrics.Handler())
+}
@@ -54,6 +54,18 @@ func ProxyAuthContext(next http.HandlerFunc) http.HandlerFunc {
 	})
 }
 
+func ProxyAuthRequired(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.GetStoreContext(r)
+		if !ctx.IsProxyAuthorized {
+			w.Header().Add(server.HEADER_STREMTHRU_AUTHENTICATE, "Basic")
+			shared.ErrorForbidden(r).Send(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func getStoreName(r *http.Request) (store.StoreName, *core.StoreError) {
 	name := r.Header.Get("X-StremThru-Store-Name")
 	if name == "" {
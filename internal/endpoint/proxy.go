@@ -1,19 +1,34 @@
 package endpoint
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/core"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
 	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/content_proxy"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
 	"github.com/MunifTanjim/stremthru/internal/server"
 	"github.com/MunifTanjim/stremthru/internal/shared"
 	store_video "github.com/MunifTanjim/stremthru/internal/store/video"
 	"github.com/MunifTanjim/stremthru/internal/util"
 )
 
+func getTunnelTypeLabel(tunnelType config.TunnelType) string {
+	switch tunnelType {
+	case config.TUNNEL_TYPE_AUTO:
+		return "auto"
+	case config.TUNNEL_TYPE_FORCED:
+		return "forced"
+	default:
+		return "none"
+	}
+}
+
 func handleProxyLinkAccess(w http.ResponseWriter, r *http.Request) {
 	ctx := server.GetReqCtx(r)
 	ctx.RedactURLPathValues(r, "token")
@@ -30,7 +45,7 @@ func handleProxyLinkAccess(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, link, headers, tunnelType, err := shared.UnwrapProxyLinkToken(encodedToken)
+	user, link, headers, tunnelType, usage, linkStore, err := shared.UnwrapProxyLinkToken(encodedToken)
 	if err != nil {
 		SendError(w, r, err)
 		return
@@ -43,10 +58,8 @@ func handleProxyLinkAccess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if isGetReq && user != "" {
-		cpStore := contentProxyConnectionStore.WithScope(user)
-
 		if limit := config.ContentProxyConnectionLimit.Get(user); limit > 0 {
-			activeConnectionCount, err := cpStore.Count()
+			activeConnectionCount, err := content_proxy.CountActiveConnections(user)
 			if err != nil {
 				ctx.Log.Error("[proxy] failed to count connections", "error", err)
 			} else if activeConnectionCount >= limit {
@@ -55,13 +68,55 @@ func handleProxyLinkAccess(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		if err := cpStore.Set(ctx.RequestId, contentProxyConnection{IP: core.GetRequestIP(r), Link: link}); err != nil {
+		connCtx, untrack, err := content_proxy.TrackConnection(r.Context(), user, core.GetRequestIP(r), link)
+		if err != nil {
 			ctx.Log.Error("[proxy] failed to record connection", "error", err)
-		} else {
-			defer cpStore.Del(ctx.RequestId)
+			SendError(w, r, err)
+			return
 		}
+		defer untrack()
+		r = r.WithContext(connCtx)
+	}
+
+	if isGetReq {
+		cpw, err := content_proxy.NewResponseWriter(w, r, user)
+		if err != nil {
+			if errors.Is(err, content_proxy.ErrQuotaExceeded) {
+				store_video.Redirect(store_video.StoreVideoNameContentProxyQuotaExceeded, w, r)
+			} else {
+				ctx.Log.Error("[proxy] failed to get remaining quota", "error", err)
+				SendError(w, r, err)
+			}
+			return
+		}
+		defer cpw.Close()
+		w = cpw
+	}
+
+	var regenerateLink func() (string, error)
+	cacheKey := ""
+	if linkStore != nil {
+		regenerateLink = func() (string, error) {
+			ctx.Log.Info("[proxy] regenerating expired link", "store.code", linkStore.Code)
+			link, err := linkStore.GenerateLink(r)
+			if err != nil {
+				return "", err
+			}
+			shared.SetProxyLinkTokenLink(encodedToken, link)
+			return link, nil
+		}
+		cacheKey = linkStore.GetCacheKey()
+	}
+
+	if isGetReq && usage != nil {
+		sw := chillstreams_usage.NewStreamWriter(w, encodedToken, usage)
+		defer sw.Close()
+		w = sw
 	}
-	bytesWritten, err := shared.ProxyResponse(w, r, link, tunnelType)
+
+	trackDone := metrics.TrackProxyConnection(getTunnelTypeLabel(tunnelType))
+	bytesWritten, err := shared.ProxyResponse(w, r, link, tunnelType, regenerateLink, cacheKey)
+	trackDone(bytesWritten, err)
 	ctx.Log.Info("[proxy] connection closed", "user", user, "size", util.ToSize(bytesWritten), "error", err)
 }
 
@@ -173,10 +228,45 @@ func handleProxifyLinks(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, r, 200, data, nil)
 }
 
+type proxyUsageData struct {
+	User      string              `json:"user"`
+	Usage     content_proxy.Usage `json:"usage"`
+	Quota     content_proxy.Quota `json:"quota"`
+	RateLimit int64               `json:"rate_limit"`
+}
+
+func handleProxyUsage(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodGet) {
+		shared.ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	isAuthorized, user, _ := getProxyAuthorization(r, false)
+	if !isAuthorized {
+		w.Header().Add(server.HEADER_STREMTHRU_AUTHENTICATE, "Basic")
+		shared.ErrorForbidden(r).Send(w, r)
+		return
+	}
+
+	usage, err := content_proxy.GetUsage(user)
+	if err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	SendResponse(w, r, 200, proxyUsageData{
+		User:      user,
+		Usage:     *usage,
+		Quota:     content_proxy.GetQuota(user),
+		RateLimit: config.ContentProxy.RateLimit.Get(user),
+	}, nil)
+}
+
 func AddProxyEndpoints(mux *http.ServeMux) {
 	withCors := shared.Middleware(shared.EnableCORS)
 
 	mux.HandleFunc("/v0/proxy", withCors(handleProxifyLinks))
+	mux.HandleFunc("/v0/proxy/usage", withCors(handleProxyUsage))
 	mux.HandleFunc("/v0/proxy/{token}", withCors(handleProxyLinkAccess))
 	mux.HandleFunc("/v0/proxy/{token}/{filename}", withCors(handleProxyLinkAccess))
 }
@@ -1,26 +1,34 @@
 package endpoint
 
 import (
+	"errors"
 	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/config"
 
 	"github.com/MunifTanjim/stremthru/internal/buddy"
 	"github.com/MunifTanjim/stremthru/internal/context"
-	"github.com/MunifTanjim/stremthru/internal/kv"
+	"github.com/MunifTanjim/stremthru/internal/magnet_cache"
 	"github.com/MunifTanjim/stremthru/internal/peer_token"
 	"github.com/MunifTanjim/stremthru/internal/server"
 	"github.com/MunifTanjim/stremthru/internal/shared"
 	store_util "github.com/MunifTanjim/stremthru/internal/store/util"
 	store_video "github.com/MunifTanjim/stremthru/internal/store/video"
 	"github.com/MunifTanjim/stremthru/internal/torrent_info"
+	"github.com/MunifTanjim/stremthru/internal/torrent_stream"
 	"github.com/MunifTanjim/stremthru/store"
 )
 
 func getUser(ctx *context.StoreContext) (*store.User, error) {
 	params := &store.GetUserParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	return ctx.Store.GetUser(params)
 }
 
@@ -43,6 +51,7 @@ type AddMagnetPayload struct {
 func checkMagnet(ctx *context.StoreContext, magnets []string, sid string, localOnly bool) (*store.CheckMagnetData, error) {
 	params := &store.CheckMagnetParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	params.Magnets = magnets
 	params.SId = sid
 	params.LocalOnly = localOnly
@@ -148,6 +157,215 @@ func handleStoreMagnetsCheck(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, r, 200, data, err)
 }
 
+type CheckMagnetAvailabilityPayloadStore struct {
+	Name  store.StoreName `json:"name"`
+	Token string          `json:"token"`
+}
+
+type CheckMagnetAvailabilityPayload struct {
+	Magnets []string                              `json:"magnets"`
+	SId     string                                `json:"sid"`
+	Stores  []CheckMagnetAvailabilityPayloadStore `json:"stores"`
+}
+
+type MagnetAvailabilityStoreItem struct {
+	Status    store.MagnetStatus `json:"status"`
+	Files     []store.MagnetFile `json:"files"`
+	LastKnown bool               `json:"last_known,omitempty"`
+	CheckedAt *time.Time         `json:"checked_at,omitempty"`
+}
+
+type MagnetAvailabilityItem struct {
+	Hash   string                                          `json:"hash"`
+	Magnet string                                          `json:"magnet"`
+	Stores map[store.StoreName]MagnetAvailabilityStoreItem `json:"stores"`
+}
+
+type MagnetAvailabilityData struct {
+	Items  []MagnetAvailabilityItem   `json:"items"`
+	Errors map[store.StoreName]string `json:"errors,omitempty"`
+}
+
+// getLastKnownMagnetAvailability reads the availability from the magnet cache,
+// for stores that can not be checked live.
+func getLastKnownMagnetAvailability(s store.Store, hashes []string, sid string) (map[string]MagnetAvailabilityStoreItem, error) {
+	var nsid *torrent_stream.NormalizedStremId
+	if sid != "" {
+		nsid, _ = torrent_stream.NormalizeStreamId(sid)
+	}
+	mcs, err := magnet_cache.GetByHashes(s.GetName().Code(), hashes, nsid)
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string]MagnetAvailabilityStoreItem, len(mcs))
+	for _, mc := range mcs {
+		item := MagnetAvailabilityStoreItem{
+			Status:    store.MagnetStatusUnknown,
+			Files:     []store.MagnetFile{},
+			LastKnown: true,
+			CheckedAt: &mc.ModifiedAt.Time,
+		}
+		if mc.IsCached {
+			item.Status = store.MagnetStatusCached
+			item.Files = mc.Files.ToStoreMagnetFiles(mc.Hash)
+		}
+		byHash[mc.Hash] = item
+	}
+	return byHash, nil
+}
+
+func checkMagnetAvailability(ctx *context.StoreContext, r *http.Request, payload *CheckMagnetAvailabilityPayload) (*MagnetAvailabilityData, error) {
+	magnetByHash := map[string]core.MagnetLink{}
+	hashes := []string{}
+	for _, m := range payload.Magnets {
+		magnet, err := core.ParseMagnetLink(m)
+		if err != nil {
+			return nil, shared.ErrorBadRequest(r, "invalid magnet: "+m)
+		}
+		if _, seen := magnetByHash[magnet.Hash]; seen {
+			continue
+		}
+		magnetByHash[magnet.Hash] = magnet
+		hashes = append(hashes, magnet.Hash)
+	}
+
+	stores := payload.Stores
+	if len(stores) == 0 && ctx.IsProxyAuthorized {
+		for _, name := range config.StoreAuthToken.ListStores(ctx.ProxyAuthUser) {
+			stores = append(stores, CheckMagnetAvailabilityPayloadStore{Name: store.StoreName(name)})
+		}
+	}
+	if len(stores) == 0 {
+		return nil, shared.ErrorBadRequest(r, "missing stores")
+	}
+	seenStore := map[store.StoreName]struct{}{}
+	uniqueStores := make([]CheckMagnetAvailabilityPayloadStore, 0, len(stores))
+	for i := range stores {
+		if !stores[i].Name.IsValid() {
+			return nil, shared.ErrorBadRequest(r, "invalid store: "+string(stores[i].Name))
+		}
+		if _, seen := seenStore[stores[i].Name]; seen {
+			continue
+		}
+		seenStore[stores[i].Name] = struct{}{}
+		if stores[i].Token == "" && ctx.IsProxyAuthorized {
+			stores[i].Token = config.StoreAuthToken.GetToken(ctx.ProxyAuthUser, string(stores[i].Name))
+		}
+		uniqueStores = append(uniqueStores, stores[i])
+	}
+	stores = uniqueStores
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	byStore := map[store.StoreName]map[string]MagnetAvailabilityStoreItem{}
+	errByStore := map[store.StoreName]string{}
+	for _, ps := range stores {
+		wg.Go(func() {
+			s := shared.GetStore(string(ps.Name))
+
+			var byHash map[string]MagnetAvailabilityStoreItem
+			var err error
+			if ps.Token != "" {
+				sCtx := &context.StoreContext{
+					Store:             s,
+					StoreAuthToken:    ps.Token,
+					IsProxyAuthorized: ctx.IsProxyAuthorized,
+					ProxyAuthUser:     ctx.ProxyAuthUser,
+				}
+				params := &store.CheckMagnetParams{
+					Magnets:  hashes,
+					ClientIP: shared.GetClientIP(r, sCtx),
+					SId:      payload.SId,
+				}
+				params.APIKey = ps.Token
+				params.Context = r.Context()
+				var data *store.CheckMagnetData
+				if data, err = s.CheckMagnet(params); err == nil {
+					byHash = make(map[string]MagnetAvailabilityStoreItem, len(data.Items))
+					for _, item := range data.Items {
+						files := item.Files
+						if files == nil {
+							files = []store.MagnetFile{}
+						}
+						byHash[strings.ToLower(item.Hash)] = MagnetAvailabilityStoreItem{
+							Status: item.Status,
+							Files:  files,
+						}
+					}
+				}
+			}
+			if byHash == nil {
+				lastKnown, lkErr := getLastKnownMagnetAvailability(s, hashes, payload.SId)
+				if lkErr != nil {
+					err = errors.Join(err, lkErr)
+				}
+				byHash = lastKnown
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errByStore[ps.Name] = err.Error()
+			}
+			byStore[ps.Name] = byHash
+		})
+	}
+	wg.Wait()
+
+	data := &MagnetAvailabilityData{
+		Items: make([]MagnetAvailabilityItem, len(hashes)),
+	}
+	if len(errByStore) > 0 {
+		data.Errors = errByStore
+	}
+	for i, hash := range hashes {
+		item := MagnetAvailabilityItem{
+			Hash:   hash,
+			Magnet: magnetByHash[hash].Link,
+			Stores: make(map[store.StoreName]MagnetAvailabilityStoreItem, len(byStore)),
+		}
+		for storeName, byHash := range byStore {
+			if storeItem, ok := byHash[hash]; ok {
+				item.Stores[storeName] = storeItem
+			} else {
+				item.Stores[storeName] = MagnetAvailabilityStoreItem{
+					Status: store.MagnetStatusUnknown,
+					Files:  []store.MagnetFile{},
+				}
+			}
+		}
+		data.Items[i] = item
+	}
+	return data, nil
+}
+
+func handleStoreMagnetsAvailability(w http.ResponseWriter, r *http.Request) {
+	if !shared.IsMethod(r, http.MethodPost) {
+		shared.ErrorMethodNotAllowed(r).Send(w, r)
+		return
+	}
+
+	payload := &CheckMagnetAvailabilityPayload{}
+	if err := shared.ReadRequestBodyJSON(r, payload); err != nil {
+		SendError(w, r, err)
+		return
+	}
+
+	if len(payload.Magnets) == 0 {
+		shared.ErrorBadRequest(r, "missing magnets").Send(w, r)
+		return
+	}
+
+	if len(payload.Magnets) > 500 {
+		shared.ErrorBadRequest(r, "too many magnets, max allowed 500").Send(w, r)
+		return
+	}
+
+	ctx := context.GetStoreContext(r)
+	data, err := checkMagnetAvailability(ctx, r, payload)
+	SendResponse(w, r, 200, data, err)
+}
+
 func listMagnets(ctx *context.StoreContext, r *http.Request) (*store.ListMagnetsData, error) {
 	queryParams := r.URL.Query()
 	limit, err := GetQueryInt(queryParams, "limit", 100)
@@ -168,6 +386,7 @@ func listMagnets(ctx *context.StoreContext, r *http.Request) (*store.ListMagnets
 		ClientIP: ctx.ClientIP,
 	}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	data, err := ctx.Store.ListMagnets(params)
 
 	if err == nil {
@@ -199,6 +418,7 @@ func handleStoreMagnetsList(w http.ResponseWriter, r *http.Request) {
 func addMagnet(ctx *context.StoreContext, magnet string, torrent *multipart.FileHeader) (*store.AddMagnetData, error) {
 	params := &store.AddMagnetParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	params.Magnet = magnet
 	if ctx.ClientIP != "" {
 		params.ClientIP = ctx.ClientIP
@@ -307,6 +527,7 @@ func handleStoreMagnets(w http.ResponseWriter, r *http.Request) {
 func getMagnet(ctx *context.StoreContext, magnetId string) (*store.GetMagnetData, error) {
 	params := &store.GetMagnetParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	params.Id = magnetId
 	if ctx.ClientIP != "" {
 		params.ClientIP = ctx.ClientIP
@@ -341,6 +562,7 @@ func handleStoreMagnetGet(w http.ResponseWriter, r *http.Request) {
 func removeMagnet(ctx *context.StoreContext, magnetId string) (*store.RemoveMagnetData, error) {
 	params := &store.RemoveMagnetParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	params.Id = magnetId
 	return ctx.Store.RemoveMagnet(params)
 }
@@ -398,15 +620,6 @@ func handleStoreLinkGenerate(w http.ResponseWriter, r *http.Request) {
 	SendResponse(w, r, 200, link, err)
 }
 
-type contentProxyConnection struct {
-	IP   string `json:"ip"`
-	Link string `json:"link"`
-}
-
-var contentProxyConnectionStore = kv.NewKVStore[contentProxyConnection](&kv.KVStoreConfig{
-	Type: "cproxyconn",
-})
-
 func handleStatic(w http.ResponseWriter, r *http.Request) {
 	if !shared.IsMethod(r, http.MethodGet) && !shared.IsMethod(r, http.MethodHead) {
 		shared.ErrorMethodNotAllowed(r).Send(w, r)
@@ -423,10 +636,12 @@ func handleStatic(w http.ResponseWriter, r *http.Request) {
 func AddStoreEndpoints(mux *http.ServeMux) {
 	withCors := shared.Middleware(shared.EnableCORS)
 	withStore := StoreMiddleware(ProxyAuthContext, StoreContext, StoreRequired)
+	withProxyAuth := StoreMiddleware(ProxyAuthContext, ProxyAuthRequired)
 
 	mux.HandleFunc("/v0/store/user", withStore(handleStoreUser))
 	mux.HandleFunc("/v0/store/magnets", withStore(handleStoreMagnets))
 	mux.HandleFunc("/v0/store/magnets/check", withStore(handleStoreMagnetsCheck))
+	mux.HandleFunc("/v0/store/magnets/availability", withProxyAuth(handleStoreMagnetsAvailability))
 	mux.HandleFunc("/v0/store/magnets/{magnetId}", withStore(handleStoreMagnet))
 	mux.HandleFunc("/v0/store/link/generate", withStore(handleStoreLinkGenerate))
 
@@ -1,13 +1,32 @@
 package endpoint
 
 import (
+	"crypto/subtle"
 	"net/http"
 
+	"github.com/MunifTanjim/stremthru/core"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/shared"
 	"github.com/MunifTanjim/stremthru/internal/torznab"
 )
 
+// isTorznabAuthorized checks the `apikey` against `STREMTHRU_PROXY_AUTH`,
+// either `username:password` or its base64 encoded form.
+func isTorznabAuthorized(apiKey string) bool {
+	if config.IsPublicInstance {
+		return true
+	}
+	if apiKey == "" {
+		return false
+	}
+	auth, err := core.ParseBasicAuth(apiKey)
+	if err != nil {
+		return false
+	}
+	password := config.ProxyAuthPassword.GetPassword(auth.Username)
+	return password != "" && subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password)) == 1
+}
+
 func handleTorznab(w http.ResponseWriter, r *http.Request) {
 	t := r.URL.Query().Get("t")
 
@@ -26,12 +45,20 @@ func handleTorznab(w http.ResponseWriter, r *http.Request) {
 			shared.SendXML(w, r, 200, torznab.ErrorIncorrectParameter(err.Error()))
 			return
 		}
+		if !isTorznabAuthorized(query.APIKey) {
+			shared.SendXML(w, r, 200, torznab.ErrorIncorrectUserCreds)
+			return
+		}
 		items, err := torznab.StremThruIndexer.Search(query)
 		if err != nil {
 			shared.SendXML(w, r, 200, torznab.ErrorUnknownError(err.Error()))
 			return
 		}
-		w.Header().Set("Cache-Control", "public, max-age=7200")
+		if config.IsPublicInstance {
+			w.Header().Set("Cache-Control", "public, max-age=7200")
+		} else {
+			w.Header().Set("Cache-Control", "private, max-age=7200")
+		}
 		shared.SendXML(w, r, 200, torznab.ResultFeed{
 			Info:  torznab.StremThruIndexer.Info(),
 			Items: items,
@@ -41,6 +68,7 @@ func handleTorznab(w http.ResponseWriter, r *http.Request) {
 		shared.SendXML(w, r, 200, torznab.ErrorIncorrectParameter(t))
 	}
 }
+
 func AddTorznabEndpoints(mux *http.ServeMux) {
 	if !config.Feature.HasTorrentInfo() {
 		return
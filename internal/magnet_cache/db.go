@@ -35,7 +35,9 @@ func (mc MagnetCache) IsStale() bool {
 	return mc.ModifiedAt.Before(time.Now().Add(-staleTime))
 }
 
-func GetByHashes(store store.StoreCode, hashes []string, sid string) ([]MagnetCache, error) {
+// GetByHashes returns the magnet cache entries for the hashes. When `nsid`
+// is given, cached entries are only returned if the file for it is known.
+func GetByHashes(store store.StoreCode, hashes []string, nsid *torrent_stream.NormalizedStremId) ([]MagnetCache, error) {
 	if len(hashes) == 0 {
 		return []MagnetCache{}, nil
 	}
@@ -46,16 +48,22 @@ func GetByHashes(store store.StoreCode, hashes []string, sid string) ([]MagnetCa
 	}
 
 	args_len := len(hashes) + 1
-	if sid != "" {
+	if nsid != nil {
 		args_len += 1
 	}
 	arg_idx := 0
 	args := make([]any, args_len)
 
 	query := "SELECT store, hash, is_cached, modified_at FROM " + TableName
-	if sid != "" {
-		query += " LEFT JOIN " + torrent_stream.TableName + " ON " + TableName + ".hash = " + torrent_stream.TableName + ".h WHERE (is_cached = " + db.BooleanFalse + " OR " + torrent_stream.TableName + ".sid IN (?, '*')) AND"
-		args[arg_idx] = sid
+	if nsid != nil {
+		query += " LEFT JOIN " + torrent_stream.TableName + " ON " + TableName + ".hash = " + torrent_stream.TableName + ".h WHERE (is_cached = " + db.BooleanFalse + " OR "
+		if nsid.IsAnime {
+			query += torrent_stream.TableName + ".sid = '*' OR " + torrent_stream.TableName + ".asid = ?) AND"
+			args[arg_idx] = nsid.ToASId()
+		} else {
+			query += torrent_stream.TableName + ".sid IN (?, '*')) AND"
+			args[arg_idx] = nsid.ToSId()
+		}
 		arg_idx += 1
 	} else {
 		query += " WHERE"
@@ -16,7 +16,7 @@ import (
 var DefaultHTTPClient = func() *http.Client {
 	transport := config.DefaultHTTPTransport.Clone()
 	return &http.Client{
-		Transport: transport,
+		Transport: config.TraceHTTPTransport(transport),
 		Timeout:   60 * time.Second,
 	}
 }()
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var cacheLookupsTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "cache",
+	Name:      "lookups_total",
+	Help:      "Number of cache lookups, by cache name and result (hit or miss).",
+}, []string{"cache", "result"}))
+
+type CacheLookupCounter struct {
+	hit  prometheus.Counter
+	miss prometheus.Counter
+}
+
+func (c *CacheLookupCounter) Observe(isHit bool) {
+	if c == nil {
+		return
+	}
+	if isHit {
+		c.hit.Inc()
+	} else {
+		c.miss.Inc()
+	}
+}
+
+// NewCacheLookupCounter returns the lookup counter for the named cache.
+// Unnamed caches are not tracked, Observe is a no-op for those.
+func NewCacheLookupCounter(name string) *CacheLookupCounter {
+	if name == "" {
+		return nil
+	}
+	return &CacheLookupCounter{
+		hit:  cacheLookupsTotal.WithLabelValues(name, "hit"),
+		miss: cacheLookupsTotal.WithLabelValues(name, "miss"),
+	}
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var chillstreamsRequestsTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "chillstreams",
+	Name:      "requests_total",
+	Help:      "Number of Chillstreams API calls, by operation and outcome.",
+}, []string{"operation", "outcome"}))
+
+var chillstreamsRequestDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "chillstreams",
+	Name:      "request_duration_seconds",
+	Help:      "Latency of Chillstreams API calls, by operation.",
+	Buckets:   latencyBuckets,
+}, []string{"operation"}))
+
+// ObserveChillstreamsRequest records a Chillstreams API call. The outcome is
+// the response status code, or `error` if there was no response.
+func ObserveChillstreamsRequest(operation, outcome string, duration time.Duration) {
+	chillstreamsRequestsTotal.WithLabelValues(operation, outcome).Inc()
+	chillstreamsRequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
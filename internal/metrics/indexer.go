@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var indexerSearchDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "indexer",
+	Name:      "search_duration_seconds",
+	Help:      "Latency of indexer searches, by indexer and status.",
+	Buckets:   latencyBuckets,
+}, []string{"indexer", "status"}))
+
+func ObserveIndexerSearch(indexer string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	indexerSearchDuration.WithLabelValues(indexer, status).Observe(duration.Seconds())
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "stremthru"
+
+var registry = func() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}()
+
+// durations of upstream calls, from a few milliseconds to a minute
+var latencyBuckets = []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+func register[C prometheus.Collector](c C) C {
+	registry.MustRegister(c)
+	return c
+}
+
+func Handler() http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if config.Metrics.Token == "" {
+		return handler
+	}
+
+	token := []byte(config.Metrics.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(reqToken), token) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var proxyActiveConnections = register(prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: "proxy",
+	Name:      "active_connections",
+	Help:      "Number of content proxy connections currently open.",
+}))
+
+var proxyConnectionsTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "proxy",
+	Name:      "connections_total",
+	Help:      "Number of content proxy connections, by tunnel type and status.",
+}, []string{"tunnel", "status"}))
+
+var proxyBytesTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "proxy",
+	Name:      "bytes_total",
+	Help:      "Number of bytes sent through the content proxy, by tunnel type.",
+}, []string{"tunnel"}))
+
+// TrackProxyConnection marks a proxy connection as open, the returned func
+// must be called with the result once it is closed.
+func TrackProxyConnection(tunnelType string) func(bytesWritten int64, err error) {
+	proxyActiveConnections.Inc()
+	return func(bytesWritten int64, err error) {
+		proxyActiveConnections.Dec()
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		proxyConnectionsTotal.WithLabelValues(tunnelType, status).Inc()
+		if bytesWritten > 0 {
+			proxyBytesTotal.WithLabelValues(tunnelType).Add(float64(bytesWritten))
+		}
+	}
+}
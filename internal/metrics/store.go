@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var storeRequestsTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "store",
+	Name:      "requests_total",
+	Help:      "Number of store requests, by store, operation and status.",
+}, []string{"store", "operation", "status"}))
+
+var storeRequestDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "store",
+	Name:      "request_duration_seconds",
+	Help:      "Latency of store requests, by store and operation.",
+	Buckets:   latencyBuckets,
+}, []string{"store", "operation"}))
+
+var storeErrorsTotal = register(prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "store",
+	Name:      "errors_total",
+	Help:      "Number of failed store requests, by store, operation and error code.",
+}, []string{"store", "operation", "code"}))
+
+// ObserveStoreRequest records a store request that started at start and
+// finished with err.
+func ObserveStoreRequest(storeName, operation string, start time.Time, err error) {
+	storeRequestDuration.WithLabelValues(storeName, operation).Observe(time.Since(start).Seconds())
+	if err == nil {
+		storeRequestsTotal.WithLabelValues(storeName, operation, "success").Inc()
+		return
+	}
+	storeRequestsTotal.WithLabelValues(storeName, operation, "error").Inc()
+
+	code := core.ErrorCodeUnknown
+	var serr *core.StoreError
+	if errors.As(err, &serr) && serr.Code != "" {
+		code = serr.Code
+	}
+	storeErrorsTotal.WithLabelValues(storeName, operation, string(code)).Inc()
+}
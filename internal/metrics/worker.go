@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var workerRunDuration = register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "worker",
+	Name:      "run_duration_seconds",
+	Help:      "Duration of worker runs, by worker and status.",
+	Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 3 * 3600, 6 * 3600},
+}, []string{"worker", "status"}))
+
+func ObserveWorkerRun(worker, status string, duration time.Duration) {
+	workerRunDuration.WithLabelValues(worker, status).Observe(duration.Seconds())
+}
+
+var queueDepthDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "worker", "queue_depth"),
+	"Number of items in the worker queue, by queue.",
+	[]string{"queue"},
+	nil,
+)
+
+type queueDepthCollector struct {
+	count func() (map[string]int, error)
+}
+
+func (c queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+func (c queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	countByQueue, err := c.count()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+		return
+	}
+	for queue, count := range countByQueue {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(count), queue)
+	}
+}
+
+// RegisterQueueDepth reports the worker queue depths, counted at scrape time.
+func RegisterQueueDepth(count func() (map[string]int, error)) {
+	register(queueDepthCollector{count: count})
+}
@@ -17,9 +17,12 @@ import (
 )
 
 var defaultHTTPClient = func() *http.Client {
-	client := config.GetHTTPClient(config.TUNNEL_TYPE_NONE)
-	client.Timeout = 30 * time.Second
-	return client
+	transport := config.DefaultHTTPTransport.Clone()
+	transport.Proxy = config.Tunnel.GetProxy(config.TUNNEL_TYPE_NONE)
+	return &http.Client{
+		Transport: config.TracePropagatingHTTPTransport(transport),
+		Timeout:   30 * time.Second,
+	}
 }()
 
 type APIClientConfig struct {
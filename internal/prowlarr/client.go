@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
 )
 
 type Client struct {
@@ -15,16 +19,102 @@ type Client struct {
 	client  *http.Client
 }
 
+type Category struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
 type SearchResult struct {
-	GUID        string `json:"guid"`
-	Title       string `json:"title"`
-	InfoHash    string `json:"infoHash"`
-	Indexer     string `json:"indexer"`
-	Seeders     int    `json:"seeders"`
-	Leechers    int    `json:"leechers"`
-	Size        int64  `json:"size"`
-	DownloadURL string `json:"downloadUrl"`
-	PublishDate string `json:"publishDate"`
+	GUID        string     `json:"guid"`
+	Title       string     `json:"title"`
+	InfoHash    string     `json:"infoHash"`
+	IndexerId   int        `json:"indexerId"`
+	Indexer     string     `json:"indexer"`
+	Protocol    string     `json:"protocol"`
+	Categories  []Category `json:"categories"`
+	Seeders     int        `json:"seeders"`
+	Leechers    int        `json:"leechers"`
+	Size        int64      `json:"size"`
+	Files       int        `json:"files"`
+	Age         int        `json:"age"` // days
+	DownloadURL string     `json:"downloadUrl"`
+	MagnetURL   string     `json:"magnetUrl"`
+	PublishDate string     `json:"publishDate"`
+	ImdbId      int        `json:"imdbId"`
+	TmdbId      int        `json:"tmdbId"`
+	TvdbId      int        `json:"tvdbId"`
+}
+
+func (r SearchResult) IsTorrent() bool {
+	return r.Protocol == "torrent"
+}
+
+type SearchType string
+
+const (
+	SearchTypeSearch SearchType = "search"
+	SearchTypeTV     SearchType = "tvsearch"
+	SearchTypeMovie  SearchType = "movie"
+)
+
+type SearchParams struct {
+	Type       SearchType
+	Query      string
+	ImdbId     string // e.g. tt0903747
+	TmdbId     string
+	TvdbId     string
+	Season     int
+	Episode    int
+	IndexerIds []int
+	Categories []int
+	Limit      int
+	Offset     int
+}
+
+// Prowlarr reads ID based parameters from tokens embedded in the query,
+// e.g. `{ImdbId:tt0903747}{Season:1}{Episode:2}`.
+func (p *SearchParams) query() string {
+	var q strings.Builder
+	q.WriteString(p.Query)
+	if p.ImdbId != "" {
+		q.WriteString("{ImdbId:" + p.ImdbId + "}")
+	}
+	if p.TmdbId != "" {
+		q.WriteString("{TmdbId:" + p.TmdbId + "}")
+	}
+	if p.TvdbId != "" {
+		q.WriteString("{TvdbId:" + p.TvdbId + "}")
+	}
+	if p.Season > 0 {
+		q.WriteString("{Season:" + strconv.Itoa(p.Season) + "}")
+		if p.Episode > 0 {
+			q.WriteString("{Episode:" + strconv.Itoa(p.Episode) + "}")
+		}
+	}
+	return q.String()
+}
+
+func (p *SearchParams) Values() url.Values {
+	params := url.Values{}
+	params.Set("query", p.query())
+	t := p.Type
+	if t == "" {
+		t = SearchTypeSearch
+	}
+	params.Set("type", string(t))
+	for _, id := range p.IndexerIds {
+		params.Add("indexerIds", strconv.Itoa(id))
+	}
+	for _, cat := range p.Categories {
+		params.Add("categories", strconv.Itoa(cat))
+	}
+	if p.Limit > 0 {
+		params.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		params.Set("offset", strconv.Itoa(p.Offset))
+	}
+	return params
 }
 
 func NewClient(baseURL, apiKey string) *Client {
@@ -32,19 +122,16 @@ func NewClient(baseURL, apiKey string) *Client {
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   max(30*time.Second, IndexerTimeout),
+			Transport: config.TraceHTTPTransport(http.DefaultTransport),
 		},
 	}
 }
 
-// Search queries Prowlarr for torrents matching the query
-func (c *Client) Search(ctx context.Context, query string, searchType string) ([]SearchResult, error) {
-	params := url.Values{}
-	params.Set("query", query)
-	params.Set("type", searchType)
-
+// Search queries Prowlarr's JSON search API
+func (c *Client) Search(ctx context.Context, params *SearchParams) ([]SearchResult, error) {
 	req, _ := http.NewRequestWithContext(ctx, "GET",
-		c.baseURL+"/api/v1/search?"+params.Encode(), nil)
+		c.baseURL+"/api/v1/search?"+params.Values().Encode(), nil)
 	req.Header.Set("X-Api-Key", c.apiKey)
 
 	resp, err := c.client.Do(req)
@@ -65,6 +152,51 @@ func (c *Client) Search(ctx context.Context, query string, searchType string) ([
 	return results, nil
 }
 
+type Indexer struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	Enable   bool   `json:"enable"`
+	Protocol string `json:"protocol"` // "torrent" or "usenet"
+	Privacy  string `json:"privacy"`  // "public", "semiPrivate" or "private"
+}
+
+func (i Indexer) IsTorrent() bool {
+	return i.Protocol == "torrent"
+}
+
+func (i Indexer) IsPrivate() bool {
+	return i.Privacy == "private" || i.Privacy == "semiPrivate"
+}
+
+// GetIndexers lists the indexers configured in Prowlarr
+func (c *Client) GetIndexers(ctx context.Context) ([]Indexer, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/indexer", nil)
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prowlarr list indexers failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("prowlarr returned %d", resp.StatusCode)
+	}
+
+	var indexers []Indexer
+	if err := json.NewDecoder(resp.Body).Decode(&indexers); err != nil {
+		return nil, err
+	}
+
+	return indexers, nil
+}
+
+// TorznabBaseURL returns the base of the indexer's native Torznab endpoint,
+// which is served at `{baseURL}/{id}/api`.
+func TorznabBaseURL(baseURL string, indexerId int) string {
+	return strings.TrimRight(baseURL, "/") + "/" + strconv.Itoa(indexerId)
+}
+
 // GetInstance returns a singleton Prowlarr client or nil if not configured
 func GetInstance() *Client {
 	if !IsConfigured() {
@@ -72,4 +204,3 @@ func GetInstance() *Client {
 	}
 	return NewClient(URL, APIKey)
 }
-
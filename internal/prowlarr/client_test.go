@@ -0,0 +1,54 @@
+package prowlarr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchParamsValues(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		params SearchParams
+		result string
+	}{
+		{
+			"text",
+			SearchParams{Query: "Breaking Bad"},
+			"query=Breaking+Bad&type=search",
+		},
+		{
+			"movie",
+			SearchParams{
+				Type:       SearchTypeMovie,
+				ImdbId:     "tt0111161",
+				TmdbId:     "278",
+				IndexerIds: []int{3},
+				Categories: []int{2000},
+			},
+			"categories=2000&indexerIds=3&query=%7BImdbId%3Att0111161%7D%7BTmdbId%3A278%7D&type=movie",
+		},
+		{
+			"episode",
+			SearchParams{
+				Type:       SearchTypeTV,
+				ImdbId:     "tt0903747",
+				TvdbId:     "81189",
+				Season:     1,
+				Episode:    2,
+				IndexerIds: []int{1, 2},
+				Limit:      100,
+			},
+			"indexerIds=1&indexerIds=2&limit=100&query=%7BImdbId%3Att0903747%7D%7BTvdbId%3A81189%7D%7BSeason%3A1%7D%7BEpisode%3A2%7D&type=tvsearch",
+		},
+		{
+			"episode without season",
+			SearchParams{Type: SearchTypeTV, TvdbId: "81189", Episode: 2},
+			"query=%7BTvdbId%3A81189%7D&type=tvsearch",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.result, tc.params.Values().Encode())
+		})
+	}
+}
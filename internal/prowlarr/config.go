@@ -2,12 +2,19 @@ package prowlarr
 
 import (
 	"os"
+	"time"
 )
 
 var (
 	Enabled bool
 	URL     string
 	APIKey  string
+
+	// IndexerTimeout bounds each per-indexer search
+	IndexerTimeout time.Duration
+
+	// SearchLogRetention is how long search logs are kept around
+	SearchLogRetention time.Duration
 )
 
 func init() {
@@ -18,10 +25,19 @@ func init() {
 	if URL == "" {
 		URL = "http://localhost:9696"
 	}
+
+	IndexerTimeout = 20 * time.Second
+	if timeout, err := time.ParseDuration(os.Getenv("PROWLARR_INDEXER_TIMEOUT")); err == nil && timeout > 0 {
+		IndexerTimeout = timeout
+	}
+
+	SearchLogRetention = 7 * 24 * time.Hour
+	if retention, err := time.ParseDuration(os.Getenv("PROWLARR_SEARCH_LOG_RETENTION")); err == nil && retention > 0 {
+		SearchLogRetention = retention
+	}
 }
 
 // IsConfigured returns true if Prowlarr is properly configured
 func IsConfigured() bool {
 	return Enabled && URL != "" && APIKey != ""
 }
-
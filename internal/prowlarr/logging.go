@@ -3,13 +3,18 @@ package prowlarr
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/MunifTanjim/stremthru/internal/logger"
 )
 
-// LogSearchRequest logs a Prowlarr search to the database for performance tracking
+var log = logger.Scoped("prowlarr")
+
+// SearchLogParams describes a single indexer search for performance tracking
 type SearchLogParams struct {
 	IndexerName   string        // e.g., "EZTV", "The Pirate Bay", etc.
 	SearchQuery   string        // Search query text
@@ -23,73 +28,234 @@ type SearchLogParams struct {
 	ErrorType     string        // "timeout", "http_error", "parse_error", "no_results"
 	ErrorMessage  string        // Error message if failed
 	UserID        string        // Optional user UUID
+	At            time.Time     // When the search happened, defaults to now
+}
+
+const (
+	logQueueSize          = 10000
+	logBatchSize          = 500
+	logFlushInterval      = 5 * time.Second
+	logFlushTimeout       = 10 * time.Second
+	indexerIdsRefreshedBy = 5 * time.Minute
+)
+
+var searchLogColumns = []string{
+	"indexer_id",
+	"search_query",
+	"search_type",
+	"imdb_id",
+	"tmdb_id",
+	"response_time",
+	"http_status",
+	"results_count",
+	"was_successful",
+	"error_type",
+	"error_message",
+	"user_uuid",
+	"created_at",
 }
 
-// LogSearchToDatabase logs a Prowlarr search to the PostgreSQL database (Chillstreams)
-func LogSearchToDatabase(ctx context.Context, db *sql.DB, params SearchLogParams) error {
-	// Find indexer ID by name in Chillstreams PostgreSQL database (case-insensitive)
-	var indexerID *uuid.UUID
-	err := db.QueryRowContext(ctx,
-		`SELECT id FROM prowlarr_indexers WHERE LOWER(indexer_name) = LOWER($1)`,
-		params.IndexerName,
-	).Scan(&indexerID)
+// searchLogRecorder buffers search logs in memory and writes them to the
+// Chillstreams database in batches, off the request path.
+type searchLogRecorder struct {
+	db       *sql.DB
+	queue    chan SearchLogParams
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	dropped  atomic.Int64
+
+	indexerIds   map[string]string // lowercased indexer_name -> id
+	indexerIdsAt time.Time
+}
 
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to find indexer: %w", err)
+var (
+	recorder     *searchLogRecorder
+	recorderOnce sync.Once
+)
+
+func newSearchLogRecorder(db *sql.DB, queueSize int) *searchLogRecorder {
+	r := &searchLogRecorder{
+		db:    db,
+		queue: make(chan SearchLogParams, queueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
 	}
+	go r.run()
+	return r
+}
 
-	// Insert search log into PostgreSQL
-	_, err = db.ExecContext(ctx, `
-		INSERT INTO prowlarr_search_logs
-		(indexer_id, search_query, search_type, imdb_id, tmdb_id,
-		 response_time, http_status, results_count, was_successful,
-		 error_type, error_message, user_uuid)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	`,
-		indexerID,
-		params.SearchQuery,
-		params.SearchType,
-		nilIfEmpty(params.IMDBId),
-		nilIfEmpty(params.TMDBId),
-		int(params.ResponseTime.Milliseconds()),
-		params.HTTPStatus,
-		params.ResultsCount,
-		params.WasSuccessful,
-		nilIfEmpty(params.ErrorType),
-		nilIfEmpty(params.ErrorMessage),
-		nilIfEmpty(params.UserID),
-	)
+// InitSearchLog starts the search log recorder backed by the given database.
+func InitSearchLog(db *sql.DB) {
+	recorderOnce.Do(func() {
+		recorder = newSearchLogRecorder(db, logQueueSize)
+	})
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to insert search log: %w", err)
+// StopSearchLog flushes the buffered search logs and stops the recorder.
+func StopSearchLog() {
+	if recorder == nil {
+		return
 	}
+	recorder.close()
+}
 
-	// Update indexer metrics in PostgreSQL
-	if indexerID != nil {
-		_, err = db.ExecContext(ctx, `
-			UPDATE prowlarr_indexers
-			SET
-				total_requests_24h = total_requests_24h + 1,
-				successful_requests_24h = CASE WHEN $1 THEN successful_requests_24h + 1 ELSE successful_requests_24h END,
-				failed_requests_24h = CASE WHEN NOT $1 THEN failed_requests_24h + 1 ELSE failed_requests_24h END,
-				timeout_count_24h = CASE WHEN $2 = 'timeout' THEN timeout_count_24h + 1 ELSE timeout_count_24h END,
-				last_check_at = CURRENT_TIMESTAMP,
-				last_success_at = CASE WHEN $1 THEN CURRENT_TIMESTAMP ELSE last_success_at END,
-				last_failure_at = CASE WHEN NOT $1 THEN CURRENT_TIMESTAMP ELSE last_failure_at END,
-				updated_at = CURRENT_TIMESTAMP
-			WHERE id = $3
-		`,
-			params.WasSuccessful,
-			params.ErrorType,
-			indexerID,
-		)
+func (r *searchLogRecorder) close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+func IsSearchLogEnabled() bool {
+	return recorder != nil
+}
+
+func getSearchLogDB() *sql.DB {
+	if recorder == nil {
+		return nil
+	}
+	return recorder.db
+}
 
+// RecordSearch queues the search log without blocking. When the queue is
+// full the entry is dropped.
+func RecordSearch(params SearchLogParams) {
+	if recorder == nil {
+		return
+	}
+	recorder.record(params)
+}
+
+func (r *searchLogRecorder) record(params SearchLogParams) {
+	if params.At.IsZero() {
+		params.At = time.Now()
+	}
+	select {
+	case r.queue <- params:
+	default:
+		if dropped := r.dropped.Add(1); dropped%1000 == 1 {
+			log.Warn("search log queue is full, dropping entries", "dropped", dropped)
+		}
+	}
+}
+
+func (r *searchLogRecorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SearchLogParams, 0, logBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.flush(batch); err != nil {
+			log.Error("failed to write search logs", "error", err, "count", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case params := <-r.queue:
+			batch = append(batch, params)
+			if len(batch) >= logBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.stop:
+			for {
+				select {
+				case params := <-r.queue:
+					batch = append(batch, params)
+					if len(batch) >= logBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *searchLogRecorder) getIndexerId(ctx context.Context, name string) string {
+	if r.indexerIds == nil || time.Since(r.indexerIdsAt) > indexerIdsRefreshedBy {
+		indexerIds, err := r.loadIndexerIds(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to update indexer metrics: %w", err)
+			log.Warn("failed to load prowlarr indexers", "error", err)
+		} else {
+			r.indexerIds = indexerIds
+		}
+		r.indexerIdsAt = time.Now()
+	}
+	return r.indexerIds[strings.ToLower(name)]
+}
+
+func (r *searchLogRecorder) loadIndexerIds(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, indexer_name FROM prowlarr_indexers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexerIds := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		indexerIds[strings.ToLower(name)] = id
+	}
+	return indexerIds, rows.Err()
+}
+
+func (r *searchLogRecorder) flush(batch []SearchLogParams) error {
+	ctx, cancel := context.WithTimeout(context.Background(), logFlushTimeout)
+	defer cancel()
+
+	var query strings.Builder
+	query.WriteString("INSERT INTO prowlarr_search_logs (")
+	query.WriteString(strings.Join(searchLogColumns, ", "))
+	query.WriteString(") VALUES ")
+
+	args := make([]any, 0, len(batch)*len(searchLogColumns))
+	for i := range batch {
+		params := &batch[i]
+		if i > 0 {
+			query.WriteString(",")
+		}
+		query.WriteString("(")
+		for j := range searchLogColumns {
+			if j > 0 {
+				query.WriteString(",")
+			}
+			query.WriteString("$" + strconv.Itoa(len(args)+j+1))
 		}
+		query.WriteString(")")
+		args = append(args,
+			nilIfEmpty(r.getIndexerId(ctx, params.IndexerName)),
+			params.SearchQuery,
+			params.SearchType,
+			nilIfEmpty(params.IMDBId),
+			nilIfEmpty(params.TMDBId),
+			int(params.ResponseTime.Milliseconds()),
+			params.HTTPStatus,
+			params.ResultsCount,
+			params.WasSuccessful,
+			nilIfEmpty(params.ErrorType),
+			nilIfEmpty(params.ErrorMessage),
+			nilIfEmpty(params.UserID),
+			params.At,
+		)
 	}
 
-	return nil
+	_, err := r.db.ExecContext(ctx, query.String(), args...)
+	return err
 }
 
 // nilIfEmpty returns nil if the string is empty, otherwise returns the string
@@ -99,4 +265,3 @@ func nilIfEmpty(s string) interface{} {
 	}
 	return s
 }
-
@@ -0,0 +1,73 @@
+package prowlarr
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSearchLogTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE prowlarr_indexers (id TEXT PRIMARY KEY, indexer_name TEXT NOT NULL);
+		CREATE TABLE prowlarr_search_logs (
+			indexer_id TEXT,
+			search_query TEXT,
+			search_type TEXT,
+			imdb_id TEXT,
+			tmdb_id TEXT,
+			response_time INTEGER,
+			http_status INTEGER,
+			results_count INTEGER,
+			was_successful BOOLEAN,
+			error_type TEXT,
+			error_message TEXT,
+			user_uuid TEXT,
+			created_at TIMESTAMP
+		);
+		INSERT INTO prowlarr_indexers (id, indexer_name) VALUES ('indexer-1', 'EZTV');
+	`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestSearchLogRecorderFlushesOnClose(t *testing.T) {
+	db := newSearchLogTestDB(t)
+
+	r := newSearchLogRecorder(db, 10)
+	r.record(SearchLogParams{IndexerName: "eztv", SearchQuery: "tt0903747", ResponseTime: 1500 * time.Millisecond, WasSuccessful: true, ResultsCount: 3})
+	r.record(SearchLogParams{IndexerName: "Unknown", SearchQuery: "tt0111161", ErrorType: "timeout"})
+	r.close()
+	r.close()
+
+	rows, err := db.Query(`SELECT indexer_id, search_query, response_time, error_type FROM prowlarr_search_logs ORDER BY search_query`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type row struct {
+		indexerId    sql.NullString
+		query        string
+		responseTime int
+		errorType    sql.NullString
+	}
+	result := []row{}
+	for rows.Next() {
+		item := row{}
+		require.NoError(t, rows.Scan(&item.indexerId, &item.query, &item.responseTime, &item.errorType))
+		result = append(result, item)
+	}
+	require.NoError(t, rows.Err())
+
+	assert.Equal(t, []row{
+		{indexerId: sql.NullString{}, query: "tt0111161", errorType: sql.NullString{String: "timeout", Valid: true}},
+		{indexerId: sql.NullString{String: "indexer-1", Valid: true}, query: "tt0903747", responseTime: 1500},
+	}, result, "buffered logs are written on close, with the indexer id matched by name")
+}
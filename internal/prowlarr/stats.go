@@ -0,0 +1,128 @@
+package prowlarr
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const StatsWindow = 24 * time.Hour
+
+var errSearchLogDisabled = errors.New("prowlarr search log is not enabled")
+
+// RollupIndexerStats recomputes the `*_24h` counters of every indexer from
+// the search logs inside the sliding window, so that old searches decay out.
+func RollupIndexerStats(ctx context.Context) error {
+	db := getSearchLogDB()
+	if db == nil {
+		return errSearchLogDisabled
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE prowlarr_indexers AS i
+		SET
+			total_requests_24h = COALESCE(s.total, 0),
+			successful_requests_24h = COALESCE(s.successful, 0),
+			failed_requests_24h = COALESCE(s.failed, 0),
+			timeout_count_24h = COALESCE(s.timeouts, 0),
+			last_check_at = COALESCE(s.last_check_at, i.last_check_at),
+			last_success_at = COALESCE(s.last_success_at, i.last_success_at),
+			last_failure_at = COALESCE(s.last_failure_at, i.last_failure_at),
+			updated_at = CURRENT_TIMESTAMP
+		FROM prowlarr_indexers AS x
+		LEFT JOIN (
+			SELECT
+				indexer_id,
+				COUNT(*) AS total,
+				COUNT(*) FILTER (WHERE was_successful) AS successful,
+				COUNT(*) FILTER (WHERE NOT was_successful) AS failed,
+				COUNT(*) FILTER (WHERE error_type = 'timeout') AS timeouts,
+				MAX(created_at) AS last_check_at,
+				MAX(created_at) FILTER (WHERE was_successful) AS last_success_at,
+				MAX(created_at) FILTER (WHERE NOT was_successful) AS last_failure_at
+			FROM prowlarr_search_logs
+			WHERE created_at > $1 AND indexer_id IS NOT NULL
+			GROUP BY indexer_id
+		) AS s ON s.indexer_id = x.id
+		WHERE i.id = x.id
+	`, time.Now().Add(-StatsWindow))
+	return err
+}
+
+// PurgeSearchLogs deletes the search logs older than the retention period.
+func PurgeSearchLogs(ctx context.Context) (int64, error) {
+	db := getSearchLogDB()
+	if db == nil {
+		return 0, errSearchLogDisabled
+	}
+
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM prowlarr_search_logs WHERE created_at < $1`,
+		time.Now().Add(-SearchLogRetention),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type IndexerStats struct {
+	IndexerId   string
+	IndexerName string
+	Total       int64
+	Successful  int64
+	Timeouts    int64
+	SuccessRate float64
+	TimeoutRate float64
+	P50         time.Duration
+	P95         time.Duration
+}
+
+// GetIndexerStats returns the per indexer stats for the searches inside the
+// sliding window.
+func GetIndexerStats(ctx context.Context, window time.Duration) ([]IndexerStats, error) {
+	db := getSearchLogDB()
+	if db == nil {
+		return nil, errSearchLogDisabled
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			i.id,
+			i.indexer_name,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE l.was_successful),
+			COUNT(*) FILTER (WHERE l.error_type = 'timeout'),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY l.response_time),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY l.response_time)
+		FROM prowlarr_indexers AS i
+		JOIN prowlarr_search_logs AS l ON l.indexer_id = i.id
+		WHERE l.created_at > $1
+		GROUP BY i.id, i.indexer_name
+		ORDER BY i.indexer_name
+	`, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []IndexerStats{}
+	for rows.Next() {
+		item := IndexerStats{}
+		var p50, p95 float64
+		if err := rows.Scan(&item.IndexerId, &item.IndexerName, &item.Total, &item.Successful, &item.Timeouts, &p50, &p95); err != nil {
+			return nil, err
+		}
+		if item.Total > 0 {
+			item.SuccessRate = float64(item.Successful) / float64(item.Total)
+			item.TimeoutRate = float64(item.Timeouts) / float64(item.Total)
+		}
+		item.P50 = time.Duration(p50) * time.Millisecond
+		item.P95 = time.Duration(p95) * time.Millisecond
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
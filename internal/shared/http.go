@@ -11,9 +11,11 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MunifTanjim/stremthru/core"
 	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/content_proxy"
 	"github.com/MunifTanjim/stremthru/internal/context"
 	"github.com/MunifTanjim/stremthru/internal/server"
 )
@@ -141,52 +143,194 @@ var proxyHttpClientByTunnelType = map[config.TunnelType]*http.Client{
 		transport := config.DefaultHTTPTransport.Clone()
 		transport.Proxy = config.Tunnel.GetProxy(config.TUNNEL_TYPE_NONE)
 		return &http.Client{
-			Transport: transport,
+			Transport: config.TraceHTTPTransport(transport),
 		}
 	}(),
 	config.TUNNEL_TYPE_AUTO: func() *http.Client {
 		transport := config.DefaultHTTPTransport.Clone()
 		transport.Proxy = config.Tunnel.GetProxy(config.TUNNEL_TYPE_AUTO)
 		return &http.Client{
-			Transport: transport,
+			Transport: config.TraceHTTPTransport(transport),
 		}
 	}(),
 	config.TUNNEL_TYPE_FORCED: func() *http.Client {
 		transport := config.DefaultHTTPTransport.Clone()
 		transport.Proxy = config.Tunnel.GetProxy(config.TUNNEL_TYPE_FORCED)
 		return &http.Client{
-			Transport: transport,
+			Transport: config.TraceHTTPTransport(transport),
 		}
 	}(),
 }
 
-func ProxyResponse(w http.ResponseWriter, r *http.Request, url string, tunnelType config.TunnelType) (bytesWritten int64, err error) {
-	request, err := http.NewRequest(r.Method, url, nil)
-	if err != nil {
-		e := ErrorInternalServerError(r, "failed to create request")
-		e.Cause = err
-		SendError(w, r, e)
-		return
+const (
+	proxyResumeMaxAttempts = 5
+	proxyResumeBackoff     = 1 * time.Second
+)
+
+// proxyContentRange is the byte range of the upstream response, used to
+// resume it from the last byte written.
+type proxyContentRange struct {
+	start int64
+	// -1 if unknown
+	end int64
+	// -1 if unknown
+	size int64
+}
+
+func (cr proxyContentRange) rangeFrom(offset int64) string {
+	if cr.end < 0 {
+		return "bytes=" + strconv.FormatInt(offset, 10) + "-"
 	}
+	return "bytes=" + strconv.FormatInt(offset, 10) + "-" + strconv.FormatInt(cr.end, 10)
+}
 
-	copyHeaders(r.Header, request.Header, true)
+func parseContentRange(value string) (cr proxyContentRange, ok bool) {
+	value, ok = strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return cr, false
+	}
+	rng, size, ok := strings.Cut(value, "/")
+	if !ok {
+		return cr, false
+	}
+	start, end, ok := strings.Cut(rng, "-")
+	if !ok {
+		return cr, false
+	}
+	var err error
+	if cr.start, err = strconv.ParseInt(start, 10, 64); err != nil {
+		return cr, false
+	}
+	if cr.end, err = strconv.ParseInt(end, 10, 64); err != nil {
+		return cr, false
+	}
+	cr.size = -1
+	if size != "*" {
+		if cr.size, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return cr, false
+		}
+	}
+	return cr, true
+}
 
-	proxyHttpClient := proxyHttpClientByTunnelType[tunnelType]
+func getResumableContentRange(response *http.Response) (cr proxyContentRange, ok bool) {
+	switch response.StatusCode {
+	case http.StatusOK:
+		if response.Header.Get("Accept-Ranges") != "bytes" {
+			return cr, false
+		}
+		cr.size = response.ContentLength
+		cr.end = -1
+		if cr.size >= 0 {
+			cr.end = cr.size - 1
+		}
+		return cr, true
+	case http.StatusPartialContent:
+		// multipart/byteranges responses do not have it
+		return parseContentRange(response.Header.Get("Content-Range"))
+	default:
+		return cr, false
+	}
+}
 
-	response, err := proxyHttpClient.Do(request)
+// copyResponseBody copies the body to w, returns `readErr` if the upstream
+// failed, as opposed to the client.
+func copyResponseBody(w io.Writer, body io.Reader, buf []byte) (written int64, readErr error, writeErr error) {
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			nw, werr := w.Write(buf[:n])
+			written += int64(nw)
+			if werr != nil {
+				return written, nil, werr
+			}
+			if nw != n {
+				return written, nil, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil, nil
+		}
+		if rerr != nil {
+			return written, rerr, nil
+		}
+	}
+}
+
+// ProxyResponse proxies the response for url to w. For GET requests, if the
+// upstream connection drops, the rest of the response is requested using
+// `Range` and the client connection stays open. If regenerateLink is not nil,
+// it is used to get a new url once the upstream responds with 403/410. If
+// cacheKey is not empty, GET requests are served through the content proxy
+// cache, when enabled.
+func ProxyResponse(w http.ResponseWriter, r *http.Request, url string, tunnelType config.TunnelType, regenerateLink func() (string, error), cacheKey string) (bytesWritten int64, err error) {
+	upstream := &proxyUpstream{
+		r:          r,
+		client:     proxyHttpClientByTunnelType[tunnelType],
+		url:        url,
+		regenerate: regenerateLink,
+	}
+
+	if cacheKey != "" && IsMethod(r, http.MethodGet) {
+		if cache := content_proxy.GetCache(); cache != nil {
+			if bytesWritten, ok, err := proxyCachedResponse(w, r, upstream, cache, cacheKey); ok {
+				return bytesWritten, err
+			}
+		}
+	}
+
+	response, err := upstream.do("")
 	if err != nil {
 		e := ErrorBadGateway(r, "failed to request url")
 		e.Cause = err
 		SendError(w, r, e)
 		return
 	}
-	defer response.Body.Close()
+	defer func() {
+		response.Body.Close()
+	}()
 
 	copyHeaders(response.Header, w.Header(), false)
 
 	w.WriteHeader(response.StatusCode)
 
-	return io.Copy(w, response.Body)
+	cr, isResumable := getResumableContentRange(response)
+	isResumable = isResumable && IsMethod(r, http.MethodGet)
+
+	buf := make([]byte, 32*1024)
+	for attempt := 0; ; attempt++ {
+		written, readErr, writeErr := copyResponseBody(w, response.Body, buf)
+		bytesWritten += written
+		if writeErr != nil {
+			return bytesWritten, writeErr
+		}
+		if readErr == nil {
+			return bytesWritten, nil
+		}
+		if !isResumable || attempt >= proxyResumeMaxAttempts || r.Context().Err() != nil {
+			return bytesWritten, readErr
+		}
+
+		offset := cr.start + bytesWritten
+		server.GetReqCtx(r).Log.Warn("[proxy] upstream connection failed, resuming", "error", readErr, "offset", offset, "attempt", attempt+1)
+
+		select {
+		case <-r.Context().Done():
+			return bytesWritten, readErr
+		case <-time.After(time.Duration(attempt+1) * proxyResumeBackoff):
+		}
+
+		response.Body.Close()
+		response, err = upstream.do(cr.rangeFrom(offset))
+		if err != nil {
+			response = &http.Response{Body: http.NoBody}
+			return bytesWritten, errors.Join(readErr, err)
+		}
+		rcr, ok := parseContentRange(response.Header.Get("Content-Range"))
+		if response.StatusCode != http.StatusPartialContent || !ok || rcr.start != offset || (cr.size >= 0 && rcr.size >= 0 && rcr.size != cr.size) {
+			return bytesWritten, errors.Join(readErr, errors.New("failed to resume upstream response: "+response.Status))
+		}
+	}
 }
 
 func extractRequestScheme(r *http.Request) string {
@@ -0,0 +1,123 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentRange(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		cr    proxyContentRange
+		ok    bool
+	}{
+		{"bytes 0-99/1000", proxyContentRange{start: 0, end: 99, size: 1000}, true},
+		{"bytes 100-999/*", proxyContentRange{start: 100, end: 999, size: -1}, true},
+		{"bytes */1000", proxyContentRange{}, false},
+		{"bytes 0-99", proxyContentRange{}, false},
+		{"0-99/1000", proxyContentRange{}, false},
+		{"bytes a-99/1000", proxyContentRange{}, false},
+		{"", proxyContentRange{}, false},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			cr, ok := parseContentRange(tc.value)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.cr, cr)
+			}
+		})
+	}
+}
+
+func TestProxyContentRangeFrom(t *testing.T) {
+	assert.Equal(t, "bytes=10-99", proxyContentRange{start: 0, end: 99, size: 100}.rangeFrom(10))
+	assert.Equal(t, "bytes=10-", proxyContentRange{start: 0, end: -1, size: -1}.rangeFrom(10))
+}
+
+func newProxyRequest(t *testing.T) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v0/proxy/token", nil)
+	return server.SetReqCtx(r, &server.ReqCtx{Log: logger.New(context.Background())})
+}
+
+func TestProxyResponseResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10*1024)
+	dropAfter := 40 * 1024
+
+	requestCount := atomic.Int32{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestCount.Add(1) == 1 {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:dropAfter])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer upstream.Close()
+
+	w := httptest.NewRecorder()
+	bytesWritten, err := ProxyResponse(w, newProxyRequest(t), upstream.URL, config.TUNNEL_TYPE_NONE, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), bytesWritten)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, bytes.Equal(content, w.Body.Bytes()), "resumed response has the full content")
+	assert.Equal(t, int32(2), requestCount.Load())
+}
+
+func TestProxyResponseRegenerateLink(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer upstream.Close()
+
+	regenerateCount := 0
+	regenerate := func() (string, error) {
+		regenerateCount++
+		return upstream.URL + "/fresh", nil
+	}
+
+	w := httptest.NewRecorder()
+	_, err := ProxyResponse(w, newProxyRequest(t), upstream.URL+"/expired", config.TUNNEL_TYPE_NONE, regenerate, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "content", w.Body.String())
+	assert.Equal(t, 1, regenerateCount)
+
+	regenerate = func() (string, error) {
+		regenerateCount++
+		return upstream.URL + "/expired", nil
+	}
+	w = httptest.NewRecorder()
+	_, err = ProxyResponse(w, newProxyRequest(t), upstream.URL+"/expired", config.TUNNEL_TYPE_NONE, regenerate, "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, w.Code, "link is regenerated only once")
+	assert.Equal(t, 2, regenerateCount)
+}
+
+func TestSetProxyLinkTokenLink(t *testing.T) {
+	proxyLinkTokenCache.Add("token", proxyLinkData{User: "user", Value: "https://example.com/expired"})
+
+	SetProxyLinkTokenLink("token", "https://example.com/fresh")
+
+	_, link, _, _, _, _, err := UnwrapProxyLinkToken("token")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/fresh", link)
+}
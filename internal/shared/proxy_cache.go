@@ -0,0 +1,172 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/content_proxy"
+	"github.com/MunifTanjim/stremthru/internal/server"
+)
+
+// parseRequestRange parses a single `Range` of the request, `end` is -1 if
+// open ended. Suffix and multiple ranges are not supported.
+func parseRequestRange(value string) (start, end int64, ok bool) {
+	if value == "" {
+		return 0, -1, true
+	}
+	value, ok = strings.CutPrefix(value, "bytes=")
+	if !ok || strings.Contains(value, ",") {
+		return 0, 0, false
+	}
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok || startStr == "" {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	end = -1
+	if endStr != "" {
+		if end, err = strconv.ParseInt(endStr, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+	}
+	return start, end, true
+}
+
+// fetchRange reads the bytes `start` to `end` of the upstream content,
+// retrying if the upstream connection fails.
+func (u *proxyUpstream) fetchRange(ctx context.Context, start, end int64) (data []byte, cr proxyContentRange, contentType string, err error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, cr, "", err
+			case <-time.After(time.Duration(attempt) * proxyResumeBackoff):
+			}
+		}
+
+		var response *http.Response
+		response, err = u.doWithContext(ctx, "bytes="+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10))
+		if err != nil {
+			return nil, cr, "", err
+		}
+
+		var ok bool
+		cr, ok = parseContentRange(response.Header.Get("Content-Range"))
+		if response.StatusCode != http.StatusPartialContent || !ok || cr.start != start || cr.size < 0 {
+			response.Body.Close()
+			return nil, cr, "", errors.New("unexpected upstream response: " + response.Status)
+		}
+		contentType = response.Header.Get("Content-Type")
+
+		data, err = io.ReadAll(io.LimitReader(response.Body, cr.end-cr.start+1))
+		response.Body.Close()
+		if err == nil && int64(len(data)) != cr.end-cr.start+1 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			return data, cr, contentType, nil
+		}
+		if attempt >= proxyResumeMaxAttempts || ctx.Err() != nil {
+			return nil, cr, "", err
+		}
+		server.GetReqCtx(u.r).Log.Warn("[proxy] failed to fetch chunk, retrying", "error", err, "start", start, "attempt", attempt+1)
+	}
+}
+
+// proxyCachedResponse serves the GET request from the content proxy cache,
+// fetching the missing chunks from the upstream. If the response can not be
+// served from the cache, `ok` is false and nothing is written to w.
+func proxyCachedResponse(w http.ResponseWriter, r *http.Request, upstream *proxyUpstream, cache *content_proxy.ChunkCache, key string) (bytesWritten int64, ok bool, err error) {
+	start, end, ok := parseRequestRange(r.Header.Get("Range"))
+	if !ok {
+		return 0, false, nil
+	}
+
+	chunkSize := cache.ChunkSize()
+
+	// fetched while looking up the size, so that the first chunk is not
+	// requested twice
+	var firstChunk []byte
+
+	meta, hasMeta := cache.GetMeta(key)
+	if !hasMeta {
+		idx := start / chunkSize
+		data, cr, contentType, err := upstream.fetchRange(r.Context(), idx*chunkSize, (idx+1)*chunkSize-1)
+		if err != nil {
+			server.GetReqCtx(r).Log.Warn("[proxy] failed to fetch content for cache", "error", err)
+			return 0, false, nil
+		}
+		meta = &content_proxy.CacheMeta{Size: cr.size, ContentType: contentType}
+		if err := cache.SetMeta(key, meta); err != nil {
+			server.GetReqCtx(r).Log.Error("[proxy] failed to cache content meta", "error", err)
+		}
+		firstChunk = data
+	}
+
+	if start >= meta.Size {
+		// upstream responds with 416
+		return 0, false, nil
+	}
+	if end < 0 || end >= meta.Size {
+		end = meta.Size - 1
+	}
+
+	getChunk := func(idx int64) ([]byte, error) {
+		return cache.GetChunk(r.Context(), key, idx, func(ctx context.Context) ([]byte, error) {
+			if firstChunk != nil && idx == start/chunkSize {
+				return firstChunk, nil
+			}
+			data, _, _, err := upstream.fetchRange(ctx, idx*chunkSize, min((idx+1)*chunkSize, meta.Size)-1)
+			return data, err
+		})
+	}
+
+	firstIdx, lastIdx := start/chunkSize, end/chunkSize
+
+	chunk, err := getChunk(firstIdx)
+	if err != nil {
+		server.GetReqCtx(r).Log.Warn("[proxy] failed to fetch chunk for cache", "error", err)
+		return 0, false, nil
+	}
+
+	if meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	if r.Header.Get("Range") != "" {
+		w.Header().Set("Content-Range", "bytes "+strconv.FormatInt(start, 10)+"-"+strconv.FormatInt(end, 10)+"/"+strconv.FormatInt(meta.Size, 10))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for idx := firstIdx; idx <= lastIdx; idx++ {
+		if idx != firstIdx {
+			if chunk, err = getChunk(idx); err != nil {
+				return bytesWritten, true, err
+			}
+		}
+
+		chunkStart := idx * chunkSize
+		from, to := max(start, chunkStart)-chunkStart, min(end+1, chunkStart+int64(len(chunk)))-chunkStart
+		if from >= to {
+			return bytesWritten, true, io.ErrUnexpectedEOF
+		}
+		n, err := w.Write(chunk[from:to])
+		bytesWritten += int64(n)
+		if err != nil {
+			return bytesWritten, true, err
+		}
+	}
+
+	return bytesWritten, true, nil
+}
@@ -0,0 +1,38 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequestRange(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		start int64
+		end   int64
+		ok    bool
+	}{
+		{"", 0, -1, true},
+		{"bytes=0-99", 0, 99, true},
+		{"bytes=100-", 100, -1, true},
+		{"bytes= 10-20", 10, 20, true},
+		{"bytes=5-5", 5, 5, true},
+		{"bytes=-100", 0, 0, false},
+		{"bytes=0-99,200-299", 0, 0, false},
+		{"bytes=99-0", 0, 0, false},
+		{"bytes=a-99", 0, 0, false},
+		{"bytes=0-b", 0, 0, false},
+		{"bytes=0", 0, 0, false},
+		{"items=0-99", 0, 0, false},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			start, end, ok := parseRequestRange(tc.value)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.start, start)
+				assert.Equal(t, tc.end, end)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/MunifTanjim/stremthru/internal/tracing"
+)
+
+func isProxyLinkExpired(statusCode int) bool {
+	return statusCode == http.StatusForbidden || statusCode == http.StatusGone
+}
+
+type proxyUpstream struct {
+	r          *http.Request
+	client     *http.Client
+	url        string
+	regenerate func() (string, error)
+}
+
+// do requests the upstream, it is not cancelled with the request, the body is
+// closed once the request is done instead.
+func (u *proxyUpstream) do(rangeHeader string) (*http.Response, error) {
+	return u.doWithContext(tracing.Detach(u.r.Context()), rangeHeader)
+}
+
+// doWithContext requests the upstream, bound to ctx.
+func (u *proxyUpstream) doWithContext(ctx context.Context, rangeHeader string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, u.r.Method, u.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	copyHeaders(u.r.Header, request.Header, true)
+	if rangeHeader != "" {
+		request.Header.Set("Range", rangeHeader)
+		request.Header.Del("If-Range")
+	}
+
+	response, err := u.client.Do(request)
+	if err != nil || u.regenerate == nil || !isProxyLinkExpired(response.StatusCode) {
+		return response, err
+	}
+
+	response.Body.Close()
+	link, err := u.regenerate()
+	if err != nil {
+		return nil, err
+	}
+	u.url = link
+	// regenerated only once, a fresh link failing again is not expired
+	u.regenerate = nil
+	return u.doWithContext(ctx, rangeHeader)
+}
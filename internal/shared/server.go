@@ -8,6 +8,7 @@ import (
 
 	"github.com/MunifTanjim/stremthru/internal/logger"
 	"github.com/MunifTanjim/stremthru/internal/server"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
 	"github.com/rs/xid"
 )
 
@@ -60,6 +61,11 @@ func RootServerContext(next http.Handler) http.Handler {
 		}
 		w.Header().Set("Request-ID", ctx.RequestId)
 
+		r, span := tracing.StartServerSpan(r, ctx.RequestId)
+		defer func() {
+			tracing.EndServerSpan(span, ctx.ReqMethod, ctx.ReqPath, rw.getStatusCode(), ctx.Error)
+		}()
+
 		ctx.Log = logger.New(r.Context(), "req.id", ctx.RequestId)
 
 		next.ServeHTTP(rw, r)
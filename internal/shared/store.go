@@ -10,7 +10,10 @@ import (
 
 	"github.com/MunifTanjim/stremthru/core"
 	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
 	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/content_proxy"
 	"github.com/MunifTanjim/stremthru/internal/context"
 	"github.com/MunifTanjim/stremthru/store"
 	"github.com/MunifTanjim/stremthru/store/alldebrid"
@@ -25,42 +28,42 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var adStore = alldebrid.NewStoreClient(&alldebrid.StoreClientConfig{
+var adStore = observeStore(alldebrid.NewStoreClient(&alldebrid.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("alldebrid")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var drStore = debrider.NewStoreClient(&debrider.StoreClientConfig{
+}))
+var drStore = observeStore(debrider.NewStoreClient(&debrider.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("debrider")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var dlStore = debridlink.NewStoreClient(&debridlink.StoreClientConfig{
+}))
+var dlStore = observeStore(debridlink.NewStoreClient(&debridlink.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("debridlink")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var edStore = easydebrid.NewStoreClient(&easydebrid.StoreClientConfig{
+}))
+var edStore = observeStore(easydebrid.NewStoreClient(&easydebrid.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("easydebrid")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var pmStore = premiumize.NewStoreClient(&premiumize.StoreClientConfig{
+}))
+var pmStore = observeStore(premiumize.NewStoreClient(&premiumize.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("premiumize")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var ppStore = pikpak.NewStoreClient(&pikpak.StoreClientConfig{
+}))
+var ppStore = observeStore(pikpak.NewStoreClient(&pikpak.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("pikpak")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var ocStore = offcloud.NewStoreClient(&offcloud.StoreClientConfig{
+}))
+var ocStore = observeStore(offcloud.NewStoreClient(&offcloud.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("offcloud")),
 	UserAgent:  config.StoreClientUserAgent,
-})
-var rdStore = realdebrid.NewStoreClient(&realdebrid.StoreClientConfig{
+}))
+var rdStore = observeStore(realdebrid.NewStoreClient(&realdebrid.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("realdebrid")),
 	UserAgent:  "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
-})
-var tbStore = torbox.NewStoreClient(&torbox.StoreClientConfig{
+}))
+var tbStore = observeStore(torbox.NewStoreClient(&torbox.StoreClientConfig{
 	HTTPClient: config.GetHTTPClient(config.StoreTunnel.GetTypeForAPI("torbox")),
 	UserAgent:  config.StoreClientUserAgent,
-})
+}))
 
 func GetStore(name string) store.Store {
 	switch store.StoreName(name) {
@@ -115,6 +118,8 @@ func GetStoreByCode(code string) store.Store {
 type proxyLinkTokenData struct {
 	EncLink    string            `json:"enc_link"`
 	EncFormat  string            `json:"enc_format"`
+	EncStore   string            `json:"enc_store,omitempty"`
+	EncUsage   string            `json:"enc_usage,omitempty"`
 	TunnelType config.TunnelType `json:"tunt,omitempty"`
 }
 
@@ -123,9 +128,73 @@ type proxyLinkData struct {
 	Value   string            `json:"v"`
 	Headers map[string]string `json:"reqh,omitempty"`
 	TunT    config.TunnelType `json:"tunt,omitempty"`
+	// encrypted ProxyLinkStore, kept encrypted in the cache
+	EncStore string `json:"encs,omitempty"`
+	// encrypted chillstreams.LogUsageRequest, kept encrypted in the cache
+	EncUsage string `json:"encu,omitempty"`
+}
+
+// ProxyLinkStore is the store link the proxied link was generated from, used
+// to generate a new link once the proxied link expires.
+type ProxyLinkStore struct {
+	Code     store.StoreCode `json:"c"`
+	APIKey   string          `json:"k"`
+	Link     string          `json:"l"`
+	ClientIP string          `json:"ip,omitempty"`
+	FileHash string          `json:"h,omitempty"`
+	FileIdx  int             `json:"i,omitempty"`
+	// set if APIKey is a Chillstreams pool key, used to get the current key
+	// of the device once the link is regenerated
+	PoolKeyUserId   string `json:"pku,omitempty"`
+	PoolKeyDeviceId string `json:"pkd,omitempty"`
+}
+
+// ResolvePoolKey returns the current Chillstreams pool key leased for the
+// device of the user, for the store. The key of a proxied link may have been
+// rotated or failed over since the link was created.
+var ResolvePoolKey func(r *http.Request, userId, deviceId, storeName string) (string, error)
+
+// GetCacheKey returns the key for the content proxy cache, empty if the
+// content can not be cached.
+func (s *ProxyLinkStore) GetCacheKey() string {
+	if s.FileHash == "" || s.FileIdx < 0 {
+		return ""
+	}
+	return content_proxy.CacheKey(string(s.Code), s.FileHash, s.FileIdx)
+}
+
+func (s *ProxyLinkStore) GenerateLink(r *http.Request) (string, error) {
+	storeClient := GetStoreByCode(string(s.Code))
+	if storeClient == nil {
+		return "", errors.New("invalid store code: " + string(s.Code))
+	}
+
+	params := &store.GenerateLinkParams{}
+	params.APIKey = s.APIKey
+	params.Context = r.Context()
+	params.Link = s.Link
+	params.ClientIP = s.ClientIP
+
+	if s.PoolKeyUserId != "" && ResolvePoolKey != nil {
+		apiKey, err := ResolvePoolKey(r, s.PoolKeyUserId, s.PoolKeyDeviceId, string(storeClient.GetName()))
+		if err != nil {
+			return "", err
+		}
+		params.APIKey = apiKey
+	}
+
+	data, err := storeClient.GenerateLink(params)
+	if err != nil {
+		return "", err
+	}
+	return data.Link, nil
 }
 
 func CreateProxyLink(r *http.Request, link string, headers map[string]string, tunnelType config.TunnelType, expiresIn time.Duration, user, password string, shouldEncrypt bool, filename string) (string, error) {
+	return createProxyLink(r, link, headers, tunnelType, expiresIn, user, password, shouldEncrypt, filename, nil, nil)
+}
+
+func createProxyLink(r *http.Request, link string, headers map[string]string, tunnelType config.TunnelType, expiresIn time.Duration, user, password string, shouldEncrypt bool, filename string, usage *chillstreams.LogUsageRequest, linkStore *ProxyLinkStore) (string, error) {
 	var encodedToken string
 
 	if !shouldEncrypt && expiresIn == 0 {
@@ -149,6 +218,8 @@ func CreateProxyLink(r *http.Request, link string, headers map[string]string, tu
 
 		var encLink string
 		var encFormat string
+		var encStore string
+		var encUsage string
 
 		if shouldEncrypt {
 			encryptedLink, err := core.Encrypt(password, linkBlob)
@@ -157,6 +228,31 @@ func CreateProxyLink(r *http.Request, link string, headers map[string]string, tu
 			}
 			encLink = encryptedLink
 			encFormat = core.EncryptionFormat
+
+			// has the store api key, only included in encrypted tokens
+			if linkStore != nil {
+				blob, err := json.Marshal(linkStore)
+				if err != nil {
+					return "", err
+				}
+				encStore, err = core.Encrypt(password, string(blob))
+				if err != nil {
+					return "", err
+				}
+			}
+
+			// has the chillstreams user and pool key ids, only included in
+			// encrypted tokens
+			if usage != nil {
+				blob, err := json.Marshal(usage)
+				if err != nil {
+					return "", err
+				}
+				encUsage, err = core.Encrypt(password, string(blob))
+				if err != nil {
+					return "", err
+				}
+			}
 		} else {
 			encLink = core.Base64Encode(linkBlob)
 			encFormat = "base64"
@@ -170,6 +266,8 @@ func CreateProxyLink(r *http.Request, link string, headers map[string]string, tu
 			Data: &proxyLinkTokenData{
 				EncLink:    encLink,
 				EncFormat:  encFormat,
+				EncStore:   encStore,
+				EncUsage:   encUsage,
 				TunnelType: tunnelType,
 			},
 		}
@@ -198,6 +296,7 @@ func CreateProxyLink(r *http.Request, link string, headers map[string]string, tu
 func GenerateStremThruLink(r *http.Request, ctx *context.StoreContext, link string) (*store.GenerateLinkData, error) {
 	params := &store.GenerateLinkParams{}
 	params.APIKey = ctx.StoreAuthToken
+	params.Context = ctx.Context
 	params.Link = link
 	if ctx.ClientIP != "" {
 		params.ClientIP = ctx.ClientIP
@@ -209,18 +308,37 @@ func GenerateStremThruLink(r *http.Request, ctx *context.StoreContext, link stri
 	}
 
 	storeName := string(ctx.Store.GetName())
-	if config.StoreContentProxy.IsEnabled(storeName) && ctx.StoreAuthToken == config.StoreAuthToken.GetToken(ctx.ProxyAuthUser, storeName) {
+	usesPoolKey := ctx.ChillstreamsUsage != nil
+	if config.StoreContentProxy.IsEnabled(storeName) && (usesPoolKey || ctx.StoreAuthToken == config.StoreAuthToken.GetToken(ctx.ProxyAuthUser, storeName)) {
 		if ctx.IsProxyAuthorized {
 			tunnelType := config.StoreTunnel.GetTypeForStream(string(ctx.Store.GetName()))
-			proxyLink, err := CreateProxyLink(r, data.Link, nil, tunnelType, 12*time.Hour, ctx.ProxyAuthUser, ctx.ProxyAuthPassword, true, "")
+			linkStore := &ProxyLinkStore{
+				Code:     ctx.Store.GetName().Code(),
+				APIKey:   ctx.StoreAuthToken,
+				Link:     link,
+				ClientIP: params.ClientIP,
+				FileHash: ctx.FileHash,
+				FileIdx:  ctx.FileIdx,
+			}
+			if usesPoolKey {
+				linkStore.PoolKeyUserId = ctx.ChillstreamsUsage.UserID
+				linkStore.PoolKeyDeviceId = ctx.ChillstreamsDeviceId
+			}
+			proxyLink, err := createProxyLink(r, data.Link, nil, tunnelType, 12*time.Hour, ctx.ProxyAuthUser, ctx.ProxyAuthPassword, true, "", ctx.ChillstreamsUsage, linkStore)
 			if err != nil {
 				return nil, err
 			}
 
 			data.Link = proxyLink
+			// usage is reported by the content proxy with the bytes actually served
+			return data, nil
 		}
 	}
 
+	if usesPoolKey {
+		chillstreams_usage.Record(ctx.ChillstreamsUsage)
+	}
+
 	return data, nil
 }
 
@@ -231,6 +349,16 @@ var proxyLinkTokenCache = func() cache.Cache[proxyLinkData] {
 	})
 }()
 
+// SetProxyLinkTokenLink replaces the link of the token in the cache, so that
+// the later requests with the token use the regenerated link.
+func SetProxyLinkTokenLink(encodedToken, link string) {
+	proxyLink := &proxyLinkData{}
+	if proxyLinkTokenCache.Get(encodedToken, proxyLink) {
+		proxyLink.Value = link
+		proxyLinkTokenCache.Add(encodedToken, *proxyLink)
+	}
+}
+
 func getUserCredsFromJWT(t *jwt.Token) (user, password string, err error) {
 	user, err = t.Claims.GetSubject()
 	if err != nil {
@@ -240,25 +368,63 @@ func getUserCredsFromJWT(t *jwt.Token) (user, password string, err error) {
 	return user, password, nil
 }
 
-func UnwrapProxyLinkToken(encodedToken string) (user string, link string, headers map[string]string, tunnelType config.TunnelType, err error) {
+func getProxyLinkStore(proxyLink *proxyLinkData) (*ProxyLinkStore, error) {
+	if proxyLink.EncStore == "" {
+		return nil, nil
+	}
+	blob, err := core.Decrypt(config.ProxyAuthPassword.GetPassword(proxyLink.User), proxyLink.EncStore)
+	if err != nil {
+		return nil, err
+	}
+	linkStore := &ProxyLinkStore{}
+	if err := json.Unmarshal([]byte(blob), linkStore); err != nil {
+		return nil, err
+	}
+	return linkStore, nil
+}
+
+func getProxyLinkUsage(proxyLink *proxyLinkData) (*chillstreams.LogUsageRequest, error) {
+	if proxyLink.EncUsage == "" {
+		return nil, nil
+	}
+	blob, err := core.Decrypt(config.ProxyAuthPassword.GetPassword(proxyLink.User), proxyLink.EncUsage)
+	if err != nil {
+		return nil, err
+	}
+	usage := &chillstreams.LogUsageRequest{}
+	if err := json.Unmarshal([]byte(blob), usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func UnwrapProxyLinkToken(encodedToken string) (user string, link string, headers map[string]string, tunnelType config.TunnelType, usage *chillstreams.LogUsageRequest, linkStore *ProxyLinkStore, err error) {
 	proxyLink := &proxyLinkData{}
 	if found := proxyLinkTokenCache.Get(encodedToken, proxyLink); found {
-		return proxyLink.User, proxyLink.Value, proxyLink.Headers, proxyLink.TunT, nil
+		linkStore, err := getProxyLinkStore(proxyLink)
+		if err != nil {
+			return "", "", nil, "", nil, nil, err
+		}
+		usage, err := getProxyLinkUsage(proxyLink)
+		if err != nil {
+			return "", "", nil, "", nil, nil, err
+		}
+		return proxyLink.User, proxyLink.Value, proxyLink.Headers, proxyLink.TunT, usage, linkStore, nil
 	}
 
 	if encodedBlob, ok := strings.CutPrefix(encodedToken, "base64."); ok {
 		blob, err := core.Base64DecodeToByte(encodedBlob)
 		if err != nil {
-			return "", "", nil, "", err
+			return "", "", nil, "", nil, nil, err
 		}
 		if err := json.Unmarshal(blob, proxyLink); err != nil {
-			return "", "", nil, "", err
+			return "", "", nil, "", nil, nil, err
 		}
 		user, pass, _ := strings.Cut(proxyLink.User, ":")
 		if pass != config.ProxyAuthPassword.GetPassword(user) {
 			err := core.NewAPIError("unauthorized")
 			err.StatusCode = http.StatusUnauthorized
-			return "", "", nil, "", err
+			return "", "", nil, "", nil, nil, err
 		}
 		proxyLink.User = user
 	} else {
@@ -277,20 +443,20 @@ func UnwrapProxyLinkToken(encodedToken string) (user string, link string, header
 				err = rerr
 			}
 
-			return "", "", nil, "", err
+			return "", "", nil, "", nil, nil, err
 		}
 
 		var linkBlob string
 		if claims.Data.EncFormat == "base64" {
 			blob, err := core.Base64Decode(claims.Data.EncLink)
 			if err != nil {
-				return "", "", nil, "", err
+				return "", "", nil, "", nil, nil, err
 			}
 			linkBlob = blob
 		} else {
 			blob, err := core.Decrypt(password, claims.Data.EncLink)
 			if err != nil {
-				return "", "", nil, "", err
+				return "", "", nil, "", nil, nil, err
 			}
 			linkBlob = blob
 		}
@@ -300,6 +466,8 @@ func UnwrapProxyLinkToken(encodedToken string) (user string, link string, header
 		proxyLink.User = user
 		proxyLink.TunT = claims.Data.TunnelType
 		proxyLink.Value = link
+		proxyLink.EncStore = claims.Data.EncStore
+		proxyLink.EncUsage = claims.Data.EncUsage
 
 		if hasHeaders {
 			proxyLink.Headers = map[string]string{}
@@ -313,5 +481,15 @@ func UnwrapProxyLinkToken(encodedToken string) (user string, link string, header
 
 	proxyLinkTokenCache.Add(encodedToken, *proxyLink)
 
-	return proxyLink.User, proxyLink.Value, proxyLink.Headers, proxyLink.TunT, nil
+	linkStore, err = getProxyLinkStore(proxyLink)
+	if err != nil {
+		return "", "", nil, "", nil, nil, err
+	}
+
+	usage, err = getProxyLinkUsage(proxyLink)
+	if err != nil {
+		return "", "", nil, "", nil, nil, err
+	}
+
+	return proxyLink.User, proxyLink.Value, proxyLink.Headers, proxyLink.TunT, usage, linkStore, nil
 }
@@ -0,0 +1,100 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/metrics"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
+	"github.com/MunifTanjim/stremthru/store"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// observedStore records the request count, latency and error codes of the
+// store operations, and traces them as part of the incoming request.
+type observedStore struct {
+	s    store.Store
+	name string
+}
+
+func (o *observedStore) observe(operation string, params *store.Ctx) func(err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(params.GetContext(), "store."+operation, attribute.String("store.name", o.name))
+	params.Context = ctx
+	return func(err error) {
+		metrics.ObserveStoreRequest(o.name, operation, start, err)
+		tracing.End(span, err)
+	}
+}
+
+func (o *observedStore) GetName() store.StoreName {
+	return o.s.GetName()
+}
+
+func (o *observedStore) GetUser(params *store.GetUserParams) (*store.User, error) {
+	done := o.observe("get_user", &params.Ctx)
+	data, err := o.s.GetUser(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) CheckMagnet(params *store.CheckMagnetParams) (*store.CheckMagnetData, error) {
+	done := o.observe("check_magnet", &params.Ctx)
+	data, err := o.s.CheckMagnet(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) AddMagnet(params *store.AddMagnetParams) (*store.AddMagnetData, error) {
+	done := o.observe("add_magnet", &params.Ctx)
+	data, err := o.s.AddMagnet(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) GetMagnet(params *store.GetMagnetParams) (*store.GetMagnetData, error) {
+	done := o.observe("get_magnet", &params.Ctx)
+	data, err := o.s.GetMagnet(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) ListMagnets(params *store.ListMagnetsParams) (*store.ListMagnetsData, error) {
+	done := o.observe("list_magnets", &params.Ctx)
+	data, err := o.s.ListMagnets(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) RemoveMagnet(params *store.RemoveMagnetParams) (*store.RemoveMagnetData, error) {
+	done := o.observe("remove_magnet", &params.Ctx)
+	data, err := o.s.RemoveMagnet(params)
+	done(err)
+	return data, err
+}
+
+func (o *observedStore) GenerateLink(params *store.GenerateLinkParams) (*store.GenerateLinkData, error) {
+	done := o.observe("generate_link", &params.Ctx)
+	data, err := o.s.GenerateLink(params)
+	done(err)
+	return data, err
+}
+
+type observedPoolKeyStore struct {
+	*observedStore
+	pks store.PoolKeyStore
+}
+
+func (o *observedPoolKeyStore) ValidatePoolKey(poolKey string) error {
+	start := time.Now()
+	err := o.pks.ValidatePoolKey(poolKey)
+	metrics.ObserveStoreRequest(o.name, "validate_pool_key", start, err)
+	return err
+}
+
+func observeStore(s store.Store) store.Store {
+	o := &observedStore{s: s, name: string(s.GetName())}
+	if pks, ok := s.(store.PoolKeyStore); ok {
+		return &observedPoolKeyStore{observedStore: o, pks: pks}
+	}
+	return o
+}
@@ -20,7 +20,7 @@ import (
 var DefaultHTTPClient = func() *http.Client {
 	transport := config.DefaultHTTPTransport.Clone()
 	return &http.Client{
-		Transport: transport,
+		Transport: config.TraceHTTPTransport(transport),
 		Timeout:   30 * time.Second,
 	}
 }()
@@ -282,7 +282,7 @@ func (c Client) ProxyResource(w http.ResponseWriter, r *http.Request, params *Pr
 	}
 	adjustClientIPHeader(params.Ctx, params.ClientIP, r)
 	w.Header().Del("Access-Control-Allow-Origin")
-	shared.ProxyResponse(w, r, params.BaseURL.JoinPath(path).String(), config.TUNNEL_TYPE_AUTO)
+	shared.ProxyResponse(w, r, params.BaseURL.JoinPath(path).String(), config.TUNNEL_TYPE_AUTO, nil, "")
 }
 
 func NormalizeManifestURL(manifestUrl string) (string, error) {
@@ -224,7 +224,7 @@ func getCatalogItems(s store.Store, storeToken string, clientIp string, idr *Par
 			if hasMore && offset >= max_fetch_list_items {
 				worker_queue.StoreCrawlerQueue.Queue(worker_queue.StoreCrawlerQueueItem{
 					StoreCode:  string(storeCode),
-					StoreToken: storeToken,
+					StoreToken: worker_queue.SecretToken(storeToken),
 				})
 				break
 			}
@@ -106,7 +106,8 @@ func (uderr *userDataError) Error() string {
 func (ud UserData) GetRequestContext(r *http.Request, idr *ParsedId) (*context.StoreContext, error) {
 	rCtx := server.GetReqCtx(r)
 	ctx := &context.StoreContext{
-		Log: rCtx.Log,
+		Context: r.Context(),
+		Log:     rCtx.Log,
 	}
 
 	storeToken := ud.StoreToken
@@ -0,0 +1,162 @@
+package stremio_torz
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	indexerHealthSampleSize       = 50
+	indexerHealthMinSamples       = 5
+	indexerHealthTimeoutFactor    = 1.5
+	indexerHealthMinTimeout       = 2 * time.Second
+	indexerHealthFailureThreshold = 3
+	indexerHealthBaseCooldown     = 1 * time.Minute
+	indexerHealthMaxCooldown      = 15 * time.Minute
+)
+
+var errIndexerSearchTimeout = errors.New("indexer search timed out")
+
+type indexerHealthState struct {
+	latencies []time.Duration // ring buffer of search latencies
+	next      int
+
+	consecutiveFailures int
+	cooldown            time.Duration
+	skipUntil           time.Time
+	probing             bool
+}
+
+func (s *indexerHealthState) p95() time.Duration {
+	if len(s.latencies) < indexerHealthMinSamples {
+		return 0
+	}
+	sorted := slices.Clone(s.latencies)
+	slices.Sort(sorted)
+	idx := (len(sorted)*95 + 99) / 100
+	return sorted[idx-1]
+}
+
+// indexerHealthTracker keeps track of the latency and failures of each
+// indexer, to derive per-indexer deadlines and skip the failing ones.
+//
+// After indexerHealthFailureThreshold consecutive failures an indexer is
+// skipped for a cooldown. Once the cooldown is over, a single probe search is
+// let through: a success brings the indexer back, a failure doubles the
+// cooldown.
+type indexerHealthTracker struct {
+	mu         sync.Mutex
+	stateById  map[string]*indexerHealthState
+	maxTimeout time.Duration
+	now        func() time.Time
+}
+
+func newIndexerHealthTracker(maxTimeout time.Duration) *indexerHealthTracker {
+	return &indexerHealthTracker{
+		stateById:  map[string]*indexerHealthState{},
+		maxTimeout: maxTimeout,
+		now:        time.Now,
+	}
+}
+
+func (t *indexerHealthTracker) getState(id string) *indexerHealthState {
+	s, ok := t.stateById[id]
+	if !ok {
+		s = &indexerHealthState{}
+		t.stateById[id] = s
+	}
+	return s
+}
+
+// IsSkipped reports whether the indexer is cooling down, without taking the
+// probe.
+func (t *indexerHealthTracker) IsSkipped(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getState(id)
+	if s.consecutiveFailures < indexerHealthFailureThreshold {
+		return false
+	}
+	return s.probing || t.now().Before(s.skipUntil)
+}
+
+// Allow reports whether the indexer should be searched now. While the
+// indexer is cooling down only one probe is allowed at a time, it must be
+// followed by Record or Release.
+func (t *indexerHealthTracker) Allow(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getState(id)
+	if s.consecutiveFailures < indexerHealthFailureThreshold {
+		return true
+	}
+	if s.probing || t.now().Before(s.skipUntil) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// GetTimeout returns the deadline for a search on the indexer, derived from
+// its observed p95 latency and capped at the configured max timeout.
+func (t *indexerHealthTracker) GetTimeout(id string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p95 := t.getState(id).p95()
+	if p95 == 0 {
+		return t.maxTimeout
+	}
+	timeout := time.Duration(float64(p95) * indexerHealthTimeoutFactor)
+	return min(max(timeout, indexerHealthMinTimeout), t.maxTimeout)
+}
+
+// Release gives up the probe without an outcome, e.g. the search was
+// cancelled.
+func (t *indexerHealthTracker) Release(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.getState(id).probing = false
+}
+
+func (t *indexerHealthTracker) Record(id string, duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.getState(id)
+	s.probing = false
+
+	// timed out searches are sampled too, at the deadline they hit, so that
+	// the deadline can grow back for an indexer that got slower
+	if err == nil || errors.Is(err, errIndexerSearchTimeout) {
+		if len(s.latencies) < indexerHealthSampleSize {
+			s.latencies = append(s.latencies, duration)
+		} else {
+			s.latencies[s.next] = duration
+		}
+		s.next = (s.next + 1) % indexerHealthSampleSize
+	}
+
+	if err == nil {
+		s.consecutiveFailures = 0
+		s.cooldown = 0
+		s.skipUntil = time.Time{}
+		return
+	}
+
+	s.consecutiveFailures++
+	if s.consecutiveFailures < indexerHealthFailureThreshold {
+		return
+	}
+	if s.cooldown == 0 {
+		s.cooldown = indexerHealthBaseCooldown
+	} else {
+		s.cooldown = min(s.cooldown*2, indexerHealthMaxCooldown)
+	}
+	s.skipUntil = t.now().Add(s.cooldown)
+}
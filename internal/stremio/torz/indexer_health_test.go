@@ -0,0 +1,101 @@
+package stremio_torz
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tznc "github.com/MunifTanjim/stremthru/internal/torznab/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexerHealthTrackerTimeout(t *testing.T) {
+	tracker := newIndexerHealthTracker(10 * time.Second)
+
+	assert.Equal(t, 10*time.Second, tracker.GetTimeout("a"), "max timeout without samples")
+
+	for range indexerHealthMinSamples {
+		tracker.Record("a", 2*time.Second, nil)
+	}
+	assert.Equal(t, 3*time.Second, tracker.GetTimeout("a"))
+
+	for range indexerHealthMinSamples {
+		tracker.Record("b", 100*time.Millisecond, nil)
+	}
+	assert.Equal(t, indexerHealthMinTimeout, tracker.GetTimeout("b"))
+
+	for range indexerHealthMinSamples {
+		tracker.Record("c", 20*time.Second, nil)
+	}
+	assert.Equal(t, 10*time.Second, tracker.GetTimeout("c"))
+}
+
+func TestIndexerHealthTrackerSkip(t *testing.T) {
+	now := time.Now()
+	tracker := newIndexerHealthTracker(10 * time.Second)
+	tracker.now = func() time.Time { return now }
+
+	err := errors.New("boom")
+	for range indexerHealthFailureThreshold - 1 {
+		tracker.Record("a", time.Second, err)
+		assert.True(t, tracker.Allow("a"))
+	}
+
+	tracker.Record("a", time.Second, err)
+	assert.False(t, tracker.Allow("a"), "skipped after threshold")
+
+	now = now.Add(indexerHealthBaseCooldown)
+	assert.True(t, tracker.Allow("a"), "probe after cooldown")
+	assert.False(t, tracker.Allow("a"), "single probe at a time")
+
+	tracker.Record("a", time.Second, err)
+	now = now.Add(indexerHealthBaseCooldown)
+	assert.False(t, tracker.Allow("a"), "cooldown doubled after failed probe")
+	now = now.Add(indexerHealthBaseCooldown)
+	assert.True(t, tracker.Allow("a"))
+
+	tracker.Record("a", time.Second, nil)
+	assert.True(t, tracker.Allow("a"), "back after successful probe")
+	assert.True(t, tracker.Allow("a"))
+}
+
+func TestIndexerHealthTrackerRelease(t *testing.T) {
+	now := time.Now()
+	tracker := newIndexerHealthTracker(10 * time.Second)
+	tracker.now = func() time.Time { return now }
+
+	err := errors.New("boom")
+	for range indexerHealthFailureThreshold {
+		tracker.Record("a", time.Second, err)
+	}
+	assert.True(t, tracker.IsSkipped("a"))
+
+	now = now.Add(indexerHealthBaseCooldown)
+	assert.False(t, tracker.IsSkipped("a"), "cooldown over")
+	assert.True(t, tracker.Allow("a"), "probe after cooldown")
+	assert.True(t, tracker.IsSkipped("a"), "probing")
+
+	tracker.Release("a")
+	assert.False(t, tracker.IsSkipped("a"), "probe released without outcome")
+	assert.True(t, tracker.Allow("a"), "probe again")
+}
+
+type testIndexer struct {
+	tznc.Indexer
+	id      string
+	baseURL string
+}
+
+func (i testIndexer) GetId() string {
+	return i.id
+}
+
+func (i testIndexer) GetBaseURL() string {
+	return i.baseURL
+}
+
+func TestGetIndexerHealthKey(t *testing.T) {
+	a := testIndexer{id: "prowlarr/1", baseURL: "a.example.com/1/api"}
+	b := testIndexer{id: "prowlarr/1", baseURL: "b.example.com/1/api"}
+	assert.NotEqual(t, getIndexerHealthKey(a), getIndexerHealthKey(b))
+}
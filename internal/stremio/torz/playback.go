@@ -90,6 +90,7 @@ func handleStrem(w http.ResponseWriter, r *http.Request) {
 			ClientIP: ctx.ClientIP,
 		}
 		amParams.APIKey = ctx.StoreAuthToken
+		amParams.Context = ctx.Context
 		if encodedLink == "" {
 			amParams.Magnet = magnetHash
 		} else {
@@ -240,6 +241,11 @@ func handleStrem(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		ctx.ChillstreamsUsage = s.GetChillstreamsUsage(magnet.Hash, amRes.Status == store.MagnetStatusDownloaded, file.Size)
+		ctx.ChillstreamsDeviceId = s.DeviceID
+		ctx.FileHash = magnet.Hash
+		ctx.FileIdx = file.Idx
+
 		glRes, err := shared.GenerateStremThruLink(r, ctx.StoreContext, link)
 		if err != nil {
 			return &stremResult{
@@ -251,6 +257,10 @@ func handleStrem(w http.ResponseWriter, r *http.Request) {
 
 		stremLinkCache.Add(cacheKey, glRes.Link)
 
+		if ctx.ChillstreamsUsage != nil {
+			s.RecordChillstreamsStream(ctx.Log)
+		}
+
 		return &stremResult{
 			link: glRes.Link,
 		}, nil
@@ -3,6 +3,7 @@ package stremio_torz
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"net/url"
 	"slices"
@@ -16,43 +17,42 @@ import (
 	"github.com/MunifTanjim/stremthru/internal/buddy"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/imdb_title"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
 	"github.com/MunifTanjim/stremthru/internal/prowlarr"
 	"github.com/MunifTanjim/stremthru/internal/shared"
 	stremio_shared "github.com/MunifTanjim/stremthru/internal/stremio/shared"
 	stremio_transformer "github.com/MunifTanjim/stremthru/internal/stremio/transformer"
-	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
 	"github.com/MunifTanjim/stremthru/internal/torrent_info"
 	"github.com/MunifTanjim/stremthru/internal/torrent_stream"
 	tznc "github.com/MunifTanjim/stremthru/internal/torznab/client"
 	"github.com/MunifTanjim/stremthru/internal/torznab/jackett"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
 	"github.com/MunifTanjim/stremthru/internal/util"
 	"github.com/MunifTanjim/stremthru/store"
 	"github.com/MunifTanjim/stremthru/stremio"
 	"github.com/alitto/pond/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var streamTemplate = stremio_transformer.StreamTemplateDefault
 var torzLazyPull = config.Stremio.Torz.LazyPull
 
 // logIndexerSearch logs an indexer search to the database for performance tracking
-func logIndexerSearch(indexerID, query string, duration time.Duration, httpStatus, resultsCount int, wasSuccessful bool, errorType, errorMsg string) {
-	if stremio_userdata.IndexerDB == nil {
-		log.Debug("IndexerDB is nil, skipping prowlarr logging", "indexerId", indexerID)
+func logIndexerSearch(indexer tznc.Indexer, query string, duration time.Duration, httpStatus, resultsCount int, wasSuccessful bool, errorType, errorMsg string) {
+	indexerID := indexer.GetId()
+	if !prowlarr.IsSearchLogEnabled() {
 		return
 	}
 
-	// Extract indexer name from ID (e.g., "prowlarr/all" -> "Prowlarr (All)" or "yts" -> "YTS")
+	// Prefer the indexer's own name (e.g. Prowlarr indexers), otherwise
+	// extract it from ID (e.g., "jackett/yts" -> "YTS" or "yts" -> "YTS")
 	displayName := indexerID
-	if strings.Contains(indexerID, "/") {
+	if named, ok := indexer.(interface{ GetName() string }); ok && named.GetName() != "" {
+		displayName = named.GetName()
+	} else if strings.Contains(indexerID, "/") {
 		parts := strings.Split(indexerID, "/")
 		if len(parts) >= 2 {
-			// Handle Prowlarr format: "prowlarr/all" -> "Prowlarr (All)"
-			subName := strings.ToLower(parts[1])
-			if subName == "all" {
-				displayName = "Prowlarr (All)"
-			} else {
-				displayName = strings.ToUpper(parts[1])
-			}
+			displayName = strings.ToUpper(parts[1])
 		}
 	} else {
 		// For direct indexer IDs, normalize them
@@ -72,17 +72,7 @@ func logIndexerSearch(indexerID, query string, duration time.Duration, httpStatu
 		}
 	}
 
-	log.Info("logging indexer search",
-		"indexerId", indexerID,
-		"displayName", displayName,
-		"duration", duration.Milliseconds(),
-		"results", resultsCount,
-		"success", wasSuccessful)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err := prowlarr.LogSearchToDatabase(ctx, stremio_userdata.IndexerDB, prowlarr.SearchLogParams{
+	prowlarr.RecordSearch(prowlarr.SearchLogParams{
 		IndexerName:   displayName,
 		SearchQuery:   query,
 		SearchType:    "torrent",
@@ -93,12 +83,6 @@ func logIndexerSearch(indexerID, query string, duration time.Duration, httpStatu
 		ErrorType:     errorType,
 		ErrorMessage:  errorMsg,
 	})
-
-	if err != nil {
-		log.Error("failed to log indexer search to database", "error", err, "indexerName", displayName)
-	} else {
-		log.Info("successfully logged indexer search to database", "indexerName", displayName, "results", resultsCount)
-	}
 }
 
 type WrappedStream struct {
@@ -128,9 +112,10 @@ func (s WrappedStream) GetHDR() string {
 }
 
 type indexerSearchQueryMeta struct {
-	titles     []string
-	year       int
-	season, ep int
+	titles         []string
+	year           int
+	season, ep     int
+	tmdbId, tvdbId string
 }
 
 func (m *indexerSearchQueryMeta) MatchesTitle(parsedTitle string, normalizer *util.StringNormalizer) bool {
@@ -150,9 +135,23 @@ type indexerSearchQuery struct {
 
 var torrentFetchPool = pond.NewPool(20)
 
+var indexerHealth = newIndexerHealthTracker(config.Stremio.Torz.IndexerMaxTimeout)
+
+// getIndexerHealthKey identifies the indexer across the configured indexer
+// managers, the indexer ids are only unique within one.
+func getIndexerHealthKey(indexer tznc.Indexer) string {
+	if i, ok := indexer.(interface{ GetBaseURL() string }); ok {
+		return i.GetBaseURL() + "|" + indexer.GetId()
+	}
+	return indexer.GetId()
+}
+
 func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]WrappedStream, []string, error) {
 	log = ctx.Log
 
+	traceCtx, span := tracing.Start(ctx.Context, "torz.GetStreamsFromIndexers", attribute.String("strem.id", stremId))
+	defer span.End()
+
 	log.Info("GetStreamsFromIndexers called",
 		"indexerCount", len(ctx.Indexers),
 		"stremType", stremType,
@@ -163,7 +162,6 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 		return []WrappedStream{}, []string{}, nil
 	}
 
-
 	nsid, err := torrent_stream.NormalizeStreamId(stremId)
 	if err != nil {
 		return nil, nil, err
@@ -200,7 +198,9 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 			}
 		}
 	} else {
-		it, err := imdb_title.Get(nsid.Id)
+		it, err := tracing.DB(traceCtx, "imdb_title.Get", func() (*imdb_title.IMDBTitle, error) {
+			return imdb_title.Get(nsid.Id)
+		})
 		if err != nil {
 			return nil, nil, err
 		}
@@ -218,11 +218,27 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 		if it.OrigTitle != "" && it.OrigTitle != it.Title {
 			queryMeta.titles = append(queryMeta.titles, it.OrigTitle)
 		}
+		if tmdbIds, err := imdb_title.GetTMDBIdByIMDBId([]string{nsid.Id}); err != nil {
+			log.Warn("failed to get tmdb id", "error", err, "imdbId", nsid.Id)
+		} else if id := tmdbIds[nsid.Id]; id != "" && id != "0" {
+			queryMeta.tmdbId = id
+		}
+		if tvdbIds, err := imdb_title.GetTVDBIdByIMDBId([]string{nsid.Id}); err != nil {
+			log.Warn("failed to get tvdb id", "error", err, "imdbId", nsid.Id)
+		} else if id := tvdbIds[nsid.Id]; id != "" && id != "0" {
+			queryMeta.tvdbId = id
+		}
 	}
 
 	sQueries := make([]indexerSearchQuery, 0, len(ctx.Indexers)*2)
 	for i := range ctx.Indexers {
 		indexer := ctx.Indexers[i]
+		healthKey := getIndexerHealthKey(indexer)
+
+		if indexerHealth.IsSkipped(healthKey) {
+			log.Debug("skipping unhealthy indexer", "indexer", indexer.GetId())
+			continue
+		}
 
 		query, err := indexer.NewSearchQuery(func(caps tznc.Caps) tznc.Function {
 			if nsid.IsSeries() && caps.SupportsFunction(tznc.FunctionSearchTV) {
@@ -235,11 +251,24 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 		})
 		if err != nil {
 			log.Error("failed to create search query", "error", err, "indexer", indexer.GetId())
+			indexerHealth.Record(healthKey, 0, err)
+			metrics.ObserveIndexerSearch(indexer.GetId(), 0, err)
 			continue
 		}
 		query.SetLimit(-1)
-		if !nsid.IsAnime && query.IsSupported(tznc.SearchParamIMDBId) {
-			query.Set(tznc.SearchParamIMDBId, nsid.Id)
+		supportsIMDBId := query.IsSupported(tznc.SearchParamIMDBId)
+		supportsTMDBId := queryMeta.tmdbId != "" && query.IsSupported(tznc.SearchParamTMDBId)
+		supportsTVDBId := queryMeta.tvdbId != "" && query.IsSupported(tznc.SearchParamTVDBId)
+		if !nsid.IsAnime && (supportsIMDBId || supportsTMDBId || supportsTVDBId) {
+			if supportsIMDBId {
+				query.Set(tznc.SearchParamIMDBId, nsid.Id)
+			}
+			if supportsTMDBId {
+				query.Set(tznc.SearchParamTMDBId, queryMeta.tmdbId)
+			}
+			if supportsTVDBId {
+				query.Set(tznc.SearchParamTVDBId, queryMeta.tvdbId)
+			}
 			is_exact := !nsid.IsSeries()
 			if nsid.IsSeries() {
 				if query.IsSupported(tznc.SearchParamSeason) && nsid.Season != "" {
@@ -281,8 +310,8 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 							q.WriteString(util.ZeroPadInt(queryMeta.ep, 2))
 						}
 						sQueries = append(sQueries, indexerSearchQuery{
-						 indexer: indexer,
-						 query:   query.Clone().Set(tznc.SearchParamQ, q.String()),
+							indexer: indexer,
+							query:   query.Clone().Set(tznc.SearchParamQ, q.String()),
 						})
 					}
 				} else if queryMeta.year > 0 {
@@ -299,58 +328,126 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 		}
 	}
 
-	var wg sync.WaitGroup
+	// the probe of a cooling down indexer is only taken once its searches
+	// are about to run, every search records its outcome
+	allowedByHealthKey := map[string]bool{}
+	sQueries = slices.DeleteFunc(sQueries, func(sq indexerSearchQuery) bool {
+		healthKey := getIndexerHealthKey(sq.indexer)
+		allowed, seen := allowedByHealthKey[healthKey]
+		if !seen {
+			allowed = indexerHealth.Allow(healthKey)
+			allowedByHealthKey[healthKey] = allowed
+			if !allowed {
+				log.Debug("skipping unhealthy indexer", "indexer", sq.indexer.GetId())
+			}
+		}
+		return !allowed
+	})
+
+	type indexerSearchResult struct {
+		i        int
+		items    []tznc.Torz
+		err      error
+		duration time.Duration
+	}
+
+	resultQuota := config.Stremio.Torz.IndexerResultQuota
+	// the pending searches are cancelled once the result quota is met
+	searchesCtx, cancelSearches := context.WithCancel(traceCtx)
+	defer cancelSearches()
+	resultCh := make(chan indexerSearchResult, len(sQueries))
 	results := make([][]tznc.Torz, len(sQueries))
 	errs := make([]error, len(sQueries))
-	log.Info("TRACE: Starting indexer searches", "sQueryCount", len(sQueries))
 	for i := range sQueries {
-		wg.Add(1)
 		go func(sq indexerSearchQuery, i int) {
-			defer wg.Done()
-			log.Info("TRACE: Executing indexer search", "indexer", sq.indexer.GetId(), "index", i)
+			healthKey := getIndexerHealthKey(sq.indexer)
+			timeout := indexerHealth.GetTimeout(healthKey)
+			spanCtx, span := tracing.Start(searchesCtx, "indexer.search", attribute.String("indexer.id", sq.indexer.GetId()))
+			searchCtx, cancel := context.WithTimeout(spanCtx, timeout)
+			defer cancel()
+			done := make(chan indexerSearchResult, 1)
 			start := time.Now()
-			results[i], errs[i] = sq.indexer.Search(sq.query)
-			duration := time.Since(start)
+			go func() {
+				items, err := sq.indexer.Search(sq.query.WithContext(searchCtx))
+				done <- indexerSearchResult{i: i, items: items, err: err, duration: time.Since(start)}
+			}()
+
+			var res indexerSearchResult
+			select {
+			case res = <-done:
+			case <-searchCtx.Done():
+				// the search is cancelled with the context, its result is
+				// dropped into the buffered channel
+				res = indexerSearchResult{i: i, err: searchCtx.Err(), duration: time.Since(start)}
+			}
+			if res.err != nil && errors.Is(searchCtx.Err(), context.DeadlineExceeded) {
+				res = indexerSearchResult{i: i, err: errIndexerSearchTimeout, duration: timeout}
+			}
+			span.SetAttributes(attribute.Int("indexer.results", len(res.items)))
+			tracing.End(span, res.err)
+			if spanCtx.Err() != nil {
+				// the request is gone or the result quota is met, says
+				// nothing about the indexer
+				indexerHealth.Release(healthKey)
+				log.Debug("indexer search cancelled", "indexer", sq.indexer.GetId(), "query", sq.query.Encode(), "duration", res.duration.String())
+				resultCh <- res
+				return
+			}
+			indexerHealth.Record(healthKey, res.duration, res.err)
+			metrics.ObserveIndexerSearch(sq.indexer.GetId(), res.duration, res.err)
+
+			duration := res.duration
 
 			// Log per-indexer search performance
 			indexerName := sq.indexer.GetId()
 			resultCount := 0
-			if errs[i] == nil {
-				resultCount = len(results[i])
+			if res.err == nil {
+				resultCount = len(res.items)
 			}
 
-			log.Info("TRACE: Completed indexer search", "indexer", indexerName, "results", resultCount, "duration_ms", duration.Milliseconds())
-
-			// Import the userdata package to get access to the logger
 			log.Info("per-indexer search result",
 				"indexer", indexerName,
 				"stremId", stremId,
 				"results", resultCount,
 				"duration_ms", duration.Milliseconds(),
-				"error", errs[i])
+				"error", res.err)
 
-			if errs[i] == nil {
-				log.Debug("indexer search completed", "indexer", sq.indexer.GetId(), "query", sq.query.Encode(), "duration", duration.String(), "count", len(results[i]))
+			if res.err == nil {
+				log.Debug("indexer search completed", "indexer", sq.indexer.GetId(), "query", sq.query.Encode(), "duration", duration.String(), "count", len(res.items))
 
 				// Log successful Prowlarr search to database
-				if stremio_userdata.IndexerDB != nil {
-					go logIndexerSearch(sq.indexer.GetId(), sq.query.Encode(), duration, 200, len(results[i]), true, "", "")
+				if prowlarr.IsSearchLogEnabled() {
+					logIndexerSearch(sq.indexer, sq.query.Encode(), duration, 200, len(res.items), true, "", "")
 				}
 			} else {
-				log.Error("indexer search failed", "error", errs[i], "indexer", sq.indexer.GetId(), "query", sq.query.Encode(), "duration", duration.String())
+				log.Error("indexer search failed", "error", res.err, "indexer", sq.indexer.GetId(), "query", sq.query.Encode(), "duration", duration.String())
 
 				// Log failed Prowlarr search to database
-				if stremio_userdata.IndexerDB != nil {
+				if prowlarr.IsSearchLogEnabled() {
 					errorType := "http_error"
-					if duration > 30*time.Second {
+					var netErr net.Error
+					if errors.Is(res.err, errIndexerSearchTimeout) || errors.Is(res.err, context.DeadlineExceeded) || (errors.As(res.err, &netErr) && netErr.Timeout()) {
 						errorType = "timeout"
 					}
-					go logIndexerSearch(sq.indexer.GetId(), sq.query.Encode(), duration, 500, 0, false, errorType, errs[i].Error())
+					logIndexerSearch(sq.indexer, sq.query.Encode(), duration, 500, 0, false, errorType, res.err.Error())
 				}
 			}
+
+			resultCh <- res
 		}(sQueries[i], i)
 	}
-	wg.Wait()
+
+	resultCount := 0
+	for range sQueries {
+		res := <-resultCh
+		results[res.i], errs[res.i] = res.items, res.err
+		resultCount += len(res.items)
+		if resultQuota > 0 && resultCount >= resultQuota {
+			log.Debug("indexer result quota met, cancelling pending searches", "count", resultCount, "quota", resultQuota)
+			cancelSearches()
+			break
+		}
+	}
 
 	if len(results) == 0 && len(errs) > 0 {
 		return nil, nil, errors.Join(errs...)
@@ -396,12 +493,16 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 		}
 	}
 
-	tInfoByHash, err := torrent_info.GetByHashes(hashes)
+	tInfoByHash, err := tracing.DB(traceCtx, "torrent_info.GetByHashes", func() (map[string]torrent_info.TorrentInfo, error) {
+		return torrent_info.GetByHashes(hashes)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	filesByHashes, err := torrent_stream.GetFilesByHashes(hashes)
+	filesByHashes, err := tracing.DB(traceCtx, "torrent_stream.GetFilesByHashes", func() (map[string]torrent_stream.Files, error) {
+		return torrent_stream.GetFilesByHashes(hashes)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -611,13 +712,17 @@ func GetStreamsFromIndexers(ctx *RequestContext, stremType, stremId string) ([]W
 	return wrappedStreams, hashes, nil
 }
 
-func GetStreamsForHashes(stremType, stremId string, hashes []string, nsid *torrent_stream.NormalizedStremId) ([]WrappedStream, error) {
-	tInfoByHash, err := torrent_info.GetByHashes(hashes)
+func GetStreamsForHashes(ctx context.Context, stremType, stremId string, hashes []string, nsid *torrent_stream.NormalizedStremId) ([]WrappedStream, error) {
+	tInfoByHash, err := tracing.DB(ctx, "torrent_info.GetByHashes", func() (map[string]torrent_info.TorrentInfo, error) {
+		return torrent_info.GetByHashes(hashes)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	filesByHashes, err := torrent_stream.GetFilesByHashes(hashes)
+	filesByHashes, err := tracing.DB(ctx, "torrent_stream.GetFilesByHashes", func() (map[string]torrent_stream.Files, error) {
+		return torrent_stream.GetFilesByHashes(hashes)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -791,7 +896,9 @@ func handleStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashes, err := torrent_info.ListHashesByStremId(id)
+	hashes, err := tracing.DB(ctx.Context, "torrent_info.ListHashesByStremId", func() ([]string, error) {
+		return torrent_info.ListHashesByStremId(id)
+	})
 	if err != nil {
 		SendError(w, r, err)
 		return
@@ -816,7 +923,7 @@ func handleStream(w http.ResponseWriter, r *http.Request) {
 	var wrappedStreams []WrappedStream
 	var getStreamsError error
 	wg.Go(func() {
-		wrappedStreams, getStreamsError = GetStreamsForHashes(contentType, id, hashes, nsid)
+		wrappedStreams, getStreamsError = GetStreamsForHashes(ctx.Context, contentType, id, hashes, nsid)
 	})
 
 	var wrappedStreamsFromIndexers []WrappedStream
@@ -868,11 +975,13 @@ func handleStream(w http.ResponseWriter, r *http.Request) {
 	var hasErrByStoreCode map[string]struct{}
 	var checkMagnetError error
 	if !isP2P && len(hashes) > 0 {
-		cmRes := ud.CheckMagnet(&store.CheckMagnetParams{
+		cmParams := &store.CheckMagnetParams{
 			Magnets:  hashes,
 			ClientIP: ctx.ClientIP,
 			SId:      id,
-		}, ctx.Log)
+		}
+		cmParams.Context = ctx.Context
+		cmRes := ud.CheckMagnet(cmParams, ctx.Log)
 		if cmRes.HasErr && len(cmRes.ByHash) == 0 {
 			checkMagnetError = errors.Join(cmRes.Err...)
 		} else {
@@ -138,7 +138,8 @@ func (ud *UserData) GetRequestContext(r *http.Request) (*RequestContext, error)
 	rCtx := server.GetReqCtx(r)
 	ctx := &RequestContext{
 		StoreContext: &context.StoreContext{
-			Log: rCtx.Log,
+			Context: r.Context(),
+			Log:     rCtx.Log,
 		},
 	}
 
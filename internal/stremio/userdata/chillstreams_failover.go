@@ -0,0 +1,277 @@
+package stremio_userdata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/shared"
+	"github.com/MunifTanjim/stremthru/store"
+	"golang.org/x/sync/singleflight"
+)
+
+var chillstreamsKeyBreaker = chillstreams.NewKeyCircuitBreaker()
+
+func init() {
+	shared.ResolvePoolKey = resolvePoolKey
+}
+
+// resolvePoolKey returns the current pool key leased for the device, used
+// for the proxied links created with a pool key that was rotated or failed
+// over since.
+func resolvePoolKey(r *http.Request, userId, deviceId, storeName string) (string, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	return LeasePoolKey(ctx, userId, deviceId, storeName)
+}
+
+// LeasePoolKey returns the pool key of the store leased for the device of
+// the user, skipping the keys whose circuit is open.
+func LeasePoolKey(ctx context.Context, userId, deviceId, storeName string) (string, error) {
+	leases := getChillstreamsPoolKeyLeases()
+	if leases == nil {
+		return "", errors.New("chillstreams not configured")
+	}
+
+	resp, err := requestPoolKey(ctx, leases, chillstreams.GetPoolKeyRequest{
+		UserID:   userId,
+		DeviceID: deviceId,
+		Action:   "init",
+		Store:    storeName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !resp.Allowed {
+		return "", errors.New("chillstreams pool key not allowed: " + resp.Message)
+	}
+	if resp.PoolKey == "" {
+		return "", errors.New("empty pool key received")
+	}
+	return resp.PoolKey, nil
+}
+
+// GetChillstreamsKeyHealthStats returns the pool key circuit breaker counters.
+func GetChillstreamsKeyHealthStats() chillstreams.KeyCircuitBreakerStats {
+	return chillstreamsKeyBreaker.Stats()
+}
+
+// requestPoolKey asks for the pool key lease of req, skipping keys whose
+// circuit is open.
+func requestPoolKey(ctx context.Context, leases *chillstreams.PoolKeyLeaseCache, req chillstreams.GetPoolKeyRequest) (*chillstreams.GetPoolKeyResponse, error) {
+	resp, err := leases.Get(ctx, req)
+	if err != nil || !resp.Allowed || !chillstreamsKeyBreaker.IsOpen(resp.PoolKeyID) {
+		return resp, err
+	}
+
+	leases.Invalidate(req)
+	req.Action = "failover"
+	req.ExcludePoolKeyIDs = chillstreamsKeyBreaker.GetOpenKeyIds()
+	resp, err = leases.Get(ctx, req)
+	if err != nil || !resp.Allowed {
+		return resp, err
+	}
+	if chillstreamsKeyBreaker.IsOpen(resp.PoolKeyID) {
+		leases.Invalidate(req)
+		return nil, errors.New("no healthy pool key available")
+	}
+	return resp, nil
+}
+
+// poolKeyFailoverStore wraps a store authenticated with a Chillstreams pool
+// key. When the store rejects the key, the key is reported to Chillstreams and
+// taken out of rotation, a replacement is requested and the call is retried
+// once with it.
+type poolKeyFailoverStore struct {
+	store.PoolKeyStore
+	rs       *resolvedStore
+	deviceId string
+	log      *logger.Logger
+
+	m          sync.Mutex
+	replacedBy map[string]string
+	failovers  singleflight.Group
+}
+
+func newPoolKeyFailoverStore(rs *resolvedStore, deviceId string, log *logger.Logger) store.Store {
+	pks, ok := rs.Store.(store.PoolKeyStore)
+	if !ok {
+		return rs.Store
+	}
+	return &poolKeyFailoverStore{
+		PoolKeyStore: pks,
+		rs:           rs,
+		deviceId:     deviceId,
+		log:          log,
+		replacedBy:   map[string]string{},
+	}
+}
+
+// resolveAPIKey returns the key to use in place of apiKey, following any
+// replacement made after a failover.
+func (s *poolKeyFailoverStore) resolveAPIKey(apiKey string) string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for range len(s.replacedBy) {
+		replacement, ok := s.replacedBy[apiKey]
+		if !ok {
+			break
+		}
+		apiKey = replacement
+	}
+	return apiKey
+}
+
+// failover swaps the failed pool key for a replacement. It returns the new key,
+// or an empty string if err is not a pool key failure or no replacement is
+// available. The concurrent failovers of the same key share the replacement.
+func (s *poolKeyFailoverStore) failover(failedAPIKey string, err error) string {
+	status, ok := chillstreams.GetKeyHealthStatus(err)
+	if !ok {
+		return ""
+	}
+
+	replacement, _, _ := s.failovers.Do(failedAPIKey, func() (any, error) {
+		return s.replace(failedAPIKey, status, err), nil
+	})
+	return replacement.(string)
+}
+
+// replace requests the replacement of the failed pool key. The lock is only
+// held to read and swap the key, the calls to Chillstreams and the store run
+// without it so that resolveAPIKey is not blocked.
+func (s *poolKeyFailoverStore) replace(failedAPIKey string, status chillstreams.KeyHealthStatus, err error) string {
+	s.m.Lock()
+	if replacement, ok := s.replacedBy[failedAPIKey]; ok {
+		s.m.Unlock()
+		return replacement
+	}
+	rs := s.rs
+	if rs.AuthToken != failedAPIKey {
+		s.m.Unlock()
+		return ""
+	}
+	userId := rs.ChillstreamsAuth
+	poolKeyId := rs.PoolKeyID
+	s.m.Unlock()
+
+	storeName := string(s.GetName())
+
+	until := chillstreamsKeyBreaker.Trip(poolKeyId, status)
+	s.log.Warn("chillstreams pool key failed", "error", err, "status", status, "userId", userId, "poolKeyId", poolKeyId, "store", storeName, "until", until)
+
+	client, leases := getChillstreamsClient(), getChillstreamsPoolKeyLeases()
+	if client == nil || leases == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.ReportKeyHealth(ctx, chillstreams.ReportKeyHealthRequest{
+		UserID:    userId,
+		PoolKeyID: poolKeyId,
+		Store:     storeName,
+		Status:    status,
+		Message:   err.Error(),
+	}); err != nil {
+		s.log.Error("failed to report chillstreams pool key health", "error", err, "poolKeyId", poolKeyId, "store", storeName)
+	}
+
+	resp, err := requestPoolKey(ctx, leases, chillstreams.GetPoolKeyRequest{
+		UserID:   userId,
+		DeviceID: s.deviceId,
+		Action:   "failover",
+		Store:    storeName,
+	})
+	if err != nil {
+		s.log.Error("failed to get replacement chillstreams pool key", "error", err, "poolKeyId", poolKeyId, "store", storeName)
+		return ""
+	}
+	if !resp.Allowed || resp.PoolKey == "" || resp.PoolKeyID == poolKeyId {
+		s.log.Warn("no replacement chillstreams pool key", "message", resp.Message, "poolKeyId", poolKeyId, "store", storeName)
+		return ""
+	}
+	if err := s.ValidatePoolKey(resp.PoolKey); err != nil {
+		s.log.Error("replacement chillstreams pool key rejected", "error", err, "poolKeyId", resp.PoolKeyID, "store", storeName)
+		return ""
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	if replacement, ok := s.replacedBy[failedAPIKey]; ok {
+		return replacement
+	}
+	if rs.AuthToken != failedAPIKey {
+		// swapped by someone else meanwhile
+		return rs.AuthToken
+	}
+	s.replacedBy[failedAPIKey] = resp.PoolKey
+	rs.AuthToken = resp.PoolKey
+	rs.PoolKeyID = resp.PoolKeyID
+
+	s.log.Info("chillstreams pool key replaced", "userId", userId, "poolKeyId", resp.PoolKeyID, "failedPoolKeyId", poolKeyId, "store", storeName)
+
+	return resp.PoolKey
+}
+
+func (s *poolKeyFailoverStore) GetUser(params *store.GetUserParams) (*store.User, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	return s.PoolKeyStore.GetUser(params)
+}
+
+func (s *poolKeyFailoverStore) CheckMagnet(params *store.CheckMagnetParams) (*store.CheckMagnetData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	data, err := s.PoolKeyStore.CheckMagnet(params)
+	if err != nil {
+		if apiKey := s.failover(params.APIKey, err); apiKey != "" {
+			params.APIKey = apiKey
+			return s.PoolKeyStore.CheckMagnet(params)
+		}
+	}
+	return data, err
+}
+
+func (s *poolKeyFailoverStore) AddMagnet(params *store.AddMagnetParams) (*store.AddMagnetData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	data, err := s.PoolKeyStore.AddMagnet(params)
+	if err != nil {
+		if apiKey := s.failover(params.APIKey, err); apiKey != "" {
+			params.APIKey = apiKey
+			return s.PoolKeyStore.AddMagnet(params)
+		}
+	}
+	return data, err
+}
+
+func (s *poolKeyFailoverStore) GetMagnet(params *store.GetMagnetParams) (*store.GetMagnetData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	return s.PoolKeyStore.GetMagnet(params)
+}
+
+func (s *poolKeyFailoverStore) ListMagnets(params *store.ListMagnetsParams) (*store.ListMagnetsData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	return s.PoolKeyStore.ListMagnets(params)
+}
+
+func (s *poolKeyFailoverStore) RemoveMagnet(params *store.RemoveMagnetParams) (*store.RemoveMagnetData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	return s.PoolKeyStore.RemoveMagnet(params)
+}
+
+func (s *poolKeyFailoverStore) GenerateLink(params *store.GenerateLinkParams) (*store.GenerateLinkData, error) {
+	params.APIKey = s.resolveAPIKey(params.APIKey)
+	data, err := s.PoolKeyStore.GenerateLink(params)
+	if err != nil {
+		if apiKey := s.failover(params.APIKey, err); apiKey != "" {
+			params.APIKey = apiKey
+			return s.PoolKeyStore.GenerateLink(params)
+		}
+	}
+	return data, err
+}
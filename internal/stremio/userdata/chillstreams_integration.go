@@ -2,30 +2,57 @@ package stremio_userdata
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/chillstreams"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/device"
 	"github.com/MunifTanjim/stremthru/internal/logger"
-	"github.com/MunifTanjim/stremthru/store/torbox"
+	"github.com/MunifTanjim/stremthru/store"
 )
 
 var chillstreamsClient *chillstreams.Client
-var chillstreamsClientInit bool
+var chillstreamsPoolKeyLeases *chillstreams.PoolKeyLeaseCache
+var chillstreamsClientInit sync.Once
 
-func getChillstreamsClient() *chillstreams.Client {
-	if !chillstreamsClientInit {
+func initChillstreamsClient() {
+	chillstreamsClientInit.Do(func() {
 		if config.ChillstreamsAPIKey != "" {
 			chillstreamsClient = chillstreams.NewClient(config.ChillstreamsAPIURL, config.ChillstreamsAPIKey)
+			chillstreamsPoolKeyLeases = chillstreams.NewPoolKeyLeaseCache(&chillstreams.PoolKeyLeaseCacheConfig{
+				Client:      chillstreamsClient,
+				DefaultTTL:  config.ChillstreamsPoolKeyLeaseTTL,
+				GracePeriod: config.ChillstreamsPoolKeyGracePeriod,
+			})
 		}
-		chillstreamsClientInit = true
-	}
+	})
+}
+
+func getChillstreamsClient() *chillstreams.Client {
+	initChillstreamsClient()
 	return chillstreamsClient
 }
 
+func getChillstreamsPoolKeyLeases() *chillstreams.PoolKeyLeaseCache {
+	initChillstreamsClient()
+	return chillstreamsPoolKeyLeases
+}
+
+// GetChillstreamsPoolKeyLeaseStats returns the pool key lease cache counters,
+// or nil if Chillstreams is not configured.
+func GetChillstreamsPoolKeyLeaseStats() *chillstreams.PoolKeyLeaseStats {
+	leases := getChillstreamsPoolKeyLeases()
+	if leases == nil {
+		return nil
+	}
+	stats := leases.Stats()
+	return &stats
+}
+
 // InitializeStoresWithChillstreams fetches pool keys from Chillstreams and injects them into stores
 func (ud *UserDataStores) InitializeStoresWithChillstreams(r *http.Request, log *logger.Logger) error {
 	// Log using the standard chillproxy logging pattern
@@ -36,15 +63,13 @@ func (ud *UserDataStores) InitializeStoresWithChillstreams(r *http.Request, log
 		return nil
 	}
 
-	client := getChillstreamsClient()
-	if client == nil {
+	leases := getChillstreamsPoolKeyLeases()
+	if leases == nil {
 		log.Debug("chillstreams client not initialized", "apiKeyEmpty", config.ChillstreamsAPIKey == "", "apiUrlEmpty", config.ChillstreamsAPIURL == "")
 		return nil // Chillstreams not configured, skip
 	}
 
 	log.Info("chillstreams client ready")
-	deviceID := device.GenerateDeviceID(r)
-	log.Debug("device id generated", "deviceId", deviceID)
 
 	storeCount := 0
 	for i := range ud.stores {
@@ -52,21 +77,31 @@ func (ud *UserDataStores) InitializeStoresWithChillstreams(r *http.Request, log
 		storeCount++
 
 		if s.ChillstreamsAuth == "" {
-			log.Debug("store skipped - no chillstreams auth", "store", s.Store.GetName(), "index", i)
+			log.Debug("store skipped - no chillstreams auth", "index", i)
 			continue // No Chillstreams auth for this store
 		}
 
-		log.Info("requesting chillstreams pool key", "userId", s.ChillstreamsAuth, "store", s.Store.GetName())
+		if s.Store == nil {
+			return errors.New("invalid userdata, invalid store")
+		}
 
-		// Fetch pool key from Chillstreams
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		defer cancel()
+		deviceID, err := device.ResolveDeviceID(r, s.ChillstreamsAuth, ud.Device)
+		if err != nil {
+			log.Warn("chillstreams device rejected", "error", err, "userId", s.ChillstreamsAuth)
+			return fmt.Errorf("chillstreams device rejected: %w", err)
+		}
 
-		resp, err := client.GetPoolKey(ctx, chillstreams.GetPoolKeyRequest{
+		log.Info("requesting chillstreams pool key", "userId", s.ChillstreamsAuth, "deviceId", deviceID, "store", s.Store.GetName())
+
+		// Fetch pool key lease, from cache when possible
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		resp, err := requestPoolKey(ctx, leases, chillstreams.GetPoolKeyRequest{
 			UserID:   s.ChillstreamsAuth,
 			DeviceID: deviceID,
 			Action:   "init",
+			Store:    string(s.Store.GetName()),
 		})
+		cancel()
 
 		if err != nil {
 			log.Error("failed to get chillstreams pool key", "error", err, "userId", s.ChillstreamsAuth)
@@ -78,91 +113,89 @@ func (ud *UserDataStores) InitializeStoresWithChillstreams(r *http.Request, log
 			return fmt.Errorf("authentication failed: %s", resp.Message)
 		}
 
-		// Store pool key ID for usage logging
-		s.PoolKeyID = resp.PoolKeyID
-
-		// Inject pool key into store client
-		if resp.PoolKey != "" {
-			log.Debug("pool key received from chillstreams", "poolKey", resp.PoolKey, "poolKeyLength", len(resp.PoolKey), "poolKeyId", resp.PoolKeyID)
-
-			switch client := s.Store.(type) {
-			case *torbox.StoreClient:
-				// Verify the pool key before injection
-				if len(resp.PoolKey) != 36 {
-					log.Error("invalid pool key format", "poolKey", resp.PoolKey, "expectedLength", 36, "actualLength", len(resp.PoolKey))
-					return fmt.Errorf("invalid pool key format received from chillstreams")
-				}
-
-				// CRITICAL: Set BOTH client API key AND AuthToken
-				// client.SetAPIKey affects the TorBox client's internal state
-				// s.AuthToken is used in CheckMagnet params
-				client.SetAPIKey(resp.PoolKey)
-				s.AuthToken = resp.PoolKey // ✅ This is used by CheckMagnet
-
-				// Verify the key was set correctly
-				actualKey := client.GetAPIKey()
-				log.Info("💛 torpool pool key injected", "userId", s.ChillstreamsAuth, "poolKeyId", resp.PoolKeyID, "deviceCount", resp.DeviceCount, "store", s.Store.GetName(), "keySet", actualKey == resp.PoolKey, "keyLength", len(actualKey), "authTokenSet", s.AuthToken == resp.PoolKey)
-
-				if actualKey != resp.PoolKey {
-					log.Error("API key mismatch after setting", "expected", resp.PoolKey, "actual", actualKey)
-					return fmt.Errorf("failed to set TorBox API key correctly")
-				}
-
-				if s.AuthToken != resp.PoolKey {
-					log.Error("AuthToken mismatch after setting", "expected", resp.PoolKey, "actual", s.AuthToken)
-					return fmt.Errorf("failed to set AuthToken correctly")
-				}
-			default:
-				log.Debug("chillstreams auth not supported for this store type", "store", s.Store.GetName())
-			}
-		} else {
+		if resp.PoolKey == "" {
 			log.Error("empty pool key received from chillstreams", "userId", s.ChillstreamsAuth, "poolKeyId", resp.PoolKeyID)
 			return fmt.Errorf("empty pool key received")
 		}
+
+		if err := s.injectPoolKey(resp.PoolKey, resp.PoolKeyID); err != nil {
+			log.Error("failed to inject chillstreams pool key", "error", err, "userId", s.ChillstreamsAuth, "poolKeyId", resp.PoolKeyID, "store", s.Store.GetName())
+			return fmt.Errorf("chillstreams pool key rejected: %w", err)
+		}
+		s.DeviceID = deviceID
+		s.Store = newPoolKeyFailoverStore(s, deviceID, log)
+
+		log.Info("chillstreams pool key injected", "userId", s.ChillstreamsAuth, "poolKeyId", resp.PoolKeyID, "deviceCount", resp.DeviceCount, "store", s.Store.GetName())
 	}
 
 	log.Debug("chillstreams initialization complete", "totalStores", storeCount)
 
+	return nil
+}
 
+// injectPoolKey validates poolKey against the store's credential format and
+// uses it as the auth token for every subsequent call to the store.
+func (s *resolvedStore) injectPoolKey(poolKey, poolKeyId string) error {
+	pks, ok := s.Store.(store.PoolKeyStore)
+	if !ok {
+		return errors.New("pool key not supported for store: " + string(s.Store.GetName()))
+	}
+	if err := pks.ValidatePoolKey(poolKey); err != nil {
+		return err
+	}
+	s.AuthToken = poolKey
+	s.PoolKeyID = poolKeyId
 	return nil
 }
 
-// LogChillstreamsUsage logs usage to Chillstreams for stores using Chillstreams auth
-func (ud *UserDataStores) LogChillstreamsUsage(hash string, cached bool, bytes int64) {
-	if !config.EnableChillstreamsAuth {
-		return
+// GetChillstreamsUsage returns the usage event to report for a stream served
+// with the store's pool key, or nil if the store is not using one. size is
+// reported as-is unless the stream goes through the content proxy, which
+// reports the bytes actually served instead.
+func (s *resolvedStore) GetChillstreamsUsage(hash string, cached bool, size int64) *chillstreams.LogUsageRequest {
+	if !config.EnableChillstreamsAuth || s.ChillstreamsAuth == "" || s.PoolKeyID == "" {
+		return nil
 	}
+	return &chillstreams.LogUsageRequest{
+		UserID:    s.ChillstreamsAuth,
+		PoolKeyID: s.PoolKeyID,
+		Action:    "stream-served",
+		Hash:      hash,
+		Cached:    cached,
+		Bytes:     size,
+	}
+}
 
-	client := getChillstreamsClient()
-	if client == nil {
+// RecordChillstreamsStream counts a stream served to the device the store's
+// pool key was requested for.
+func (s *resolvedStore) RecordChillstreamsStream(log *logger.Logger) {
+	if s.ChillstreamsAuth == "" || s.DeviceID == "" {
 		return
 	}
+	if err := device.RecordStream(s.ChillstreamsAuth, s.DeviceID); err != nil {
+		log.Warn("failed to record chillstreams device stream", "error", err, "userId", s.ChillstreamsAuth, "deviceId", s.DeviceID)
+	}
+}
 
-	for i := range ud.stores {
-		s := &ud.stores[i]
-		if s.ChillstreamsAuth == "" || s.PoolKeyID == "" {
-			continue
-		}
-
-		// Log usage asynchronously (fire and forget)
-		go func(userID, poolKeyID, hash string, cached bool, bytes int64) {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-
-			err := client.LogUsage(ctx, chillstreams.LogUsageRequest{
-				UserID:    userID,
-				PoolKeyID: poolKeyID,
-				Action:    "stream-served",
-				Hash:      hash,
-				Cached:    cached,
-				Bytes:     bytes,
-			})
+// RevokeChillstreamsDevice revokes the user's device and frees its slot in
+// the Chillstreams device limit. The device stays revoked even if freeing
+// the slot fails, the failure is only logged.
+func RevokeChillstreamsDevice(ctx context.Context, userId, deviceId string) error {
+	if err := device.Revoke(userId, deviceId); err != nil {
+		return err
+	}
 
-			if err != nil {
-				// Log error but don't fail the request
-				// (usage logging is non-critical)
-			}
-		}(s.ChillstreamsAuth, s.PoolKeyID, hash, cached, bytes)
+	client := getChillstreamsClient()
+	if client == nil {
+		return nil
 	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := client.ReleaseDevice(ctx, chillstreams.ReleaseDeviceRequest{
+		UserID:   userId,
+		DeviceID: deviceId,
+	}); err != nil {
+		log.Warn("failed to release revoked chillstreams device", "error", err, "user_id", userId, "device_id", deviceId)
+	}
+	return nil
 }
-
@@ -1,23 +1,33 @@
 package stremio_userdata
 
 import (
-	"database/sql"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/MunifTanjim/stremthru/core"
 	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/prowlarr"
+	"github.com/MunifTanjim/stremthru/internal/torznab"
 	torznab_client "github.com/MunifTanjim/stremthru/internal/torznab/client"
 	"github.com/MunifTanjim/stremthru/internal/torznab/jackett"
+	"github.com/MunifTanjim/stremthru/internal/util"
 )
 
 type IndexerName string
 
 const (
-	IndexerNameGeneric   IndexerName = "generic"
-	IndexerNameJackett   IndexerName = "jackett"
-	IndexerNameProwlarr  IndexerName = "prowlarr"
+	IndexerNameGeneric  IndexerName = "generic"
+	IndexerNameJackett  IndexerName = "jackett"
+	IndexerNameProwlarr IndexerName = "prowlarr"
 )
 
 type Indexer struct {
@@ -26,13 +36,7 @@ type Indexer struct {
 	APIKey string      `json:"ak,omitempty"`
 }
 
-// Global DB instance for indexer logging (set by main.go)
-var IndexerDB *sql.DB
-
-// InitializeIndexerDB sets the database connection for Prowlarr logging
-func InitializeIndexerDB(db *sql.DB) {
-	IndexerDB = db
-}
+var log = logger.Scoped("stremio/userdata")
 
 // type rawIndexer Indexer
 //
@@ -105,43 +109,183 @@ func (ud UserDataIndexers) StripSecrets() UserDataIndexers {
 	return ud
 }
 
+// the caches can be backed by redis, so the api key is not kept in plain
+// text in the cache key.
+func getIndexerCacheKey(baseURL, apiKey string, extra ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{baseURL, apiKey}, extra...), ":")))
+	return hex.EncodeToString(sum[:])
+}
+
 var jackettCache = cache.NewCache[*jackett.Client](&cache.CacheConfig{
 	Lifetime: 6 * time.Hour,
 	Name:     "stremio:userdata:indexers:jackett",
 })
 
-// prowlarrTorznabClient wraps torznab_client.Client to implement the Indexer interface
-type prowlarrTorznabClient struct {
-	*torznab_client.Client
-	id string
+// prowlarrIndexer is a single indexer configured in Prowlarr. Caps are read
+// from its native Torznab endpoint, searches go through Prowlarr's JSON API.
+type prowlarrIndexer struct {
+	torznab *torznab_client.Client
+	client  *prowlarr.Client
+	id      int
+	name    string
+	private bool
 }
 
-func (ptc *prowlarrTorznabClient) GetId() string {
-	return "prowlarr/" + ptc.id
+func (pi *prowlarrIndexer) GetId() string {
+	return "prowlarr/" + strconv.Itoa(pi.id)
+}
+
+func (pi *prowlarrIndexer) GetBaseURL() string {
+	return pi.torznab.GetBaseURL()
+}
+
+func (pi *prowlarrIndexer) GetName() string {
+	return pi.name
+}
+
+func (pi *prowlarrIndexer) NewSearchQuery(fn func(caps torznab_client.Caps) torznab_client.Function) (*torznab_client.Query, error) {
+	return pi.torznab.NewSearchQuery(fn)
+}
+
+func (pi *prowlarrIndexer) toSearchParams(query *torznab_client.Query) *prowlarr.SearchParams {
+	v := query.Values()
+	params := &prowlarr.SearchParams{
+		Query:      v.Get(torznab_client.SearchParamQ),
+		ImdbId:     v.Get(torznab_client.SearchParamIMDBId),
+		TmdbId:     v.Get(torznab_client.SearchParamTMDBId),
+		TvdbId:     v.Get(torznab_client.SearchParamTVDBId),
+		Season:     util.SafeParseInt(v.Get(torznab_client.SearchParamSeason), 0),
+		Episode:    util.SafeParseInt(v.Get(torznab_client.SearchParamEp), 0),
+		IndexerIds: []int{pi.id},
+		Categories: query.GetCat(),
+		Limit:      query.GetLimit(),
+		Offset:     query.GetOffset(),
+	}
+	if year := v.Get(torznab_client.SearchParamYear); year != "" && params.Query != "" {
+		params.Query += " " + year
+	}
+	switch query.GetT() {
+	case torznab_client.FunctionSearchMovie:
+		params.Type = prowlarr.SearchTypeMovie
+		if len(params.Categories) == 0 {
+			params.Categories = []int{torznab.CategoryMovies.ID}
+		}
+	case torznab_client.FunctionSearchTV:
+		params.Type = prowlarr.SearchTypeTV
+		if len(params.Categories) == 0 {
+			params.Categories = []int{torznab.CategoryTV.ID}
+		}
+	default:
+		params.Type = prowlarr.SearchTypeSearch
+	}
+	return params
 }
 
-func (ptc *prowlarrTorznabClient) Search(query *torznab_client.Query) ([]torznab_client.Torz, error) {
-	// Prowlarr uses the same Torznab protocol as Jackett
-	// We'll use jackett's search response type since Prowlarr implements the same protocol
-	params := &jackett.Ctx{}
-	q := query.Values()
-	params.Query = &q
+func (pi *prowlarrIndexer) Search(query *torznab_client.Query) ([]torznab_client.Torz, error) {
+	ctx, cancel := context.WithTimeout(query.Context(), prowlarr.IndexerTimeout)
+	defer cancel()
 
-	var resp torznab_client.Response[jackett.SearchResponse]
-	_, err := ptc.Client.Request("GET", "/api", params, &resp)
+	items, err := pi.client.Search(ctx, pi.toSearchParams(query))
 	if err != nil {
 		return nil, err
 	}
 
-	items := resp.Data.Channel.Items
 	result := make([]torznab_client.Torz, 0, len(items))
 	for i := range items {
 		item := &items[i]
-		result = append(result, *item.ToTorz())
+		if !item.IsTorrent() {
+			continue
+		}
+		t := torznab_client.Torz{
+			Indexer:  item.Indexer,
+			Hash:     strings.ToLower(item.InfoHash),
+			Title:    item.Title,
+			Size:     item.Size,
+			Seeders:  item.Seeders,
+			Leechers: item.Leechers,
+			Private:  pi.private,
+		}
+		if strings.HasPrefix(item.MagnetURL, "magnet:?") {
+			t.MagnetLink = item.MagnetURL
+		} else if strings.HasPrefix(item.DownloadURL, "magnet:?") {
+			t.MagnetLink = item.DownloadURL
+		} else if strings.HasPrefix(item.DownloadURL, "http") {
+			t.SourceLink = item.DownloadURL
+		}
+		if t.MagnetLink != "" && t.Hash == "" {
+			if m, err := core.ParseMagnetLink(t.MagnetLink); err == nil {
+				t.Hash = m.Hash
+			}
+		}
+		result = append(result, t)
 	}
 	return result, nil
 }
 
+var prowlarrIndexersCache = cache.NewCache[[]prowlarr.Indexer](&cache.CacheConfig{
+	Lifetime: 15 * time.Minute,
+	Name:     "stremio:userdata:indexers:prowlarr",
+})
+
+// indexers are kept around so that their caps stay cached
+var prowlarrIndexerCache = cache.NewLRUCache[*prowlarrIndexer](&cache.CacheConfig{
+	Lifetime: 6 * time.Hour,
+	Name:     "stremio:userdata:indexers:prowlarr:indexer",
+})
+
+func getProwlarrIndexers(baseURL, apiKey string) ([]prowlarr.Indexer, error) {
+	key := getIndexerCacheKey(baseURL, apiKey)
+	var indexers []prowlarr.Indexer
+	if prowlarrIndexersCache.Get(key, &indexers) {
+		return indexers, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), prowlarr.IndexerTimeout)
+	defer cancel()
+
+	items, err := prowlarr.NewClient(baseURL, apiKey).GetIndexers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexers = make([]prowlarr.Indexer, 0, len(items))
+	for i := range items {
+		item := &items[i]
+		if item.Enable && item.IsTorrent() {
+			indexers = append(indexers, *item)
+		}
+	}
+	if err := prowlarrIndexersCache.Add(key, indexers); err != nil {
+		return nil, err
+	}
+	return indexers, nil
+}
+
+func getProwlarrIndexer(baseURL, apiKey string, indexer *prowlarr.Indexer) *prowlarrIndexer {
+	key := getIndexerCacheKey(baseURL, apiKey, strconv.Itoa(indexer.Id))
+	var pi *prowlarrIndexer
+	if prowlarrIndexerCache.Get(key, &pi) && pi.name == indexer.Name {
+		return pi
+	}
+
+	httpClient := config.GetHTTPClient(config.TUNNEL_TYPE_AUTO)
+	httpClient.Timeout = prowlarr.IndexerTimeout
+
+	pi = &prowlarrIndexer{
+		torznab: torznab_client.NewClient(&torznab_client.ClientConfig{
+			BaseURL:    prowlarr.TorznabBaseURL(baseURL, indexer.Id),
+			HTTPClient: httpClient,
+			APIKey:     apiKey,
+		}),
+		client:  prowlarr.NewClient(baseURL, apiKey),
+		id:      indexer.Id,
+		name:    indexer.Name,
+		private: indexer.IsPrivate(),
+	}
+	prowlarrIndexerCache.Add(key, pi)
+	return pi
+}
+
 func (ud *UserDataIndexers) Compress() {
 	for i := range ud.Indexers {
 		indexer := &ud.Indexers[i]
@@ -171,7 +315,7 @@ func (ud *UserDataIndexers) Prepare() ([]torznab_client.Indexer, error) {
 				return indexers, err
 			}
 
-			key := u.BaseURL + ":" + apiKey
+			key := getIndexerCacheKey(u.BaseURL, apiKey)
 			var client *jackett.Client
 			if !jackettCache.Get(key, &client) {
 				client = jackett.NewClient(&jackett.ClientConfig{
@@ -187,21 +331,16 @@ func (ud *UserDataIndexers) Prepare() ([]torznab_client.Indexer, error) {
 			indexers = append(indexers, c)
 
 		case IndexerNameProwlarr:
-			// Prowlarr acts as a Torznab indexer aggregator
-			// Use the base URL + /api/v2.0/indexers/all/results/torznab as the endpoint
-			torznabURL := baseURL + "/api/v2.0/indexers/all/results/torznab"
-
-			tc := torznab_client.NewClient(&torznab_client.ClientConfig{
-				BaseURL: torznabURL,
-				APIKey:  apiKey,
-			})
-
-			// Wrap the torznab client to implement the Indexer interface
-			client := &prowlarrTorznabClient{
-				Client: tc,
-				id:     "all",
+			baseURL = strings.TrimRight(baseURL, "/")
+			items, err := getProwlarrIndexers(baseURL, apiKey)
+			if err != nil {
+				// an unreachable Prowlarr should not take down the other indexers
+				log.Warn("failed to list prowlarr indexers", "error", err, "url", baseURL)
+				continue
+			}
+			for i := range items {
+				indexers = append(indexers, getProwlarrIndexer(baseURL, apiKey, &items[i]))
 			}
-			indexers = append(indexers, client)
 
 		default:
 			return indexers, errors.New("unsupported indexer: " + string(indexer.Name))
@@ -209,5 +348,3 @@ func (ud *UserDataIndexers) Prepare() ([]torznab_client.Indexer, error) {
 	}
 	return indexers, nil
 }
-
-
@@ -34,6 +34,7 @@ type Store struct {
 
 type UserDataStores struct {
 	Stores           []Store         `json:"stores"`
+	Device           string          `json:"device,omitempty"` // Chillstreams device token
 	stores           []resolvedStore `json:"-"`
 	isStremThruStore bool            `json:"-"`
 	isP2P            bool            `json:"-"`
@@ -63,6 +64,7 @@ func (ud UserDataStores) StripSecrets() UserDataStores {
 		s := &ud.Stores[i]
 		s.Token = ""
 	}
+	ud.Device = ""
 	return ud
 }
 
@@ -166,6 +168,7 @@ type resolvedStore struct {
 	AuthToken        string
 	ChillstreamsAuth string // Chillstreams user UUID (if using auth)
 	PoolKeyID        string // Pool key ID for usage logging
+	DeviceID         string // Chillstreams device the pool key was requested for
 }
 
 type storesResult[T any] struct {
@@ -243,6 +246,7 @@ func (ud *UserDataStores) CheckMagnet(params *store.CheckMagnetParams, log *logg
 		SId:      params.SId,
 	}
 	cmParams.APIKey = firstStore.AuthToken
+	cmParams.Context = params.Context
 
 	// DEBUG: Log the API key being used for CheckMagnet
 	log.Info("🔑 CheckMagnet API key check", "authToken", firstStore.AuthToken, "authTokenLength", len(firstStore.AuthToken), "store", firstStore.Store.GetName())
@@ -289,6 +293,7 @@ func (ud *UserDataStores) CheckMagnet(params *store.CheckMagnetParams, log *logg
 				SId:      params.SId,
 			}
 			cmParams.APIKey = s.AuthToken
+			cmParams.Context = params.Context
 			cmRes, err := s.Store.CheckMagnet(cmParams)
 			storeCode := strings.ToUpper(string(s.Store.GetName().Code()))
 			if err != nil {
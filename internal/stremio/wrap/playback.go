@@ -196,6 +196,9 @@ func handleStrem(w http.ResponseWriter, r *http.Request) {
 			torrent_stream.TagStremId(magnet.Hash, file.Name, sid)
 		}
 
+		ctx.FileHash = magnet.Hash
+		ctx.FileIdx = file.Idx
+
 		glRes, err := shared.GenerateStremThruLink(r, ctx, link)
 		if err != nil {
 			return &stremResult{
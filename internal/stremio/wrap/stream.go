@@ -19,6 +19,7 @@ import (
 	stremio_transformer "github.com/MunifTanjim/stremthru/internal/stremio/transformer"
 	"github.com/MunifTanjim/stremthru/internal/torrent_info"
 	"github.com/MunifTanjim/stremthru/internal/torrent_stream"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
 	"github.com/MunifTanjim/stremthru/internal/worker"
 	"github.com/MunifTanjim/stremthru/store"
 	"github.com/MunifTanjim/stremthru/stremio"
@@ -75,7 +76,9 @@ func (ud UserData) fetchStream(ctx *context.StoreContext, r *http.Request, rType
 		chunkIdxOffset = 1
 		wg.Go(func() {
 
-			hashes, err := torrent_info.ListHashesByStremId(stremId)
+			hashes, err := tracing.DB(ctx.Context, "torrent_info.ListHashesByStremId", func() ([]string, error) {
+				return torrent_info.ListHashesByStremId(stremId)
+			})
 			if err != nil {
 				if errors.Is(err, torrent_stream.ErrUnsupportedStremId) {
 					return
@@ -89,7 +92,7 @@ func (ud UserData) fetchStream(ctx *context.StoreContext, r *http.Request, rType
 				return
 			}
 
-			streams, err := stremio_torz.GetStreamsForHashes(rType, stremId, hashes, nsid)
+			streams, err := stremio_torz.GetStreamsForHashes(ctx.Context, rType, stremId, hashes, nsid)
 			if err != nil {
 				errs[0] = err
 				return
@@ -0,0 +1,303 @@
+package sync_history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+const TableName = "sync_history"
+
+type LinkType string
+
+const (
+	LinkTypeStremioTrakt   LinkType = "stremio_trakt"
+	LinkTypeStremioStremio LinkType = "stremio_stremio"
+)
+
+type Target string
+
+const (
+	TargetStremio Target = "stremio"
+	TargetTrakt   Target = "trakt"
+)
+
+type Action string
+
+const (
+	ActionStremioLibraryUpdate Action = "stremio.library.update"
+	ActionTraktHistoryAdd      Action = "trakt.history.add"
+	ActionTraktWatchlistAdd    Action = "trakt.watchlist.add"
+	ActionTraktWatchlistRemove Action = "trakt.watchlist.remove"
+	ActionTraktPlaybackPause   Action = "trakt.playback.pause"
+	ActionTraktPlaybackRemove  Action = "trakt.playback.remove"
+)
+
+// Change is a single change applied by a sync run. Before is the state of
+// the item before the change, absent if the item did not exist. After is the
+// applied change.
+type Change struct {
+	Target          Target          `json:"target"`
+	TargetAccountId string          `json:"target_account_id"`
+	Action          Action          `json:"action"`
+	ItemId          string          `json:"item_id"`
+	Before          json.RawMessage `json:"before,omitempty"`
+	After           json.RawMessage `json:"after,omitempty"`
+}
+
+func NewChange(target Target, targetAccountId string, action Action, itemId string, before, after any) Change {
+	change := Change{
+		Target:          target,
+		TargetAccountId: targetAccountId,
+		Action:          action,
+		ItemId:          itemId,
+	}
+	if before != nil {
+		change.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		change.After, _ = json.Marshal(after)
+	}
+	return change
+}
+
+type Entry struct {
+	Id       int64
+	LinkType LinkType
+	LinkId   string
+	JobName  string
+	JobId    string
+	Change
+	RevertedAt db.Timestamp
+	CAt        db.Timestamp
+}
+
+var Column = struct {
+	Id              string
+	LinkType        string
+	LinkId          string
+	JobName         string
+	JobId           string
+	Target          string
+	TargetAccountId string
+	Action          string
+	ItemId          string
+	Before          string
+	After           string
+	RevertedAt      string
+	CAt             string
+}{
+	Id:              "id",
+	LinkType:        "link_type",
+	LinkId:          "link_id",
+	JobName:         "job_name",
+	JobId:           "job_id",
+	Target:          "target",
+	TargetAccountId: "target_account_id",
+	Action:          "action",
+	ItemId:          "item_id",
+	Before:          "before_data",
+	After:           "after_data",
+	RevertedAt:      "reverted_at",
+	CAt:             "cat",
+}
+
+var columns = []string{
+	Column.Id,
+	Column.LinkType,
+	Column.LinkId,
+	Column.JobName,
+	Column.JobId,
+	Column.Target,
+	Column.TargetAccountId,
+	Column.Action,
+	Column.ItemId,
+	Column.Before,
+	Column.After,
+	Column.RevertedAt,
+	Column.CAt,
+}
+
+func toNullString(data json.RawMessage) db.NullString {
+	return db.NullString{String: string(data)}
+}
+
+var query_insert_before_values = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES `,
+	TableName,
+	db.JoinColumnNames(
+		Column.LinkType,
+		Column.LinkId,
+		Column.JobName,
+		Column.JobId,
+		Column.Target,
+		Column.TargetAccountId,
+		Column.Action,
+		Column.ItemId,
+		Column.Before,
+		Column.After,
+	),
+)
+var query_insert_values_placeholder = "(" + util.RepeatJoin("?", 10, ",") + ")"
+
+func Record(linkType LinkType, linkId, jobName, jobId string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for cChanges := range slices.Chunk(changes, 100) {
+		query := query_insert_before_values + util.RepeatJoin(query_insert_values_placeholder, len(cChanges), ",")
+		args := make([]any, 0, len(cChanges)*10)
+		for i := range cChanges {
+			change := &cChanges[i]
+			args = append(args,
+				linkType,
+				linkId,
+				jobName,
+				jobId,
+				change.Target,
+				change.TargetAccountId,
+				change.Action,
+				change.ItemId,
+				toNullString(change.Before),
+				toNullString(change.After),
+			)
+		}
+		if _, err := db.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanEntries(rows *sql.Rows) ([]Entry, error) {
+	defer rows.Close()
+
+	items := []Entry{}
+	for rows.Next() {
+		item := Entry{}
+		var before, after db.NullString
+		if err := rows.Scan(
+			&item.Id,
+			&item.LinkType,
+			&item.LinkId,
+			&item.JobName,
+			&item.JobId,
+			&item.Target,
+			&item.TargetAccountId,
+			&item.Action,
+			&item.ItemId,
+			&before,
+			&after,
+			&item.RevertedAt,
+			&item.CAt,
+		); err != nil {
+			return nil, err
+		}
+		if !before.IsZero() && !before.Is("null") {
+			item.Before = json.RawMessage(before.String)
+		}
+		if !after.IsZero() && !after.Is("null") {
+			item.After = json.RawMessage(after.String)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+var query_get_by_link = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s = ? ORDER BY %s DESC LIMIT ?`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.LinkType,
+	Column.LinkId,
+	Column.Id,
+)
+
+// GetByLink returns the latest entries of the link, newest first.
+func GetByLink(linkType LinkType, linkId string, limit int) ([]Entry, error) {
+	rows, err := db.Query(query_get_by_link, linkType, linkId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEntries(rows)
+}
+
+var query_get_last_job = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s = ? AND %s = (SELECT %s FROM %s WHERE %s = ? AND %s = ? ORDER BY %s DESC LIMIT 1) ORDER BY %s DESC`,
+	strings.Join(columns, ", "),
+	TableName,
+	Column.LinkType,
+	Column.LinkId,
+	Column.JobId,
+	Column.JobId,
+	TableName,
+	Column.LinkType,
+	Column.LinkId,
+	Column.Id,
+	Column.Id,
+)
+
+// GetLastRun returns the entries of the last sync run of the link, newest
+// first.
+func GetLastRun(linkType LinkType, linkId string) ([]Entry, error) {
+	rows, err := db.Query(query_get_last_job, linkType, linkId, linkType, linkId)
+	if err != nil {
+		return nil, err
+	}
+	return scanEntries(rows)
+}
+
+var query_set_reverted_before_values = fmt.Sprintf(
+	`UPDATE %s SET %s = ? WHERE %s IN `,
+	TableName,
+	Column.RevertedAt,
+	Column.Id,
+)
+
+func SetReverted(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := query_set_reverted_before_values + "(" + util.RepeatJoin("?", len(ids), ",") + ")"
+	args := make([]any, 1+len(ids))
+	args[0] = db.Timestamp{Time: time.Now()}
+	for i, id := range ids {
+		args[i+1] = id
+	}
+	_, err := db.Exec(query, args...)
+	return err
+}
+
+var query_delete_by_link = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ? AND %s = ?`,
+	TableName,
+	Column.LinkType,
+	Column.LinkId,
+)
+
+func DeleteByLink(linkType LinkType, linkId string) error {
+	_, err := db.Exec(query_delete_by_link, linkType, linkId)
+	return err
+}
+
+var query_purge = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s < ?`,
+	TableName,
+	Column.CAt,
+)
+
+// Purge deletes the entries older than the retention period.
+func Purge(retention time.Duration) error {
+	_, err := db.Exec(query_purge, db.Timestamp{Time: time.Now().Add(-retention)})
+	return err
+}
@@ -3,6 +3,7 @@ package sync_stremio_trakt
 import (
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -38,15 +39,113 @@ func (d SyncDirection) ShouldSyncToStremio() bool {
 }
 
 func (d SyncDirection) IsDisabled() bool {
-	return d == SyncDirectionNone
+	return d == SyncDirectionNone || d == ""
+}
+
+// SyncRemovalPolicy decides what happens to an item removed on one side.
+type SyncRemovalPolicy string
+
+const (
+	// the item is kept on the other side
+	SyncRemovalPolicyKeep SyncRemovalPolicy = "keep"
+	// the item is removed from the other side too, unless it was changed
+	// there after the removal
+	SyncRemovalPolicyPropagate SyncRemovalPolicy = "propagate"
+)
+
+func (p SyncRemovalPolicy) IsValid() bool {
+	switch p {
+	case SyncRemovalPolicyKeep, SyncRemovalPolicyPropagate:
+		return true
+	}
+	return false
+}
+
+func (p SyncRemovalPolicy) ShouldPropagate() bool {
+	return p == SyncRemovalPolicyPropagate
 }
 
 type SyncConfigWatched struct {
 	Direction SyncDirection `json:"dir"`
 }
 
+// SyncConfigWatchlist syncs the Trakt watchlist with the not-yet-watched
+// items of the Stremio library.
+type SyncConfigWatchlist struct {
+	Direction SyncDirection     `json:"dir"`
+	OnRemove  SyncRemovalPolicy `json:"on_remove"`
+}
+
+// SyncConfigProgress syncs the in-progress playback position of the Stremio
+// library items with the Trakt playback progress.
+type SyncConfigProgress struct {
+	Direction SyncDirection     `json:"dir"`
+	OnRemove  SyncRemovalPolicy `json:"on_remove"`
+}
+
+// SyncConfigRatings syncs the Trakt ratings to Stremio. Stremio does not have
+// ratings, so the rated movies and shows are only added to the library.
+type SyncConfigRatings struct {
+	Direction SyncDirection `json:"dir"`
+}
+
 type SyncConfig struct {
-	Watched SyncConfigWatched `json:"watched"`
+	Watched   SyncConfigWatched   `json:"watched"`
+	Watchlist SyncConfigWatchlist `json:"watchlist"`
+	Progress  SyncConfigProgress  `json:"progress"`
+	Ratings   SyncConfigRatings   `json:"ratings"`
+}
+
+// Normalize fills in the defaults for the channels missing in the config,
+// i.e. the ones added after the link was created.
+func (sc *SyncConfig) Normalize() {
+	if sc.Watched.Direction == "" {
+		sc.Watched.Direction = SyncDirectionNone
+	}
+	if sc.Watchlist.Direction == "" {
+		sc.Watchlist.Direction = SyncDirectionNone
+	}
+	if sc.Watchlist.OnRemove == "" {
+		sc.Watchlist.OnRemove = SyncRemovalPolicyKeep
+	}
+	if sc.Progress.Direction == "" {
+		sc.Progress.Direction = SyncDirectionNone
+	}
+	if sc.Progress.OnRemove == "" {
+		sc.Progress.OnRemove = SyncRemovalPolicyKeep
+	}
+	if sc.Ratings.Direction == "" {
+		sc.Ratings.Direction = SyncDirectionNone
+	}
+}
+
+func (sc SyncConfig) Validate() error {
+	if !sc.Watched.Direction.IsValid() {
+		return errors.New("invalid watched sync direction")
+	}
+	if !sc.Watchlist.Direction.IsValid() {
+		return errors.New("invalid watchlist sync direction")
+	}
+	if !sc.Watchlist.OnRemove.IsValid() {
+		return errors.New("invalid watchlist removal policy")
+	}
+	if !sc.Progress.Direction.IsValid() {
+		return errors.New("invalid progress sync direction")
+	}
+	if !sc.Progress.OnRemove.IsValid() {
+		return errors.New("invalid progress removal policy")
+	}
+	if sc.Ratings.Direction != SyncDirectionNone && sc.Ratings.Direction != SyncDirectionTraktToStremio {
+		return errors.New("invalid ratings sync direction")
+	}
+	return nil
+}
+
+func (sc SyncConfig) IsDisabled() bool {
+	return sc.Watched.Direction.IsDisabled() &&
+		sc.Watchlist.Direction.IsDisabled() &&
+		sc.Progress.Direction.IsDisabled() &&
+		sc.Ratings.Direction.IsDisabled()
 }
 
 func (sc SyncConfig) Value() (driver.Value, error) {
@@ -54,15 +153,38 @@ func (sc SyncConfig) Value() (driver.Value, error) {
 }
 
 func (sc *SyncConfig) Scan(value any) error {
-	return db.JSONScan(value, sc)
+	if err := db.JSONScan(value, sc); err != nil {
+		return err
+	}
+	sc.Normalize()
+	return nil
 }
 
 type SyncStateWatched struct {
 	LastSyncedAt *time.Time `json:"last_synced_at"`
 }
 
+type SyncStateWatchlist struct {
+	LastSyncedAt *time.Time `json:"last_synced_at"`
+	// imdb ids in the Trakt watchlist at the last sync, to detect removals
+	Ids []string `json:"ids,omitempty"`
+}
+
+type SyncStateProgress struct {
+	LastSyncedAt *time.Time `json:"last_synced_at"`
+	// video ids with Trakt playback progress at the last sync, to detect removals
+	Ids []string `json:"ids,omitempty"`
+}
+
+type SyncStateRatings struct {
+	LastSyncedAt *time.Time `json:"last_synced_at"`
+}
+
 type SyncState struct {
-	Watched SyncStateWatched `json:"watched"`
+	Watched   SyncStateWatched   `json:"watched"`
+	Watchlist SyncStateWatchlist `json:"watchlist"`
+	Progress  SyncStateProgress  `json:"progress"`
+	Ratings   SyncStateRatings   `json:"ratings"`
 }
 
 func (ss SyncState) Value() (driver.Value, error) {
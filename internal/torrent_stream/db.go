@@ -107,6 +107,10 @@ func (arr Files) ToStoreMagnetFiles(hash string) []store.MagnetFile {
 			Size:      f.Size,
 			Source:    f.Source,
 			VideoHash: f.VideoHash,
+			ASId:      f.ASId,
+		}
+		if f.SId != "*" {
+			files[i].SId = f.SId
 		}
 		if !hasActualPath && strings.HasPrefix(f.Path, "/") {
 			hasActualPath = true
@@ -298,6 +302,14 @@ var query_get_file = fmt.Sprintf(
 	Column.SId,
 )
 
+var query_get_anime_file = fmt.Sprintf(
+	"SELECT %s, %s, %s FROM %s WHERE %s = ? AND %s = ?",
+	Column.Path, Column.Idx, Column.Size,
+	TableName,
+	Column.Hash,
+	Column.ASId,
+)
+
 func GetFile(hash string, sid string) (*File, error) {
 	if strings.HasPrefix(sid, "kitsu:") {
 		return getAnimeFileForKitsu(hash, sid)
@@ -305,7 +317,11 @@ func GetFile(hash string, sid string) (*File, error) {
 	if strings.HasPrefix(sid, "mal:") {
 		return getAnimeFileForMAL(hash, sid)
 	}
-	row := db.QueryRow(query_get_file, hash, sid)
+	query, arg := query_get_file, sid
+	if asid, ok := strings.CutPrefix(sid, "anidb:"); ok {
+		query, arg = query_get_anime_file, asid
+	}
+	row := db.QueryRow(query, hash, arg)
 	var file File
 	if err := row.Scan(&file.Path, &file.Idx, &file.Size); err != nil {
 		if err == sql.ErrNoRows {
@@ -588,6 +604,39 @@ func GetStremIdByHashes(hashes []string) (*url.Values, error) {
 	return byHash, nil
 }
 
+var query_list_recent_strem_ids = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s LIKE 'tt%%' AND %s > ? GROUP BY %s ORDER BY MAX(%s) DESC LIMIT ?`,
+	Column.SId,
+	TableName,
+	Column.SId,
+	Column.UAt,
+	Column.SId,
+	Column.UAt,
+)
+
+// ListRecentStremIds returns the imdb strem ids tagged after `since`, most
+// recent first.
+func ListRecentStremIds(since time.Time, limit int) ([]string, error) {
+	rows, err := db.Query(query_list_recent_strem_ids, db.Timestamp{Time: since}, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sids := []string{}
+	for rows.Next() {
+		var sid string
+		if err := rows.Scan(&sid); err != nil {
+			return nil, err
+		}
+		sids = append(sids, sid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sids, nil
+}
+
 type Stats struct {
 	TotalCount    int            `json:"total_count"`
 	CountBySource map[string]int `json:"count_by_source"`
@@ -0,0 +1,19 @@
+package torrent_stream
+
+import (
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesToStoreMagnetFiles(t *testing.T) {
+	files := Files{
+		{Path: "/Show/S01E05.mkv", Idx: 4, Size: 100, SId: "*", ASId: "69:5"},
+		{Path: "/Show/S01E06.mkv", Idx: 5, Size: 200, SId: "tt0388629:1:6"},
+	}
+	assert.Equal(t, []store.MagnetFile{
+		{Idx: 4, Path: "/Show/S01E05.mkv", Name: "S01E05.mkv", Size: 100, ASId: "69:5"},
+		{Idx: 5, Path: "/Show/S01E06.mkv", Name: "S01E06.mkv", Size: 200, SId: "tt0388629:1:6"},
+	}, files.ToStoreMagnetFiles("hash"))
+}
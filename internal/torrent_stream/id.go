@@ -42,6 +42,34 @@ func (nsid NormalizedStremId) ToClean() string {
 	return nsid.Id
 }
 
+// ToSId returns the canonical strem id, i.e. `tt<id>[:<season>[:<episode>]]`
+// for imdb and `anidb:<id>[:<episode>]` for anime.
+func (nsid NormalizedStremId) ToSId() string {
+	if nsid.IsAnime {
+		if nsid.Episode == "" {
+			return "anidb:" + nsid.Id
+		}
+		return "anidb:" + nsid.Id + ":" + nsid.Episode
+	}
+	sid := nsid.Id
+	if nsid.Season != "" {
+		sid += ":" + nsid.Season
+		if nsid.Episode != "" {
+			sid += ":" + nsid.Episode
+		}
+	}
+	return sid
+}
+
+// ToASId returns the value of the `asid` column for anime, i.e.
+// `<anidb_id>:<episode>`. It is empty for non-anime.
+func (nsid NormalizedStremId) ToASId() string {
+	if !nsid.IsAnime {
+		return ""
+	}
+	return nsid.Id + ":" + nsid.Episode
+}
+
 var normalizedStremIdCache = cache.NewLRUCache[NormalizedStremId](&cache.CacheConfig{
 	Lifetime: 60 * time.Second,
 	Name:     "normalized_strem_id",
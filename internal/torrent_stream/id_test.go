@@ -0,0 +1,25 @@
+package torrent_stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizedStremIdToSId(t *testing.T) {
+	for _, tc := range []struct {
+		nsid NormalizedStremId
+		sid  string
+		asid string
+	}{
+		{NormalizedStremId{Id: "tt0111161"}, "tt0111161", ""},
+		{NormalizedStremId{Id: "tt0903747", Season: "1", Episode: "2"}, "tt0903747:1:2", ""},
+		{NormalizedStremId{IsAnime: true, Id: "69", Season: "1", Episode: "5"}, "anidb:69:5", "69:5"},
+		{NormalizedStremId{IsAnime: true, Id: "5101"}, "anidb:5101", "5101:"},
+	} {
+		t.Run(tc.sid, func(t *testing.T) {
+			assert.Equal(t, tc.sid, tc.nsid.ToSId())
+			assert.Equal(t, tc.asid, tc.nsid.ToASId())
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package torznab
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/MunifTanjim/stremthru/internal/anidb"
+	"github.com/MunifTanjim/stremthru/internal/anime"
+	"github.com/MunifTanjim/stremthru/internal/torrent_info"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+// max anidb ids looked up for the title of the query, each of them is a
+// separate lookup for hashes.
+const animeTitleSearchLimit = 3
+
+type animeEpisode struct {
+	anidbId string
+	episode string
+}
+
+func (ae animeEpisode) stremId() string {
+	if ae.episode == "" {
+		return "anidb:" + ae.anidbId
+	}
+	return "anidb:" + ae.anidbId + ":" + ae.episode
+}
+
+// getAnimeEpisodesByAniDBIds uses the episode of the query as is, the
+// id maps do not have the episode mapping. So only the first season (or
+// absolute numbering) can be matched.
+func getAnimeEpisodesByAniDBIds(q Query, anidbIds []string) []animeEpisode {
+	episode := ""
+	if q.Season == "" || q.Season == "1" {
+		episode = q.Ep
+	} else if q.Ep != "" {
+		return nil
+	}
+	episodes := make([]animeEpisode, len(anidbIds))
+	for i, anidbId := range anidbIds {
+		episodes[i] = animeEpisode{anidbId: anidbId, episode: episode}
+	}
+	return episodes
+}
+
+func getAnimeEpisodesByTVDBId(q Query) ([]animeEpisode, error) {
+	maps, err := anidb.GetTVDBEpisodeMapsByTVDBId(q.TVDBId)
+	if err != nil {
+		return nil, err
+	}
+	if len(maps) == 0 {
+		anidbIds, err := anime.GetAniDBIdsByTVDBId(q.TVDBId)
+		if err != nil {
+			return nil, err
+		}
+		return getAnimeEpisodesByAniDBIds(q, anidbIds), nil
+	}
+
+	season := util.SafeParseInt(q.Season, -1)
+	if q.Ep == "" || season == -1 {
+		anidbIds := maps.GetAniDBIds(season)
+		episodes := make([]animeEpisode, len(anidbIds))
+		for i, anidbId := range anidbIds {
+			episodes[i] = animeEpisode{anidbId: anidbId}
+		}
+		return episodes, nil
+	}
+
+	anidbId, episode := maps.GetAniDBEpisode(season, util.SafeParseInt(q.Ep, -1))
+	if episode == -1 {
+		return nil, nil
+	}
+	return []animeEpisode{{anidbId: anidbId, episode: strconv.Itoa(episode)}}, nil
+}
+
+func getAnimeEpisodes(q Query) ([]animeEpisode, error) {
+	switch {
+	case q.TVDBId != "":
+		return getAnimeEpisodesByTVDBId(q)
+	case q.TMDBId != "":
+		anidbIds, err := anime.GetAniDBIdsByTMDBId(q.TMDBId)
+		if err != nil {
+			return nil, err
+		}
+		return getAnimeEpisodesByAniDBIds(q, anidbIds), nil
+	case q.Q != "":
+		var seasons []int
+		if season := util.SafeParseInt(q.Season, -1); season > 0 {
+			seasons = []int{season}
+		}
+		anidbIds, err := anidb.SearchIdsByTitle(q.Q, seasons, q.Year, animeTitleSearchLimit)
+		if err != nil {
+			return nil, err
+		}
+		episodes := make([]animeEpisode, len(anidbIds))
+		for i, anidbId := range anidbIds {
+			episodes[i] = animeEpisode{anidbId: anidbId, episode: q.Ep}
+		}
+		return episodes, nil
+	default:
+		return nil, nil
+	}
+}
+
+func searchAnime(q Query) ([]ResultItem, error) {
+	episodes, err := getAnimeEpisodes(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(episodes) == 0 {
+		log.Debug("no anidb ids found for query", "q", q.Q, "tvdbid", q.TVDBId, "tmdbid", q.TMDBId)
+		return []ResultItem{}, nil
+	}
+
+	hashes := []string{}
+	for _, episode := range episodes {
+		episodeHashes, err := torrent_info.ListHashesByStremId(episode.stremId())
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range episodeHashes {
+			if !slices.Contains(hashes, hash) {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+
+	tInfoByHash, err := torrent_info.GetByHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []ResultItem{}
+	for _, hash := range hashes {
+		tInfo, ok := tInfoByHash[hash]
+		if !ok || tInfo.Private || tInfo.Size == -1 {
+			continue
+		}
+		item := toResultItem(&tInfo, "")
+		if item.Category != CategoryMovies {
+			item.Category = CategoryTV_Anime
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,88 @@
+package torznab
+
+import (
+	"os"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/anidb"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m,
+		"20250522132245_create_table_anime_id_map",
+		"20250610135450_create_table_anidb_tvdb_episode_map",
+		"20251021234212_add_new_cols_to_anime_id_map",
+	))
+}
+
+func setupAnimeIds(t *testing.T) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, "anime_id_map", anidb.TVDBEpisodeMapTableName)
+
+	_, err := db.Exec(`INSERT INTO anime_id_map (type, anidb, mal, tvdb, tmdb) VALUES ('TV', '100', '1', '500', '900'), ('TV', '101', '2', '500', '900')`)
+	require.NoError(t, err)
+	require.NoError(t, anidb.UpsertTVDBEpisodeMaps([]anidb.AniDBTVDBEpisodeMap{
+		{AniDBId: "200", TVDBId: "600", AniDBSeason: 1, TVDBSeason: 1, Start: 1, End: 12},
+		{AniDBId: "201", TVDBId: "600", AniDBSeason: 1, TVDBSeason: 1, Start: 1, End: 12, Offset: 12},
+		{AniDBId: "202", TVDBId: "600", AniDBSeason: 1, TVDBSeason: 2},
+	}))
+}
+
+func getAnimeStremIds(t *testing.T, q Query) []string {
+	episodes, err := getAnimeEpisodes(q)
+	require.NoError(t, err)
+	stremIds := []string{}
+	for _, episode := range episodes {
+		stremIds = append(stremIds, episode.stremId())
+	}
+	return stremIds
+}
+
+func TestGetAnimeEpisodes(t *testing.T) {
+	setupAnimeIds(t)
+
+	for _, tc := range []struct {
+		name     string
+		q        Query
+		stremIds []string
+	}{
+		{"tvdb episode map", Query{TVDBId: "600", Season: "1", Ep: "15"}, []string{"anidb:201:3"}},
+		{"tvdb episode map unknown episode", Query{TVDBId: "600", Season: "1", Ep: "30"}, []string{}},
+		{"tvdb episode map season", Query{TVDBId: "600", Season: "2"}, []string{"anidb:202"}},
+		{"tvdb episode map all seasons", Query{TVDBId: "600"}, []string{"anidb:200", "anidb:201", "anidb:202"}},
+		{"tvdb id map", Query{TVDBId: "500", Season: "1", Ep: "2"}, []string{"anidb:100:2", "anidb:101:2"}},
+		{"tvdb id map later season", Query{TVDBId: "500", Season: "2", Ep: "2"}, []string{}},
+		{"tmdb id map", Query{TMDBId: "900", Ep: "3"}, []string{"anidb:100:3", "anidb:101:3"}},
+		{"unknown tmdb id", Query{TMDBId: "999"}, []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tc.stremIds, getAnimeStremIds(t, tc.q))
+		})
+	}
+}
+
+func TestShouldSearchAnime(t *testing.T) {
+	anime := []int{CategoryTV_Anime.ID}
+	for _, tc := range []struct {
+		name      string
+		q         Query
+		imdbIds   []string
+		itemCount int
+		result    bool
+	}{
+		{"id without imdb mapping", Query{TVDBId: "1"}, nil, 0, true},
+		{"id found by imdb", Query{TVDBId: "1", Categories: anime}, []string{"tt1"}, 5, false},
+		{"id not found by imdb", Query{TVDBId: "1", Categories: anime}, []string{"tt1"}, 0, true},
+		{"id not anime", Query{TVDBId: "1"}, []string{"tt1"}, 0, false},
+		{"title", Query{Q: "show", Categories: anime}, []string{"tt1"}, 5, true},
+		{"title not anime", Query{Q: "show"}, []string{"tt1"}, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.result, shouldSearchAnime(tc.q, tc.imdbIds, tc.itemCount))
+		})
+	}
+}
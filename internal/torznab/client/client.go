@@ -35,6 +35,11 @@ type Client struct {
 	caps *cache.CachedValue[Caps]
 }
 
+// GetBaseURL returns the base url without credentials.
+func (c *Client) GetBaseURL() string {
+	return c.BaseURL.Host + c.BaseURL.Path
+}
+
 func NewClient(conf *ClientConfig) *Client {
 	if conf.HTTPClient == nil {
 		conf.HTTPClient = config.GetHTTPClient(config.TUNNEL_TYPE_AUTO)
@@ -1,6 +1,8 @@
 package torznab_client
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
@@ -26,6 +28,13 @@ var torzFileCache = cache.NewCache[torzFileCached](&cache.CacheConfig{
 	LocalCapacity: 5120,
 })
 
+// source links can carry the api key of the indexer (e.g. prowlarr download
+// links), and the cache can be backed by redis.
+func getTorzFileCacheKey(sourceLink string) string {
+	sum := sha256.Sum256([]byte(sourceLink))
+	return hex.EncodeToString(sum[:])
+}
+
 type Torz struct {
 	Indexer string
 
@@ -56,8 +65,9 @@ func (t *Torz) EnsureMagnet() error {
 		return errors.New("no source link to generate magnet")
 	}
 
+	cacheKey := getTorzFileCacheKey(t.SourceLink)
 	cachedTorz := torzFileCached{}
-	if torzFileCache.Get(t.SourceLink, &cachedTorz) {
+	if torzFileCache.Get(cacheKey, &cachedTorz) {
 		t.Hash = cachedTorz.Hash
 		t.MagnetLink = cachedTorz.MagnetLink
 		if cachedTorz.Private {
@@ -89,7 +99,7 @@ func (t *Torz) EnsureMagnet() error {
 
 			cachedTorz.Hash = t.Hash
 			cachedTorz.MagnetLink = t.MagnetLink
-			torzFileCache.Add(t.SourceLink, cachedTorz)
+			torzFileCache.Add(cacheKey, cachedTorz)
 			return nil
 		}
 	}
@@ -125,7 +135,7 @@ func (t *Torz) EnsureMagnet() error {
 	cachedTorz.MagnetLink = t.MagnetLink
 	cachedTorz.Private = t.Private
 	cachedTorz.Files = t.Files
-	torzFileCache.Add(t.SourceLink, cachedTorz)
+	torzFileCache.Add(cacheKey, cachedTorz)
 
 	return nil
 }
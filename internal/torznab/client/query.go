@@ -1,6 +1,7 @@
 package torznab_client
 
 import (
+	"context"
 	"net/url"
 	"slices"
 	"strconv"
@@ -25,6 +26,7 @@ const (
 	SearchParamYear     SearchParam = "year"
 	SearchParamIMDBId   SearchParam = "imdbid"
 	SearchParamTVDBId   SearchParam = "tvdbid"
+	SearchParamTMDBId   SearchParam = "tmdbid"
 	SearchParamTVMazeId SearchParam = "tvmazeid"
 	SearchParamTraktId  SearchParam = "traktid"
 )
@@ -33,6 +35,20 @@ type Query struct {
 	caps   *Caps
 	t      Function
 	values url.Values
+	ctx    context.Context
+}
+
+func (q *Query) Context() context.Context {
+	if q.ctx == nil {
+		return context.Background()
+	}
+	return q.ctx
+}
+
+// WithContext returns a shallow copy of the query with its context changed to ctx.
+func (q Query) WithContext(ctx context.Context) *Query {
+	q.ctx = ctx
+	return &q
 }
 
 func (q Query) Clone() *Query {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -51,7 +52,13 @@ var lastMappedIMDBIdCached struct {
 func (sti stremThruIndexer) Search(q Query) ([]ResultItem, error) {
 	imdbIds := []string{}
 
-	if q.IMDBId == "" && q.Q == "" {
+	if q.IMDBId == "" && (q.TVDBId != "" || q.TMDBId != "") {
+		ids, err := getIMDBIdsByTVDBOrTMDBId(q)
+		if err != nil {
+			return nil, err
+		}
+		imdbIds = append(imdbIds, ids...)
+	} else if q.IMDBId == "" && q.Q == "" {
 		if lastMappedIMDBIdCached.staleAt.Before(time.Now()) {
 			imdbId, err := imdb_torrent.GetLastMappedIMDBId()
 			if err != nil {
@@ -83,6 +90,99 @@ func (sti stremThruIndexer) Search(q Query) ([]ResultItem, error) {
 		imdbIds = append(imdbIds, q.IMDBId)
 	}
 
+	items, err := searchByIMDBIds(q, imdbIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if shouldSearchAnime(q, imdbIds, len(items)) {
+		animeItems, err := searchAnime(q)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]struct{}, len(items))
+		for i := range items {
+			seen[items[i].InfoHash] = struct{}{}
+		}
+		for i := range animeItems {
+			if _, ok := seen[animeItems[i].InfoHash]; !ok {
+				items = append(items, animeItems[i])
+			}
+		}
+	}
+
+	if q.Offset > 0 {
+		items = items[min(q.Offset, len(items)):]
+	}
+
+	if q.Limit > 0 {
+		items = items[:min(q.Limit, len(items))]
+	}
+
+	return items, nil
+}
+
+// shouldSearchAnime reports whether the anime search (by anidb ids) should
+// run after the search by imdb ids found itemCount items.
+func shouldSearchAnime(q Query, imdbIds []string, itemCount int) bool {
+	isIdQuery := q.IMDBId != "" || q.TVDBId != "" || q.TMDBId != ""
+	// ids without imdb mapping are mostly anime
+	if isIdQuery && q.IMDBId == "" && len(imdbIds) == 0 {
+		return true
+	}
+	// the anime search is costly, it is skipped if the id was already found
+	if isIdQuery && itemCount > 0 {
+		return false
+	}
+	return q.HasAnime()
+}
+
+// getIMDBIdsByTVDBOrTMDBId resolves the tvdb / tmdb id of the query, as movie
+// or show based on the search type.
+func getIMDBIdsByTVDBOrTMDBId(q Query) ([]string, error) {
+	isMovie, isShow := q.Type == "movie", q.Type == "tvsearch"
+	if !isMovie && !isShow {
+		isMovie, isShow = q.HasMovies(), q.HasTVShows()
+		if !isMovie && !isShow {
+			isMovie, isShow = true, true
+		}
+	}
+
+	imdbIds := []string{}
+	for _, resolve := range []struct {
+		id  string
+		get func(movieIds, showIds []string) (map[string]string, map[string]string, error)
+	}{
+		{q.TVDBId, imdb_title.GetIMDBIdByTVDBId},
+		{q.TMDBId, imdb_title.GetIMDBIdByTMDBId},
+	} {
+		if resolve.id == "" {
+			continue
+		}
+		var movieIds, showIds []string
+		if isMovie {
+			movieIds = []string{resolve.id}
+		}
+		if isShow {
+			showIds = []string{resolve.id}
+		}
+		movieMap, showMap, err := resolve.get(movieIds, showIds)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range []map[string]string{showMap, movieMap} {
+			if imdbId := m[resolve.id]; imdbId != "" && !slices.Contains(imdbIds, imdbId) {
+				imdbIds = append(imdbIds, imdbId)
+			}
+		}
+	}
+	if len(imdbIds) == 0 {
+		log.Debug("no imdb ids found for ids", "tvdbid", q.TVDBId, "tmdbid", q.TMDBId)
+	}
+	return imdbIds, nil
+}
+
+func searchByIMDBIds(q Query, imdbIds []string) ([]ResultItem, error) {
 	if len(imdbIds) == 0 {
 		return []ResultItem{}, nil
 	}
@@ -229,48 +329,49 @@ func (sti stremThruIndexer) Search(q Query) ([]ResultItem, error) {
 		); err != nil {
 			return nil, err
 		}
-		var category Category
-		switch tInfo.Category {
-		case torrent_info.TorrentInfoCategoryMovie:
-			category = CategoryMovies
-		case torrent_info.TorrentInfoCategorySeries:
-			category = CategoryTV
-		case torrent_info.TorrentInfoCategoryXXX:
-			category = CategoryXXX
-		default:
-			category = CategoryOther
-		}
-		audio := strings.Join(tInfo.Audio, ", ")
-		if len(tInfo.Channels) > 0 {
-			audio += " | " + strings.Join(tInfo.Channels, ", ")
-		}
-		items = append(items, ResultItem{
-			Audio:       audio,
-			Category:    category,
-			Codec:       tInfo.Codec,
-			IMDB:        imdbId,
-			InfoHash:    tInfo.Hash,
-			Language:    strings.Join(tInfo.Languages, ", "),
-			Leechers:    tInfo.Leechers,
-			PublishDate: tInfo.CreatedAt.Time,
-			Resolution:  tInfo.Resolution,
-			Seeders:     tInfo.Seeders,
-			Site:        tInfo.Site,
-			Size:        tInfo.Size,
-			Title:       tInfo.TorrentTitle,
-			Year:        tInfo.Year,
-		})
+		items = append(items, toResultItem(&tInfo, imdbId))
 	}
-
-	if q.Offset > 0 {
-		items = items[min(q.Offset, len(items)):]
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	if q.Limit > 0 {
-		items = items[:min(q.Limit, len(items))]
+	return items, nil
+}
+
+func getCategory(tInfo *torrent_info.TorrentInfo) Category {
+	switch tInfo.Category {
+	case torrent_info.TorrentInfoCategoryMovie:
+		return CategoryMovies
+	case torrent_info.TorrentInfoCategorySeries:
+		return CategoryTV
+	case torrent_info.TorrentInfoCategoryXXX:
+		return CategoryXXX
+	default:
+		return CategoryOther
 	}
+}
 
-	return items, nil
+func toResultItem(tInfo *torrent_info.TorrentInfo, imdbId string) ResultItem {
+	audio := strings.Join(tInfo.Audio, ", ")
+	if len(tInfo.Channels) > 0 {
+		audio += " | " + strings.Join(tInfo.Channels, ", ")
+	}
+	return ResultItem{
+		Audio:       audio,
+		Category:    getCategory(tInfo),
+		Codec:       tInfo.Codec,
+		IMDB:        imdbId,
+		InfoHash:    tInfo.Hash,
+		Language:    strings.Join(tInfo.Languages, ", "),
+		Leechers:    tInfo.Leechers,
+		PublishDate: tInfo.CreatedAt.Time,
+		Resolution:  tInfo.Resolution,
+		Seeders:     tInfo.Seeders,
+		Site:        tInfo.Site,
+		Size:        tInfo.Size,
+		Title:       tInfo.TorrentTitle,
+		Year:        tInfo.Year,
+	}
 }
 
 func (sti stremThruIndexer) Download(urlStr string) (io.ReadCloser, http.Header, error) {
@@ -303,12 +404,12 @@ var StremThruIndexer = stremThruIndexer{
 			{
 				Name:            "tv-search",
 				Available:       true,
-				SupportedParams: []string{"q,imdbid,season,ep"},
+				SupportedParams: []string{"q,imdbid,tvdbid,tmdbid,season,ep"},
 			},
 			{
 				Name:            "movie-search",
 				Available:       true,
-				SupportedParams: []string{"q,imdbid"},
+				SupportedParams: []string{"q,imdbid,tmdbid"},
 			},
 		},
 		Categories: []CapsCategory{
@@ -317,6 +418,7 @@ var StremThruIndexer = stremThruIndexer{
 			},
 			{
 				Category: CategoryTV,
+				Subcat:   []Category{CategoryTV_Anime},
 			},
 		},
 	},
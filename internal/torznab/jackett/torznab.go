@@ -105,6 +105,7 @@ func (tc TorznabClient) GetId() string {
 
 func (tc TorznabClient) Search(query *torznab_client.Query) ([]torznab_client.Torz, error) {
 	params := &Ctx{}
+	params.Context = query.Context()
 	q := query.Values()
 	params.Query = &q
 	var resp torznab_client.Response[SearchResponse]
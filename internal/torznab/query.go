@@ -20,6 +20,7 @@ type Query struct {
 
 	// identifier types
 	TVDBId   string
+	TMDBId   string
 	TVRageId string
 	IMDBId   string
 	TVMazeId string
@@ -35,6 +36,15 @@ func (query Query) HasTVShows() bool {
 	return false
 }
 
+func (query Query) HasAnime() bool {
+	for _, cat := range query.Categories {
+		if cat == CategoryTV_Anime.ID {
+			return true
+		}
+	}
+	return false
+}
+
 func (query Query) HasMovies() bool {
 	for _, cat := range query.Categories {
 		if 2000 <= cat && cat < 3000 {
@@ -107,6 +117,10 @@ func (query Query) ToValues() *url.Values {
 		v.Set("tvdbid", query.TVDBId)
 	}
 
+	if query.TMDBId != "" {
+		v.Set("tmdbid", query.TMDBId)
+	}
+
 	if query.TVRageId != "" {
 		v.Set("rid", query.TVRageId)
 	}
@@ -220,6 +234,24 @@ func ParseQuery(q url.Values) (Query, error) {
 			if !strings.HasPrefix(query.IMDBId, "tt") {
 				query.IMDBId = "tt" + query.IMDBId
 			}
+
+		case "tvdbid":
+			if len(vals) > 1 {
+				return query, errors.New("Multiple tvdbid parameters not allowed")
+			}
+			if _, err := strconv.Atoi(vals[0]); err != nil {
+				return query, errors.New("Invalid tvdbid")
+			}
+			query.TVDBId = vals[0]
+
+		case "tmdbid":
+			if len(vals) > 1 {
+				return query, errors.New("Multiple tmdbid parameters not allowed")
+			}
+			if _, err := strconv.Atoi(vals[0]); err != nil {
+				return query, errors.New("Invalid tmdbid")
+			}
+			query.TMDBId = vals[0]
 		}
 	}
 
@@ -1,6 +1,7 @@
 package torznab
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,3 +17,17 @@ func TestQueryEncode(t *testing.T) {
 		assert.Equal(t, row.left.Encode(), row.right.Encode())
 	}
 }
+
+func TestParseQuery(t *testing.T) {
+	for _, query := range []Query{
+		{Type: "tvsearch", TVDBId: "81797", Season: "1", Ep: "2", Categories: []int{5070}},
+		{Type: "movie", TMDBId: "129", IMDBId: "tt0245429"},
+	} {
+		parsed, err := ParseQuery(*query.ToValues())
+		assert.NoError(t, err)
+		assert.Equal(t, query, parsed)
+	}
+
+	_, err := ParseQuery(url.Values{"t": {"tvsearch"}, "tvdbid": {"abc"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"context"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB runs the database lookup `fn` in a span. The `db` helpers do not take a
+// context, so the span is created around the call to the table package.
+func DB[T any](ctx context.Context, operation string, fn func() (T, error)) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := tracer.Start(
+		ctx,
+		"db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBOperationName(operation)),
+	)
+	data, err := fn()
+	End(span, err)
+	return data, err
+}
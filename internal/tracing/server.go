@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var requestIdKey = attribute.Key("stremthru.request.id")
+
+// StartServerSpan starts the span for the incoming request. The trace of
+// the caller is continued only if the `traceparent` header is trusted,
+// otherwise a new trace is started, linked to the caller's one, so that the
+// caller can not force the sampling.
+func StartServerSpan(r *http.Request, requestId string) (*http.Request, trace.Span) {
+	ctx := r.Context()
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			requestIdKey.String(requestId),
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.ClientAddress(r.RemoteAddr),
+			semconv.UserAgentOriginal(r.UserAgent()),
+		),
+	}
+	remoteCtx := otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+	if config.Tracing.TrustTraceParent {
+		ctx = remoteCtx
+	} else if remote := trace.SpanContextFromContext(remoteCtx); remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+	ctx, span := tracer.Start(ctx, r.Method, opts...)
+	return r.WithContext(ctx), span
+}
+
+// EndServerSpan ends the span for the incoming request. The `path` should
+// have the sensitive values redacted.
+func EndServerSpan(span trace.Span, method, path string, statusCode int, err error) {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	span.SetName(method + " " + path)
+	span.SetAttributes(
+		semconv.URLPath(path),
+		semconv.HTTPResponseStatusCode(statusCode),
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	if statusCode >= 500 {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	span.End()
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartServerSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.NeverSample())),
+		sdktrace.WithSpanProcessor(recorder),
+	))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+		return r
+	}
+
+	trustTraceParent := config.Tracing.TrustTraceParent
+	t.Cleanup(func() {
+		config.Tracing.TrustTraceParent = trustTraceParent
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		config.Tracing.TrustTraceParent = false
+		r, span := StartServerSpan(newRequest(), "req")
+		span.End()
+
+		sc := trace.SpanContextFromContext(r.Context())
+		assert.NotEqual(t, traceId, sc.TraceID().String())
+		assert.False(t, sc.IsSampled(), "caller can not force the sampling")
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		config.Tracing.TrustTraceParent = true
+		r, span := StartServerSpan(newRequest(), "req")
+		span.End()
+
+		sc := trace.SpanContextFromContext(r.Context())
+		assert.Equal(t, traceId, sc.TraceID().String())
+		assert.True(t, sc.IsSampled())
+	})
+}
@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var log = logger.Scoped("tracing")
+
+var tracer = otel.Tracer("github.com/MunifTanjim/stremthru")
+
+var provider *sdktrace.TracerProvider
+
+func Init() {
+	if !config.Tracing.IsEnabled() {
+		return
+	}
+
+	exporter, err := otlptracehttp.New(
+		context.Background(),
+		otlptracehttp.WithEndpointURL(config.Tracing.Endpoint),
+		otlptracehttp.WithHeaders(config.Tracing.Headers),
+	)
+	if err != nil {
+		log.Error("failed to create exporter", "error", err)
+		return
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("stremthru"),
+		semconv.ServiceVersion(config.Version),
+	))
+	if err != nil {
+		log.Warn("failed to merge resource", "error", err)
+		res = resource.Default()
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.Tracing.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warn("otel error", "error", err)
+	}))
+}
+
+// Close flushes the pending spans to the collector.
+func Close() {
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Warn("failed to shutdown", "error", err)
+	}
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Detach returns a context with the span of ctx, but without its
+// cancellation and deadline.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(ctx))
+}
@@ -0,0 +1,128 @@
+package trakt
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/request"
+)
+
+type PlaybackItemEpisode struct {
+	MinimalItemEpisode
+	Runtime int `json:"runtime,omitempty"` // in minutes
+}
+
+type PlaybackItem struct {
+	Id       int64                `json:"id"`
+	Progress float64              `json:"progress"` // 0.0 - 100.0
+	PausedAt time.Time            `json:"paused_at"`
+	Type     ItemType             `json:"type"` // "movie" or "episode"
+	Movie    *ListItemMovie       `json:"movie,omitempty"`
+	Episode  *PlaybackItemEpisode `json:"episode,omitempty"`
+	Show     *ListItemShow        `json:"show,omitempty"`
+}
+
+// GetRuntime returns the runtime of the item in minutes, available only with
+// extended info.
+func (item PlaybackItem) GetRuntime() int {
+	switch item.Type {
+	case ItemTypeMovie:
+		if item.Movie != nil {
+			return item.Movie.Runtime
+		}
+	case ItemTypeEpisode:
+		if item.Episode != nil {
+			return item.Episode.Runtime
+		}
+	}
+	return 0
+}
+
+type GetPlaybackData = []PlaybackItem
+
+type GetPlaybackParams struct {
+	Ctx
+	Type     HistoryItemType
+	StartAt  *time.Time
+	EndAt    *time.Time
+	Extended bool
+	Page     int
+	Limit    int
+}
+
+func (c APIClient) GetPlayback(params *GetPlaybackParams) (request.APIResponse[GetPlaybackData], error) {
+	path := "/sync/playback"
+	if params.Type != "" {
+		path += "/" + string(params.Type)
+	}
+
+	params.Query = &url.Values{}
+	if params.Page > 0 {
+		params.Query.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.Limit > 0 {
+		params.Query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.StartAt != nil {
+		params.Query.Set("start_at", params.StartAt.UTC().Format(time.RFC3339))
+	}
+	if params.EndAt != nil {
+		params.Query.Set("end_at", params.EndAt.UTC().Format(time.RFC3339))
+	}
+	if params.Extended {
+		params.Query.Set("extended", "full")
+	}
+
+	response := paginatedResponseData[PlaybackItem]{}
+	res, err := c.Request("GET", path, params, &response)
+	return request.NewAPIResponse(res, response.data), err
+}
+
+type RemovePlaybackData struct {
+	ResponseError
+}
+
+type RemovePlaybackParams struct {
+	Ctx
+	Id int64
+}
+
+func (c APIClient) RemovePlayback(params *RemovePlaybackParams) (request.APIResponse[RemovePlaybackData], error) {
+	response := RemovePlaybackData{}
+	res, err := c.Request("DELETE", "/sync/playback/"+strconv.FormatInt(params.Id, 10), params, &response)
+	return request.NewAPIResponse(res, response), err
+}
+
+type ScrobbleParamsEpisode struct {
+	Season int         `json:"season,omitempty"`
+	Number int         `json:"number,omitempty"`
+	Ids    ListItemIds `json:"ids"`
+}
+
+type ScrobbleData struct {
+	ResponseError
+	Id       int64                `json:"id"`
+	Action   string               `json:"action"`
+	Progress float64              `json:"progress"`
+	Movie    *ListItemMovie       `json:"movie,omitempty"`
+	Episode  *PlaybackItemEpisode `json:"episode,omitempty"`
+	Show     *ListItemShow        `json:"show,omitempty"`
+}
+
+type ScrobbleParams struct {
+	Ctx
+	Movie    *SyncWatchlistParamsItem `json:"movie,omitempty"`
+	Show     *SyncWatchlistParamsItem `json:"show,omitempty"`
+	Episode  *ScrobbleParamsEpisode   `json:"episode,omitempty"`
+	Progress float64                  `json:"progress"`
+}
+
+// PauseScrobble saves the playback progress of the item, the same way the
+// Trakt apps do when the playback is paused.
+func (c APIClient) PauseScrobble(params *ScrobbleParams) (request.APIResponse[ScrobbleData], error) {
+	params.JSON = params
+	response := ScrobbleData{}
+	res, err := c.Request("POST", "/scrobble/pause", params, &response)
+	return request.NewAPIResponse(res, response), err
+}
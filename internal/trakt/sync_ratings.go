@@ -0,0 +1,46 @@
+package trakt
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/request"
+)
+
+type RatingItem struct {
+	RatedAt time.Time           `json:"rated_at"`
+	Rating  int                 `json:"rating"` // 1 - 10
+	Type    ItemType            `json:"type"`
+	Movie   *ListItemMovie      `json:"movie,omitempty"`
+	Show    *ListItemShow       `json:"show,omitempty"`
+	Episode *MinimalItemEpisode `json:"episode,omitempty"`
+}
+
+type GetRatingsData = []RatingItem
+
+type GetRatingsParams struct {
+	Ctx
+	Type  HistoryItemType
+	Page  int
+	Limit int
+}
+
+func (c APIClient) GetRatings(params *GetRatingsParams) (request.APIResponse[GetRatingsData], error) {
+	path := "/sync/ratings"
+	if params.Type != "" {
+		path += "/" + string(params.Type)
+	}
+
+	params.Query = &url.Values{}
+	if params.Page > 0 {
+		params.Query.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.Limit > 0 {
+		params.Query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	response := paginatedResponseData[RatingItem]{}
+	res, err := c.Request("GET", path, params, &response)
+	return request.NewAPIResponse(res, response.data), err
+}
@@ -0,0 +1,104 @@
+package trakt
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/request"
+)
+
+type WatchlistItem struct {
+	Rank     int            `json:"rank"`
+	Id       int64          `json:"id"`
+	ListedAt time.Time      `json:"listed_at"`
+	Notes    string         `json:"notes,omitempty"`
+	Type     ItemType       `json:"type"`
+	Movie    *ListItemMovie `json:"movie,omitempty"`
+	Show     *ListItemShow  `json:"show,omitempty"`
+}
+
+type GetWatchlistData = []WatchlistItem
+
+type GetWatchlistParams struct {
+	Ctx
+	Type  HistoryItemType
+	Page  int
+	Limit int
+}
+
+func (c APIClient) GetWatchlist(params *GetWatchlistParams) (request.APIResponse[GetWatchlistData], error) {
+	path := "/sync/watchlist"
+	if params.Type != "" {
+		path += "/" + string(params.Type) + "/added"
+	}
+
+	params.Query = &url.Values{}
+	if params.Page > 0 {
+		params.Query.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.Limit > 0 {
+		params.Query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	response := paginatedResponseData[WatchlistItem]{}
+	res, err := c.Request("GET", path, params, &response)
+	return request.NewAPIResponse(res, response.data), err
+}
+
+type SyncWatchlistParamsItem struct {
+	Ids ListItemIds `json:"ids"`
+}
+
+type SyncWatchlistCount struct {
+	Movies   int `json:"movies"`
+	Shows    int `json:"shows"`
+	Seasons  int `json:"seasons"`
+	Episodes int `json:"episodes"`
+}
+
+type SyncWatchlistNotFound struct {
+	Movies   []SyncHistoryResponseNotFoundItem `json:"movies"`
+	Shows    []SyncHistoryResponseNotFoundItem `json:"shows"`
+	Seasons  []SyncHistoryResponseNotFoundItem `json:"seasons"`
+	Episodes []SyncHistoryResponseNotFoundItem `json:"episodes"`
+}
+
+type AddToWatchlistData struct {
+	ResponseError
+	Added    SyncWatchlistCount    `json:"added"`
+	Existing SyncWatchlistCount    `json:"existing"`
+	NotFound SyncWatchlistNotFound `json:"not_found"`
+}
+
+type AddToWatchlistParams struct {
+	Ctx
+	Movies []SyncWatchlistParamsItem `json:"movies,omitempty"`
+	Shows  []SyncWatchlistParamsItem `json:"shows,omitempty"`
+}
+
+func (c APIClient) AddToWatchlist(params *AddToWatchlistParams) (request.APIResponse[AddToWatchlistData], error) {
+	params.JSON = params
+	response := AddToWatchlistData{}
+	res, err := c.Request("POST", "/sync/watchlist", params, &response)
+	return request.NewAPIResponse(res, response), err
+}
+
+type RemoveFromWatchlistData struct {
+	ResponseError
+	Deleted  SyncWatchlistCount    `json:"deleted"`
+	NotFound SyncWatchlistNotFound `json:"not_found"`
+}
+
+type RemoveFromWatchlistParams struct {
+	Ctx
+	Movies []SyncWatchlistParamsItem `json:"movies,omitempty"`
+	Shows  []SyncWatchlistParamsItem `json:"shows,omitempty"`
+}
+
+func (c APIClient) RemoveFromWatchlist(params *RemoveFromWatchlistParams) (request.APIResponse[RemoveFromWatchlistData], error) {
+	params.JSON = params
+	response := RemoveFromWatchlistData{}
+	res, err := c.Request("POST", "/sync/watchlist/remove", params, &response)
+	return request.NewAPIResponse(res, response), err
+}
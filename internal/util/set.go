@@ -19,6 +19,10 @@ func (s *Set[T]) Del(v T) {
 	delete(s.m, v)
 }
 
+func (s *Set[T]) Len() int {
+	return len(s.m)
+}
+
 func (s *Set[T]) Seq() iter.Seq[T] {
 	return func(yield func(T) bool) {
 		for key := range s.m {
@@ -0,0 +1,292 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/worker/worker_queue"
+)
+
+var (
+	ErrWorkerNotFound   = errors.New("worker is not running on this instance")
+	ErrWorkerJobRunning = errors.New("worker job is already running")
+	ErrWorkerJobIdle    = errors.New("worker job is not running")
+	ErrInvalidInterval  = errors.New("interval must be at least 1 minute")
+)
+
+const minWorkerInterval = 1 * time.Minute
+
+// JobProgress is the progress of a worker job, reported by the executor.
+type JobProgress struct {
+	Processed int64  `json:"processed"`
+	Errors    int64  `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type workerRun struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	jobId     string
+	startedAt time.Time
+	processed atomic.Int64
+	errors    atomic.Int64
+	lastError atomic.Pointer[string]
+	isDone    atomic.Bool
+}
+
+func (r *workerRun) progress() *JobProgress {
+	p := &JobProgress{
+		Processed: r.processed.Load(),
+		Errors:    r.errors.Load(),
+	}
+	if lastError := r.lastError.Load(); lastError != nil {
+		p.LastError = *lastError
+	}
+	return p
+}
+
+var idleWorkerRun = func() *workerRun {
+	r := &workerRun{}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.isDone.Store(true)
+	return r
+}()
+
+func (w *Worker) getRun() *workerRun {
+	if r := w.run.Load(); r != nil {
+		return r
+	}
+	return idleWorkerRun
+}
+
+func (w *Worker) startRun(jobId string) *workerRun {
+	r := &workerRun{jobId: jobId, startedAt: time.Now()}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	w.run.Store(r)
+	return r
+}
+
+func (w *Worker) endRun(r *workerRun) {
+	r.isDone.Store(true)
+	r.cancel()
+}
+
+// Context is cancelled when the current job is cancelled. Long running
+// executors should stop early once it is done.
+func (w *Worker) Context() context.Context {
+	return w.getRun().ctx
+}
+
+// IsCancelled reports if the current job is cancelled.
+func (w *Worker) IsCancelled() bool {
+	r := w.getRun()
+	return !r.isDone.Load() && r.ctx.Err() != nil
+}
+
+// ReportProcessed adds to the number of items processed by the current job.
+func (w *Worker) ReportProcessed(count int) {
+	w.getRun().processed.Add(int64(count))
+}
+
+// ReportError adds to the number of errors in the current job.
+func (w *Worker) ReportError(err error) {
+	r := w.getRun()
+	r.errors.Add(1)
+	if err != nil {
+		msg := err.Error()
+		r.lastError.Store(&msg)
+	}
+}
+
+func (w *Worker) getInterval() time.Duration {
+	return time.Duration(w.interval.Load())
+}
+
+var workerById sync.Map // map[string]*Worker
+
+func getWorker(id string) (*Worker, error) {
+	if w, ok := workerById.Load(id); ok {
+		return w.(*Worker), nil
+	}
+	return nil, ErrWorkerNotFound
+}
+
+type WorkerState struct {
+	IsEnabled bool               `json:"is_enabled"`
+	IsPaused  bool               `json:"is_paused"`
+	IsRunning bool               `json:"is_running"`
+	Interval  time.Duration      `json:"interval"`
+	Job       *WorkerJobProgress `json:"job,omitempty"`
+}
+
+type WorkerJobProgress struct {
+	Id          string    `json:"id"`
+	StartedAt   time.Time `json:"started_at"`
+	IsCancelled bool      `json:"is_cancelled"`
+	JobProgress
+}
+
+// GetWorkerState returns the state of the worker on this instance.
+func GetWorkerState(id string) WorkerState {
+	w, err := getWorker(id)
+	if err != nil {
+		state := WorkerState{}
+		if details, ok := WorkerDetailsById[id]; ok {
+			state.Interval = details.Interval
+		}
+		return state
+	}
+
+	state := WorkerState{
+		IsEnabled: true,
+		IsPaused:  w.isPaused.Load(),
+		Interval:  w.getInterval(),
+	}
+	if r := w.run.Load(); r != nil {
+		state.IsRunning = !r.isDone.Load()
+		state.Job = &WorkerJobProgress{
+			Id:          r.jobId,
+			StartedAt:   r.startedAt,
+			IsCancelled: r.ctx.Err() != nil && state.IsRunning,
+			JobProgress: *r.progress(),
+		}
+	}
+	return state
+}
+
+// tryRunJob runs the job unless the worker is already running on this
+// instance. Scheduled and triggered runs both go through it, so those never
+// overlap.
+func (w *Worker) tryRunJob(force bool) error {
+	if !w.isRunning.CompareAndSwap(false, true) {
+		w.Log.Debug("skipping, already running")
+		return nil
+	}
+	defer w.isRunning.Store(false)
+	return w.runJob(force)
+}
+
+// TriggerWorker runs the worker now, even if it is paused or its last job
+// finished recently.
+func TriggerWorker(id string) error {
+	w, err := getWorker(id)
+	if err != nil {
+		return err
+	}
+	if !w.isRunning.CompareAndSwap(false, true) {
+		return ErrWorkerJobRunning
+	}
+	w.Log.Info("triggered")
+	go func() {
+		defer w.isRunning.Store(false)
+		if err := w.runJob(true); err != nil {
+			w.Log.Error("Worker Failure", "error", err)
+		}
+	}()
+	return nil
+}
+
+// PauseWorker stops the scheduled runs of the worker. The running job, if
+// any, is not affected. The pause is only for this instance, and is reset on
+// restart.
+func PauseWorker(id string) error {
+	w, err := getWorker(id)
+	if err != nil {
+		return err
+	}
+	if !w.isPaused.Swap(true) {
+		w.Log.Info("paused")
+	}
+	return nil
+}
+
+func ResumeWorker(id string) error {
+	w, err := getWorker(id)
+	if err != nil {
+		return err
+	}
+	if w.isPaused.Swap(false) {
+		w.Log.Info("resumed")
+	}
+	return nil
+}
+
+// SetWorkerInterval reschedules the worker with the new interval, the next
+// run happens after the new interval. The interval is only changed for this
+// instance, and is reset on restart.
+func SetWorkerInterval(id string, interval time.Duration) error {
+	if interval < minWorkerInterval {
+		return ErrInvalidInterval
+	}
+
+	w, err := getWorker(id)
+	if err != nil {
+		return err
+	}
+
+	task, err := w.scheduler.Lookup(w.taskId)
+	if err != nil {
+		return err
+	}
+	t := task.Clone()
+	t.Interval = interval
+	w.scheduler.Del(w.taskId)
+	if err := w.scheduler.AddWithID(w.taskId, t); err != nil {
+		return err
+	}
+
+	w.interval.Store(int64(interval))
+	w.Log.Info("interval changed", "interval", interval.String())
+	return nil
+}
+
+// CancelWorkerJob asks the running job of the worker to stop.
+func CancelWorkerJob(id string) error {
+	w, err := getWorker(id)
+	if err != nil {
+		return err
+	}
+	r := w.getRun()
+	if r.isDone.Load() {
+		return ErrWorkerJobIdle
+	}
+	r.cancel()
+	w.Log.Info("cancelling", "jobId", r.jobId)
+	return nil
+}
+
+// trackQueueItem reports the progress of the queue item processor, and leaves
+// the remaining items in the queue once the job is cancelled.
+func trackQueueItem[T any](w *Worker, f func(item T) error) func(item T) error {
+	return func(item T) error {
+		if w.IsCancelled() {
+			return worker_queue.ErrWorkerQueueItemDelayed
+		}
+		err := f(item)
+		if err == nil {
+			w.ReportProcessed(1)
+		} else if err != worker_queue.ErrWorkerQueueItemDelayed {
+			w.ReportError(err)
+		}
+		return err
+	}
+}
+
+// trackQueueGroup is trackQueueItem for grouped queue items.
+func trackQueueGroup[T any](w *Worker, f func(groupKey string, items []T) error) func(groupKey string, items []T) error {
+	return func(groupKey string, items []T) error {
+		if w.IsCancelled() {
+			return worker_queue.ErrWorkerQueueItemDelayed
+		}
+		err := f(groupKey, items)
+		if err == nil {
+			w.ReportProcessed(len(items))
+		} else if err != worker_queue.ErrWorkerQueueItemDelayed {
+			w.ReportError(err)
+		}
+		return err
+	}
+}
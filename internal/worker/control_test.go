@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m,
+		"20250101000000_init",
+		"20250517140331_create_table_mdblist_list",
+		"20250521115616_fix_mdblist_item_id",
+		"20250604045321_alter_col_to_str_mdblist_list_id",
+		"20250708120053_add_col_eat_kv",
+		"20251029204711_create_table_job_log",
+		"20251222120000_create_table_chillstreams_usage_outbox",
+		"20251230120000_add_cols_chillstreams_usage_outbox_claim",
+		"20251226120000_create_table_sync_history",
+	))
+}
+
+const testWorkerName = "test-worker"
+
+func newTestWorker(t *testing.T, executor func(w *Worker) error) *Worker {
+	dbtest.Require(t)
+	dbtest.Truncate(t, "job_log")
+
+	WorkerDetailsById[testWorkerName] = &WorkerDetail{Title: "Test Worker"}
+	w := NewWorker(&WorkerConfig{
+		Name:     testWorkerName,
+		Interval: 1 * time.Hour,
+		Executor: executor,
+		ShouldWait: func() (bool, string) {
+			return false, ""
+		},
+		OnStart: func() {},
+		OnEnd:   func() {},
+	})
+	t.Cleanup(func() {
+		w.scheduler.Stop()
+		workerById.Delete(testWorkerName)
+		delete(WorkerDetailsById, testWorkerName)
+	})
+	return w
+}
+
+func TestPauseResumeWorker(t *testing.T) {
+	runCount := atomic.Int32{}
+	w := newTestWorker(t, func(w *Worker) error {
+		runCount.Add(1)
+		return nil
+	})
+
+	require.NoError(t, PauseWorker(testWorkerName))
+	assert.True(t, GetWorkerState(testWorkerName).IsPaused)
+
+	require.NoError(t, w.tryRunJob(false))
+	assert.Equal(t, int32(0), runCount.Load(), "scheduled run is skipped while paused")
+
+	require.NoError(t, ResumeWorker(testWorkerName))
+	assert.False(t, GetWorkerState(testWorkerName).IsPaused)
+
+	require.NoError(t, w.tryRunJob(false))
+	assert.Equal(t, int32(1), runCount.Load(), "scheduled run happens after resume")
+
+	assert.ErrorIs(t, PauseWorker("unknown-worker"), ErrWorkerNotFound)
+}
+
+func TestTriggerWorker(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runCount := atomic.Int32{}
+	w := newTestWorker(t, func(w *Worker) error {
+		runCount.Add(1)
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	require.NoError(t, PauseWorker(testWorkerName))
+	require.NoError(t, TriggerWorker(testWorkerName), "paused worker can be triggered")
+	<-started
+
+	assert.ErrorIs(t, TriggerWorker(testWorkerName), ErrWorkerJobRunning)
+	require.NoError(t, w.tryRunJob(true))
+	assert.Equal(t, int32(1), runCount.Load(), "runs never overlap")
+	assert.True(t, GetWorkerState(testWorkerName).IsRunning)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return !w.isRunning.Load()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	state := GetWorkerState(testWorkerName)
+	assert.False(t, state.IsRunning)
+	require.NotNil(t, state.Job)
+
+	job, err := w.jobTracker.GetLast()
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, state.Job.Id, job.Id)
+	assert.Equal(t, "done", job.Status)
+}
+
+func TestSetWorkerInterval(t *testing.T) {
+	newTestWorker(t, func(w *Worker) error { return nil })
+
+	assert.ErrorIs(t, SetWorkerInterval(testWorkerName, time.Second), ErrInvalidInterval)
+
+	require.NoError(t, SetWorkerInterval(testWorkerName, 2*time.Hour))
+	assert.Equal(t, 2*time.Hour, GetWorkerState(testWorkerName).Interval)
+}
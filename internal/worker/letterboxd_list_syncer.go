@@ -16,7 +16,7 @@ func InitSyncLetterboxdList(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
 		log := w.Log
 
-		worker_queue.LetterboxdListSyncerQueue.Process(func(item worker_queue.LetterboxdListSyncerQueueItem) error {
+		worker_queue.LetterboxdListSyncerQueue.Process(trackQueueItem(w, func(item worker_queue.LetterboxdListSyncerQueueItem) error {
 			l, err := letterboxd.GetListById(item.ListId)
 			if err != nil {
 				return err
@@ -238,7 +238,7 @@ func InitSyncLetterboxdList(conf *WorkerConfig) *Worker {
 			letterboxd.InvalidateListCache(l)
 
 			return nil
-		})
+		}))
 
 		return nil
 
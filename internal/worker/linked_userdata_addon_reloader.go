@@ -18,7 +18,7 @@ func InitLinkedUserdataAddonReloaderWorker(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
 		log := w.Log
 
-		worker_queue.LinkedUserdataAddonReloaderQueue.Process(func(item worker_queue.UserdataAddonReloaderQueueItem) error {
+		worker_queue.LinkedUserdataAddonReloaderQueue.Process(trackQueueItem(w, func(item worker_queue.UserdataAddonReloaderQueueItem) error {
 			accountIds, err := stremio_userdata_account.GetAccountIds(item.Addon, item.Key)
 			if err != nil {
 				log.Error("failed to get account ids", "error", err, "addon", item.Addon, "key", item.Key)
@@ -125,7 +125,7 @@ func InitLinkedUserdataAddonReloaderWorker(conf *WorkerConfig) *Worker {
 			}
 
 			return nil
-		})
+		}))
 
 		return nil
 	}
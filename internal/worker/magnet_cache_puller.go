@@ -17,7 +17,7 @@ import (
 
 func InitMagnetCachePullerWorker(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
-		worker_queue.MagnetCachePullerQueue.ProcessGroup(func(key string, items []worker_queue.MagnetCachePullerQueueItem) error {
+		worker_queue.MagnetCachePullerQueue.ProcessGroup(trackQueueGroup(w, func(key string, items []worker_queue.MagnetCachePullerQueueItem) error {
 			storeCode, sid, _ := strings.Cut(key, ":")
 
 			s := shared.GetStoreByCode(storeCode)
@@ -38,10 +38,19 @@ func InitMagnetCachePullerWorker(conf *WorkerConfig) *Worker {
 					clientIps = append(clientIps, item.ClientIP)
 					seenClientIp[item.ClientIP] = struct{}{}
 				}
-				if _, seen := seenStoreToken[item.StoreToken]; !seen {
-					storeTokens = append(storeTokens, item.StoreToken)
-					seenStoreToken[item.StoreToken] = struct{}{}
+				storeToken := string(item.StoreToken)
+				if storeToken == "" {
+					continue
 				}
+				if _, seen := seenStoreToken[storeToken]; !seen {
+					storeTokens = append(storeTokens, storeToken)
+					seenStoreToken[storeToken] = struct{}{}
+				}
+			}
+
+			if len(storeTokens) == 0 {
+				w.Log.Warn("dropping items, store token no longer available", "store.name", s.GetName(), "count", len(items))
+				return nil
 			}
 
 			for i, cHashes := range slices.Collect(slices.Chunk(hashes, 500)) {
@@ -84,11 +93,14 @@ func InitMagnetCachePullerWorker(conf *WorkerConfig) *Worker {
 								}
 								seenByName[f.Name] = true
 								files = append(files, torrent_stream.File{
-									Idx:    f.Idx,
-									Path:   f.Path,
-									Name:   f.Name,
-									Size:   f.Size,
-									Source: f.Source,
+									Idx:       f.Idx,
+									Path:      f.Path,
+									Name:      f.Name,
+									Size:      f.Size,
+									Source:    f.Source,
+									VideoHash: f.VideoHash,
+									SId:       f.SId,
+									ASId:      f.ASId,
 								})
 							}
 						}
@@ -100,7 +112,7 @@ func InitMagnetCachePullerWorker(conf *WorkerConfig) *Worker {
 			}
 
 			return nil
-		})
+		}))
 
 		return nil
 	}
@@ -17,7 +17,7 @@ func InitMapAnimeIdWorker(conf *WorkerConfig) *Worker {
 	pool := anizip.GetMappingsPool()
 
 	conf.Executor = func(w *Worker) error {
-		worker_queue.AnimeIdMapperQueue.ProcessGroup(func(service string, items []worker_queue.AnimeIdMapperQueueItem) error {
+		worker_queue.AnimeIdMapperQueue.ProcessGroup(trackQueueGroup(w, func(service string, items []worker_queue.AnimeIdMapperQueueItem) error {
 			if service != anime.IdMapColumn.AniList {
 				return nil
 			}
@@ -94,7 +94,7 @@ func InitMapAnimeIdWorker(conf *WorkerConfig) *Worker {
 			}
 
 			return nil
-		})
+		}))
 
 		return nil
 	}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+)
+
+type usageReporter struct {
+	client *chillstreams.Client
+	log    *logger.Logger
+}
+
+func (ur usageReporter) send(events []chillstreams_usage.UsageEvent) error {
+	reqs := make([]chillstreams.LogUsageRequest, len(events))
+	for i := range events {
+		reqs[i] = events[i].ToRequest()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return ur.client.LogUsageBatch(ctx, reqs)
+}
+
+// report delivers the events. A batch rejected by Chillstreams is split to
+// single out the bad events, so that those do not hold back the rest. It
+// stops at the first failure caused by Chillstreams being unavailable, and
+// returns that failure with the number of events delivered.
+func (ur usageReporter) report(events []chillstreams_usage.UsageEvent) (sentCount int, unavailableErr error, err error) {
+	sendErr := ur.send(events)
+	if sendErr == nil {
+		if err := chillstreams_usage.Delete(events); err != nil {
+			return 0, nil, err
+		}
+		return len(events), nil, nil
+	}
+
+	isUnavailable := chillstreams.IsUnavailable(sendErr)
+	if isUnavailable || len(events) == 1 {
+		deadCount, err := chillstreams_usage.MarkFailed(events, sendErr)
+		if err != nil {
+			return 0, nil, err
+		}
+		if deadCount > 0 {
+			ur.log.Error("dead-lettered usage events", "count", deadCount, "error", sendErr)
+		}
+		if isUnavailable {
+			return 0, sendErr, nil
+		}
+		if deadCount == 0 {
+			ur.log.Warn("usage event rejected, will retry", "error", sendErr, "id", events[0].Id)
+		}
+		return 0, nil, nil
+	}
+
+	mid := len(events) / 2
+	sentCount, unavailableErr, err = ur.report(events[:mid])
+	if unavailableErr != nil || err != nil {
+		return sentCount, unavailableErr, err
+	}
+	rightSentCount, unavailableErr, err := ur.report(events[mid:])
+	return sentCount + rightSentCount, unavailableErr, err
+}
+
+func InitReportChillstreamsUsageWorker(conf *WorkerConfig) *Worker {
+	client := chillstreams.NewClient(config.ChillstreamsAPIURL, config.ChillstreamsAPIKey)
+
+	batchSize := 100
+
+	conf.Executor = func(w *Worker) error {
+		log := w.Log
+
+		reporter := usageReporter{client: client, log: log}
+
+		sentCount := 0
+		for {
+			events, err := chillstreams_usage.Claim(batchSize)
+			if err != nil {
+				return err
+			}
+			if len(events) == 0 {
+				break
+			}
+
+			count, unavailableErr, err := reporter.report(events)
+			sentCount += count
+			if err != nil {
+				return err
+			}
+			if unavailableErr != nil {
+				log.Warn("failed to report usage, will retry", "error", unavailableErr)
+				break
+			}
+
+			if len(events) < batchSize {
+				break
+			}
+		}
+
+		if sentCount > 0 {
+			log.Info("reported usage", "count", sentCount)
+		}
+
+		if count, err := chillstreams_usage.PurgeDead(); err != nil {
+			log.Error("failed to purge dead usage events", "error", err)
+		} else if count > 0 {
+			log.Info("purged dead usage events", "count", count)
+		}
+
+		return nil
+	}
+
+	worker := NewWorker(conf)
+
+	return worker
+}
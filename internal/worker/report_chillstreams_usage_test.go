@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MunifTanjim/stremthru/internal/chillstreams"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupUsageReporter(t *testing.T, handler func(events []chillstreams.LogUsageRequest) int) usageReporter {
+	dbtest.Require(t)
+	dbtest.Truncate(t, chillstreams_usage.TableName)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := chillstreams.LogUsageBatchRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(handler(req.Events))
+	}))
+	t.Cleanup(server.Close)
+
+	return usageReporter{
+		client: chillstreams.NewClient(server.URL, "test-key"),
+		log:    logger.Scoped("test"),
+	}
+}
+
+func enqueueUsageEvents(t *testing.T, hashes ...string) []chillstreams_usage.UsageEvent {
+	for _, hash := range hashes {
+		require.NoError(t, chillstreams_usage.Enqueue(&chillstreams.LogUsageRequest{UserID: "user", Hash: hash}))
+	}
+	events, err := chillstreams_usage.Claim(len(hashes))
+	require.NoError(t, err)
+	require.Len(t, events, len(hashes))
+	return events
+}
+
+func TestUsageReporterSplitsRejectedBatch(t *testing.T) {
+	requestCount := atomic.Int32{}
+	reporter := setupUsageReporter(t, func(events []chillstreams.LogUsageRequest) int {
+		requestCount.Add(1)
+		if slices.ContainsFunc(events, func(e chillstreams.LogUsageRequest) bool { return e.Hash == "bad" }) {
+			return http.StatusBadRequest
+		}
+		return http.StatusOK
+	})
+
+	events := enqueueUsageEvents(t, "a", "b", "bad", "c")
+	sentCount, unavailableErr, err := reporter.report(events)
+	require.NoError(t, err)
+	assert.NoError(t, unavailableErr)
+	assert.Equal(t, 3, sentCount, "good events are delivered")
+	assert.Equal(t, int32(5), requestCount.Load())
+
+	stats, err := chillstreams_usage.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Pending, "bad event is kept for retry")
+
+	hasDue, err := chillstreams_usage.HasDue()
+	require.NoError(t, err)
+	assert.False(t, hasDue, "bad event is retried after backoff")
+}
+
+func TestUsageReporterUnavailable(t *testing.T) {
+	requestCount := atomic.Int32{}
+	reporter := setupUsageReporter(t, func(events []chillstreams.LogUsageRequest) int {
+		requestCount.Add(1)
+		return http.StatusServiceUnavailable
+	})
+
+	events := enqueueUsageEvents(t, "a", "b", "c")
+	sentCount, unavailableErr, err := reporter.report(events)
+	require.NoError(t, err)
+	assert.Error(t, unavailableErr)
+	assert.Equal(t, 0, sentCount)
+	assert.Equal(t, int32(1), requestCount.Load(), "batch is not split when chillstreams is unavailable")
+
+	stats, err := chillstreams_usage.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Pending)
+}
+
+func TestUsageReporterClaim(t *testing.T) {
+	idempotencyKeys := []string{}
+	reporter := setupUsageReporter(t, func(events []chillstreams.LogUsageRequest) int {
+		for _, e := range events {
+			idempotencyKeys = append(idempotencyKeys, e.IdempotencyKey)
+		}
+		return http.StatusOK
+	})
+
+	events := enqueueUsageEvents(t, "a", "b")
+
+	claimed, err := chillstreams_usage.Claim(10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "claimed events are not claimed again")
+	hasDue, err := chillstreams_usage.HasDue()
+	require.NoError(t, err)
+	assert.False(t, hasDue)
+
+	sentCount, unavailableErr, err := reporter.report(events)
+	require.NoError(t, err)
+	assert.NoError(t, unavailableErr)
+	assert.Equal(t, 2, sentCount)
+	require.Len(t, idempotencyKeys, 2)
+	assert.NotEmpty(t, idempotencyKeys[0])
+	assert.NotEqual(t, idempotencyKeys[0], idempotencyKeys[1], "each event has its own idempotency key")
+	assert.Equal(t, []string{events[0].IdempotencyKey, events[1].IdempotencyKey}, idempotencyKeys)
+}
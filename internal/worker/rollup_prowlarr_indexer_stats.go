@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/prowlarr"
+)
+
+func InitRollupProwlarrIndexerStatsWorker(conf *WorkerConfig) *Worker {
+	conf.Executor = func(w *Worker) error {
+		log := w.Log
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		if err := prowlarr.RollupIndexerStats(ctx); err != nil {
+			return err
+		}
+
+		if count, err := prowlarr.PurgeSearchLogs(ctx); err != nil {
+			log.Error("failed to purge search logs", "error", err)
+		} else if count > 0 {
+			log.Info("purged search logs", "count", count)
+		}
+
+		return nil
+	}
+
+	worker := NewWorker(conf)
+
+	return worker
+}
@@ -13,11 +13,15 @@ func InitCrawlStoreWorker(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
 		log := w.Log
 
-		worker_queue.StoreCrawlerQueue.Process(func(item worker_queue.StoreCrawlerQueueItem) error {
+		worker_queue.StoreCrawlerQueue.Process(trackQueueItem(w, func(item worker_queue.StoreCrawlerQueueItem) error {
 			s := shared.GetStoreByCode(item.StoreCode)
 			if s == nil {
 				return nil
 			}
+			if item.StoreToken == "" {
+				log.Warn("dropping item, store token no longer available", "store.code", item.StoreCode)
+				return nil
+			}
 
 			tSource := torrent_info.TorrentInfoSource(item.StoreCode)
 			discardFileIdx := s.GetName().Code() != store.StoreCodeRealDebrid
@@ -30,7 +34,7 @@ func InitCrawlStoreWorker(conf *WorkerConfig) *Worker {
 					Limit:  limit,
 					Offset: offset,
 				}
-				params.APIKey = item.StoreToken
+				params.APIKey = string(item.StoreToken)
 				res, err := s.ListMagnets(params)
 				if err != nil {
 					log.Error("failed to list magnets", "error", err)
@@ -66,7 +70,7 @@ func InitCrawlStoreWorker(conf *WorkerConfig) *Worker {
 			}
 
 			return nil
-		})
+		}))
 
 		return nil
 
@@ -5,7 +5,7 @@ import (
 	"github.com/MunifTanjim/stremthru/internal/util"
 )
 
-var syncAniDBTitlesJobTracker *JobTracker[struct{}]
+var syncAniDBTitlesJobTracker *JobTracker[JobProgress]
 
 func isAnidbTitlesSyncedToday() bool {
 	if syncAniDBTitlesJobTracker == nil {
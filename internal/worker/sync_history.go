@@ -0,0 +1,567 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	stremio_account "github.com/MunifTanjim/stremthru/internal/stremio/account"
+	stremio_api "github.com/MunifTanjim/stremthru/internal/stremio/api"
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
+	"github.com/MunifTanjim/stremthru/internal/trakt"
+	trakt_account "github.com/MunifTanjim/stremthru/internal/trakt/account"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+const syncHistoryRetention = 30 * 24 * time.Hour
+
+// syncRecorder applies the changes of a sync run and records them in the
+// sync history. In dry-run mode the changes are only collected, nothing is
+// applied or recorded.
+type syncRecorder struct {
+	dryRun   bool
+	log      *logger.Logger
+	linkType sync_history.LinkType
+	linkId   string
+	jobName  string
+	jobId    string
+	changes  []sync_history.Change
+}
+
+func (r *syncRecorder) apply(changes []sync_history.Change, apply func() error) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if !r.dryRun {
+		if err := apply(); err != nil {
+			return err
+		}
+		util.LogError(r.log, sync_history.Record(r.linkType, r.linkId, r.jobName, r.jobId, changes), "failed to record sync history")
+	}
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+func (r *syncRecorder) updateStremioLibraryItems(client *stremio_api.Client, token, accountId string, items []stremio_api.LibraryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(items))
+	for i := range items {
+		ids[i] = items[i].Id
+	}
+	res, err := client.GetAllLibraryItems(&stremio_api.GetAllLibraryItemsParams{
+		Ctx: stremio_api.Ctx{APIKey: token},
+		Ids: ids,
+	})
+	if err != nil {
+		return err
+	}
+	beforeById := make(map[string]stremio_api.LibraryItem, len(res.Data))
+	for _, item := range res.Data {
+		beforeById[item.Id] = item
+	}
+
+	changes := make([]sync_history.Change, len(items))
+	for i, item := range items {
+		var before any
+		if beforeItem, ok := beforeById[item.Id]; ok {
+			before = beforeItem
+		}
+		changes[i] = sync_history.NewChange(sync_history.TargetStremio, accountId, sync_history.ActionStremioLibraryUpdate, item.Id, before, item)
+	}
+
+	return r.apply(changes, func() error {
+		_, err := client.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
+			Ctx:     stremio_api.Ctx{APIKey: token},
+			Changes: items,
+		})
+		return err
+	})
+}
+
+func (r *syncRecorder) addToTraktHistory(client *trakt.APIClient, accountId string, params *trakt.AddToHistoryParams) error {
+	changes := []sync_history.Change{}
+	for _, item := range params.Movies {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktHistoryAdd, item.Ids.IMDB, nil, trakt.AddToHistoryParams{Movies: []trakt.SyncHistoryParamsItem{item}}))
+	}
+	for _, item := range params.Shows {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktHistoryAdd, item.Ids.IMDB, nil, trakt.AddToHistoryParams{Shows: []trakt.SyncHistoryShow{item}}))
+	}
+	return r.apply(changes, func() error {
+		_, err := client.AddToHistory(params)
+		return err
+	})
+}
+
+func (r *syncRecorder) addToTraktWatchlist(client *trakt.APIClient, accountId string, params *trakt.AddToWatchlistParams) error {
+	changes := []sync_history.Change{}
+	for _, item := range params.Movies {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktWatchlistAdd, item.Ids.IMDB, nil, trakt.AddToWatchlistParams{Movies: []trakt.SyncWatchlistParamsItem{item}}))
+	}
+	for _, item := range params.Shows {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktWatchlistAdd, item.Ids.IMDB, nil, trakt.AddToWatchlistParams{Shows: []trakt.SyncWatchlistParamsItem{item}}))
+	}
+	return r.apply(changes, func() error {
+		_, err := client.AddToWatchlist(params)
+		return err
+	})
+}
+
+func (r *syncRecorder) removeFromTraktWatchlist(client *trakt.APIClient, accountId string, params *trakt.RemoveFromWatchlistParams) error {
+	changes := []sync_history.Change{}
+	for _, item := range params.Movies {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktWatchlistRemove, item.Ids.IMDB, nil, trakt.RemoveFromWatchlistParams{Movies: []trakt.SyncWatchlistParamsItem{item}}))
+	}
+	for _, item := range params.Shows {
+		changes = append(changes, sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktWatchlistRemove, item.Ids.IMDB, nil, trakt.RemoveFromWatchlistParams{Shows: []trakt.SyncWatchlistParamsItem{item}}))
+	}
+	return r.apply(changes, func() error {
+		_, err := client.RemoveFromWatchlist(params)
+		return err
+	})
+}
+
+// pauses the scrobble for the video, before is the playback progress on
+// trakt prior to the change, if any.
+func (r *syncRecorder) pauseTraktScrobble(client *trakt.APIClient, accountId, videoId string, before, params *trakt.ScrobbleParams) (int64, error) {
+	var beforeData any
+	if before != nil {
+		beforeData = before
+	}
+	var playbackId int64
+	err := r.apply([]sync_history.Change{
+		sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktPlaybackPause, videoId, beforeData, params),
+	}, func() error {
+		res, err := client.PauseScrobble(params)
+		playbackId = res.Data.Id
+		return err
+	})
+	return playbackId, err
+}
+
+func (r *syncRecorder) removeTraktPlayback(client *trakt.APIClient, accountId, videoId string, playback *trakt.PlaybackItem) error {
+	return r.apply([]sync_history.Change{
+		sync_history.NewChange(sync_history.TargetTrakt, accountId, sync_history.ActionTraktPlaybackRemove, videoId, newTraktScrobbleParams(videoId, playback.Progress), nil),
+	}, func() error {
+		_, err := client.RemovePlayback(&trakt.RemovePlaybackParams{Id: playback.Id})
+		return err
+	})
+}
+
+// returns the scrobble params for the video id, i.e. the imdb id for movie
+// and `{imdb_id}:{season}:{episode}` for episode.
+func newTraktScrobbleParams(videoId string, progress float64) *trakt.ScrobbleParams {
+	params := &trakt.ScrobbleParams{Progress: progress}
+	imdbId, episodeId, isEpisode := strings.Cut(videoId, ":")
+	if !isEpisode {
+		params.Movie = &trakt.SyncWatchlistParamsItem{Ids: trakt.ListItemIds{IMDB: imdbId}}
+		return params
+	}
+	season, episode, _ := strings.Cut(episodeId, ":")
+	params.Show = &trakt.SyncWatchlistParamsItem{Ids: trakt.ListItemIds{IMDB: imdbId}}
+	params.Episode = &trakt.ScrobbleParamsEpisode{
+		Season: util.SafeParseInt(season, 0),
+		Number: util.SafeParseInt(episode, 0),
+	}
+	return params
+}
+
+type syncRevertTarget struct {
+	stremioClient   *stremio_api.Client
+	stremioToken    string
+	stremioItemIds  []string
+	stremioItemById map[string]stremio_api.LibraryItem
+
+	traktClient      *trakt.APIClient
+	traktPlayback    map[string]trakt.PlaybackItem
+	traktWatchlist   map[string]trakt.WatchlistItem
+	traktWatchedAt   map[string]time.Time
+	traktWatchedFrom time.Time
+}
+
+func getSyncRevertTarget(targets map[string]*syncRevertTarget, entry *sync_history.Entry) (*syncRevertTarget, error) {
+	key := string(entry.Target) + ":" + entry.TargetAccountId
+	if target, ok := targets[key]; ok {
+		target.track(entry)
+		return target, nil
+	}
+
+	target := &syncRevertTarget{}
+	switch entry.Target {
+	case sync_history.TargetStremio:
+		account, err := stremio_account.GetById(entry.TargetAccountId)
+		if err != nil || account == nil {
+			return nil, fmt.Errorf("stremio account not found: %w", err)
+		}
+		token, err := account.GetValidToken()
+		if err != nil {
+			return nil, err
+		}
+		target.stremioClient = stremio_api.NewClient(&stremio_api.ClientConfig{})
+		target.stremioToken = token
+	case sync_history.TargetTrakt:
+		account, err := trakt_account.GetById(entry.TargetAccountId)
+		if err != nil || account == nil {
+			return nil, fmt.Errorf("trakt account not found: %w", err)
+		}
+		target.traktClient = trakt.GetAPIClient(account.OAuthTokenId)
+	default:
+		return nil, fmt.Errorf("unsupported sync history target: %s", entry.Target)
+	}
+	target.track(entry)
+	targets[key] = target
+	return target, nil
+}
+
+// track notes what the entry needs from the current state of the target, so
+// that it is fetched at once for all the entries of the run.
+func (t *syncRevertTarget) track(entry *sync_history.Entry) {
+	switch entry.Action {
+	case sync_history.ActionStremioLibraryUpdate:
+		t.stremioItemIds = append(t.stremioItemIds, entry.ItemId)
+	case sync_history.ActionTraktHistoryAdd:
+		if t.traktWatchedFrom.IsZero() || entry.CAt.Before(t.traktWatchedFrom) {
+			t.traktWatchedFrom = entry.CAt.Time
+		}
+	}
+}
+
+func (t *syncRevertTarget) getStremioLibraryItem(id string) (*stremio_api.LibraryItem, error) {
+	if t.stremioItemById == nil {
+		res, err := t.stremioClient.GetAllLibraryItems(&stremio_api.GetAllLibraryItemsParams{
+			Ctx: stremio_api.Ctx{APIKey: t.stremioToken},
+			Ids: t.stremioItemIds,
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.stremioItemById = make(map[string]stremio_api.LibraryItem, len(res.Data))
+		for _, item := range res.Data {
+			t.stremioItemById[item.Id] = item
+		}
+	}
+	if item, ok := t.stremioItemById[id]; ok {
+		return &item, nil
+	}
+	return nil, nil
+}
+
+func (t *syncRevertTarget) getTraktPlayback(videoId string) (*trakt.PlaybackItem, error) {
+	if t.traktPlayback == nil {
+		items, err := getAllTraktPages(func(page, limit int) ([]trakt.PlaybackItem, error) {
+			res, err := t.traktClient.GetPlayback(&trakt.GetPlaybackParams{Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.traktPlayback = map[string]trakt.PlaybackItem{}
+		for _, item := range items {
+			switch item.Type {
+			case trakt.ItemTypeMovie:
+				if item.Movie != nil && item.Movie.Ids.IMDB != "" {
+					t.traktPlayback[item.Movie.Ids.IMDB] = item
+				}
+			case trakt.ItemTypeEpisode:
+				if item.Show != nil && item.Show.Ids.IMDB != "" && item.Episode != nil {
+					t.traktPlayback[fmt.Sprintf("%s:%d:%d", item.Show.Ids.IMDB, item.Episode.Season, item.Episode.Number)] = item
+				}
+			}
+		}
+	}
+	if item, ok := t.traktPlayback[videoId]; ok {
+		return &item, nil
+	}
+	return nil, nil
+}
+
+func (t *syncRevertTarget) getTraktWatchlistItem(imdbId string) (*trakt.WatchlistItem, error) {
+	if t.traktWatchlist == nil {
+		items, err := getAllTraktPages(func(page, limit int) ([]trakt.WatchlistItem, error) {
+			res, err := t.traktClient.GetWatchlist(&trakt.GetWatchlistParams{Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		t.traktWatchlist = map[string]trakt.WatchlistItem{}
+		for _, item := range items {
+			if item.Movie != nil && item.Movie.Ids.IMDB != "" {
+				t.traktWatchlist[item.Movie.Ids.IMDB] = item
+			} else if item.Show != nil && item.Show.Ids.IMDB != "" {
+				t.traktWatchlist[item.Show.Ids.IMDB] = item
+			}
+		}
+	}
+	if item, ok := t.traktWatchlist[imdbId]; ok {
+		return &item, nil
+	}
+	return nil, nil
+}
+
+// returns when the movie or show was last watched on trakt, considering only
+// the history since the run.
+func (t *syncRevertTarget) getTraktWatchedAt(imdbId string) (time.Time, error) {
+	if t.traktWatchedAt == nil {
+		items, err := getAllTraktPages(func(page, limit int) ([]trakt.HistoryItem, error) {
+			res, err := t.traktClient.GetHistory(&trakt.GetHistoryParams{StartAt: &t.traktWatchedFrom, Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+		t.traktWatchedAt = map[string]time.Time{}
+		for _, item := range items {
+			id := ""
+			if item.Movie != nil {
+				id = item.Movie.Ids.IMDB
+			} else if item.Show != nil {
+				id = item.Show.Ids.IMDB
+			}
+			if id != "" && item.WatchedAt.After(t.traktWatchedAt[id]) {
+				t.traktWatchedAt[id] = item.WatchedAt
+			}
+		}
+	}
+	return t.traktWatchedAt[imdbId], nil
+}
+
+// the changes made by the sync itself land slightly before or after the
+// entry is recorded.
+const syncRevertConflictGrace = 1 * time.Minute
+
+func isChangedAfterSync(entry *sync_history.Entry, t time.Time) bool {
+	return t.After(entry.CAt.Add(syncRevertConflictGrace))
+}
+
+// getSyncRevertConflict compares the current state of the item with the
+// entry's after, and returns why reverting the entry would overwrite the
+// changes made after the sync, if it would.
+func getSyncRevertConflict(target *syncRevertTarget, entry *sync_history.Entry) (string, error) {
+	switch entry.Action {
+	case sync_history.ActionStremioLibraryUpdate:
+		after := stremio_api.LibraryItem{}
+		if err := json.Unmarshal(entry.After, &after); err != nil {
+			return "", err
+		}
+		item, err := target.getStremioLibraryItem(entry.ItemId)
+		if err != nil {
+			return "", err
+		}
+		if item == nil {
+			return "deleted after the sync", nil
+		}
+		if item.MTime.After(after.MTime.Time) {
+			return "changed after the sync", nil
+		}
+
+	case sync_history.ActionTraktHistoryAdd:
+		watchedAt, err := target.getTraktWatchedAt(entry.ItemId)
+		if err != nil {
+			return "", err
+		}
+		if isChangedAfterSync(entry, watchedAt) {
+			return "watched again after the sync", nil
+		}
+
+	case sync_history.ActionTraktWatchlistAdd:
+		item, err := target.getTraktWatchlistItem(entry.ItemId)
+		if err != nil {
+			return "", err
+		}
+		if item != nil && isChangedAfterSync(entry, item.ListedAt) {
+			return "added to watchlist again after the sync", nil
+		}
+
+	case sync_history.ActionTraktPlaybackPause:
+		after := trakt.ScrobbleParams{}
+		if err := json.Unmarshal(entry.After, &after); err != nil {
+			return "", err
+		}
+		playback, err := target.getTraktPlayback(entry.ItemId)
+		if err != nil {
+			return "", err
+		}
+		if playback == nil {
+			if entry.Before != nil {
+				return "playback removed after the sync", nil
+			}
+		} else if isChangedAfterSync(entry, playback.PausedAt) || math.Abs(playback.Progress-after.Progress) >= 1 {
+			return "playback changed after the sync", nil
+		}
+
+	case sync_history.ActionTraktPlaybackRemove:
+		playback, err := target.getTraktPlayback(entry.ItemId)
+		if err != nil {
+			return "", err
+		}
+		if playback != nil {
+			return "playback resumed after the sync", nil
+		}
+	}
+
+	return "", nil
+}
+
+func revertSyncHistoryEntry(target *syncRevertTarget, entry *sync_history.Entry) error {
+	switch entry.Action {
+	case sync_history.ActionStremioLibraryUpdate:
+		item := stremio_api.LibraryItem{}
+		if entry.Before != nil {
+			if err := json.Unmarshal(entry.Before, &item); err != nil {
+				return err
+			}
+		} else {
+			// did not exist before the sync
+			if err := json.Unmarshal(entry.After, &item); err != nil {
+				return err
+			}
+			item.Removed = true
+		}
+		item.MTime = stremio_api.JSONTime{Time: time.Now()}
+		_, err := target.stremioClient.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
+			Ctx:     stremio_api.Ctx{APIKey: target.stremioToken},
+			Changes: []stremio_api.LibraryItem{item},
+		})
+		return err
+
+	case sync_history.ActionTraktHistoryAdd:
+		params := &trakt.RemoveFromHistoryParams{}
+		if err := json.Unmarshal(entry.After, params); err != nil {
+			return err
+		}
+		_, err := target.traktClient.RemoveFromHistory(params)
+		return err
+
+	case sync_history.ActionTraktWatchlistAdd:
+		params := &trakt.RemoveFromWatchlistParams{}
+		if err := json.Unmarshal(entry.After, params); err != nil {
+			return err
+		}
+		_, err := target.traktClient.RemoveFromWatchlist(params)
+		return err
+
+	case sync_history.ActionTraktWatchlistRemove:
+		params := &trakt.AddToWatchlistParams{}
+		if err := json.Unmarshal(entry.After, params); err != nil {
+			return err
+		}
+		_, err := target.traktClient.AddToWatchlist(params)
+		return err
+
+	case sync_history.ActionTraktPlaybackPause:
+		if entry.Before != nil {
+			params := &trakt.ScrobbleParams{}
+			if err := json.Unmarshal(entry.Before, params); err != nil {
+				return err
+			}
+			_, err := target.traktClient.PauseScrobble(params)
+			return err
+		}
+		playback, err := target.getTraktPlayback(entry.ItemId)
+		if err != nil || playback == nil {
+			return err
+		}
+		_, err = target.traktClient.RemovePlayback(&trakt.RemovePlaybackParams{Id: playback.Id})
+		return err
+
+	case sync_history.ActionTraktPlaybackRemove:
+		params := &trakt.ScrobbleParams{}
+		if err := json.Unmarshal(entry.Before, params); err != nil {
+			return err
+		}
+		_, err := target.traktClient.PauseScrobble(params)
+		return err
+	}
+
+	return fmt.Errorf("unsupported sync history action: %s", entry.Action)
+}
+
+var errSyncHistoryNothingToRevert = errors.New("nothing to revert")
+
+type SyncRevertConflict struct {
+	Entry  sync_history.Entry
+	Reason string
+}
+
+type SyncRevertResult struct {
+	Reverted  []sync_history.Entry
+	Conflicts []SyncRevertConflict
+}
+
+// RevertLastSyncRun reverts the changes applied by the last sync run of the
+// link, newest first. The entries whose items were changed after the sync
+// are skipped and returned as conflicts, they are left as is for a later
+// attempt.
+func RevertLastSyncRun(linkType sync_history.LinkType, linkId string) (*SyncRevertResult, error) {
+	entries, err := sync_history.GetLastRun(linkType, linkId)
+	if err != nil {
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(entry sync_history.Entry) bool {
+		return !entry.RevertedAt.IsZero()
+	})
+	if len(entries) == 0 {
+		return nil, errSyncHistoryNothingToRevert
+	}
+
+	var errs []error
+
+	targets := map[string]*syncRevertTarget{}
+	targetByEntryId := map[int64]*syncRevertTarget{}
+	for i := range entries {
+		entry := &entries[i]
+		target, err := getSyncRevertTarget(targets, entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", entry.Action, entry.ItemId, err))
+			continue
+		}
+		targetByEntryId[entry.Id] = target
+	}
+
+	result := &SyncRevertResult{
+		Reverted:  []sync_history.Entry{},
+		Conflicts: []SyncRevertConflict{},
+	}
+	for i := range entries {
+		entry := &entries[i]
+		target, ok := targetByEntryId[entry.Id]
+		if !ok {
+			continue
+		}
+		conflict, err := getSyncRevertConflict(target, entry)
+		if err == nil && conflict != "" {
+			result.Conflicts = append(result.Conflicts, SyncRevertConflict{Entry: *entry, Reason: conflict})
+			continue
+		}
+		if err == nil {
+			err = revertSyncHistoryEntry(target, entry)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", entry.Action, entry.ItemId, err))
+			continue
+		}
+		result.Reverted = append(result.Reverted, *entry)
+	}
+
+	ids := make([]int64, len(result.Reverted))
+	for i := range result.Reverted {
+		ids[i] = result.Reverted[i].Id
+	}
+	if err := sync_history.SetReverted(ids); err != nil {
+		errs = append(errs, err)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func IsSyncHistoryNothingToRevert(err error) bool {
+	return errors.Is(err, errSyncHistoryNothingToRevert)
+}
@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	stremio_api "github.com/MunifTanjim/stremthru/internal/stremio/api"
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
+	"github.com/MunifTanjim/stremthru/internal/trakt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSyncRecorderDryRun(t *testing.T) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, sync_history.TableName)
+
+	changes := []sync_history.Change{
+		sync_history.NewChange(sync_history.TargetTrakt, "account", sync_history.ActionTraktWatchlistAdd, "tt0000001", nil, map[string]string{"id": "tt0000001"}),
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		linkId := "dry-run"
+		if !dryRun {
+			linkId = "run"
+		}
+		rec := &syncRecorder{
+			dryRun:   dryRun,
+			log:      logger.Scoped("test"),
+			linkType: sync_history.LinkTypeStremioTrakt,
+			linkId:   linkId,
+			jobName:  "test",
+			jobId:    "job",
+		}
+		applyCount := 0
+		require.NoError(t, rec.apply(changes, func() error {
+			applyCount++
+			return nil
+		}))
+		assert.Equal(t, changes, rec.changes)
+
+		entries, err := sync_history.GetByLink(sync_history.LinkTypeStremioTrakt, linkId, 10)
+		require.NoError(t, err)
+		if dryRun {
+			assert.Equal(t, 0, applyCount, "dry-run applies nothing")
+			assert.Empty(t, entries, "dry-run records nothing")
+		} else {
+			assert.Equal(t, 1, applyCount)
+			require.Len(t, entries, 1)
+			assert.Equal(t, changes[0].ItemId, entries[0].ItemId)
+		}
+	}
+}
+
+func newSyncHistoryEntry(change sync_history.Change, cat time.Time) *sync_history.Entry {
+	return &sync_history.Entry{
+		Change: change,
+		CAt:    db.Timestamp{Time: cat},
+	}
+}
+
+func TestRevertSyncHistoryEntryStremio(t *testing.T) {
+	syncedAt := time.Now().Add(-1 * time.Hour).Truncate(time.Millisecond)
+
+	createdAt := stremio_api.JSONTime{Time: syncedAt.Add(-24 * time.Hour)}
+	currentById := map[string]stremio_api.LibraryItem{
+		"tt0000001": {Id: "tt0000001", Name: "synced", CTime: createdAt, MTime: stremio_api.JSONTime{Time: syncedAt}},
+		"tt0000002": {Id: "tt0000002", Name: "changed", CTime: createdAt, MTime: stremio_api.JSONTime{Time: syncedAt.Add(30 * time.Minute)}},
+	}
+	var putItems []stremio_api.LibraryItem
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/datastoreGet":
+			items := []stremio_api.LibraryItem{}
+			for _, item := range currentById {
+				items = append(items, item)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"result": items})
+		case "/api/datastorePut":
+			payload := struct {
+				Changes []stremio_api.LibraryItem `json:"changes"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			putItems = append(putItems, payload.Changes...)
+			json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"success": true}})
+		}
+	}))
+	defer server.Close()
+
+	newEntry := func(id string) *sync_history.Entry {
+		before := stremio_api.LibraryItem{Id: id, Name: "before", CTime: createdAt, MTime: createdAt}
+		after := stremio_api.LibraryItem{Id: id, Name: "synced", CTime: createdAt, MTime: stremio_api.JSONTime{Time: syncedAt}}
+		return newSyncHistoryEntry(sync_history.NewChange(sync_history.TargetStremio, "account", sync_history.ActionStremioLibraryUpdate, id, before, after), syncedAt)
+	}
+
+	target := &syncRevertTarget{
+		stremioClient:  stremio_api.NewClient(&stremio_api.ClientConfig{BaseURL: server.URL}),
+		stremioToken:   "token",
+		stremioItemIds: []string{"tt0000001", "tt0000002", "tt0000003"},
+	}
+
+	entry := newEntry("tt0000001")
+	conflict, err := getSyncRevertConflict(target, entry)
+	require.NoError(t, err)
+	assert.Empty(t, conflict)
+	require.NoError(t, revertSyncHistoryEntry(target, entry))
+	require.Len(t, putItems, 1)
+	assert.Equal(t, "before", putItems[0].Name, "before is written back")
+
+	conflict, err = getSyncRevertConflict(target, newEntry("tt0000002"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed after the sync", conflict)
+
+	conflict, err = getSyncRevertConflict(target, newEntry("tt0000003"))
+	require.NoError(t, err)
+	assert.Equal(t, "deleted after the sync", conflict)
+}
+
+func TestRevertSyncHistoryEntryTrakt(t *testing.T) {
+	syncedAt := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+
+	playback := []trakt.PlaybackItem{
+		{Id: 1, Progress: 40, PausedAt: syncedAt, Type: trakt.ItemTypeMovie, Movie: &trakt.ListItemMovie{}},
+		{Id: 2, Progress: 70, PausedAt: syncedAt.Add(30 * time.Minute), Type: trakt.ItemTypeMovie, Movie: &trakt.ListItemMovie{}},
+		{Id: 3, Progress: 10, PausedAt: syncedAt.Add(30 * time.Minute), Type: trakt.ItemTypeMovie, Movie: &trakt.ListItemMovie{}},
+	}
+	playback[0].Movie.Ids.IMDB = "tt0000001"
+	playback[1].Movie.Ids.IMDB = "tt0000002"
+	playback[2].Movie.Ids.IMDB = "tt0000003"
+
+	history := []trakt.HistoryItem{
+		{Id: 1, WatchedAt: syncedAt.Add(30 * time.Minute), Type: trakt.ItemTypeMovie, Movie: &trakt.ListItemMovie{}},
+	}
+	history[0].Movie.Ids.IMDB = "tt0000002"
+
+	var historyStartAt string
+	scrobbled := []trakt.ScrobbleParams{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sync/playback":
+			json.NewEncoder(w).Encode(playback)
+		case "/sync/history":
+			historyStartAt = r.URL.Query().Get("start_at")
+			json.NewEncoder(w).Encode(history)
+		case "/scrobble/pause":
+			params := trakt.ScrobbleParams{}
+			json.NewDecoder(r.Body).Decode(&params)
+			scrobbled = append(scrobbled, params)
+			json.NewEncoder(w).Encode(map[string]any{"id": 1})
+		}
+	}))
+	defer server.Close()
+
+	client := trakt.NewAPIClient(&trakt.APIClientConfig{
+		OAuth: trakt.APIClientConfigOAuth{
+			GetTokenSource: func(oauth2.Config) oauth2.TokenSource { return nil },
+		},
+	})
+	client.BaseURL, _ = url.Parse(server.URL)
+
+	target := &syncRevertTarget{traktClient: client}
+
+	newPauseEntry := func(id string, before, after float64) *sync_history.Entry {
+		entry := newSyncHistoryEntry(sync_history.NewChange(sync_history.TargetTrakt, "account", sync_history.ActionTraktPlaybackPause, id, newTraktScrobbleParams(id, before), newTraktScrobbleParams(id, after)), syncedAt)
+		target.track(entry)
+		return entry
+	}
+
+	entry := newPauseEntry("tt0000001", 20, 40)
+	conflict, err := getSyncRevertConflict(target, entry)
+	require.NoError(t, err)
+	assert.Empty(t, conflict)
+	require.NoError(t, revertSyncHistoryEntry(target, entry))
+	require.Len(t, scrobbled, 1)
+	assert.Equal(t, float64(20), scrobbled[0].Progress, "before progress is restored")
+
+	conflict, err = getSyncRevertConflict(target, newPauseEntry("tt0000002", 20, 40))
+	require.NoError(t, err)
+	assert.Equal(t, "playback changed after the sync", conflict)
+
+	conflict, err = getSyncRevertConflict(target, newPauseEntry("tt0000004", 20, 40))
+	require.NoError(t, err)
+	assert.Equal(t, "playback removed after the sync", conflict)
+
+	removeEntry := newSyncHistoryEntry(sync_history.NewChange(sync_history.TargetTrakt, "account", sync_history.ActionTraktPlaybackRemove, "tt0000003", newTraktScrobbleParams("tt0000003", 50), nil), syncedAt)
+	conflict, err = getSyncRevertConflict(target, removeEntry)
+	require.NoError(t, err)
+	assert.Equal(t, "playback resumed after the sync", conflict)
+
+	newHistoryEntry := func(id string) *sync_history.Entry {
+		entry := newSyncHistoryEntry(sync_history.NewChange(sync_history.TargetTrakt, "account", sync_history.ActionTraktHistoryAdd, id, nil, trakt.AddToHistoryParams{Movies: []trakt.SyncHistoryParamsItem{{Ids: trakt.ListItemIds{IMDB: id}}}}), syncedAt)
+		target.track(entry)
+		return entry
+	}
+	historyEntries := []*sync_history.Entry{newHistoryEntry("tt0000001"), newHistoryEntry("tt0000002")}
+
+	conflict, err = getSyncRevertConflict(target, historyEntries[0])
+	require.NoError(t, err)
+	assert.Empty(t, conflict)
+	assert.Equal(t, syncedAt.UTC().Format(time.RFC3339), historyStartAt, "history is fetched since the run")
+
+	conflict, err = getSyncRevertConflict(target, historyEntries[1])
+	require.NoError(t, err)
+	assert.Equal(t, "watched again after the sync", conflict)
+}
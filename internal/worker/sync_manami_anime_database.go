@@ -7,7 +7,7 @@ import (
 	"github.com/MunifTanjim/stremthru/internal/util"
 )
 
-var syncManamiAnimeDatabaseJobTracker *JobTracker[struct{}]
+var syncManamiAnimeDatabaseJobTracker *JobTracker[JobProgress]
 
 func isManamiAnimeDatabaseSyncedThisWeek() bool {
 	if syncManamiAnimeDatabaseJobTracker == nil {
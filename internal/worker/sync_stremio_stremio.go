@@ -9,15 +9,17 @@ import (
 	stremio_account "github.com/MunifTanjim/stremthru/internal/stremio/account"
 	stremio_api "github.com/MunifTanjim/stremthru/internal/stremio/api"
 	"github.com/MunifTanjim/stremthru/internal/stremio/cinemeta"
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
 	"github.com/MunifTanjim/stremthru/internal/sync/stremio_stremio"
 	"github.com/MunifTanjim/stremthru/internal/util"
 	stremio_watched_bitfield "github.com/MunifTanjim/stremthru/stremio/watched_bitfield"
 )
 
-func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
+var syncStremioStremioLink = func() func(link *sync_stremio_stremio.SyncStremioStremioLink, log *logger.Logger, rec *syncRecorder) error {
 	type Ctx struct {
 		now        time.Time
 		log        *logger.Logger
+		rec        *syncRecorder
 		link       *sync_stremio_stremio.SyncStremioStremioLink
 		isFullSync bool
 		includeIds []string
@@ -36,13 +38,14 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 	}
 
 	type SyncParams struct {
-		SourceMovies []stremio_api.LibraryItem
-		TargetMovies []stremio_api.LibraryItem
-		SourceSeries []stremio_api.LibraryItem
-		TargetSeries []stremio_api.LibraryItem
-		TargetClient *stremio_api.Client
-		TargetToken  string
-		Direction    string
+		SourceMovies    []stremio_api.LibraryItem
+		TargetMovies    []stremio_api.LibraryItem
+		SourceSeries    []stremio_api.LibraryItem
+		TargetSeries    []stremio_api.LibraryItem
+		TargetClient    *stremio_api.Client
+		TargetToken     string
+		TargetAccountId string
+		Direction       string
 	}
 
 	syncMovies := func(ctx *Ctx, params *SyncParams) error {
@@ -84,10 +87,7 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := params.TargetClient.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
-			Ctx:     stremio_api.Ctx{APIKey: params.TargetToken},
-			Changes: itemsToUpdate,
-		})
+		err := ctx.rec.updateStremioLibraryItems(params.TargetClient, params.TargetToken, params.TargetAccountId, itemsToUpdate)
 		if err != nil {
 			return err
 		}
@@ -178,10 +178,7 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := params.TargetClient.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
-			Ctx:     stremio_api.Ctx{APIKey: params.TargetToken},
-			Changes: itemsToUpdate,
-		})
+		err := ctx.rec.updateStremioLibraryItems(params.TargetClient, params.TargetToken, params.TargetAccountId, itemsToUpdate)
 		if err != nil {
 			return err
 		}
@@ -190,7 +187,7 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 		return nil
 	}
 
-	syncWatched := func(link *sync_stremio_stremio.SyncStremioStremioLink, log *logger.Logger) error {
+	syncWatched := func(link *sync_stremio_stremio.SyncStremioStremioLink, log *logger.Logger, rec *syncRecorder) error {
 		log = log.With(
 			"account_a_id", link.AccountAId,
 			"account_b_id", link.AccountBId,
@@ -198,6 +195,7 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 
 		ctx := &Ctx{
 			log:  log,
+			rec:  rec,
 			link: link,
 		}
 
@@ -298,13 +296,14 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 
 		if link.SyncConfig.Watched.Direction.ShouldSyncAToB() {
 			params := &SyncParams{
-				SourceMovies: ctx.accountAMovies,
-				TargetMovies: ctx.accountBMovies,
-				SourceSeries: ctx.accountASeries,
-				TargetSeries: ctx.accountBSeries,
-				TargetClient: ctx.clientB,
-				TargetToken:  ctx.tokenB,
-				Direction:    "A to B",
+				SourceMovies:    ctx.accountAMovies,
+				TargetMovies:    ctx.accountBMovies,
+				SourceSeries:    ctx.accountASeries,
+				TargetSeries:    ctx.accountBSeries,
+				TargetClient:    ctx.clientB,
+				TargetToken:     ctx.tokenB,
+				TargetAccountId: link.AccountBId,
+				Direction:       "A to B",
 			}
 			if err := syncMovies(ctx, params); err != nil {
 				log.Error("failed to sync movies from A to B", "error", err)
@@ -318,13 +317,14 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 
 		if link.SyncConfig.Watched.Direction.ShouldSyncBToA() {
 			params := &SyncParams{
-				SourceMovies: ctx.accountBMovies,
-				TargetMovies: ctx.accountAMovies,
-				SourceSeries: ctx.accountBSeries,
-				TargetSeries: ctx.accountASeries,
-				TargetClient: ctx.clientA,
-				TargetToken:  ctx.tokenA,
-				Direction:    "B to A",
+				SourceMovies:    ctx.accountBMovies,
+				TargetMovies:    ctx.accountAMovies,
+				SourceSeries:    ctx.accountBSeries,
+				TargetSeries:    ctx.accountASeries,
+				TargetClient:    ctx.clientA,
+				TargetToken:     ctx.tokenA,
+				TargetAccountId: link.AccountAId,
+				Direction:       "B to A",
 			}
 			if err := syncMovies(ctx, params); err != nil {
 				log.Error("failed to sync movies from B to A", "error", err)
@@ -336,6 +336,10 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 			}
 		}
 
+		if rec.dryRun {
+			return nil
+		}
+
 		link.SyncState.Watched.LastSyncedAt = &ctx.now
 		err = sync_stremio_stremio.SetSyncState(link.AccountAId, link.AccountBId, link.SyncState)
 		if err != nil {
@@ -345,21 +349,56 @@ func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 		return nil
 	}
 
+	return syncWatched
+}()
+
+func getStremioStremioLinkId(link *sync_stremio_stremio.SyncStremioStremioLink) string {
+	return link.AccountAId + ":" + link.AccountBId
+}
+
+// DryRunSyncStremioStremioLink computes the changes the sync would apply for
+// the link, without applying them.
+func DryRunSyncStremioStremioLink(link *sync_stremio_stremio.SyncStremioStremioLink) ([]sync_history.Change, error) {
+	log := logger.Scoped("worker/sync-stremio-stremio")
+	rec := &syncRecorder{
+		dryRun: true,
+		log:    log,
+	}
+	err := syncStremioStremioLink(link, log, rec)
+	return rec.changes, err
+}
+
+func InitSyncStremioStremioWorker(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
 		log := w.Log
 
+		util.LogError(log, sync_history.Purge(syncHistoryRetention), "failed to purge sync history")
+
 		links, err := sync_stremio_stremio.GetAll()
 		if err != nil {
 			return err
 		}
 
 		for _, link := range links {
+			if w.IsCancelled() {
+				break
+			}
 			if !link.SyncConfig.Watched.Direction.IsDisabled() {
-				if err := syncWatched(&link, log); err != nil {
+				rec := &syncRecorder{
+					log:      log,
+					linkType: sync_history.LinkTypeStremioStremio,
+					linkId:   getStremioStremioLinkId(&link),
+					jobName:  conf.Name,
+					jobId:    w.JobId(),
+				}
+				if err := syncStremioStremioLink(&link, log, rec); err != nil {
 					log.Error("failed to sync link", "error", err,
 						"account_a_id", link.AccountAId,
 						"account_b_id", link.AccountBId,
 					)
+					w.ReportError(err)
+				} else {
+					w.ReportProcessed(1)
 				}
 			}
 		}
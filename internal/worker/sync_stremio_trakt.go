@@ -1,7 +1,11 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +15,7 @@ import (
 	stremio_account "github.com/MunifTanjim/stremthru/internal/stremio/account"
 	stremio_api "github.com/MunifTanjim/stremthru/internal/stremio/api"
 	"github.com/MunifTanjim/stremthru/internal/stremio/cinemeta"
+	sync_history "github.com/MunifTanjim/stremthru/internal/sync/history"
 	"github.com/MunifTanjim/stremthru/internal/sync/stremio_trakt"
 	"github.com/MunifTanjim/stremthru/internal/trakt"
 	trakt_account "github.com/MunifTanjim/stremthru/internal/trakt/account"
@@ -19,10 +24,11 @@ import (
 	stremio_watched_bitfield "github.com/MunifTanjim/stremthru/stremio/watched_bitfield"
 )
 
-func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
+var syncStremioTraktLink = func() func(link *sync_stremio_trakt.SyncStremioTraktLink, log *logger.Logger, rec *syncRecorder) error {
 	type Ctx struct {
 		now        time.Time
 		log        *logger.Logger
+		rec        *syncRecorder
 		link       *sync_stremio_trakt.SyncStremioTraktLink
 		isFullSync bool
 
@@ -162,7 +168,7 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := ctx.traktClient.AddToHistory(&trakt.AddToHistoryParams{
+		err := ctx.rec.addToTraktHistory(ctx.traktClient, ctx.traktAccount.Id, &trakt.AddToHistoryParams{
 			Movies: moviesToAdd,
 		})
 		if err != nil {
@@ -317,7 +323,7 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := ctx.traktClient.AddToHistory(&trakt.AddToHistoryParams{
+		err := ctx.rec.addToTraktHistory(ctx.traktClient, ctx.traktAccount.Id, &trakt.AddToHistoryParams{
 			Shows: showsToAdd,
 		})
 		if err != nil {
@@ -385,10 +391,7 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := ctx.stremioClient.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
-			Ctx:     stremio_api.Ctx{APIKey: ctx.stremioToken},
-			Changes: itemsToUpdate,
-		})
+		err := ctx.rec.updateStremioLibraryItems(ctx.stremioClient, ctx.stremioToken, ctx.stremioAccount.Id, itemsToUpdate)
 		if err != nil {
 			return err
 		}
@@ -493,10 +496,7 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 			return nil
 		}
 
-		_, err := ctx.stremioClient.UpdateLibraryItems(&stremio_api.UpdateLibraryItemsParams{
-			Ctx:     stremio_api.Ctx{APIKey: ctx.stremioToken},
-			Changes: itemsToUpdate,
-		})
+		err := ctx.rec.updateStremioLibraryItems(ctx.stremioClient, ctx.stremioToken, ctx.stremioAccount.Id, itemsToUpdate)
 		if err != nil {
 			return err
 		}
@@ -505,40 +505,75 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 		return nil
 	}
 
-	syncWatched := func(link *sync_stremio_trakt.SyncStremioTraktLink, log *logger.Logger) error {
-		log = log.With(
-			"stremio_account_id", link.StremioAccountId,
-			"trakt_account_id", link.TraktAccountId,
-		)
-
-		ctx := &Ctx{
-			log:  log,
-			link: link,
+	// returns the library items with imdb id, including the removed ones,
+	// modified after startAt. All the items are returned for zero startAt.
+	getStremioLibraryItems := func(ctx *Ctx, startAt time.Time) ([]stremio_api.LibraryItem, error) {
+		var ids []string
+		if !startAt.IsZero() {
+			tsRes, err := ctx.stremioClient.GetAllLibraryItemTimestamps(&stremio_api.GetAllLibraryItemTimestampsParams{Ctx: stremio_api.Ctx{APIKey: ctx.stremioToken}})
+			if err != nil {
+				return nil, err
+			}
+			for _, ts := range tsRes.Data {
+				if !strings.HasPrefix(ts.Id, "tt") {
+					continue
+				}
+				if ts.ModifiedAt.After(startAt) {
+					ids = append(ids, ts.Id)
+				}
+			}
+			if len(ids) == 0 {
+				return nil, nil
+			}
 		}
 
-		stremioAccount, err := stremio_account.GetById(link.StremioAccountId)
-		if err != nil || stremioAccount == nil {
-			return fmt.Errorf("stremio account not found: %w", err)
+		res, err := ctx.stremioClient.GetAllLibraryItems(&stremio_api.GetAllLibraryItemsParams{
+			Ctx: stremio_api.Ctx{APIKey: ctx.stremioToken},
+			Ids: ids,
+		})
+		if err != nil {
+			return nil, err
 		}
-		ctx.stremioAccount = stremioAccount
-
-		traktAccount, err := trakt_account.GetById(link.TraktAccountId)
-		if err != nil || traktAccount == nil {
-			return fmt.Errorf("trakt account not found: %w", err)
+		items := make([]stremio_api.LibraryItem, 0, len(res.Data))
+		for _, item := range res.Data {
+			if !strings.HasPrefix(item.Id, "tt") {
+				continue
+			}
+			items = append(items, item)
 		}
-		ctx.traktAccount = traktAccount
+		return items, nil
+	}
 
-		stremioToken, err := stremioAccount.GetValidToken()
+	// fetches the library items missing in itemById.
+	fillStremioLibraryItems := func(ctx *Ctx, itemById map[string]stremio_api.LibraryItem, ids []string) error {
+		var idsToFetch []string
+		for _, id := range ids {
+			if _, ok := itemById[id]; !ok {
+				idsToFetch = append(idsToFetch, id)
+			}
+		}
+		if len(idsToFetch) == 0 {
+			return nil
+		}
+		res, err := ctx.stremioClient.GetAllLibraryItems(&stremio_api.GetAllLibraryItemsParams{
+			Ctx: stremio_api.Ctx{APIKey: ctx.stremioToken},
+			Ids: idsToFetch,
+		})
 		if err != nil {
 			return err
 		}
-		ctx.stremioToken = stremioToken
-
-		ctx.stremioClient = stremio_api.NewClient(&stremio_api.ClientConfig{})
+		for _, item := range res.Data {
+			itemById[item.Id] = item
+		}
+		return nil
+	}
 
-		ctx.traktClient = trakt.GetAPIClient(traktAccount.OAuthTokenId)
+	updateStremioLibraryItems := func(ctx *Ctx, items []stremio_api.LibraryItem) error {
+		return ctx.rec.updateStremioLibraryItems(ctx.stremioClient, ctx.stremioToken, ctx.stremioAccount.Id, items)
+	}
 
-		ctx.now = time.Now()
+	syncWatched := func(ctx *Ctx) error {
+		link, log := ctx.link, ctx.log
 
 		var startAt time.Time
 		if link.SyncState.Watched.LastSyncedAt != nil {
@@ -546,46 +581,24 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 		}
 
 		ctx.isFullSync = startAt.IsZero()
+		ctx.stremioMovies, ctx.stremioSeries = nil, nil
+		ctx.traktMovies, ctx.traktEpisodes = nil, nil
 
 		log.Debug("starting watched sync", "is_full_sync", ctx.isFullSync, "start_at", startAt)
 
-		var stremioItemIds []string
-		if !ctx.isFullSync {
-			tsRes, err := ctx.stremioClient.GetAllLibraryItemTimestamps(&stremio_api.GetAllLibraryItemTimestampsParams{Ctx: stremio_api.Ctx{APIKey: stremioToken}})
-			if err != nil {
-				return err
-			}
-			for _, ts := range tsRes.Data {
-				if !strings.HasPrefix(ts.Id, "tt") {
-					continue
-				}
-				if ts.ModifiedAt.After(startAt) {
-					stremioItemIds = append(stremioItemIds, ts.Id)
-				}
-			}
+		stremioItems, err := getStremioLibraryItems(ctx, startAt)
+		if err != nil {
+			return err
 		}
-
-		if ctx.isFullSync || len(stremioItemIds) > 0 {
-			stremioLibItemsRes, err := ctx.stremioClient.GetAllLibraryItems(&stremio_api.GetAllLibraryItemsParams{
-				Ctx: stremio_api.Ctx{APIKey: stremioToken},
-				Ids: stremioItemIds,
-			})
-			if err != nil {
-				return err
+		for _, item := range stremioItems {
+			if item.Removed {
+				continue
 			}
-			for _, item := range stremioLibItemsRes.Data {
-				if !strings.HasPrefix(item.Id, "tt") {
-					continue
-				}
-				if item.Removed {
-					continue
-				}
-				switch item.Type {
-				case "movie":
-					ctx.stremioMovies = append(ctx.stremioMovies, item)
-				case "series":
-					ctx.stremioSeries = append(ctx.stremioSeries, item)
-				}
+			switch item.Type {
+			case "movie":
+				ctx.stremioMovies = append(ctx.stremioMovies, item)
+			case "series":
+				ctx.stremioSeries = append(ctx.stremioSeries, item)
 			}
 		}
 
@@ -642,24 +655,633 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 		}
 
 		link.SyncState.Watched.LastSyncedAt = &ctx.now
-		sync_stremio_trakt.SetSyncState(link.StremioAccountId, link.TraktAccountId, link.SyncState)
 		return nil
 	}
 
+	// Trakt watchlist <-> not-yet-watched items in Stremio library.
+	//
+	// Removals are detected on the Stremio side by the removed library items,
+	// and on the Trakt side by diffing the watchlist with the snapshot taken
+	// at the last sync. With the `propagate` removal policy, a removal is
+	// propagated unless the item was changed on the other side after it.
+	syncWatchlist := func(ctx *Ctx) error {
+		config := ctx.link.SyncConfig.Watchlist
+		state := &ctx.link.SyncState.Watchlist
+
+		var startAt time.Time
+		if state.LastSyncedAt != nil {
+			startAt = *state.LastSyncedAt
+		}
+		isFullSync := startAt.IsZero()
+
+		ctx.log.Debug("starting watchlist sync", "is_full_sync", isFullSync, "start_at", startAt)
+
+		traktItems, err := getAllTraktPages(func(page, limit int) ([]trakt.WatchlistItem, error) {
+			res, err := ctx.traktClient.GetWatchlist(&trakt.GetWatchlistParams{Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return err
+		}
+		traktItemByImdbId := map[string]trakt.WatchlistItem{}
+		for _, item := range traktItems {
+			switch item.Type {
+			case trakt.ItemTypeMovie:
+				if item.Movie != nil && item.Movie.Ids.IMDB != "" {
+					traktItemByImdbId[item.Movie.Ids.IMDB] = item
+				}
+			case trakt.ItemTypeShow:
+				if item.Show != nil && item.Show.Ids.IMDB != "" {
+					traktItemByImdbId[item.Show.Ids.IMDB] = item
+				}
+			}
+		}
+
+		removedFromTrakt := util.NewSet[string]()
+		if !isFullSync {
+			for _, id := range state.Ids {
+				if _, ok := traktItemByImdbId[id]; !ok {
+					removedFromTrakt.Add(id)
+				}
+			}
+		}
+
+		stremioItems, err := getStremioLibraryItems(ctx, startAt)
+		if err != nil {
+			return err
+		}
+		stremioItemByImdbId := map[string]stremio_api.LibraryItem{}
+		for _, item := range stremioItems {
+			if item.Type == "movie" || item.Type == "series" {
+				stremioItemByImdbId[item.Id] = item
+			}
+		}
+
+		addToTrakt := &trakt.AddToWatchlistParams{}
+		removeFromTrakt := &trakt.RemoveFromWatchlistParams{}
+		addedToTrakt, removedFromTraktBySync := util.NewSet[string](), util.NewSet[string]()
+
+		if config.Direction.ShouldSyncToTrakt() {
+			for _, item := range stremioItemByImdbId {
+				if item.Temp {
+					continue
+				}
+				traktItem, inWatchlist := traktItemByImdbId[item.Id]
+				watchlistItem := trakt.SyncWatchlistParamsItem{Ids: trakt.ListItemIds{IMDB: item.Id}}
+				if item.Removed {
+					if isFullSync || !inWatchlist || !config.OnRemove.ShouldPropagate() {
+						continue
+					}
+					// listed again on trakt after the removal from library
+					if traktItem.ListedAt.After(item.MTime.Time) {
+						continue
+					}
+					if item.Type == "movie" {
+						removeFromTrakt.Movies = append(removeFromTrakt.Movies, watchlistItem)
+					} else {
+						removeFromTrakt.Shows = append(removeFromTrakt.Shows, watchlistItem)
+					}
+					removedFromTraktBySync.Add(item.Id)
+					continue
+				}
+				// removed on trakt since the last sync, not added back
+				if inWatchlist || !isStremioLibraryItemUnwatched(&item) || removedFromTrakt.Has(item.Id) {
+					continue
+				}
+				if item.Type == "movie" {
+					addToTrakt.Movies = append(addToTrakt.Movies, watchlistItem)
+				} else {
+					addToTrakt.Shows = append(addToTrakt.Shows, watchlistItem)
+				}
+				addedToTrakt.Add(item.Id)
+			}
+		}
+
+		var stremioChanges []stremio_api.LibraryItem
+
+		if config.Direction.ShouldSyncToStremio() {
+			var ids []string
+			for imdbId, item := range traktItemByImdbId {
+				if removedFromTraktBySync.Has(imdbId) || (!isFullSync && !item.ListedAt.After(startAt)) {
+					continue
+				}
+				ids = append(ids, imdbId)
+			}
+			if config.OnRemove.ShouldPropagate() {
+				ids = append(ids, removedFromTrakt.ToSlice()...)
+			}
+			if !isFullSync {
+				if err := fillStremioLibraryItems(ctx, stremioItemByImdbId, ids); err != nil {
+					return err
+				}
+			}
+
+			for imdbId, traktItem := range traktItemByImdbId {
+				if removedFromTraktBySync.Has(imdbId) || (!isFullSync && !traktItem.ListedAt.After(startAt)) {
+					continue
+				}
+				libraryItem, exists := stremioItemByImdbId[imdbId]
+				if exists {
+					if !libraryItem.Removed && !libraryItem.Temp {
+						continue
+					}
+					// removed from library after it was listed on trakt
+					if libraryItem.Removed && !traktItem.ListedAt.After(libraryItem.MTime.Time) {
+						continue
+					}
+					libraryItem.Removed = false
+					libraryItem.Temp = false
+					libraryItem.MTime = stremio_api.JSONTime{Time: ctx.now}
+				} else {
+					sType := "movie"
+					if traktItem.Type == trakt.ItemTypeShow {
+						sType = "series"
+					}
+					meta, err := cinemeta.FetchMeta(sType, imdbId)
+					if err != nil {
+						ctx.log.Warn("failed to fetch meta", "error", err, "id", imdbId)
+						continue
+					}
+					libraryItem = createLibraryItem(ctx, meta, stremio_api.LibraryItemState{})
+				}
+				stremioChanges = append(stremioChanges, libraryItem)
+			}
+
+			if config.OnRemove.ShouldPropagate() {
+				for imdbId := range removedFromTrakt.Seq() {
+					libraryItem, exists := stremioItemByImdbId[imdbId]
+					if !exists || libraryItem.Removed || libraryItem.Temp || !isStremioLibraryItemUnwatched(&libraryItem) {
+						continue
+					}
+					// changed in stremio after the last sync
+					if libraryItem.MTime.After(startAt) {
+						continue
+					}
+					libraryItem.Removed = true
+					libraryItem.MTime = stremio_api.JSONTime{Time: ctx.now}
+					stremioChanges = append(stremioChanges, libraryItem)
+				}
+			}
+		}
+
+		if len(addToTrakt.Movies) > 0 || len(addToTrakt.Shows) > 0 {
+			if err := ctx.rec.addToTraktWatchlist(ctx.traktClient, ctx.traktAccount.Id, addToTrakt); err != nil {
+				return err
+			}
+		}
+		if len(removeFromTrakt.Movies) > 0 || len(removeFromTrakt.Shows) > 0 {
+			if err := ctx.rec.removeFromTraktWatchlist(ctx.traktClient, ctx.traktAccount.Id, removeFromTrakt); err != nil {
+				return err
+			}
+		}
+		if err := updateStremioLibraryItems(ctx, stremioChanges); err != nil {
+			return err
+		}
+
+		ctx.log.Debug("synced watchlist", "added_to_trakt", addedToTrakt.Len(), "removed_from_trakt", removedFromTraktBySync.Len(), "updated_in_stremio", len(stremioChanges))
+
+		ids := make([]string, 0, len(traktItemByImdbId)+addedToTrakt.Len())
+		for imdbId := range traktItemByImdbId {
+			if !removedFromTraktBySync.Has(imdbId) {
+				ids = append(ids, imdbId)
+			}
+		}
+		ids = append(ids, addedToTrakt.ToSlice()...)
+		slices.Sort(ids)
+
+		state.Ids = ids
+		state.LastSyncedAt = &ctx.now
+		return nil
+	}
+
+	// In-progress playback position in Stremio <-> Trakt playback progress.
+	//
+	// The most recently played side wins. Removals are detected on the Stremio
+	// side by the cleared time offset, and on the Trakt side by diffing the
+	// playback progress with the snapshot taken at the last sync.
+	syncProgress := func(ctx *Ctx) error {
+		config := ctx.link.SyncConfig.Progress
+		state := &ctx.link.SyncState.Progress
+
+		var startAt time.Time
+		if state.LastSyncedAt != nil {
+			startAt = *state.LastSyncedAt
+		}
+		isFullSync := startAt.IsZero()
+		minPlayedAt := ctx.now.Add(-syncStremioTraktProgressMaxAge)
+
+		ctx.log.Debug("starting progress sync", "is_full_sync", isFullSync, "start_at", startAt)
+
+		playbackItems, err := getAllTraktPages(func(page, limit int) ([]trakt.PlaybackItem, error) {
+			res, err := ctx.traktClient.GetPlayback(&trakt.GetPlaybackParams{Extended: true, Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return err
+		}
+		playbackByVideoId := map[string]trakt.PlaybackItem{}
+		for _, item := range playbackItems {
+			switch item.Type {
+			case trakt.ItemTypeMovie:
+				if item.Movie != nil && item.Movie.Ids.IMDB != "" {
+					playbackByVideoId[item.Movie.Ids.IMDB] = item
+				}
+			case trakt.ItemTypeEpisode:
+				if item.Show != nil && item.Show.Ids.IMDB != "" && item.Episode != nil {
+					playbackByVideoId[fmt.Sprintf("%s:%d:%d", item.Show.Ids.IMDB, item.Episode.Season, item.Episode.Number)] = item
+				}
+			}
+		}
+
+		removedFromTrakt := util.NewSet[string]()
+		if !isFullSync {
+			for _, videoId := range state.Ids {
+				if _, ok := playbackByVideoId[videoId]; !ok {
+					removedFromTrakt.Add(videoId)
+				}
+			}
+		}
+
+		stremioItems, err := getStremioLibraryItems(ctx, startAt)
+		if err != nil {
+			return err
+		}
+		stremioItemByImdbId := map[string]stremio_api.LibraryItem{}
+		for _, item := range stremioItems {
+			if item.Type == "movie" || item.Type == "series" {
+				stremioItemByImdbId[item.Id] = item
+			}
+		}
+
+		scrobbledCount, removedFromTraktCount := 0, 0
+
+		if config.Direction.ShouldSyncToTrakt() {
+			for _, item := range stremioItemByImdbId {
+				if item.Removed {
+					continue
+				}
+				videoId := getStremioLibraryItemVideoId(&item)
+				if videoId == "" {
+					continue
+				}
+				playback, hasPlayback := playbackByVideoId[videoId]
+				progress := getStremioLibraryItemProgress(&item)
+				if progress <= 0 {
+					// finished or dismissed in stremio after it was paused on trakt
+					if hasPlayback && !isFullSync && config.OnRemove.ShouldPropagate() && item.State.LastWatched.After(playback.PausedAt) {
+						if err := ctx.rec.removeTraktPlayback(ctx.traktClient, ctx.traktAccount.Id, videoId, &playback); err != nil {
+							return err
+						}
+						delete(playbackByVideoId, videoId)
+						removedFromTraktCount++
+					}
+					continue
+				}
+				if progress >= 100 || (isFullSync && item.State.LastWatched.Before(minPlayedAt)) {
+					continue
+				}
+				if hasPlayback && (!item.State.LastWatched.After(playback.PausedAt) || math.Abs(playback.Progress-progress) < 1) {
+					continue
+				}
+
+				var before *trakt.ScrobbleParams
+				if hasPlayback {
+					before = newTraktScrobbleParams(videoId, playback.Progress)
+				}
+				playbackId, err := ctx.rec.pauseTraktScrobble(ctx.traktClient, ctx.traktAccount.Id, videoId, before, newTraktScrobbleParams(videoId, progress))
+				if err != nil {
+					ctx.log.Warn("failed to scrobble progress", "error", err, "id", videoId)
+					continue
+				}
+				playbackByVideoId[videoId] = trakt.PlaybackItem{Id: playbackId, Progress: progress, PausedAt: ctx.now}
+				scrobbledCount++
+			}
+		}
+
+		var stremioChanges []stremio_api.LibraryItem
+
+		if config.Direction.ShouldSyncToStremio() {
+			var ids []string
+			for videoId, playback := range playbackByVideoId {
+				if !isFullSync && !playback.PausedAt.After(startAt) {
+					continue
+				}
+				imdbId, _, _ := strings.Cut(videoId, ":")
+				ids = append(ids, imdbId)
+			}
+			if config.OnRemove.ShouldPropagate() {
+				for videoId := range removedFromTrakt.Seq() {
+					imdbId, _, _ := strings.Cut(videoId, ":")
+					ids = append(ids, imdbId)
+				}
+			}
+			if !isFullSync {
+				if err := fillStremioLibraryItems(ctx, stremioItemByImdbId, ids); err != nil {
+					return err
+				}
+			}
+
+			for videoId, playback := range playbackByVideoId {
+				if playback.PausedAt.Equal(ctx.now) || (!isFullSync && !playback.PausedAt.After(startAt)) {
+					continue
+				}
+				if isFullSync && playback.PausedAt.Before(minPlayedAt) {
+					continue
+				}
+				imdbId, _, isEpisode := strings.Cut(videoId, ":")
+				libraryItem, exists := stremioItemByImdbId[imdbId]
+				if exists {
+					// played in stremio after it was paused on trakt
+					if libraryItem.State.LastWatched.After(playback.PausedAt) {
+						continue
+					}
+					// removed from library after it was paused on trakt
+					if libraryItem.Removed && libraryItem.MTime.After(playback.PausedAt) {
+						continue
+					}
+				}
+
+				duration := 0
+				if exists && getStremioLibraryItemVideoId(&libraryItem) == videoId {
+					duration = libraryItem.State.Duration
+				}
+				if duration == 0 {
+					duration = playback.GetRuntime() * int(time.Minute/time.Millisecond)
+				}
+				if duration == 0 {
+					continue
+				}
+				timeOffset := int(playback.Progress / 100 * float64(duration))
+				if exists && getStremioLibraryItemVideoId(&libraryItem) == videoId && libraryItem.State.TimeOffset > 0 && math.Abs(float64(timeOffset-libraryItem.State.TimeOffset)) < float64(duration)/100 {
+					continue
+				}
+
+				if exists {
+					libraryItem.Removed = false
+					libraryItem.MTime = stremio_api.JSONTime{Time: ctx.now}
+				} else {
+					sType := "movie"
+					if isEpisode {
+						sType = "series"
+					}
+					meta, err := cinemeta.FetchMeta(sType, imdbId)
+					if err != nil {
+						ctx.log.Warn("failed to fetch meta", "error", err, "id", imdbId)
+						continue
+					}
+					libraryItem = createLibraryItem(ctx, meta, stremio_api.LibraryItemState{})
+					libraryItem.Temp = true
+				}
+				libraryItem.State.VideoId = videoId
+				libraryItem.State.TimeOffset = timeOffset
+				libraryItem.State.Duration = duration
+				libraryItem.State.LastWatched = playback.PausedAt
+				if isEpisode && playback.Episode != nil {
+					libraryItem.State.Season = playback.Episode.Season
+					libraryItem.State.Episode = playback.Episode.Number
+				}
+				stremioItemByImdbId[imdbId] = libraryItem
+				stremioChanges = append(stremioChanges, libraryItem)
+			}
+
+			if config.OnRemove.ShouldPropagate() {
+				for videoId := range removedFromTrakt.Seq() {
+					imdbId, _, _ := strings.Cut(videoId, ":")
+					libraryItem, exists := stremioItemByImdbId[imdbId]
+					if !exists || libraryItem.Removed || libraryItem.State.TimeOffset == 0 || getStremioLibraryItemVideoId(&libraryItem) != videoId {
+						continue
+					}
+					// played in stremio after the last sync
+					if libraryItem.State.LastWatched.After(startAt) {
+						continue
+					}
+					libraryItem.State.TimeOffset = 0
+					libraryItem.MTime = stremio_api.JSONTime{Time: ctx.now}
+					stremioChanges = append(stremioChanges, libraryItem)
+				}
+			}
+		}
+
+		if err := updateStremioLibraryItems(ctx, stremioChanges); err != nil {
+			return err
+		}
+
+		ctx.log.Debug("synced progress", "scrobbled_to_trakt", scrobbledCount, "removed_from_trakt", removedFromTraktCount, "updated_in_stremio", len(stremioChanges))
+
+		state.Ids = slices.Sorted(maps.Keys(playbackByVideoId))
+		state.LastSyncedAt = &ctx.now
+		return nil
+	}
+
+	// Trakt ratings -> Stremio. The rated movies and shows (the show of a rated
+	// episode) are added to the library, the watched state is left untouched.
+	syncRatings := func(ctx *Ctx) error {
+		state := &ctx.link.SyncState.Ratings
+
+		var startAt time.Time
+		if state.LastSyncedAt != nil {
+			startAt = *state.LastSyncedAt
+		}
+		isFullSync := startAt.IsZero()
+
+		ctx.log.Debug("starting ratings sync", "is_full_sync", isFullSync, "start_at", startAt)
+
+		ratingItems, err := getAllTraktPages(func(page, limit int) ([]trakt.RatingItem, error) {
+			res, err := ctx.traktClient.GetRatings(&trakt.GetRatingsParams{Page: page, Limit: limit})
+			return res.Data, err
+		})
+		if err != nil {
+			return err
+		}
+
+		type ratedItem struct {
+			sType   string
+			ratedAt time.Time
+		}
+		ratedItemByImdbId := map[string]ratedItem{}
+		for _, item := range ratingItems {
+			if !isFullSync && !item.RatedAt.After(startAt) {
+				continue
+			}
+			imdbId, sType := "", ""
+			switch item.Type {
+			case trakt.ItemTypeMovie:
+				if item.Movie != nil {
+					imdbId, sType = item.Movie.Ids.IMDB, "movie"
+				}
+			case trakt.ItemTypeShow, trakt.ItemTypeSeason, trakt.ItemTypeEpisode:
+				if item.Show != nil {
+					imdbId, sType = item.Show.Ids.IMDB, "series"
+				}
+			}
+			if imdbId == "" {
+				continue
+			}
+			if rated, ok := ratedItemByImdbId[imdbId]; !ok || item.RatedAt.After(rated.ratedAt) {
+				ratedItemByImdbId[imdbId] = ratedItem{sType: sType, ratedAt: item.RatedAt}
+			}
+		}
+
+		var stremioChanges []stremio_api.LibraryItem
+		if len(ratedItemByImdbId) > 0 {
+			stremioItemByImdbId := map[string]stremio_api.LibraryItem{}
+			if err := fillStremioLibraryItems(ctx, stremioItemByImdbId, slices.Collect(maps.Keys(ratedItemByImdbId))); err != nil {
+				return err
+			}
+
+			for imdbId, rated := range ratedItemByImdbId {
+				libraryItem, exists := stremioItemByImdbId[imdbId]
+				if exists {
+					if !libraryItem.Removed && !libraryItem.Temp {
+						continue
+					}
+					// removed from library after it was rated
+					if libraryItem.Removed && !rated.ratedAt.After(libraryItem.MTime.Time) {
+						continue
+					}
+					libraryItem.Removed = false
+					libraryItem.Temp = false
+					libraryItem.MTime = stremio_api.JSONTime{Time: ctx.now}
+				} else {
+					meta, err := cinemeta.FetchMeta(rated.sType, imdbId)
+					if err != nil {
+						ctx.log.Warn("failed to fetch meta", "error", err, "id", imdbId)
+						continue
+					}
+					libraryItem = createLibraryItem(ctx, meta, stremio_api.LibraryItemState{})
+				}
+				stremioChanges = append(stremioChanges, libraryItem)
+			}
+		}
+
+		if err := updateStremioLibraryItems(ctx, stremioChanges); err != nil {
+			return err
+		}
+
+		ctx.log.Debug("synced ratings", "rated", len(ratedItemByImdbId), "added_to_stremio", len(stremioChanges))
+
+		state.LastSyncedAt = &ctx.now
+		return nil
+	}
+
+	syncLink := func(link *sync_stremio_trakt.SyncStremioTraktLink, log *logger.Logger, rec *syncRecorder) error {
+		log = log.With(
+			"stremio_account_id", link.StremioAccountId,
+			"trakt_account_id", link.TraktAccountId,
+		)
+
+		ctx := &Ctx{
+			log:  log,
+			rec:  rec,
+			link: link,
+		}
+
+		stremioAccount, err := stremio_account.GetById(link.StremioAccountId)
+		if err != nil || stremioAccount == nil {
+			return fmt.Errorf("stremio account not found: %w", err)
+		}
+		ctx.stremioAccount = stremioAccount
+
+		traktAccount, err := trakt_account.GetById(link.TraktAccountId)
+		if err != nil || traktAccount == nil {
+			return fmt.Errorf("trakt account not found: %w", err)
+		}
+		ctx.traktAccount = traktAccount
+
+		stremioToken, err := stremioAccount.GetValidToken()
+		if err != nil {
+			return err
+		}
+		ctx.stremioToken = stremioToken
+
+		ctx.stremioClient = stremio_api.NewClient(&stremio_api.ClientConfig{})
+
+		ctx.traktClient = trakt.GetAPIClient(traktAccount.OAuthTokenId)
+
+		ctx.now = time.Now()
+
+		var errs []error
+
+		if !link.SyncConfig.Watched.Direction.IsDisabled() {
+			if err := syncWatched(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if !link.SyncConfig.Watchlist.Direction.IsDisabled() {
+			if err := syncWatchlist(ctx); err != nil {
+				log.Error("failed to sync watchlist", "error", err)
+				errs = append(errs, err)
+			}
+		}
+
+		if !link.SyncConfig.Progress.Direction.IsDisabled() {
+			if err := syncProgress(ctx); err != nil {
+				log.Error("failed to sync progress", "error", err)
+				errs = append(errs, err)
+			}
+		}
+
+		if !link.SyncConfig.Ratings.Direction.IsDisabled() {
+			if err := syncRatings(ctx); err != nil {
+				log.Error("failed to sync ratings", "error", err)
+				errs = append(errs, err)
+			}
+		}
+
+		if !rec.dryRun {
+			util.LogError(log, sync_stremio_trakt.SetSyncState(link.StremioAccountId, link.TraktAccountId, link.SyncState), "failed to set sync state")
+		}
+		return errors.Join(errs...)
+	}
+
+	return syncLink
+}()
+
+func getStremioTraktLinkId(link *sync_stremio_trakt.SyncStremioTraktLink) string {
+	return link.StremioAccountId + ":" + link.TraktAccountId
+}
+
+// DryRunSyncStremioTraktLink computes the changes the sync would apply for
+// the link, without applying them.
+func DryRunSyncStremioTraktLink(link *sync_stremio_trakt.SyncStremioTraktLink) ([]sync_history.Change, error) {
+	log := logger.Scoped("worker/sync-stremio-trakt")
+	rec := &syncRecorder{
+		dryRun: true,
+		log:    log,
+	}
+	err := syncStremioTraktLink(link, log, rec)
+	return rec.changes, err
+}
+
+func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 	conf.Executor = func(w *Worker) error {
 		log := w.Log
 
+		util.LogError(log, sync_history.Purge(syncHistoryRetention), "failed to purge sync history")
+
 		links, err := sync_stremio_trakt.GetAll()
 		if err != nil {
 			return err
 		}
 
 		for _, link := range links {
-			if !link.SyncConfig.Watched.Direction.IsDisabled() {
-				err := syncWatched(&link, log)
+			if w.IsCancelled() {
+				break
+			}
+			if !link.SyncConfig.IsDisabled() {
+				rec := &syncRecorder{
+					log:      log,
+					linkType: sync_history.LinkTypeStremioTrakt,
+					linkId:   getStremioTraktLinkId(&link),
+					jobName:  conf.Name,
+					jobId:    w.JobId(),
+				}
+				err := syncStremioTraktLink(&link, log, rec)
 				if err != nil {
 					return err
 				}
+				w.ReportProcessed(1)
 			}
 		}
 
@@ -667,3 +1289,48 @@ func InitSyncStremioTraktWorker(conf *WorkerConfig) *Worker {
 	}
 	return NewWorker(conf)
 }
+
+const syncStremioTraktProgressMaxAge = 30 * 24 * time.Hour
+
+func getAllTraktPages[T any](fetch func(page, limit int) ([]T, error)) ([]T, error) {
+	page := 1
+	limit := 100
+	var allItems []T
+	for {
+		items, err := fetch(page, limit)
+		if err != nil {
+			return nil, err
+		}
+		allItems = append(allItems, items...)
+		if len(items) < limit {
+			break
+		}
+		page++
+	}
+	return allItems, nil
+}
+
+func isStremioLibraryItemUnwatched(item *stremio_api.LibraryItem) bool {
+	return item.State.TimesWatched == 0 && item.State.FlaggedWatched == 0 && item.State.Watched == ""
+}
+
+// returns the id of the video the playback position belongs to, i.e. the
+// imdb id for movie and `{imdb_id}:{season}:{episode}` for series.
+func getStremioLibraryItemVideoId(item *stremio_api.LibraryItem) string {
+	switch item.Type {
+	case "movie":
+		return item.Id
+	case "series":
+		if parts := strings.Split(item.State.VideoId, ":"); len(parts) == 3 && parts[0] == item.Id {
+			return item.State.VideoId
+		}
+	}
+	return ""
+}
+
+func getStremioLibraryItemProgress(item *stremio_api.LibraryItem) float64 {
+	if item.State.TimeOffset <= 0 || item.State.Duration <= 0 {
+		return 0
+	}
+	return float64(item.State.TimeOffset) / float64(item.State.Duration) * 100
+}
@@ -0,0 +1,441 @@
+package worker
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/imdb_title"
+	"github.com/MunifTanjim/stremthru/internal/letterboxd"
+	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/magnet_cache"
+	"github.com/MunifTanjim/stremthru/internal/mdblist"
+	"github.com/MunifTanjim/stremthru/internal/shared"
+	stremio_shared "github.com/MunifTanjim/stremthru/internal/stremio/shared"
+	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
+	"github.com/MunifTanjim/stremthru/internal/tmdb"
+	"github.com/MunifTanjim/stremthru/internal/torrent_info"
+	"github.com/MunifTanjim/stremthru/internal/torrent_stream"
+	"github.com/MunifTanjim/stremthru/internal/trakt"
+	"github.com/MunifTanjim/stremthru/store"
+	"golang.org/x/time/rate"
+)
+
+const warmMagnetCacheLookback = 7 * 24 * time.Hour
+
+// device the chillstreams pool keys are leased for.
+const warmMagnetCacheDeviceId = "stremthru-magnet-cache-warmer"
+
+type warmerListItem struct {
+	id       string
+	itemType string
+}
+
+// warmerList describes the tables of a list integration, the top ranked
+// items of its recently synced lists are warmed.
+type warmerList struct {
+	name string
+
+	listTable           string
+	listIdColumn        string
+	listUpdatedAtColumn string
+
+	itemTable        string
+	itemListIdColumn string
+	itemIdColumn     string
+	itemTypeColumn   string // optional
+	itemRankColumn   string
+
+	// returns the imdb ids of the items, in the same order
+	getIMDBIds func(items []warmerListItem) ([]string, error)
+}
+
+func (l warmerList) query() string {
+	columns := "li." + l.itemIdColumn
+	if l.itemTypeColumn != "" {
+		columns += ", li." + l.itemTypeColumn
+	}
+	return fmt.Sprintf(
+		`SELECT %s FROM %s li JOIN %s l ON l.%s = li.%s WHERE l.%s > ? GROUP BY %s ORDER BY MIN(li.%s) LIMIT ?`,
+		columns,
+		l.itemTable,
+		l.listTable,
+		l.listIdColumn,
+		l.itemListIdColumn,
+		l.listUpdatedAtColumn,
+		columns,
+		l.itemRankColumn,
+	)
+}
+
+// getTopIMDBIds returns the imdb ids of the top ranked items across the
+// lists synced after `syncedAfter`.
+func (l warmerList) getTopIMDBIds(syncedAfter time.Time, limit int) ([]string, error) {
+	rows, err := db.Query(l.query(), db.Timestamp{Time: syncedAfter}, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []warmerListItem{}
+	for rows.Next() {
+		item := warmerListItem{}
+		dest := []any{&item.id}
+		if l.itemTypeColumn != "" {
+			dest = append(dest, &item.itemType)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return l.getIMDBIds(items)
+}
+
+// maps the movie and show items to imdb ids using `getIMDBIdsByIds`, the
+// items of other types are skipped.
+func getWarmerMovieShowIMDBIds(items []warmerListItem, movieType, showType string, getIMDBIdsByIds func(movieIds, showIds []string) (map[string]string, map[string]string, error)) ([]string, error) {
+	movieIds, showIds := []string{}, []string{}
+	for _, item := range items {
+		switch item.itemType {
+		case movieType:
+			movieIds = append(movieIds, item.id)
+		case showType:
+			showIds = append(showIds, item.id)
+		}
+	}
+	imdbIdByMovieId, imdbIdByShowId, err := getIMDBIdsByIds(movieIds, showIds)
+	if err != nil {
+		return nil, err
+	}
+	imdbIds := []string{}
+	for _, item := range items {
+		imdbId := ""
+		switch item.itemType {
+		case movieType:
+			imdbId = imdbIdByMovieId[item.id]
+		case showType:
+			imdbId = imdbIdByShowId[item.id]
+		}
+		if imdbId != "" {
+			imdbIds = append(imdbIds, imdbId)
+		}
+	}
+	return imdbIds, nil
+}
+
+var warmerLists = []warmerList{
+	{
+		name:                "trakt",
+		listTable:           trakt.ListTableName,
+		listIdColumn:        trakt.ListColumn.Id,
+		listUpdatedAtColumn: trakt.ListColumn.UpdatedAt,
+		itemTable:           trakt.ListItemTableName,
+		itemListIdColumn:    trakt.ListItemColumn.ListId,
+		itemIdColumn:        trakt.ListItemColumn.ItemId,
+		itemTypeColumn:      trakt.ListItemColumn.ItemType,
+		itemRankColumn:      trakt.ListItemColumn.Idx,
+		getIMDBIds: func(items []warmerListItem) ([]string, error) {
+			return getWarmerMovieShowIMDBIds(items, string(trakt.ItemTypeMovie), string(trakt.ItemTypeShow), imdb_title.GetIMDBIdByTraktId)
+		},
+	},
+	{
+		name:                "mdblist",
+		listTable:           mdblist.ListTableName,
+		listIdColumn:        mdblist.ListColumn.Id,
+		listUpdatedAtColumn: mdblist.ListColumn.UpdatedAt,
+		itemTable:           mdblist.ListItemTableName,
+		itemListIdColumn:    mdblist.ListItemColumn.ListId,
+		itemIdColumn:        mdblist.ListItemColumn.ItemId,
+		itemRankColumn:      mdblist.ListItemColumn.Rank,
+		getIMDBIds: func(items []warmerListItem) ([]string, error) {
+			imdbIds := make([]string, len(items))
+			for i := range items {
+				imdbIds[i] = items[i].id
+			}
+			return imdbIds, nil
+		},
+	},
+	{
+		name:                "tmdb",
+		listTable:           tmdb.ListTableName,
+		listIdColumn:        tmdb.ListColumn.Id,
+		listUpdatedAtColumn: tmdb.ListColumn.UpdatedAt,
+		itemTable:           tmdb.ListItemTableName,
+		itemListIdColumn:    tmdb.ListItemColumn.ListId,
+		itemIdColumn:        tmdb.ListItemColumn.ItemId,
+		itemTypeColumn:      tmdb.ListItemColumn.ItemType,
+		itemRankColumn:      tmdb.ListItemColumn.Idx,
+		getIMDBIds: func(items []warmerListItem) ([]string, error) {
+			return getWarmerMovieShowIMDBIds(items, string(tmdb.MediaTypeMovie), string(tmdb.MediaTypeTVShow), imdb_title.GetIMDBIdByTMDBId)
+		},
+	},
+	{
+		name:                "letterboxd",
+		listTable:           letterboxd.ListTableName,
+		listIdColumn:        letterboxd.ListColumn.Id,
+		listUpdatedAtColumn: letterboxd.ListColumn.UpdatedAt,
+		itemTable:           letterboxd.ListItemTableName,
+		itemListIdColumn:    letterboxd.ListItemColumn.ListId,
+		itemIdColumn:        letterboxd.ListItemColumn.ItemId,
+		itemRankColumn:      letterboxd.ListItemColumn.Rank,
+		getIMDBIds: func(items []warmerListItem) ([]string, error) {
+			ids := make([]string, len(items))
+			for i := range items {
+				ids[i] = items[i].id
+			}
+			imdbIdByLetterboxdId, err := imdb_title.GetIMDBIdByLetterboxdId(ids)
+			if err != nil {
+				return nil, err
+			}
+			imdbIds := []string{}
+			for _, id := range ids {
+				if imdbId := imdbIdByLetterboxdId[id]; imdbId != "" {
+					imdbIds = append(imdbIds, imdbId)
+				}
+			}
+			return imdbIds, nil
+		},
+	},
+}
+
+// collects the strem ids worth warming: recently requested ones first,
+// followed by the top items of the recently synced lists.
+func getMagnetCacheWarmerStremIds(log *logger.Logger) []string {
+	limit := config.MagnetCacheWarmer.TitleLimit
+	since := time.Now().Add(-warmMagnetCacheLookback)
+
+	sids := []string{}
+	seen := map[string]struct{}{}
+	add := func(source string, ids []string, err error) {
+		if err != nil {
+			log.Error("failed to get strem ids", "error", err, "source", source)
+			return
+		}
+		for _, id := range ids {
+			if len(sids) >= limit {
+				return
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			sids = append(sids, id)
+		}
+	}
+
+	ids, err := torrent_stream.ListRecentStremIds(since, limit)
+	add("torrent_stream", ids, err)
+	for _, list := range warmerLists {
+		ids, err := list.getTopIMDBIds(since, limit)
+		add(list.name, ids, err)
+	}
+
+	return sids
+}
+
+// returns the hashes of the most seeded torrents for the strem id.
+func getMagnetCacheWarmerHashes(sid string) ([]string, error) {
+	hashes, err := torrent_info.ListHashesByStremId(sid)
+	if err != nil || len(hashes) == 0 {
+		return nil, err
+	}
+	tInfoByHash, err := torrent_info.GetByHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	tInfos := make([]torrent_info.TorrentInfo, 0, len(tInfoByHash))
+	for _, tInfo := range tInfoByHash {
+		tInfos = append(tInfos, tInfo)
+	}
+	slices.SortFunc(tInfos, func(a, b torrent_info.TorrentInfo) int {
+		return cmp.Or(cmp.Compare(b.Seeders, a.Seeders), cmp.Compare(b.Size, a.Size))
+	})
+	tInfos = tInfos[:min(len(tInfos), config.MagnetCacheWarmer.TorrentLimit)]
+	hashes = make([]string, len(tInfos))
+	for i := range tInfos {
+		hashes[i] = tInfos[i].Hash
+	}
+	return hashes, nil
+}
+
+// returns the files of the cached magnet, the file matching the strem id is
+// tagged with it, the same way playback does.
+func getMagnetCacheWarmerFiles(item *store.CheckMagnetDataItem, sid string, storeCode store.StoreCode) torrent_stream.Files {
+	videoFiles := []store.MagnetFile{}
+	for i := range item.Files {
+		if core.HasVideoExtension(item.Files[i].Name) {
+			videoFiles = append(videoFiles, item.Files[i])
+		}
+	}
+
+	var file *store.MagnetFile
+	if strings.Contains(sid, ":") {
+		file = stremio_shared.MatchFileByStremId(videoFiles, sid, item.Hash, storeCode)
+	} else if len(videoFiles) == 1 {
+		file = &videoFiles[0]
+	}
+
+	files := make(torrent_stream.Files, len(item.Files))
+	for i := range item.Files {
+		f := &item.Files[i]
+		files[i] = torrent_stream.File{
+			Idx:       f.Idx,
+			Path:      f.Path,
+			Name:      f.Name,
+			Size:      f.Size,
+			Source:    f.Source,
+			VideoHash: f.VideoHash,
+		}
+		if file != nil && f.Path == file.Path {
+			files[i].SId = sid
+		}
+	}
+	return files
+}
+
+// the store checks of each store are limited to its budget per hour, the
+// limiters are kept across runs.
+func newMagnetCacheWarmerLimiter(storeName string) *rate.Limiter {
+	budget := config.MagnetCacheWarmer.StoreBudget.Get(storeName)
+	if budget <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Every(time.Hour/time.Duration(budget)), max(1, config.MagnetCacheWarmer.TorrentLimit))
+}
+
+func InitWarmMagnetCacheWorker(conf *WorkerConfig) *Worker {
+	limiterByStore := map[string]*rate.Limiter{}
+	for _, storeName := range config.MagnetCacheWarmer.Stores {
+		limiterByStore[storeName] = newMagnetCacheWarmerLimiter(storeName)
+	}
+
+	conf.Executor = func(w *Worker) error {
+		log := w.Log
+
+		sids := getMagnetCacheWarmerStremIds(log)
+		if len(sids) == 0 {
+			log.Info("nothing to warm")
+			return nil
+		}
+
+		hashesBySid := map[string][]string{}
+		for _, sid := range sids {
+			hashes, err := getMagnetCacheWarmerHashes(sid)
+			if err != nil {
+				log.Error("failed to list torrents", "error", err, "sid", sid)
+				continue
+			}
+			if len(hashes) > 0 {
+				hashesBySid[sid] = hashes
+			}
+		}
+
+		clientIp := config.IP.GetMachineIP()
+
+		for _, storeName := range config.MagnetCacheWarmer.Stores {
+			if w.IsCancelled() {
+				break
+			}
+
+			s := shared.GetStore(storeName)
+			limiter := limiterByStore[storeName]
+			if s == nil || limiter == nil {
+				continue
+			}
+			storeCode := s.GetName().Code()
+
+			apiKey, err := stremio_userdata.LeasePoolKey(w.Context(), config.MagnetCacheWarmer.ChillstreamsUserId, warmMagnetCacheDeviceId, storeName)
+			if err != nil {
+				log.Error("failed to lease pool key", "error", err, "store.name", storeName)
+				w.ReportError(err)
+				continue
+			}
+
+			checkedCount, cachedCount := 0, 0
+			for _, sid := range sids {
+				if w.IsCancelled() {
+					break
+				}
+
+				hashes, ok := hashesBySid[sid]
+				if !ok {
+					continue
+				}
+
+				mcs, err := magnet_cache.GetByHashes(storeCode, hashes, nil)
+				if err != nil {
+					log.Error("failed to get magnet cache", "error", err, "store.name", storeName, "sid", sid)
+					continue
+				}
+				freshHashes := map[string]struct{}{}
+				for _, mc := range mcs {
+					if !mc.IsStale() {
+						freshHashes[mc.Hash] = struct{}{}
+					}
+				}
+				staleHashes := []string{}
+				for _, hash := range hashes {
+					if _, ok := freshHashes[hash]; !ok {
+						staleHashes = append(staleHashes, hash)
+					}
+				}
+				if len(staleHashes) == 0 {
+					continue
+				}
+
+				if err := limiter.WaitN(w.Context(), len(staleHashes)); err != nil {
+					break
+				}
+
+				params := &store.CheckMagnetParams{
+					Magnets:  staleHashes,
+					ClientIP: clientIp,
+					SId:      sid,
+				}
+				params.APIKey = apiKey
+				res, err := s.CheckMagnet(params)
+				if err != nil {
+					log.Error("failed to check magnet", "error", core.PackError(err), "store.name", storeName, "sid", sid)
+					w.ReportError(err)
+					break
+				}
+
+				filesByHash := map[string]torrent_stream.Files{}
+				cached := map[string]bool{}
+				for i := range res.Items {
+					item := &res.Items[i]
+					if item.Status == store.MagnetStatusUnknown {
+						continue
+					}
+					isCached := item.Status == store.MagnetStatusCached
+					cached[item.Hash] = isCached
+					if !isCached {
+						continue
+					}
+					cachedCount++
+					filesByHash[item.Hash] = getMagnetCacheWarmerFiles(item, sid, storeCode)
+				}
+				magnet_cache.BulkTouch(storeCode, filesByHash, cached, false)
+				checkedCount += len(staleHashes)
+				w.ReportProcessed(len(staleHashes))
+			}
+
+			log.Info("warmed magnet cache", "store.name", storeName, "hash_count", checkedCount, "cached_count", cachedCount)
+		}
+
+		return nil
+	}
+
+	worker := NewWorker(conf)
+
+	return worker
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/MunifTanjim/stremthru/internal/mdblist"
+	"github.com/MunifTanjim/stremthru/internal/torrent_stream"
+	"github.com/MunifTanjim/stremthru/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmerListGetTopIMDBIds(t *testing.T) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, mdblist.ListTableName, mdblist.ListItemTableName)
+
+	now := time.Now()
+	for _, list := range []struct {
+		id  string
+		uat time.Time
+	}{
+		{"recent", now},
+		{"old", now.Add(-30 * 24 * time.Hour)},
+	} {
+		_, err := db.Exec("INSERT INTO "+mdblist.ListTableName+" (id, user_id, user_name, name, slug, mediatype, uat) VALUES (?, 1, 'user', ?, ?, 'movie', ?)", list.id, list.id, list.id, db.Timestamp{Time: list.uat})
+		require.NoError(t, err)
+	}
+	for _, item := range []struct {
+		listId string
+		itemId string
+		rank   int
+	}{
+		{"recent", "tt0000002", 2},
+		{"recent", "tt0000001", 1},
+		{"recent", "tt0000003", 3},
+		{"old", "tt0000000", 0},
+	} {
+		_, err := db.Exec("INSERT INTO "+mdblist.ListItemTableName+" (list_id, item_id, rank) VALUES (?, ?, ?)", item.listId, item.itemId, item.rank)
+		require.NoError(t, err)
+	}
+
+	list := warmerLists[1]
+	require.Equal(t, "mdblist", list.name)
+	ids, err := list.getTopIMDBIds(now.Add(-warmMagnetCacheLookback), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tt0000001", "tt0000002"}, ids, "top ranked items of recently synced lists")
+}
+
+func TestGetMagnetCacheWarmerFiles(t *testing.T) {
+	item := &store.CheckMagnetDataItem{
+		Hash: "hash",
+		Files: []store.MagnetFile{
+			{Idx: 0, Path: "/Movie/Movie.mkv", Name: "Movie.mkv", Size: 100},
+			{Idx: 1, Path: "/Movie/Movie.srt", Name: "Movie.srt", Size: 1},
+		},
+	}
+	assert.Equal(t, torrent_stream.Files{
+		{Idx: 0, Path: "/Movie/Movie.mkv", Name: "Movie.mkv", Size: 100, SId: "tt0000001"},
+		{Idx: 1, Path: "/Movie/Movie.srt", Name: "Movie.srt", Size: 1},
+	}, getMagnetCacheWarmerFiles(item, "tt0000001", store.StoreCodeRealDebrid), "only video file is tagged")
+
+	item.Files = append(item.Files, store.MagnetFile{Idx: 2, Path: "/Movie/Sample.mkv", Name: "Sample.mkv", Size: 10})
+	for _, f := range getMagnetCacheWarmerFiles(item, "tt0000001", store.StoreCodeRealDebrid) {
+		assert.Empty(t, f.SId, "file is not tagged when it can not be matched")
+	}
+}
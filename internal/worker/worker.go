@@ -2,12 +2,16 @@ package worker
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/db"
 	"github.com/MunifTanjim/stremthru/internal/job_log"
 	"github.com/MunifTanjim/stremthru/internal/logger"
+	"github.com/MunifTanjim/stremthru/internal/metrics"
+	"github.com/MunifTanjim/stremthru/internal/prowlarr"
 	"github.com/MunifTanjim/stremthru/internal/util"
 	"github.com/MunifTanjim/stremthru/internal/worker/worker_queue"
 	"github.com/madflojo/tasks"
@@ -34,7 +38,22 @@ type Worker struct {
 	onStart    func()
 	onEnd      func()
 	Log        *logger.Logger
-	jobTracker *JobTracker[struct{}]
+	jobTracker *JobTracker[JobProgress]
+
+	taskId   string
+	interval atomic.Int64
+	// isPaused and interval are the state of the worker on this instance,
+	// those are not persisted and reset on restart.
+	isPaused  atomic.Bool
+	isRunning atomic.Bool
+	run       atomic.Pointer[workerRun]
+	// runJob must only be called after acquiring isRunning, see tryRunJob.
+	runJob func(force bool) error
+}
+
+// JobId returns the id of the job_log entry for the current run.
+func (w *Worker) JobId() string {
+	return w.getRun().jobId
 }
 
 type WorkerConfig struct {
@@ -116,6 +135,15 @@ var WorkerDetailsById = map[string]*WorkerDetail{
 	"sync-stremio-stremio": {
 		Title: "Sync Stremio-Stremio",
 	},
+	"report-chillstreams-usage": {
+		Title: "Report Chillstreams Usage",
+	},
+	"rollup-prowlarr-indexer-stats": {
+		Title: "Rollup Prowlarr Indexer Stats",
+	},
+	"warm-magnet-cache": {
+		Title: "Warm Magnet Cache",
+	},
 }
 
 func NewWorker(conf *WorkerConfig) *Worker {
@@ -153,152 +181,173 @@ func NewWorker(conf *WorkerConfig) *Worker {
 		onEnd:      conf.OnEnd,
 		Log:        log,
 	}
+	worker.interval.Store(int64(conf.Interval))
 
 	jobTrackerExpiresIn := max(3*24*time.Hour, 10*conf.Interval)
-	jobTracker := NewJobTracker[struct{}](conf.Name, jobTrackerExpiresIn)
+	jobTracker := NewJobTracker[JobProgress](conf.Name, jobTrackerExpiresIn)
 	worker.jobTracker = jobTracker
 
-	jobId := ""
-	id, err := worker.scheduler.Add(&tasks.Task{
-		Interval:          conf.Interval,
-		RunSingleInstance: true,
-		TaskFunc: func() (err error) {
-			isAlreadyRunning := jobId != ""
-			defer func() {
-				if perr, stack := util.HandlePanic(recover(), true); perr != nil {
-					err = perr
-					log.Error("Worker Panic", "error", err, "stack", stack)
-				} else if err == nil && !isAlreadyRunning {
-					jobId = ""
+	// force is set for runs triggered from the dashboard, those ignore the
+	// pause and the schedule of the worker.
+	worker.runJob = func(force bool) (err error) {
+		jobId := ""
+		defer func() {
+			if perr, stack := util.HandlePanic(recover(), true); perr != nil {
+				err = perr
+				log.Error("Worker Panic", "error", err, "stack", stack)
+			}
+			if err != nil && jobId != "" {
+				if terr := jobTracker.Set(jobId, "failed", err.Error(), worker.getRun().progress()); terr != nil {
+					log.Error("failed to set job status", "error", terr, "jobId", jobId, "status", "failed")
 				}
-				worker.onEnd()
-			}()
-
-			if worker.shouldSkip != nil && worker.shouldSkip() {
-				log.Info("skipping")
-				return nil
 			}
+			worker.onEnd()
+		}()
 
-			for {
-				wait, reason := worker.shouldWait()
-				if !wait {
-					break
-				}
-				log.Info("waiting, " + reason)
-				time.Sleep(1 * time.Minute)
-			}
-			worker.onStart()
+		if !force && worker.isPaused.Load() {
+			log.Debug("skipping, paused")
+			return nil
+		}
 
-			if isAlreadyRunning {
-				return nil
-			}
+		if !force && worker.shouldSkip != nil && worker.shouldSkip() {
+			log.Info("skipping")
+			return nil
+		}
 
-			lock := db.NewAdvisoryLock("worker", conf.Name)
-			if lock == nil {
-				log.Error("failed to create advisory lock", "name", conf.Name)
-				return nil
+		for {
+			wait, reason := worker.shouldWait()
+			if !wait {
+				break
 			}
+			log.Info("waiting, " + reason)
+			time.Sleep(1 * time.Minute)
+		}
+		worker.onStart()
 
-			if !lock.TryAcquire() {
-				log.Debug("skipping, another instance is running", "name", lock.GetName())
-				return nil
-			}
-			defer lock.Release()
+		lock := db.NewAdvisoryLock("worker", conf.Name)
+		if lock == nil {
+			log.Error("failed to create advisory lock", "name", conf.Name)
+			return nil
+		}
 
-			var tjob *job_log.ParsedJobLog[struct{}]
-			if conf.RunExclusive {
-				tjob, err = jobTracker.GetLast()
-				if err != nil {
-					return err
-				}
-				if tjob != nil {
-					status := tjob.Status
-					switch status {
-					case "started":
-						if !util.HasDurationPassedSince(tjob.UpdatedAt, conf.HeartbeatInterval+heartbeatIntervalTolerance) {
-							if util.HasDurationPassedSince(tjob.CreatedAt, conf.Interval) {
-								log.Warn("skipping, last job is still running, for too long", "jobId", tjob.Id, "status", status)
-							} else {
-								log.Info("skipping, last job is still running", "jobId", tjob.Id, "status", status)
-							}
-							return nil
-						}
+		if !lock.TryAcquire() {
+			log.Debug("skipping, another instance is running", "name", lock.GetName())
+			return nil
+		}
+		defer lock.Release()
 
-						log.Warn("last job heartbeat timed out, restarting", "jobId", tjob.Id, "status", status)
-						if err := jobTracker.Set(tjob.Id, "failed", "heartbeat timed out", nil); err != nil {
-							log.Error("failed to set last job status", "error", err, "jobId", tjob.Id, "status", "failed")
-						}
-					case "done":
-						if !util.HasDurationPassedSince(tjob.CreatedAt, conf.Interval) {
-							log.Info("already done", "jobId", tjob.Id, "status", status)
-							return nil
+		var tjob *job_log.ParsedJobLog[JobProgress]
+		if conf.RunExclusive {
+			tjob, err = jobTracker.GetLast()
+			if err != nil {
+				return err
+			}
+			if tjob != nil {
+				status := tjob.Status
+				switch status {
+				case "started":
+					if !util.HasDurationPassedSince(tjob.UpdatedAt, conf.HeartbeatInterval+heartbeatIntervalTolerance) {
+						if util.HasDurationPassedSince(tjob.CreatedAt, worker.getInterval()) {
+							log.Warn("skipping, last job is still running, for too long", "jobId", tjob.Id, "status", status)
+						} else {
+							log.Info("skipping, last job is still running", "jobId", tjob.Id, "status", status)
 						}
-					case "failed":
-						log.Warn("last job failed", "jobId", tjob.Id, "status", status, "error", tjob.Error)
+						return nil
 					}
+
+					log.Warn("last job heartbeat timed out, restarting", "jobId", tjob.Id, "status", status)
+					if err := jobTracker.Set(tjob.Id, "failed", "heartbeat timed out", nil); err != nil {
+						log.Error("failed to set last job status", "error", err, "jobId", tjob.Id, "status", "failed")
+					}
+				case "done":
+					if !force && !util.HasDurationPassedSince(tjob.CreatedAt, worker.getInterval()) {
+						log.Info("already done", "jobId", tjob.Id, "status", status)
+						return nil
+					}
+				case "failed":
+					log.Warn("last job failed", "jobId", tjob.Id, "status", status, "error", tjob.Error)
 				}
 			}
+		}
 
-			jobId = time.Now().Format(time.DateTime)
+		jobId = time.Now().Format(time.DateTime)
 
-			err = jobTracker.Set(jobId, "started", "", nil)
+		run := worker.startRun(jobId)
+		defer func() {
+			status := "done"
 			if err != nil {
-				log.Error("failed to set job status", "error", err, "jobId", jobId, "status", "started")
-				return err
+				status = "failed"
+			} else if run.ctx.Err() != nil {
+				status = "cancelled"
 			}
+			worker.endRun(run)
+			metrics.ObserveWorkerRun(conf.Name, status, time.Since(run.startedAt))
+		}()
 
-			if !lock.Release() {
-				log.Error("failed to release advisory lock", "name", lock.GetName())
-				return nil
-			}
+		err = jobTracker.Set(jobId, "started", "", run.progress())
+		if err != nil {
+			log.Error("failed to set job status", "error", err, "jobId", jobId, "status", "started")
+			return err
+		}
 
-			heartbeat := time.NewTicker(conf.HeartbeatInterval)
-			heartbeat_done := make(chan struct{})
-			defer close(heartbeat_done)
-			go func() {
-				for {
-					select {
-					case <-heartbeat.C:
-						if jobId == "" {
-							return
-						}
-						if err := jobTracker.Set(jobId, "started", "", nil); err != nil {
-							log.Error("failed to set job status heartbeat", "error", err, "jobId", jobId)
-						}
-					case <-heartbeat_done:
-						heartbeat.Stop()
-						return
+		if !lock.Release() {
+			log.Error("failed to release advisory lock", "name", lock.GetName())
+			return nil
+		}
+
+		heartbeat := time.NewTicker(conf.HeartbeatInterval)
+		heartbeat_done := make(chan struct{})
+		defer close(heartbeat_done)
+		go func() {
+			for {
+				select {
+				case <-heartbeat.C:
+					if err := jobTracker.Set(jobId, "started", "", run.progress()); err != nil {
+						log.Error("failed to set job status heartbeat", "error", err, "jobId", jobId)
 					}
+				case <-heartbeat_done:
+					heartbeat.Stop()
+					return
 				}
-			}()
-
-			if err = conf.Executor(worker); err != nil {
-				return err
 			}
+		}()
 
-			err = jobTracker.Set(jobId, "done", "", nil)
+		err = conf.Executor(worker)
+		if run.ctx.Err() != nil {
+			if err != nil {
+				log.Warn("cancelled job failed", "error", err, "jobId", jobId)
+			}
+			err = jobTracker.Set(jobId, "cancelled", "", run.progress())
 			if err != nil {
-				log.Error("failed to set job status", "error", err, "jobId", jobId, "status", "done")
+				log.Error("failed to set job status", "error", err, "jobId", jobId, "status", "cancelled")
 				return err
 			}
+			log.Info("cancelled", "jobId", jobId)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-			log.Info("done", "jobId", jobId)
-
+		err = jobTracker.Set(jobId, "done", "", run.progress())
+		if err != nil {
+			log.Error("failed to set job status", "error", err, "jobId", jobId, "status", "done")
 			return err
+		}
+
+		log.Info("done", "jobId", jobId)
+
+		return err
+	}
+
+	id, err := worker.scheduler.Add(&tasks.Task{
+		Interval:          conf.Interval,
+		RunSingleInstance: true,
+		TaskFunc: func() error {
+			return worker.tryRunJob(false)
 		},
 		ErrFunc: func(err error) {
 			log.Error("Worker Failure", "error", err)
-
-			defer func() {
-				if perr, stack := util.HandlePanic(recover(), true); perr != nil {
-					log.Error("Worker Err Panic", "error", perr, "stack", stack)
-				}
-				jobId = ""
-			}()
-
-			if terr := jobTracker.Set(jobId, "failed", err.Error(), nil); terr != nil {
-				log.Error("failed to set job status", "error", terr, "jobId", jobId, "status", "failed")
-			}
 		},
 	})
 
@@ -308,6 +357,9 @@ func NewWorker(conf *WorkerConfig) *Worker {
 
 	log.Info("Started Worker", "id", id)
 
+	worker.taskId = id
+	workerById.Store(conf.Name, worker)
+
 	if conf.RunAtStartupAfter != 0 {
 		if task, err := worker.scheduler.Lookup(id); err == nil && task != nil {
 			t := task.Clone()
@@ -323,6 +375,8 @@ func NewWorker(conf *WorkerConfig) *Worker {
 func InitWorkers() func() {
 	workers := []*Worker{}
 
+	metrics.RegisterQueueDepth(worker_queue.CountByQueue)
+
 	if worker := InitParseTorrentWorker(&WorkerConfig{
 		Disabled:     !config.Feature.HasTorrentInfo(),
 		Name:         "parse-torrent",
@@ -839,6 +893,59 @@ func InitWorkers() func() {
 		workers = append(workers, worker)
 	}
 
+	if worker := InitReportChillstreamsUsageWorker(&WorkerConfig{
+		Disabled:     !config.EnableChillstreamsAuth || config.ChillstreamsAPIKey == "",
+		Name:         "report-chillstreams-usage",
+		Interval:     1 * time.Minute,
+		RunExclusive: true,
+		ShouldSkip: func() bool {
+			hasDue, err := chillstreams_usage.HasDue()
+			return err == nil && !hasDue
+		},
+		ShouldWait: func() (bool, string) {
+			return false, ""
+		},
+		OnStart: func() {},
+		OnEnd:   func() {},
+	}); worker != nil {
+		workers = append(workers, worker)
+	}
+
+	if worker := InitRollupProwlarrIndexerStatsWorker(&WorkerConfig{
+		Disabled:     !prowlarr.IsSearchLogEnabled(),
+		Name:         "rollup-prowlarr-indexer-stats",
+		Interval:     5 * time.Minute,
+		RunExclusive: true,
+		ShouldWait: func() (bool, string) {
+			return false, ""
+		},
+		OnStart: func() {},
+		OnEnd:   func() {},
+	}); worker != nil {
+		workers = append(workers, worker)
+	}
+
+	if worker := InitWarmMagnetCacheWorker(&WorkerConfig{
+		Disabled:          !config.MagnetCacheWarmer.IsEnabled() || !config.Feature.HasTorrentInfo(),
+		Name:              "warm-magnet-cache",
+		Interval:          1 * time.Hour,
+		RunAtStartupAfter: 2 * time.Minute,
+		RunExclusive:      true,
+		ShouldWait: func() (bool, string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if running_worker.map_imdb_torrent {
+				return true, "map_imdb_torrent is running"
+			}
+			return false, ""
+		},
+		OnStart: func() {},
+		OnEnd:   func() {},
+	}); worker != nil {
+		workers = append(workers, worker)
+	}
+
 	return func() {
 		for _, worker := range workers {
 			worker.scheduler.Stop()
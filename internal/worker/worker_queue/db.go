@@ -0,0 +1,298 @@
+package worker_queue
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/config"
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/util"
+)
+
+const TableName = "worker_queue_item"
+
+// claimed items not completed or released within the lease are picked up
+// again, e.g. after the claiming instance crashed. The lease is renewed while
+// the claimed items are being processed, so it only needs to outlive a
+// single item (or group), and it bounds the delay before the items of a
+// crashed instance are picked up again.
+const leaseDuration = 2 * time.Minute
+
+const claimBatchSize = 1000
+
+const enqueueBatchSize = 200
+
+var Column = struct {
+	Queue        string
+	Key          string
+	GroupKey     string
+	Value        string
+	Version      string
+	ProcessAfter string
+	ClaimedBy    string
+	ClaimedUntil string
+	CAt          string
+	UAt          string
+}{
+	Queue:        "queue",
+	Key:          "key",
+	GroupKey:     "group_key",
+	Value:        "value",
+	Version:      "version",
+	ProcessAfter: "process_after",
+	ClaimedBy:    "claimed_by",
+	ClaimedUntil: "claimed_until",
+	CAt:          "cat",
+	UAt:          "uat",
+}
+
+type dbItem struct {
+	Key      string
+	GroupKey string
+	Value    string
+	Version  int
+}
+
+var query_enqueue_before_values = fmt.Sprintf(
+	`INSERT INTO %s (%s) VALUES `,
+	TableName,
+	db.JoinColumnNames(
+		Column.Queue,
+		Column.Key,
+		Column.GroupKey,
+		Column.Value,
+		Column.ProcessAfter,
+	),
+)
+var query_enqueue_values_placeholder = "(?,?,?,?,?)"
+var query_enqueue_on_conflict = fmt.Sprintf(
+	` ON CONFLICT (%s, %s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = EXCLUDED.%s, %s = %s.%s + 1, %s = %s`,
+	Column.Queue,
+	Column.Key,
+	Column.GroupKey,
+	Column.GroupKey,
+	Column.Value,
+	Column.Value,
+	Column.ProcessAfter,
+	Column.ProcessAfter,
+	Column.Version,
+	TableName,
+	Column.Version,
+	Column.UAt,
+	db.CurrentTimestamp,
+)
+
+// enqueue adds the items, or replaces them and pushes back their process time
+// if they are already queued. Bumping the version keeps an in-flight claim
+// from deleting the replaced item.
+func enqueue(queue string, items []dbItem, processAfter time.Time) error {
+	// a single statement can not upsert the same row twice
+	seen := make(map[string]int, len(items))
+	uniqueItems := make([]dbItem, 0, len(items))
+	for i := range items {
+		if idx, ok := seen[items[i].Key]; ok {
+			uniqueItems[idx] = items[i]
+			continue
+		}
+		seen[items[i].Key] = len(uniqueItems)
+		uniqueItems = append(uniqueItems, items[i])
+	}
+
+	pAfter := db.Timestamp{Time: processAfter}
+	for cItems := range slices.Chunk(uniqueItems, enqueueBatchSize) {
+		args := make([]any, 0, 5*len(cItems))
+		for i := range cItems {
+			args = append(args, queue, cItems[i].Key, cItems[i].GroupKey, cItems[i].Value, pAfter)
+		}
+		query := query_enqueue_before_values + util.RepeatJoin(query_enqueue_values_placeholder, len(cItems), ",") + query_enqueue_on_conflict
+		if _, err := db.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var query_has_item = fmt.Sprintf(
+	`SELECT 1 FROM %s WHERE %s = ? LIMIT 1`,
+	TableName,
+	Column.Queue,
+)
+
+func hasItem(queue string) (bool, error) {
+	one := 0
+	err := db.QueryRow(query_has_item, queue).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var query_count_by_queue = fmt.Sprintf(
+	`SELECT %s, COUNT(*) FROM %s GROUP BY %s`,
+	Column.Queue,
+	TableName,
+	Column.Queue,
+)
+
+// CountByQueue returns the number of items in each queue.
+func CountByQueue() (map[string]int, error) {
+	rows, err := db.Query(query_count_by_queue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	countByQueue := map[string]int{}
+	for rows.Next() {
+		var queue string
+		var count int
+		if err := rows.Scan(&queue, &count); err != nil {
+			return nil, err
+		}
+		countByQueue[queue] = count
+	}
+	return countByQueue, rows.Err()
+}
+
+var query_get_claimable = fmt.Sprintf(
+	`SELECT %s FROM %s WHERE %s = ? AND %s <= ? AND (%s IS NULL OR %s <= ?) ORDER BY %s ASC LIMIT ?`,
+	db.JoinColumnNames(
+		Column.Key,
+		Column.GroupKey,
+		Column.Value,
+		Column.Version,
+	),
+	TableName,
+	Column.Queue,
+	Column.ProcessAfter,
+	Column.ClaimedUntil,
+	Column.ClaimedUntil,
+	Column.ProcessAfter,
+)
+
+var query_claim_before_values = fmt.Sprintf(
+	`UPDATE %s SET %s = ?, %s = ? WHERE %s = ? AND %s IN `,
+	TableName,
+	Column.ClaimedBy,
+	Column.ClaimedUntil,
+	Column.Queue,
+	Column.Key,
+)
+
+// claim leases the due items of the queue to this instance. The advisory
+// lock makes sure concurrent instances never claim the same items.
+func claim(queue string) ([]dbItem, error) {
+	lock := db.NewAdvisoryLock("worker_queue", queue)
+	if lock == nil {
+		return nil, errors.New("failed to create advisory lock")
+	}
+	if !lock.TryAcquire() {
+		log.Debug("skipping claim, another instance is claiming", "queue", queue)
+		return nil, lock.Err()
+	}
+	defer lock.Release()
+
+	now := time.Now()
+	rows, err := lock.Query(query_get_claimable, queue, db.Timestamp{Time: now}, db.Timestamp{Time: now}, claimBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []dbItem{}
+	for rows.Next() {
+		item := dbItem{}
+		if err := rows.Scan(&item.Key, &item.GroupKey, &item.Value, &item.Version); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	args := make([]any, 0, 3+len(items))
+	args = append(args, config.InstanceId, db.Timestamp{Time: now.Add(leaseDuration)}, queue)
+	for i := range items {
+		args = append(args, items[i].Key)
+	}
+	query := query_claim_before_values + "(" + util.RepeatJoin("?", len(items), ",") + ")"
+	if _, err := lock.Exec(query, args...); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+var query_renew_lease = fmt.Sprintf(
+	`UPDATE %s SET %s = ? WHERE %s = ? AND %s = ? AND %s IS NOT NULL`,
+	TableName,
+	Column.ClaimedUntil,
+	Column.Queue,
+	Column.ClaimedBy,
+	Column.ClaimedUntil,
+)
+
+// renewLease extends the lease of the items of the queue still claimed by
+// this instance.
+func renewLease(queue string) error {
+	_, err := db.Exec(query_renew_lease, db.Timestamp{Time: time.Now().Add(leaseDuration)}, queue, config.InstanceId)
+	return err
+}
+
+var query_complete = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ? AND %s = ? AND %s = ?`,
+	TableName,
+	Column.Queue,
+	Column.Key,
+	Column.Version,
+)
+
+var query_release = fmt.Sprintf(
+	`UPDATE %s SET %s = '', %s = NULL WHERE %s = ? AND %s = ? AND %s = ?`,
+	TableName,
+	Column.ClaimedBy,
+	Column.ClaimedUntil,
+	Column.Queue,
+	Column.Key,
+	Column.ClaimedBy,
+)
+
+// complete removes the processed item, unless it was queued again while it
+// was being processed, in which case it is only released.
+func complete(queue string, item *dbItem) error {
+	if _, err := db.Exec(query_complete, queue, item.Key, item.Version); err != nil {
+		return err
+	}
+	return release(queue, item)
+}
+
+// release gives up the claim, so that the item is picked up again on the
+// next run.
+func release(queue string, item *dbItem) error {
+	_, err := db.Exec(query_release, queue, item.Key, config.InstanceId)
+	return err
+}
+
+var query_delete = fmt.Sprintf(
+	`DELETE FROM %s WHERE %s = ? AND %s = ?`,
+	TableName,
+	Column.Queue,
+	Column.Key,
+)
+
+func remove(queue string, key string) error {
+	_, err := db.Exec(query_delete, queue, key)
+	return err
+}
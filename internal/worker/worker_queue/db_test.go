@@ -0,0 +1,136 @@
+package worker_queue
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/internal/db"
+	"github.com/MunifTanjim/stremthru/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(dbtest.Main(m, "20251225120000_create_table_worker_queue_item"))
+}
+
+func setupQueue(t *testing.T, keys ...string) {
+	dbtest.Require(t)
+	dbtest.Truncate(t, TableName)
+	items := make([]dbItem, len(keys))
+	for i, key := range keys {
+		items[i] = dbItem{Key: key, GroupKey: "g", Value: `"` + key + `"`}
+	}
+	require.NoError(t, enqueue("test", items, time.Now().Add(-time.Second)))
+}
+
+func getClaimedKeys(items []dbItem) []string {
+	keys := make([]string, len(items))
+	for i := range items {
+		keys[i] = items[i].Key
+	}
+	return keys
+}
+
+func expireLease(t *testing.T) {
+	_, err := db.Exec("UPDATE "+TableName+" SET "+Column.ClaimedUntil+" = ?", db.Timestamp{Time: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+}
+
+func TestClaim(t *testing.T) {
+	setupQueue(t, "a", "b")
+	require.NoError(t, enqueue("test", []dbItem{{Key: "later", Value: `"later"`}}, time.Now().Add(time.Hour)))
+
+	items, err := claim("test")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, getClaimedKeys(items))
+
+	items, err = claim("test")
+	require.NoError(t, err)
+	assert.Empty(t, items, "leased items are not claimed again")
+
+	expireLease(t)
+	items, err = claim("test")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, getClaimedKeys(items), "expired lease is claimed again")
+}
+
+func TestRenewLease(t *testing.T) {
+	setupQueue(t, "a")
+
+	_, err := claim("test")
+	require.NoError(t, err)
+	expireLease(t)
+	require.NoError(t, renewLease("test"))
+
+	items, err := claim("test")
+	require.NoError(t, err)
+	assert.Empty(t, items, "renewed lease is not claimed again")
+}
+
+func TestComplete(t *testing.T) {
+	setupQueue(t, "a", "b")
+
+	items, err := claim("test")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	require.NoError(t, complete("test", &items[0]))
+	require.NoError(t, release("test", &items[1]))
+
+	items, err = claim("test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, getClaimedKeys(items), "completed item is removed, released item is claimed again")
+}
+
+func TestCompleteQueuedAgain(t *testing.T) {
+	setupQueue(t, "a")
+
+	items, err := claim("test")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, enqueue("test", []dbItem{{Key: "a", Value: `"a2"`}}, time.Now().Add(-time.Second)))
+	require.NoError(t, complete("test", &items[0]))
+
+	items, err = claim("test")
+	require.NoError(t, err)
+	require.Len(t, items, 1, "item queued again while processing is kept")
+	assert.Equal(t, `"a2"`, items[0].Value)
+}
+
+func TestEnqueueDuplicateKeys(t *testing.T) {
+	setupQueue(t)
+
+	require.NoError(t, enqueue("test", []dbItem{
+		{Key: "a", Value: `"a1"`},
+		{Key: "a", Value: `"a2"`},
+	}, time.Now().Add(-time.Second)))
+
+	items, err := claim("test")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, `"a2"`, items[0].Value)
+}
+
+func TestSecretToken(t *testing.T) {
+	type item struct {
+		Token SecretToken
+	}
+
+	blob, err := json.Marshal(item{Token: "super-secret"})
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(blob), "super-secret"))
+
+	decoded := item{}
+	require.NoError(t, json.Unmarshal(blob, &decoded))
+	assert.Equal(t, SecretToken("super-secret"), decoded.Token)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Token":{"ref":"unknown"}}`), &decoded))
+	assert.Equal(t, SecretToken(""), decoded.Token)
+
+	assert.NotEqual(t, SecretToken("a").Hash(), SecretToken("b").Hash())
+}
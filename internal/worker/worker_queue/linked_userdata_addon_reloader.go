@@ -12,6 +12,7 @@ type UserdataAddonReloaderQueueItem struct {
 }
 
 var LinkedUserdataAddonReloaderQueue = WorkerQueue[UserdataAddonReloaderQueueItem]{
+	name:         "linked-userdata-addon-reloader",
 	debounceTime: 1 * time.Minute,
 	getKey: func(item UserdataAddonReloaderQueueItem) string {
 		return item.Addon + ":" + item.Key
@@ -11,10 +11,11 @@ type MagnetCachePullerQueueItem struct {
 	Hash       string
 	SId        string
 	StoreCode  string
-	StoreToken string
+	StoreToken SecretToken
 }
 
 var MagnetCachePullerQueue = WorkerQueue[MagnetCachePullerQueueItem]{
+	name:         "magnet-cache-puller",
 	debounceTime: 5 * time.Minute,
 	getKey: func(item MagnetCachePullerQueueItem) string {
 		return item.StoreCode + ":" + item.SId + ":" + item.Hash
@@ -1,18 +1,20 @@
 package worker_queue
 
 import (
+	"encoding/json"
 	"errors"
-	"sync"
 	"time"
 )
 
 type WorkerQueueItem[T any] struct {
-	v T
-	t time.Time
+	v    T
+	item dbItem
 }
 
+// WorkerQueue is a debounced queue persisted in the database, so that queued
+// items survive restarts and can be shared by multiple instances.
 type WorkerQueue[T any] struct {
-	m            sync.Map
+	name         string
 	getKey       func(item T) string
 	getGroupKey  func(item T) string
 	transform    func(item *T) *T
@@ -22,73 +24,155 @@ type WorkerQueue[T any] struct {
 
 var ErrWorkerQueueItemDelayed = errors.New("worker queue item delayed")
 
+func (q *WorkerQueue[T]) toDBItem(item T) (dbItem, error) {
+	item = *q.transform(&item)
+	dItem := dbItem{Key: q.getKey(item)}
+	if q.getGroupKey != nil {
+		dItem.GroupKey = q.getGroupKey(item)
+	}
+	value, err := json.Marshal(item)
+	if err != nil {
+		return dItem, err
+	}
+	dItem.Value = string(value)
+	return dItem, nil
+}
+
 func (q *WorkerQueue[T]) Queue(item T) {
-	if q.Disabled {
+	q.QueueMany([]T{item})
+}
+
+// QueueMany queues the items with bulk writes.
+func (q *WorkerQueue[T]) QueueMany(items []T) {
+	if q.Disabled || len(items) == 0 {
 		return
 	}
-	item = *q.transform(&item)
-	q.m.Swap(q.getKey(item), WorkerQueueItem[T]{
-		v: item,
-		t: time.Now().Add(q.debounceTime),
-	})
+	dbItems := make([]dbItem, 0, len(items))
+	for i := range items {
+		dItem, err := q.toDBItem(items[i])
+		if err != nil {
+			log.Error("WorkerQueue queue failed", "error", err, "queue", q.name, "key", dItem.Key)
+			continue
+		}
+		dbItems = append(dbItems, dItem)
+	}
+	if err := enqueue(q.name, dbItems, time.Now().Add(q.debounceTime)); err != nil {
+		log.Error("WorkerQueue queue failed", "error", err, "queue", q.name, "count", len(dbItems))
+	}
 }
 
-func (q *WorkerQueue[T]) delete(item T) {
-	q.m.Delete(q.getKey(item))
+func (q *WorkerQueue[T]) complete(item *WorkerQueueItem[T]) {
+	if err := complete(q.name, &item.item); err != nil {
+		log.Error("WorkerQueue complete failed", "error", err, "queue", q.name, "key", item.item.Key)
+	}
+}
+
+func (q *WorkerQueue[T]) release(item *WorkerQueueItem[T]) {
+	if err := release(q.name, &item.item); err != nil {
+		log.Error("WorkerQueue release failed", "error", err, "queue", q.name, "key", item.item.Key)
+	}
+}
+
+func (q *WorkerQueue[T]) claim() []WorkerQueueItem[T] {
+	dbItems, err := claim(q.name)
+	if err != nil {
+		log.Error("WorkerQueue claim failed", "error", err, "queue", q.name)
+		return nil
+	}
+	items := make([]WorkerQueueItem[T], 0, len(dbItems))
+	for i := range dbItems {
+		item := WorkerQueueItem[T]{item: dbItems[i]}
+		if err := json.Unmarshal([]byte(item.item.Value), &item.v); err != nil {
+			log.Error("WorkerQueue invalid item, dropping", "error", err, "queue", q.name, "key", item.item.Key)
+			if err := remove(q.name, item.item.Key); err != nil {
+				log.Error("WorkerQueue remove failed", "error", err, "queue", q.name, "key", item.item.Key)
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// leaseRenewer renews the lease of the claimed items once half of it is
+// spent, so that slow processing does not let other instances claim them.
+type leaseRenewer struct {
+	queue     string
+	renewedAt time.Time
+}
+
+func (r *leaseRenewer) renew() {
+	if time.Since(r.renewedAt) < leaseDuration/2 {
+		return
+	}
+	if err := renewLease(r.queue); err != nil {
+		log.Error("WorkerQueue lease renew failed", "error", err, "queue", r.queue)
+		return
+	}
+	r.renewedAt = time.Now()
 }
 
 func (q *WorkerQueue[T]) IsEmpty() bool {
-	isEmpty := true
-	q.m.Range(func(k, v any) bool {
-		isEmpty = false
+	hasItem, err := hasItem(q.name)
+	if err != nil {
+		log.Error("WorkerQueue check failed", "error", err, "queue", q.name)
 		return false
-	})
-	return isEmpty
+	}
+	return !hasItem
 }
 
 func (q *WorkerQueue[T]) Process(f func(item T) error) {
-	q.m.Range(func(k, v any) bool {
-		_, keyOk := k.(string)
-		val, valOk := v.(WorkerQueueItem[T])
-		if keyOk && valOk && val.t.Before(time.Now()) {
-			if err := f(val.v); err != nil {
-				if err == ErrWorkerQueueItemDelayed {
-					log.Debug("WorkerQueue process delayed", "key", q.getKey(val.v))
-				} else {
-					log.Error("WorkerQueue process failed", "error", err, "key", q.getKey(val.v))
-				}
+	items := q.claim()
+	lease := leaseRenewer{queue: q.name, renewedAt: time.Now()}
+	for i := range items {
+		item := &items[i]
+		lease.renew()
+		if err := f(item.v); err != nil {
+			if err == ErrWorkerQueueItemDelayed {
+				log.Debug("WorkerQueue process delayed", "key", item.item.Key)
 			} else {
-				q.delete(val.v)
+				log.Error("WorkerQueue process failed", "error", err, "key", item.item.Key)
 			}
+			q.release(item)
+		} else {
+			q.complete(item)
 		}
-		return true
-	})
+	}
 }
 
 func (q *WorkerQueue[T]) ProcessGroup(f func(groupKey string, items []T) error) {
-	byGroupKey := map[string][]T{}
-	q.m.Range(func(k, v any) bool {
-		_, keyOk := k.(string)
-		val, valOk := v.(WorkerQueueItem[T])
-		if keyOk && valOk && val.t.Before(time.Now()) {
-			groupKey := q.getGroupKey(val.v)
-			if _, ok := byGroupKey[groupKey]; !ok {
-				byGroupKey[groupKey] = []T{}
-			}
-			byGroupKey[groupKey] = append(byGroupKey[groupKey], val.v)
+	items := q.claim()
+	lease := leaseRenewer{queue: q.name, renewedAt: time.Now()}
+	groupKeys := []string{}
+	byGroupKey := map[string][]*WorkerQueueItem[T]{}
+	for i := range items {
+		item := &items[i]
+		groupKey := item.item.GroupKey
+		if _, ok := byGroupKey[groupKey]; !ok {
+			groupKeys = append(groupKeys, groupKey)
+			byGroupKey[groupKey] = []*WorkerQueueItem[T]{}
+		}
+		byGroupKey[groupKey] = append(byGroupKey[groupKey], item)
+	}
+	for _, groupKey := range groupKeys {
+		lease.renew()
+		groupItems := byGroupKey[groupKey]
+		values := make([]T, len(groupItems))
+		for i := range groupItems {
+			values[i] = groupItems[i].v
 		}
-		return true
-	})
-	for groupKey, items := range byGroupKey {
-		if err := f(groupKey, items); err != nil {
+		if err := f(groupKey, values); err != nil {
 			if err == ErrWorkerQueueItemDelayed {
 				log.Debug("WorkerQueue processGroup delayed", "group_key", groupKey)
 			} else {
 				log.Error("WorkerQueue processGroup failed", "error", err, "group_key", groupKey)
 			}
+			for i := range groupItems {
+				q.release(groupItems[i])
+			}
 		} else {
-			for i := range items {
-				q.delete(items[i])
+			for i := range groupItems {
+				q.complete(groupItems[i])
 			}
 		}
 	}
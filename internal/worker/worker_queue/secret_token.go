@@ -0,0 +1,81 @@
+package worker_queue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/MunifTanjim/stremthru/core"
+	"github.com/MunifTanjim/stremthru/internal/cache"
+	"github.com/MunifTanjim/stremthru/internal/config"
+)
+
+// tokens of the queued items whose token can not be persisted encrypted, by
+// reference
+var secretTokenByRef = cache.NewLRUCache[string](&cache.CacheConfig{
+	Lifetime:      24 * time.Hour,
+	Name:          "worker_queue:secret_token",
+	LocalCapacity: 4096,
+})
+
+// SecretToken is a token (e.g. store token) that is never persisted in plain
+// text. It is persisted encrypted with the vault secret if configured.
+// Otherwise only a reference to the token kept in memory is persisted, and
+// the token is lost on restart and unknown to the other instances.
+type SecretToken string
+
+// Hash identifies the token without revealing it, safe to use in keys and
+// logs.
+func (t SecretToken) Hash() string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:8])
+}
+
+type persistedSecretToken struct {
+	Ref string `json:"ref"`
+	Enc string `json:"enc,omitempty"`
+}
+
+func (t SecretToken) MarshalJSON() ([]byte, error) {
+	if t == "" {
+		return json.Marshal(persistedSecretToken{})
+	}
+	pt := persistedSecretToken{Ref: t.Hash()}
+	if config.VaultSecret != "" {
+		enc, err := core.Encrypt(config.VaultSecret, string(t))
+		if err != nil {
+			return nil, err
+		}
+		pt.Enc = enc
+	} else {
+		secretTokenByRef.Add(pt.Ref, string(t))
+	}
+	return json.Marshal(pt)
+}
+
+// UnmarshalJSON resolves the token, it is empty if the token is no longer
+// available.
+func (t *SecretToken) UnmarshalJSON(data []byte) error {
+	pt := persistedSecretToken{}
+	if err := json.Unmarshal(data, &pt); err != nil {
+		return err
+	}
+	*t = ""
+	if pt.Enc != "" && config.VaultSecret != "" {
+		token, err := core.Decrypt(config.VaultSecret, pt.Enc)
+		if err != nil {
+			log.Warn("failed to decrypt secret token", "error", err, "ref", pt.Ref)
+			return nil
+		}
+		*t = SecretToken(token)
+		return nil
+	}
+	if pt.Ref != "" {
+		token := ""
+		if secretTokenByRef.Get(pt.Ref, &token) {
+			*t = SecretToken(token)
+		}
+	}
+	return nil
+}
@@ -8,13 +8,14 @@ import (
 
 type StoreCrawlerQueueItem struct {
 	StoreCode  string
-	StoreToken string
+	StoreToken SecretToken
 }
 
 var StoreCrawlerQueue = WorkerQueue[StoreCrawlerQueueItem]{
+	name:         "store-crawler",
 	debounceTime: 15 * time.Minute,
 	getKey: func(item StoreCrawlerQueueItem) string {
-		return item.StoreCode + ":" + item.StoreToken
+		return item.StoreCode + ":" + item.StoreToken.Hash()
 	},
 	transform: func(item *StoreCrawlerQueueItem) *StoreCrawlerQueueItem {
 		return item
@@ -3,19 +3,23 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/MunifTanjim/stremthru/internal/buddy"
+	chillstreams_usage "github.com/MunifTanjim/stremthru/internal/chillstreams/usage"
 	"github.com/MunifTanjim/stremthru/internal/config"
 	"github.com/MunifTanjim/stremthru/internal/db"
 	"github.com/MunifTanjim/stremthru/internal/endpoint"
 	"github.com/MunifTanjim/stremthru/internal/posthog"
 	"github.com/MunifTanjim/stremthru/internal/prowlarr"
 	"github.com/MunifTanjim/stremthru/internal/shared"
-	stremio_userdata "github.com/MunifTanjim/stremthru/internal/stremio/userdata"
+	"github.com/MunifTanjim/stremthru/internal/tracing"
 	"github.com/MunifTanjim/stremthru/internal/worker"
 	"github.com/MunifTanjim/stremthru/store"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -38,10 +42,14 @@ func main() {
 	posthog.Init()
 	defer posthog.Close()
 
+	tracing.Init()
+	defer tracing.Close()
+
 	database := db.Open()
 	defer db.Close()
 	db.Ping()
 	RunSchemaMigration(database.URI, database)
+	defer chillstreams_usage.FlushStreams()
 
 	// Initialize PostgreSQL connection for Chillstreams logging (Prowlarr searches)
 	var loggingDB *sql.DB
@@ -69,27 +77,20 @@ func main() {
 		log.Println("[PROWLARR] ⚠️  CHILLSTREAMS_DATABASE_URL not set - Prowlarr logging DISABLED")
 	}
 
-	// Initialize Prowlarr indexer database for logging
+	// Prowlarr search logs are buffered and written to the database in batches
 	if loggingDB != nil {
-		stremio_userdata.InitializeIndexerDB(loggingDB)
-		log.Println("[PROWLARR] IndexerDB initialized for logging")
-
-		log.Println("[PROWLARR] About to set up Prowlarr logger callback...")
+		prowlarr.InitSearchLog(loggingDB)
+		defer prowlarr.StopSearchLog()
 
 		// Set up Prowlarr logger callback for buddy layer
 		// Now receives indexer name as first parameter for per-indexer tracking
 		buddy.ProwlarrLogger = func(indexerName string, sid string, duration time.Duration, resultsCount int, wasSuccessful bool, errorType, errorMsg string) {
-			log.Printf("[PROWLARR] ⚡ Callback invoked for indexer=%s, sid=%s, results=%d, duration=%dms\n", indexerName, sid, resultsCount, duration.Milliseconds())
-
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-
 			httpStatus := 200
 			if !wasSuccessful {
 				httpStatus = 500
 			}
 
-			err := prowlarr.LogSearchToDatabase(ctx, loggingDB, prowlarr.SearchLogParams{
+			prowlarr.RecordSearch(prowlarr.SearchLogParams{
 				IndexerName:   indexerName, // Use the actual indexer name passed in
 				SearchQuery:   sid,
 				SearchType:    "torrent",
@@ -100,16 +101,8 @@ func main() {
 				ErrorType:     errorType,
 				ErrorMessage:  errorMsg,
 			})
-
-			if err != nil {
-				log.Printf("[PROWLARR] ❌ Failed to log search: %v\n", err)
-			} else {
-				log.Printf("[PROWLARR] ✅ Logged search for %s - %s: %d results in %dms\n", indexerName, sid, resultsCount, duration.Milliseconds())
-			}
 		}
-		log.Println("[PROWLARR] ✅ Callback logger set up for peer pulls")
-	} else {
-		log.Println("[PROWLARR] ⚠️  IndexerDB is nil - logging will not work")
+		log.Println("[PROWLARR] ✅ Search logging set up")
 	}
 
 	stopWorkers := worker.InitWorkers()
@@ -121,12 +114,14 @@ func main() {
 	endpoint.AddDashEndpoint(mux)
 	endpoint.AddAuthEndpoints(mux)
 	endpoint.AddHealthEndpoints(mux)
+	endpoint.AddMetricsEndpoints(mux)
 	endpoint.AddMetaEndpoints(mux)
 	endpoint.AddProxyEndpoints(mux)
 	endpoint.AddStoreEndpoints(mux)
 	endpoint.AddStremioEndpoints(mux)
 	endpoint.AddTorrentEndpoints(mux)
 	endpoint.AddTorznabEndpoints(mux)
+	endpoint.AddChillstreamsEndpoints(mux)
 	endpoint.AddExperimentEndpoints(mux)
 
 	handler := shared.RootServerContext(mux)
@@ -141,8 +136,26 @@ func main() {
 		server.SetKeepAlivesEnabled(false)
 	}
 
+	// the server is shut down on SIGINT/SIGTERM, so that the deferred cleanups
+	// (e.g. flushing the buffered search logs) run before exiting.
+	shutdownCtx, stopShutdown := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopShutdown()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-shutdownCtx.Done()
+		log.Println("stremthru shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("failed to shut down stremthru: %v", err)
+		}
+	}()
+
 	log.Println("stremthru listening on " + addr)
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("failed to start stremthru: %v", err)
 	}
+	<-shutdownDone
 }
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."chillstreams_usage_outbox" (
+  "id" bigserial NOT NULL PRIMARY KEY,
+  "user_id" varchar NOT NULL,
+  "pool_key_id" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "hash" varchar NOT NULL DEFAULT '',
+  "cached" boolean NOT NULL DEFAULT false,
+  "bytes" bigint NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "chillstreams_usage_outbox_idx_status_next_attempt_at" ON "public"."chillstreams_usage_outbox" ("status", "next_attempt_at");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "public"."chillstreams_usage_outbox_idx_status_next_attempt_at";
+DROP TABLE IF EXISTS "public"."chillstreams_usage_outbox";
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."chillstreams_device" (
+  "id" varchar NOT NULL PRIMARY KEY,
+  "user_id" varchar NOT NULL,
+  "label" varchar NOT NULL DEFAULT '',
+  "last_seen_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "revoked_at" timestamptz,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "chillstreams_device_idx_user_id" ON "public"."chillstreams_device" ("user_id");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "public"."chillstreams_device_idx_user_id";
+DROP TABLE IF EXISTS "public"."chillstreams_device";
+-- +goose StatementEnd
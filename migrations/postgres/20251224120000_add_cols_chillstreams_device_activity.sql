@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "public"."chillstreams_device"
+  ADD COLUMN "kind" varchar NOT NULL DEFAULT 'token',
+  ADD COLUMN "last_ip" varchar NOT NULL DEFAULT '',
+  ADD COLUMN "user_agent" varchar NOT NULL DEFAULT '',
+  ADD COLUMN "stream_count" bigint NOT NULL DEFAULT 0,
+  DROP CONSTRAINT "chillstreams_device_pkey",
+  ADD PRIMARY KEY ("user_id", "id");
+
+DROP INDEX IF EXISTS "public"."chillstreams_device_idx_user_id";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM "public"."chillstreams_device" WHERE "kind" != 'token';
+
+ALTER TABLE "public"."chillstreams_device"
+  DROP CONSTRAINT "chillstreams_device_pkey",
+  ADD PRIMARY KEY ("id"),
+  DROP COLUMN "stream_count",
+  DROP COLUMN "user_agent",
+  DROP COLUMN "last_ip",
+  DROP COLUMN "kind";
+
+CREATE INDEX IF NOT EXISTS "chillstreams_device_idx_user_id" ON "public"."chillstreams_device" ("user_id");
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."worker_queue_item" (
+  "queue" varchar NOT NULL,
+  "key" varchar NOT NULL,
+  "group_key" varchar NOT NULL DEFAULT '',
+  "value" text NOT NULL,
+  "version" integer NOT NULL DEFAULT 1,
+  "process_after" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "claimed_by" varchar NOT NULL DEFAULT '',
+  "claimed_until" timestamptz,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("queue", "key")
+);
+
+CREATE INDEX IF NOT EXISTS "worker_queue_item_idx_queue_process_after" ON "public"."worker_queue_item" ("queue", "process_after");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "public"."worker_queue_item_idx_queue_process_after";
+DROP TABLE IF EXISTS "public"."worker_queue_item";
+-- +goose StatementEnd
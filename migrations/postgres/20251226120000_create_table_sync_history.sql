@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."sync_history" (
+  "id" bigserial NOT NULL PRIMARY KEY,
+  "link_type" varchar NOT NULL,
+  "link_id" varchar NOT NULL,
+  "job_name" varchar NOT NULL,
+  "job_id" varchar NOT NULL,
+  "target" varchar NOT NULL,
+  "target_account_id" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "item_id" varchar NOT NULL,
+  "before_data" jsonb,
+  "after_data" jsonb,
+  "reverted_at" timestamptz,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "sync_history_idx_link_type_link_id_cat" ON "public"."sync_history" ("link_type", "link_id", "cat");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "public"."sync_history_idx_link_type_link_id_cat";
+DROP TABLE IF EXISTS "public"."sync_history";
+-- +goose StatementEnd
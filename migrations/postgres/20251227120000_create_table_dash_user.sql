@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."dash_user" (
+  "id" text NOT NULL,
+  "password" text NOT NULL,
+  "role" text NOT NULL,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY ("id")
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "public"."dash_user";
+-- +goose StatementEnd
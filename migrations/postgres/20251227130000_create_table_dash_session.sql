@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."dash_session" (
+  "id" text NOT NULL,
+  "user_id" text NOT NULL,
+  "role" text NOT NULL,
+  "ip" text NOT NULL DEFAULT '',
+  "user_agent" text NOT NULL DEFAULT '',
+  "eat" timestamptz NOT NULL,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "dash_session_idx_user_id" ON "public"."dash_session" ("user_id");
+CREATE INDEX IF NOT EXISTS "dash_session_idx_eat" ON "public"."dash_session" ("eat");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "dash_session_idx_eat";
+DROP INDEX IF EXISTS "dash_session_idx_user_id";
+DROP TABLE IF EXISTS "public"."dash_session";
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."content_proxy_usage" (
+  "username" text NOT NULL,
+  "day" text NOT NULL,
+  "bytes" bigint NOT NULL DEFAULT 0,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "uat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY ("username", "day")
+);
+
+CREATE INDEX IF NOT EXISTS "content_proxy_usage_idx_day" ON "public"."content_proxy_usage" ("day");
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "content_proxy_usage_idx_day";
+DROP TABLE IF EXISTS "public"."content_proxy_usage";
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "public"."content_proxy_connection" (
+  "id" text NOT NULL,
+  "username" text NOT NULL,
+  "instance_id" text NOT NULL,
+  "ip" text NOT NULL DEFAULT '',
+  "link" text NOT NULL DEFAULT '',
+  "bytes" bigint NOT NULL DEFAULT 0,
+  "killed" boolean NOT NULL DEFAULT false,
+  "hat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "cat" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS "content_proxy_connection_idx_username_hat" ON "public"."content_proxy_connection" ("username", "hat");
+CREATE INDEX IF NOT EXISTS "content_proxy_connection_idx_instance_id" ON "public"."content_proxy_connection" ("instance_id");
+
+DELETE FROM "public"."kv" WHERE "t" LIKE 'cproxyconn:%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS "content_proxy_connection_idx_instance_id";
+DROP INDEX IF EXISTS "content_proxy_connection_idx_username_hat";
+DROP TABLE IF EXISTS "public"."content_proxy_connection";
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "public"."chillstreams_usage_outbox"
+  ADD COLUMN "idempotency_key" varchar NOT NULL DEFAULT '',
+  ADD COLUMN "claimed_by" varchar NOT NULL DEFAULT '',
+  ADD COLUMN "claimed_until" timestamptz;
+
+UPDATE "public"."chillstreams_usage_outbox" SET "idempotency_key" = md5(random()::text || "id"::text) WHERE "idempotency_key" = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "public"."chillstreams_usage_outbox"
+  DROP COLUMN "claimed_until",
+  DROP COLUMN "claimed_by",
+  DROP COLUMN "idempotency_key";
+-- +goose StatementEnd
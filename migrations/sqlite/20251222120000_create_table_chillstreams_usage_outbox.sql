@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `chillstreams_usage_outbox` (
+  `id` integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  `user_id` varchar NOT NULL,
+  `pool_key_id` varchar NOT NULL,
+  `action` varchar NOT NULL,
+  `hash` varchar NOT NULL DEFAULT '',
+  `cached` bool NOT NULL DEFAULT false,
+  `bytes` integer NOT NULL DEFAULT 0,
+  `status` varchar NOT NULL DEFAULT 'pending',
+  `attempts` integer NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime NOT NULL DEFAULT (unixepoch()),
+  `last_error` varchar NOT NULL DEFAULT '',
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch())
+);
+
+CREATE INDEX IF NOT EXISTS `chillstreams_usage_outbox_idx_status_next_attempt_at` ON `chillstreams_usage_outbox` (`status`, `next_attempt_at`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `chillstreams_usage_outbox_idx_status_next_attempt_at`;
+DROP TABLE IF EXISTS `chillstreams_usage_outbox`;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `chillstreams_device` (
+  `id` varchar NOT NULL PRIMARY KEY,
+  `user_id` varchar NOT NULL,
+  `label` varchar NOT NULL DEFAULT '',
+  `last_seen_at` datetime NOT NULL DEFAULT (unixepoch()),
+  `revoked_at` datetime,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch())
+);
+
+CREATE INDEX IF NOT EXISTS `chillstreams_device_idx_user_id` ON `chillstreams_device` (`user_id`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `chillstreams_device_idx_user_id`;
+DROP TABLE IF EXISTS `chillstreams_device`;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `chillstreams_device` RENAME TO `chillstreams_device_old`;
+
+DROP INDEX IF EXISTS `chillstreams_device_idx_user_id`;
+
+CREATE TABLE IF NOT EXISTS `chillstreams_device` (
+  `id` varchar NOT NULL,
+  `user_id` varchar NOT NULL,
+  `kind` varchar NOT NULL DEFAULT 'token',
+  `label` varchar NOT NULL DEFAULT '',
+  `last_ip` varchar NOT NULL DEFAULT '',
+  `user_agent` varchar NOT NULL DEFAULT '',
+  `stream_count` integer NOT NULL DEFAULT 0,
+  `last_seen_at` datetime NOT NULL DEFAULT (unixepoch()),
+  `revoked_at` datetime,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch()),
+
+  PRIMARY KEY (`user_id`, `id`)
+);
+
+INSERT INTO `chillstreams_device` (`id`, `user_id`, `label`, `last_seen_at`, `revoked_at`, `cat`, `uat`)
+SELECT `id`, `user_id`, `label`, `last_seen_at`, `revoked_at`, `cat`, `uat` FROM `chillstreams_device_old`;
+
+DROP TABLE `chillstreams_device_old`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `chillstreams_device` RENAME TO `chillstreams_device_old`;
+
+CREATE TABLE IF NOT EXISTS `chillstreams_device` (
+  `id` varchar NOT NULL PRIMARY KEY,
+  `user_id` varchar NOT NULL,
+  `label` varchar NOT NULL DEFAULT '',
+  `last_seen_at` datetime NOT NULL DEFAULT (unixepoch()),
+  `revoked_at` datetime,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch())
+);
+
+INSERT INTO `chillstreams_device` (`id`, `user_id`, `label`, `last_seen_at`, `revoked_at`, `cat`, `uat`)
+SELECT `id`, `user_id`, `label`, `last_seen_at`, `revoked_at`, `cat`, `uat` FROM `chillstreams_device_old` WHERE `kind` = 'token';
+
+DROP TABLE `chillstreams_device_old`;
+
+CREATE INDEX IF NOT EXISTS `chillstreams_device_idx_user_id` ON `chillstreams_device` (`user_id`);
+-- +goose StatementEnd
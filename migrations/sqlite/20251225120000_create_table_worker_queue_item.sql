@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `worker_queue_item` (
+  `queue` varchar NOT NULL,
+  `key` varchar NOT NULL,
+  `group_key` varchar NOT NULL DEFAULT '',
+  `value` varchar NOT NULL,
+  `version` integer NOT NULL DEFAULT 1,
+  `process_after` datetime NOT NULL DEFAULT (unixepoch()),
+  `claimed_by` varchar NOT NULL DEFAULT '',
+  `claimed_until` datetime,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch()),
+  PRIMARY KEY (`queue`, `key`)
+);
+
+CREATE INDEX IF NOT EXISTS `worker_queue_item_idx_queue_process_after` ON `worker_queue_item` (`queue`, `process_after`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `worker_queue_item_idx_queue_process_after`;
+DROP TABLE IF EXISTS `worker_queue_item`;
+-- +goose StatementEnd
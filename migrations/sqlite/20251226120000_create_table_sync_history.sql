@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `sync_history` (
+  `id` integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  `link_type` varchar NOT NULL,
+  `link_id` varchar NOT NULL,
+  `job_name` varchar NOT NULL,
+  `job_id` varchar NOT NULL,
+  `target` varchar NOT NULL,
+  `target_account_id` varchar NOT NULL,
+  `action` varchar NOT NULL,
+  `item_id` varchar NOT NULL,
+  `before_data` json,
+  `after_data` json,
+  `reverted_at` datetime,
+  `cat` datetime NOT NULL DEFAULT (unixepoch())
+);
+
+CREATE INDEX IF NOT EXISTS `sync_history_idx_link_type_link_id_cat` ON `sync_history` (`link_type`, `link_id`, `cat`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `sync_history_idx_link_type_link_id_cat`;
+DROP TABLE IF EXISTS `sync_history`;
+-- +goose StatementEnd
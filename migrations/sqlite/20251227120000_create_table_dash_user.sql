@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `dash_user` (
+  `id` varchar NOT NULL,
+  `password` varchar NOT NULL,
+  `role` varchar NOT NULL,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch()),
+
+  PRIMARY KEY (`id`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS `dash_user`;
+-- +goose StatementEnd
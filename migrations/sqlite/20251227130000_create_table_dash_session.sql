@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `dash_session` (
+  `id` varchar NOT NULL,
+  `user_id` varchar NOT NULL,
+  `role` varchar NOT NULL,
+  `ip` varchar NOT NULL DEFAULT '',
+  `user_agent` varchar NOT NULL DEFAULT '',
+  `eat` datetime NOT NULL,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch()),
+
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `dash_session_idx_user_id` ON `dash_session` (`user_id`);
+CREATE INDEX IF NOT EXISTS `dash_session_idx_eat` ON `dash_session` (`eat`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `dash_session_idx_eat`;
+DROP INDEX IF EXISTS `dash_session_idx_user_id`;
+DROP TABLE IF EXISTS `dash_session`;
+-- +goose StatementEnd
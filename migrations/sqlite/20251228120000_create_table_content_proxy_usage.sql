@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `content_proxy_usage` (
+  `username` varchar NOT NULL,
+  `day` varchar NOT NULL,
+  `bytes` integer NOT NULL DEFAULT 0,
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+  `uat` datetime NOT NULL DEFAULT (unixepoch()),
+
+  PRIMARY KEY (`username`, `day`)
+);
+
+CREATE INDEX IF NOT EXISTS `content_proxy_usage_idx_day` ON `content_proxy_usage` (`day`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `content_proxy_usage_idx_day`;
+DROP TABLE IF EXISTS `content_proxy_usage`;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS `content_proxy_connection` (
+  `id` varchar NOT NULL,
+  `username` varchar NOT NULL,
+  `instance_id` varchar NOT NULL,
+  `ip` varchar NOT NULL DEFAULT '',
+  `link` varchar NOT NULL DEFAULT '',
+  `bytes` integer NOT NULL DEFAULT 0,
+  `killed` bool NOT NULL DEFAULT false,
+  `hat` datetime NOT NULL DEFAULT (unixepoch()),
+  `cat` datetime NOT NULL DEFAULT (unixepoch()),
+
+  PRIMARY KEY (`id`)
+);
+
+CREATE INDEX IF NOT EXISTS `content_proxy_connection_idx_username_hat` ON `content_proxy_connection` (`username`, `hat`);
+CREATE INDEX IF NOT EXISTS `content_proxy_connection_idx_instance_id` ON `content_proxy_connection` (`instance_id`);
+
+DELETE FROM `kv` WHERE `t` LIKE 'cproxyconn:%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS `content_proxy_connection_idx_instance_id`;
+DROP INDEX IF EXISTS `content_proxy_connection_idx_username_hat`;
+DROP TABLE IF EXISTS `content_proxy_connection`;
+-- +goose StatementEnd
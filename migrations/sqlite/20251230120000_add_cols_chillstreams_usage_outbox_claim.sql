@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `chillstreams_usage_outbox` ADD COLUMN `idempotency_key` varchar NOT NULL DEFAULT '';
+ALTER TABLE `chillstreams_usage_outbox` ADD COLUMN `claimed_by` varchar NOT NULL DEFAULT '';
+ALTER TABLE `chillstreams_usage_outbox` ADD COLUMN `claimed_until` datetime;
+
+UPDATE `chillstreams_usage_outbox` SET `idempotency_key` = lower(hex(randomblob(16))) WHERE `idempotency_key` = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `chillstreams_usage_outbox` DROP COLUMN `claimed_until`;
+ALTER TABLE `chillstreams_usage_outbox` DROP COLUMN `claimed_by`;
+ALTER TABLE `chillstreams_usage_outbox` DROP COLUMN `idempotency_key`;
+-- +goose StatementEnd
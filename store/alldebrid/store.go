@@ -3,6 +3,7 @@ package alldebrid
 import (
 	"mime/multipart"
 	"net/http"
+	"regexp"
 	"strconv"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Za-z0-9]{20}$`)
+
 type StoreClientConfig struct {
 	HTTPClient *http.Client
 	UserAgent  string
@@ -56,6 +59,13 @@ func (c *StoreClient) GetName() store.StoreName {
 	return c.Name
 }
 
+func (c *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(c.GetName())
+	}
+	return nil
+}
+
 func (c *StoreClient) GetUser(params *store.GetUserParams) (*store.User, error) {
 	res, err := c.client.GetUser(&GetUserParams{
 		Ctx: params.Ctx,
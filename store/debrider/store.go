@@ -2,6 +2,7 @@ package debrider
 
 import (
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
 func getMagnetStatusFromTaskStatus(status TaskStatus) store.MagnetStatus {
 	switch status {
 	case TaskStatusError:
@@ -45,6 +48,13 @@ func (s *StoreClient) GetName() store.StoreName {
 	return s.Name
 }
 
+func (s *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(s.GetName())
+	}
+	return nil
+}
+
 type LockedFileLink string
 
 const lockedFileLinkPrefix = "stremthru://store/debrider/"
@@ -3,6 +3,7 @@ package debridlink
 import (
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
 type StoreClientConfig struct {
 	HTTPClient *http.Client
 	UserAgent  string
@@ -44,6 +47,13 @@ func (c *StoreClient) GetName() store.StoreName {
 	return c.Name
 }
 
+func (c *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(c.GetName())
+	}
+	return nil
+}
+
 func (c *StoreClient) GetUser(params *store.GetUserParams) (*store.User, error) {
 	res, err := c.client.GetAccountInfo(&GetAccountInfoParams{
 		Ctx: params.Ctx,
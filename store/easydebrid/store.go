@@ -2,6 +2,7 @@ package easydebrid
 
 import (
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +14,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
 type StoreClientConfig struct {
 	HTTPClient *http.Client
 	UserAgent  string
@@ -38,6 +41,13 @@ func (s *StoreClient) GetName() store.StoreName {
 	return s.Name
 }
 
+func (s *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(s.GetName())
+	}
+	return nil
+}
+
 type LockedFileLink string
 
 const lockedFileLinkPrefix = "stremthru://store/easydebrid/"
@@ -8,3 +8,10 @@ var ErrorInvalidStoreName = func(name string) *core.StoreError {
 	err.StoreName = name
 	return err
 }
+
+var ErrorInvalidPoolKey = func(name StoreName) *core.StoreError {
+	err := core.NewStoreError("invalid pool key format")
+	err.Code = core.ErrorCodeUnauthorized
+	err.StoreName = string(name)
+	return err
+}
@@ -48,6 +48,14 @@ func (s *StoreClient) GetName() store.StoreName {
 	return s.Name
 }
 
+func (s *StoreClient) ValidatePoolKey(poolKey string) error {
+	email, password := parseCredential(poolKey)
+	if !strings.Contains(email, "@") || password == "" {
+		return store.ErrorInvalidPoolKey(s.GetName())
+	}
+	return nil
+}
+
 func (s *StoreClient) getMagnetFiles(ctx Ctx, requestId string, server string) ([]store.MagnetFile, string, error) {
 	magnetName := ""
 	files := []store.MagnetFile{}
@@ -60,6 +60,13 @@ func (s *StoreClient) GetName() store.StoreName {
 	return s.Name
 }
 
+func (s *StoreClient) ValidatePoolKey(poolKey string) error {
+	if username, password, ok := strings.Cut(poolKey, ":"); !ok || username == "" || password == "" {
+		return store.ErrorInvalidPoolKey(s.GetName())
+	}
+	return nil
+}
+
 func (s *StoreClient) getRecentTask(ctx Ctx, taskId string) (*Task, error) {
 	res, err := s.client.ListTasks(&ListTasksParams{
 		Ctx:   ctx,
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"path"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
@@ -20,6 +21,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Za-z0-9]{16}$`)
+
 type StoreClientConfig struct {
 	HTTPClient       *http.Client
 	UserAgent        string
@@ -163,6 +166,13 @@ func (c *StoreClient) GetName() store.StoreName {
 	return c.Name
 }
 
+func (c *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(c.GetName())
+	}
+	return nil
+}
+
 func (c *StoreClient) GetUser(params *store.GetUserParams) (*store.User, error) {
 	res, err := c.client.GetAccountInfo(&GetAccountInfoParams{
 		Ctx: params.Ctx,
@@ -3,6 +3,7 @@ package realdebrid
 import (
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
@@ -15,6 +16,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[A-Z0-9]{52}$`)
+
 func torrentStatusToMagnetStatus(status TorrentStatus) store.MagnetStatus {
 	switch status {
 	case TorrentStatusMagnetError:
@@ -132,6 +135,13 @@ func (c *StoreClient) GetName() store.StoreName {
 	return c.Name
 }
 
+func (c *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(c.GetName())
+	}
+	return nil
+}
+
 func (c *StoreClient) GetUser(params *store.GetUserParams) (*store.User, error) {
 	res, err := c.client.GetUser(&GetUserParams{
 		Ctx: params.Ctx,
@@ -123,6 +123,8 @@ type MagnetFile struct {
 	Size      int64  `json:"size"`
 	VideoHash string `json:"video_hash,omitempty"`
 	Source    string `json:"source,omitempty"`
+	SId       string `json:"sid,omitempty"`
+	ASId      string `json:"asid,omitempty"`
 }
 
 type MagnetStatus string
@@ -278,3 +280,10 @@ type Store interface {
 	RemoveMagnet(params *RemoveMagnetParams) (*RemoveMagnetData, error)
 	GenerateLink(params *GenerateLinkParams) (*GenerateLinkData, error)
 }
+
+// PoolKeyStore is implemented by stores that can authenticate with a shared
+// pool key (e.g. one assigned by Chillstreams) in place of the user's token.
+type PoolKeyStore interface {
+	Store
+	ValidatePoolKey(poolKey string) error
+}
@@ -56,26 +56,8 @@ func NewAPIClient(conf *APIClientConfig) *APIClient {
 	c.reqQuery = func(query *url.Values, params request.Context) {}
 
 	c.reqHeader = func(header *http.Header, params request.Context) {
-		// TorBox API expects "Bearer <api_key>" format
-		apiKey := params.GetAPIKey(c.apiKey)
-		authValue := "Bearer " + apiKey
-		header.Add("Authorization", authValue)
+		header.Add("Authorization", "Bearer "+params.GetAPIKey(c.apiKey))
 		header.Add("User-Agent", c.agent)
-
-		// DEBUG: Log what's being sent (first 50 chars only for security)
-		truncated := authValue
-		if len(authValue) > 50 {
-			truncated = authValue[:50] + "..."
-		}
-		// Note: Using fmt.Println since we don't have logger here
-		// This will show in Docker logs
-		if len(apiKey) == 0 {
-			println("🚨 TORBOX: Empty API key!")
-		} else if len(apiKey) != 36 {
-			println("🚨 TORBOX: Invalid API key length:", len(apiKey), "expected 36")
-		} else {
-			println("✅ TORBOX: Authorization header set:", truncated)
-		}
 	}
 
 	return c
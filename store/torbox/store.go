@@ -3,6 +3,7 @@ package torbox
 import (
 	"errors"
 	"net/http"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -16,6 +17,8 @@ import (
 	"github.com/MunifTanjim/stremthru/store"
 )
 
+var poolKeyRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 type StoreClientConfig struct {
 	HTTPClient *http.Client
 	UserAgent  string
@@ -65,14 +68,11 @@ func (c *StoreClient) GetName() store.StoreName {
 	return c.Name
 }
 
-// SetAPIKey sets the API key dynamically (for Chillstreams pool key injection)
-func (c *StoreClient) SetAPIKey(apiKey string) {
-	c.client.apiKey = apiKey
-}
-
-// GetAPIKey returns the current API key (for validation/logging)
-func (c *StoreClient) GetAPIKey() string {
-	return c.client.apiKey
+func (c *StoreClient) ValidatePoolKey(poolKey string) error {
+	if !poolKeyRegex.MatchString(poolKey) {
+		return store.ErrorInvalidPoolKey(c.GetName())
+	}
+	return nil
 }
 
 func (c *StoreClient) getCachedGetUser(params *store.GetUserParams) *store.User {